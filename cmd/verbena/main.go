@@ -14,10 +14,18 @@ import (
 
 	"github.com/1f349/mjwt"
 	"github.com/1f349/verbena/conf"
+	"github.com/1f349/verbena/internal/alias"
+	"github.com/1f349/verbena/internal/backend"
 	"github.com/1f349/verbena/internal/builder"
 	"github.com/1f349/verbena/internal/committer"
 	"github.com/1f349/verbena/internal/database"
+	"github.com/1f349/verbena/internal/delegation"
+	"github.com/1f349/verbena/internal/health"
+	"github.com/1f349/verbena/internal/notify"
+	"github.com/1f349/verbena/internal/propagation"
 	"github.com/1f349/verbena/internal/routes"
+	"github.com/1f349/verbena/internal/webhook"
+	"github.com/1f349/verbena/internal/zonetemplate"
 	"github.com/1f349/verbena/logger"
 	"github.com/charmbracelet/log"
 	"github.com/cloudflare/tableflip"
@@ -148,20 +156,67 @@ func main() {
 		// TODO: maybe some cluster info too
 	})
 
-	zoneBuilder, err := builder.New(db, time.Duration(config.GeneratorTick), zonesPath, config.BindGenConf, config.Nameservers, config.Cmd)
+	dnsBackend, err := backend.New(config.Backend, config.Cmd, config.Views)
+	if err != nil {
+		logger.Logger.Fatal("Failed to initialise DNS backend", "err", err)
+	}
+
+	events := webhook.New(db, config.AllowPrivateTargets)
+
+	templates, err := zonetemplate.Load(config.Templates)
+	if err != nil {
+		logger.Logger.Fatal("Failed to load zone templates", "err", err)
+	}
+
+	aliases, err := alias.New(config.Alias)
+	if err != nil {
+		logger.Logger.Fatal("Failed to initialise ALIAS resolver", "err", err)
+	}
+	aliases.Start()
+
+	zoneBuilder, err := builder.New(db, time.Duration(config.GeneratorTick), zonesPath, config.BindGenConf, config.Nameservers, dnsBackend, events, aliases, config.Views, config.GeoIP.Database)
 	if err != nil {
 		logger.Logger.Fatal("Failed to initialise zone builder", "err", err)
 	}
-	zoneBuilder.Start()
 
-	commit := committer.New(db, time.Duration(config.CommitterTick), config.Primary, zoneBuilder, config.Cmd)
+	notifier := notify.New(config.Notify)
+	tracker := propagation.New(config.Nameservers)
+
+	commit := committer.New(db, time.Duration(config.CommitterTick), config.Primary, zoneBuilder, dnsBackend, notifier, tracker, events)
 	commit.Start()
 
+	// Only the primary bumps serials when pools rotate or ALIAS targets change
+	if config.Primary {
+		zoneBuilder.SetPublisher(commit)
+	}
+	zoneBuilder.Start()
+
+	// DS records only need syncing on the primary as they are staged changes,
+	// failovers are published like commits so health checks also only run here
+	if config.Primary {
+		delegation.NewSyncer(db, time.Duration(config.GeneratorTick), config.Nameservers).Start()
+		health.New(db, time.Duration(config.HealthCheckTick), commit, events, config.AllowPrivateTargets).Start()
+	}
+
 	// Add routes
 	routes.AddZoneRoutes(r, db, apiKeystore, config.Nameservers)
-	routes.AddRecordRoutes(r, db, apiKeystore, config.Nameservers)
-	routes.AddZoneFileRoutes(r, db, apiKeystore, zoneBuilder.Preview)
+	routes.AddRecordRoutes(r, db, apiKeystore, config.Nameservers, config.Views, tracker, events)
+	routes.AddFailoverRoutes(r, db, apiKeystore, commit, config.AllowPrivateTargets)
+	routes.AddPoolRoutes(r, db, apiKeystore, commit)
+	routes.AddZoneFileRoutes(r, db, apiKeystore, config.Views, zoneBuilder.Preview)
+	routes.AddNotifyRoutes(r, db, apiKeystore, notifier.Status)
+	routes.AddPropagationRoutes(r, db, apiKeystore, tracker)
+	routes.AddWebhookRoutes(r, db, apiKeystore, config.AllowPrivateTargets)
+	routes.AddTemplateRoutes(r, db, apiKeystore, templates, events)
+	routes.AddDelegationRoutes(r, db, apiKeystore, events)
+	routes.AddRRsetRoutes(r, db, apiKeystore, events)
+	routes.AddGeoRoutes(r, db, apiKeystore, config.Views, events)
+	routes.AddBatchRoutes(r, db, apiKeystore, config.Views, events)
+	routes.AddLintRoutes(r, db, apiKeystore)
+	routes.AddMailAuthRoutes(r, db, apiKeystore, events)
+	routes.AddReverseRoutes(r, db, apiKeystore, config.Nameservers, events)
 	routes.AddAuthRoutes(r, db, apiKeystore, apiIssuer)
+	routes.AddOpenAPIRoutes(r)
 
 	serverApi := &http.Server{
 		Handler:           r,
@@ -2,9 +2,13 @@ package conf
 
 import (
 	"fmt"
+	"net/netip"
+	"regexp"
 	"slices"
+	"strings"
 
 	"github.com/1f349/verbena/internal/database"
+	"github.com/1f349/verbena/internal/geoip"
 	"github.com/1f349/verbena/internal/utils"
 	"gopkg.in/yaml.v3"
 )
@@ -15,17 +19,39 @@ type Conf struct {
 	Nameservers   NameserverConf     `yaml:"nameservers"`
 	ZonePath      string             `yaml:"zonePath"`
 	BindGenConf   string             `yaml:"bindGenConf"`
+	Backend       string             `yaml:"backend"`
 	GeneratorTick utils.DurationText `yaml:"generatorTick"`
 	Primary       bool               `yaml:"primary"`
 	CommitterTick utils.DurationText `yaml:"committerTick"`
 	TokenIssuer   string             `yaml:"tokenIssuer"`
 	Cmd           CmdConf            `yaml:"cmd"`
+	Notify        NotifyConf         `yaml:"notify"`
+	Alias         AliasConf          `yaml:"alias"`
+	Views         ViewsConf          `yaml:"views"`
+	GeoIP         GeoIPConf          `yaml:"geoip"`
+
+	// HealthCheckTick is how often failover health checks are considered,
+	// each check runs at its own interval, defaults to 10 seconds
+	HealthCheckTick utils.DurationText `yaml:"healthCheckTick"`
+
+	// AllowPrivateTargets lets health checks and webhooks connect to loopback,
+	// private and link-local addresses, these are refused by default
+	AllowPrivateTargets bool `yaml:"allowPrivateTargets"`
+
+	// Templates maps a template name to a list of records in the same format
+	// as rest.CreateRecord, string values may contain {{variable}} placeholders
+	Templates map[string][]map[string]any `yaml:"templates"`
 }
 
 type CmdConf struct {
-	Rndc      string `yaml:"rndc"`
-	CheckConf string `yaml:"checkconf"`
-	CheckZone string `yaml:"checkzone"`
+	Rndc         string `yaml:"rndc"`
+	CheckConf    string `yaml:"checkconf"`
+	CheckZone    string `yaml:"checkzone"`
+	Knotc        string `yaml:"knotc"`
+	KZoneCheck   string `yaml:"kzonecheck"`
+	NsdControl   string `yaml:"nsdcontrol"`
+	NsdCheckZone string `yaml:"nsdcheckzone"`
+	PdnsControl  string `yaml:"pdnscontrol"`
 }
 
 func (c *CmdConf) LoadDefaults() {
@@ -38,6 +64,161 @@ func (c *CmdConf) LoadDefaults() {
 	if c.CheckZone == "" {
 		c.CheckZone = "/usr/bin/named-checkzone"
 	}
+	if c.Knotc == "" {
+		c.Knotc = "/usr/sbin/knotc"
+	}
+	if c.KZoneCheck == "" {
+		c.KZoneCheck = "/usr/bin/kzonecheck"
+	}
+	if c.NsdControl == "" {
+		c.NsdControl = "/usr/sbin/nsd-control"
+	}
+	if c.NsdCheckZone == "" {
+		c.NsdCheckZone = "/usr/sbin/nsd-checkzone"
+	}
+	if c.PdnsControl == "" {
+		c.PdnsControl = "/usr/bin/pdns_control"
+	}
+}
+
+type NotifyConf struct {
+	// Default is the list of secondaries notified for zones missing from Zones
+	Default []string            `yaml:"default"`
+	Zones   map[string][]string `yaml:"zones"`
+}
+
+func (n *NotifyConf) GetSecondariesForZone(zoneName string) []string {
+	if s, ok := n.Zones[zoneName]; ok {
+		return s
+	}
+	return n.Default
+}
+
+type AliasConf struct {
+	// Resolvers are the recursive resolvers used to flatten ALIAS records, the
+	// system resolvers from /etc/resolv.conf are used when this is empty
+	Resolvers []string `yaml:"resolvers"`
+}
+
+type GeoIPConf struct {
+	// Database is the path of a MaxMind country or city MMDB file, the
+	// networks of each geo view are read from it into a BIND ACL file
+	Database string `yaml:"database"`
+}
+
+// ViewConf is a split-horizon view, clients matching the MatchClients list
+// are answered from the zone files generated for this view
+type ViewConf struct {
+	Name string `yaml:"name"`
+
+	// MatchClients contains addresses, prefixes or the BIND builtin lists
+	// any, none, localhost and localnets, each may be negated with "!"
+	MatchClients []string `yaml:"matchClients"`
+
+	// Geo lists regions in the form "country:GB" or "continent:EU", clients
+	// located in any of the regions by the GeoIP database match this view
+	Geo []string `yaml:"geo"`
+}
+
+var validViewName = regexp.MustCompile(`^[a-z0-9][a-z0-9-]*$`)
+
+func (v ViewConf) validate() error {
+	if !validViewName.MatchString(v.Name) {
+		return fmt.Errorf("invalid view name %q", v.Name)
+	}
+	if len(v.MatchClients) < 1 && len(v.Geo) < 1 {
+		return fmt.Errorf("view %s requires at least one match-clients entry or geo region", v.Name)
+	}
+	for _, i := range v.Geo {
+		if _, err := geoip.ParseRegion(i); err != nil {
+			return fmt.Errorf("%w in view %s", err, v.Name)
+		}
+	}
+	for _, i := range v.MatchClients {
+		client := strings.TrimPrefix(i, "!")
+		switch client {
+		case "any", "none", "localhost", "localnets":
+			continue
+		}
+		if _, err := netip.ParsePrefix(client); err == nil {
+			continue
+		}
+		if _, err := netip.ParseAddr(client); err == nil {
+			continue
+		}
+		return fmt.Errorf("invalid match-clients entry %q in view %s", i, v.Name)
+	}
+	return nil
+}
+
+// ViewsConf is the ordered list of views, BIND uses the first view matching
+// the client so more specific views should be listed first
+type ViewsConf []ViewConf
+
+var _ yaml.Unmarshaler = (*ViewsConf)(nil)
+
+func (v *ViewsConf) UnmarshalYAML(bytes *yaml.Node) error {
+	var slice []ViewConf
+	err := bytes.Decode(&slice)
+	if err != nil {
+		return err
+	}
+	seen := make(map[string]struct{}, len(slice))
+	for _, i := range slice {
+		err := i.validate()
+		if err != nil {
+			return err
+		}
+		if _, ok := seen[i.Name]; ok {
+			return fmt.Errorf("duplicate view %s", i.Name)
+		}
+		seen[i.Name] = struct{}{}
+	}
+	*v = slice
+	return nil
+}
+
+// Names returns the view names in config order
+func (v ViewsConf) Names() []string {
+	names := make([]string, 0, len(v))
+	for _, i := range v {
+		names = append(names, i.Name)
+	}
+	return names
+}
+
+// GeoRegions returns the parsed geo regions of each view which has any
+func (v ViewsConf) GeoRegions() map[string][]geoip.Region {
+	out := make(map[string][]geoip.Region)
+	for _, i := range v {
+		for _, j := range i.Geo {
+			// regions are checked when the config is loaded
+			region, err := geoip.ParseRegion(j)
+			if err != nil {
+				continue
+			}
+			out[i.Name] = append(out[i.Name], region)
+		}
+	}
+	return out
+}
+
+// Normalize lowercases, sorts and removes duplicates from a list of view names
+// and checks every view exists
+func (v ViewsConf) Normalize(views []string) ([]string, error) {
+	if len(views) == 0 {
+		return nil, nil
+	}
+	out := make([]string, 0, len(views))
+	for _, i := range views {
+		name := strings.ToLower(strings.TrimSpace(i))
+		if !slices.ContainsFunc(v, func(view ViewConf) bool { return view.Name == name }) {
+			return nil, fmt.Errorf("unknown view %q", i)
+		}
+		out = append(out, name)
+	}
+	slices.Sort(out)
+	return slices.Compact(out), nil
 }
 
 type NameserverConf struct {
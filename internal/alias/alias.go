@@ -0,0 +1,237 @@
+package alias
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/1f349/verbena/conf"
+	"github.com/1f349/verbena/logger"
+	"github.com/miekg/dns"
+)
+
+const (
+	defaultRefreshInterval = 30 * time.Second
+
+	// minTtl stops targets with tiny TTLs from being resolved constantly
+	minTtl = 30 * time.Second
+
+	// unusedTimeout is how long a target stays cached after the last lookup,
+	// this stops removed ALIAS records being refreshed forever
+	unusedTimeout = 24 * time.Hour
+)
+
+// Answer is the flattened result of resolving an ALIAS target.
+type Answer struct {
+	A    []netip.Addr
+	AAAA []netip.Addr
+	Ttl  uint32
+}
+
+type entry struct {
+	answer   Answer
+	expires  time.Time
+	lastUsed time.Time
+}
+
+// Resolver resolves the targets of ALIAS records into addresses. Answers are
+// cached for the TTL of the target and refreshed in the background, the last
+// good answer is kept when the target fails to resolve.
+type Resolver struct {
+	resolvers       []string
+	client          *dns.Client
+	refreshInterval time.Duration
+
+	mu    sync.Mutex
+	cache map[string]*entry
+}
+
+func New(aliasConf conf.AliasConf) (*Resolver, error) {
+	resolvers := make([]string, 0, len(aliasConf.Resolvers))
+	for _, i := range aliasConf.Resolvers {
+		resolvers = append(resolvers, resolverAddr(i))
+	}
+	if len(resolvers) == 0 {
+		clientConf, err := dns.ClientConfigFromFile("/etc/resolv.conf")
+		if err != nil {
+			return nil, fmt.Errorf("failed to load system resolvers: %w", err)
+		}
+		for _, i := range clientConf.Servers {
+			resolvers = append(resolvers, net.JoinHostPort(i, clientConf.Port))
+		}
+	}
+	if len(resolvers) == 0 {
+		return nil, errors.New("no resolvers are configured")
+	}
+
+	return &Resolver{
+		resolvers:       resolvers,
+		client:          &dns.Client{Net: "udp", Timeout: 5 * time.Second},
+		refreshInterval: defaultRefreshInterval,
+		cache:           make(map[string]*entry),
+	}, nil
+}
+
+func (r *Resolver) Start() {
+	go r.internalTicker()
+}
+
+func (r *Resolver) internalTicker() {
+	t := time.NewTicker(r.refreshInterval)
+	for range t.C {
+		r.refresh()
+	}
+}
+
+// refresh resolves the cached targets which have expired
+func (r *Resolver) refresh() {
+	now := time.Now()
+	var targets []string
+	r.mu.Lock()
+	for target, e := range r.cache {
+		if now.Sub(e.lastUsed) > unusedTimeout {
+			delete(r.cache, target)
+			continue
+		}
+		if now.After(e.expires) {
+			targets = append(targets, target)
+		}
+	}
+	r.mu.Unlock()
+
+	for _, target := range targets {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		answer, err := r.resolve(ctx, target)
+		cancel()
+		if err != nil {
+			logger.Logger.Warn("Failed to refresh ALIAS target, keeping the last good answer", "target", target, "err", err)
+			continue
+		}
+		r.store(target, answer, false)
+	}
+}
+
+// Lookup returns the addresses of the target, a cached answer is used until the
+// TTL expires. If resolving fails the last good answer is returned instead.
+func (r *Resolver) Lookup(ctx context.Context, target string) (Answer, error) {
+	target = dns.Fqdn(strings.ToLower(target))
+
+	r.mu.Lock()
+	e, ok := r.cache[target]
+	if ok {
+		e.lastUsed = time.Now()
+		if time.Now().Before(e.expires) {
+			answer := e.answer
+			r.mu.Unlock()
+			return answer, nil
+		}
+	}
+	r.mu.Unlock()
+
+	answer, err := r.resolve(ctx, target)
+	if err != nil {
+		if ok {
+			logger.Logger.Warn("Failed to resolve ALIAS target, using the last good answer", "target", target, "err", err)
+			r.mu.Lock()
+			answer = e.answer
+			r.mu.Unlock()
+			return answer, nil
+		}
+		return Answer{}, err
+	}
+	r.store(target, answer, true)
+	return answer, nil
+}
+
+func (r *Resolver) store(target string, answer Answer, used bool) {
+	now := time.Now()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	e, ok := r.cache[target]
+	if !ok {
+		e = &entry{lastUsed: now}
+		r.cache[target] = e
+	}
+	if used {
+		e.lastUsed = now
+	}
+	e.expires = now.Add(max(time.Duration(answer.Ttl)*time.Second, minTtl))
+	// recursive resolvers count the TTL down, keeping the first TTL while the
+	// addresses are unchanged stops each refresh republishing the zone
+	if ok && slices.Equal(e.answer.A, answer.A) && slices.Equal(e.answer.AAAA, answer.AAAA) {
+		answer.Ttl = e.answer.Ttl
+	}
+	e.answer = answer
+}
+
+// resolve queries the A and AAAA records of the target, the TTL of the answer is
+// the lowest TTL in either response including any CNAME records followed
+func (r *Resolver) resolve(ctx context.Context, target string) (Answer, error) {
+	var answer Answer
+	first := true
+	for _, qtype := range []uint16{dns.TypeA, dns.TypeAAAA} {
+		resp, err := r.exchange(ctx, target, qtype)
+		if err != nil {
+			return Answer{}, err
+		}
+		for _, rr := range resp.Answer {
+			if first || rr.Header().Ttl < answer.Ttl {
+				answer.Ttl = rr.Header().Ttl
+				first = false
+			}
+			switch rr := rr.(type) {
+			case *dns.A:
+				if addr, ok := netip.AddrFromSlice(rr.A.To4()); ok {
+					answer.A = append(answer.A, addr)
+				}
+			case *dns.AAAA:
+				if addr, ok := netip.AddrFromSlice(rr.AAAA); ok {
+					answer.AAAA = append(answer.AAAA, addr)
+				}
+			}
+		}
+	}
+	if len(answer.A) == 0 && len(answer.AAAA) == 0 {
+		return Answer{}, fmt.Errorf("no addresses found for %s", target)
+	}
+	// resolvers rotate round robin answers, sorting keeps the generated zone
+	// the same when only the order changed
+	slices.SortFunc(answer.A, netip.Addr.Compare)
+	slices.SortFunc(answer.AAAA, netip.Addr.Compare)
+	return answer, nil
+}
+
+// exchange sends the query to each resolver in order until one answers
+func (r *Resolver) exchange(ctx context.Context, target string, qtype uint16) (*dns.Msg, error) {
+	m := new(dns.Msg)
+	m.SetQuestion(target, qtype)
+	m.RecursionDesired = true
+
+	var err error
+	for _, resolver := range r.resolvers {
+		var resp *dns.Msg
+		resp, _, err = r.client.ExchangeContext(ctx, m, resolver)
+		if err != nil {
+			continue
+		}
+		if resp.Rcode != dns.RcodeSuccess {
+			err = fmt.Errorf("resolver %s responded with %s", resolver, dns.RcodeToString[resp.Rcode])
+			continue
+		}
+		return resp, nil
+	}
+	return nil, err
+}
+
+func resolverAddr(resolver string) string {
+	if _, _, err := net.SplitHostPort(resolver); err == nil {
+		return resolver
+	}
+	return net.JoinHostPort(resolver, "53")
+}
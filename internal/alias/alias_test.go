@@ -0,0 +1,141 @@
+package alias
+
+import (
+	"net"
+	"net/netip"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/1f349/verbena/conf"
+	"github.com/miekg/dns"
+)
+
+func startResolver(t *testing.T, handler dns.HandlerFunc) string {
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	started := make(chan struct{})
+	srv := &dns.Server{PacketConn: pc, Handler: handler, NotifyStartedFunc: func() { close(started) }}
+	go srv.ActivateAndServe()
+	<-started
+	t.Cleanup(func() { _ = srv.Shutdown() })
+	return pc.LocalAddr().String()
+}
+
+func TestResolver_Lookup(t *testing.T) {
+	var queries atomic.Int32
+	var failing atomic.Bool
+	var address atomic.Value
+	address.Store("192.0.2.10")
+
+	addr := startResolver(t, func(w dns.ResponseWriter, r *dns.Msg) {
+		queries.Add(1)
+		m := new(dns.Msg)
+		m.SetReply(r)
+		if failing.Load() || r.Question[0].Name != "cdn.example.net." {
+			m.Rcode = dns.RcodeServerFailure
+			_ = w.WriteMsg(m)
+			return
+		}
+		cname, _ := dns.NewRR("cdn.example.net. 3600 IN CNAME edge.example.net.")
+		m.Answer = append(m.Answer, cname)
+		switch r.Question[0].Qtype {
+		case dns.TypeA:
+			rr, _ := dns.NewRR("edge.example.net. 300 IN A " + address.Load().(string))
+			m.Answer = append(m.Answer, rr)
+		case dns.TypeAAAA:
+			rr, _ := dns.NewRR("edge.example.net. 120 IN AAAA 2001:db8::10")
+			m.Answer = append(m.Answer, rr)
+		}
+		_ = w.WriteMsg(m)
+	})
+
+	r, err := New(conf.AliasConf{Resolvers: []string{addr}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	answer, err := r.Lookup(t.Context(), "CDN.example.net")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(answer.A) != 1 || answer.A[0] != netip.MustParseAddr("192.0.2.10") || len(answer.AAAA) != 1 || answer.AAAA[0] != netip.MustParseAddr("2001:db8::10") {
+		t.Fatal("unexpected answer", answer)
+	}
+	if answer.Ttl != 120 {
+		t.Fatal("expected the lowest TTL in the answer", answer.Ttl)
+	}
+
+	// cached until the TTL expires
+	_, err = r.Lookup(t.Context(), "cdn.example.net.")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if queries.Load() != 2 {
+		t.Fatal("expected the cached answer to be used", queries.Load())
+	}
+
+	// the background refresh picks up the new address once expired
+	address.Store("192.0.2.20")
+	r.cache["cdn.example.net."].expires = time.Now().Add(-time.Second)
+	r.refresh()
+	answer, err = r.Lookup(t.Context(), "cdn.example.net")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if answer.A[0] != netip.MustParseAddr("192.0.2.20") {
+		t.Fatal("expected the refreshed answer", answer)
+	}
+
+	// the last good answer is kept when the target fails to resolve
+	failing.Store(true)
+	r.cache["cdn.example.net."].expires = time.Now().Add(-time.Second)
+	answer, err = r.Lookup(t.Context(), "cdn.example.net")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if answer.A[0] != netip.MustParseAddr("192.0.2.20") {
+		t.Fatal("expected the last good answer", answer)
+	}
+
+	// targets without a previous answer fail
+	_, err = r.Lookup(t.Context(), "missing.example.net")
+	if err == nil {
+		t.Fatal("expected an error for a target without a previous answer")
+	}
+}
+
+func TestResolver_store(t *testing.T) {
+	r, err := New(conf.AliasConf{Resolvers: []string{"127.0.0.1"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := []netip.Addr{netip.MustParseAddr("192.0.2.10")}
+
+	// a lower TTL for the same addresses keeps the published TTL
+	r.store("cdn.example.net.", Answer{A: a, Ttl: 300}, true)
+	r.store("cdn.example.net.", Answer{A: a, Ttl: 240}, false)
+	if r.cache["cdn.example.net."].answer.Ttl != 300 {
+		t.Fatal("expected the TTL to be kept", r.cache["cdn.example.net."].answer.Ttl)
+	}
+
+	// new addresses use the new TTL
+	r.store("cdn.example.net.", Answer{A: []netip.Addr{netip.MustParseAddr("192.0.2.20")}, Ttl: 240}, false)
+	if r.cache["cdn.example.net."].answer.Ttl != 240 {
+		t.Fatal("expected the new TTL", r.cache["cdn.example.net."].answer.Ttl)
+	}
+}
+
+func TestResolverAddr(t *testing.T) {
+	if resolverAddr("192.0.2.53") != "192.0.2.53:53" {
+		t.Fatal("expected the default port")
+	}
+	if resolverAddr("192.0.2.53:5353") != "192.0.2.53:5353" {
+		t.Fatal("expected the port to be kept")
+	}
+	if resolverAddr("2001:db8::53") != "[2001:db8::53]:53" {
+		t.Fatal("expected the IPv6 address to be bracketed")
+	}
+}
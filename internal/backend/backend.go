@@ -0,0 +1,81 @@
+package backend
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os/exec"
+
+	"github.com/1f349/verbena/conf"
+	"github.com/1f349/verbena/logger"
+	"github.com/charmbracelet/log"
+)
+
+// Backend abstracts the DNS server software which serves the generated zone
+// files.
+type Backend interface {
+	// CheckZone validates the zone file at path before it is loaded.
+	CheckZone(ctx context.Context, zoneName string, path string) error
+
+	// WriteConfig writes the server config which lists every loaded zone.
+	WriteConfig(w io.Writer, zonesPath string, zones []string) error
+
+	// Reload reloads the server config and all zones.
+	Reload(ctx context.Context) error
+
+	// ReloadZone reloads a single zone.
+	ReloadZone(ctx context.Context, zoneName string) error
+
+	// Notify sends NOTIFY messages for the zone to the secondaries.
+	Notify(ctx context.Context, zoneName string) error
+}
+
+const (
+	Bind     = "bind"
+	Knot     = "knot"
+	Nsd      = "nsd"
+	PowerDns = "powerdns"
+)
+
+// New returns the named backend, only BIND supports split-horizon views so an
+// error is returned when views are configured for the other backends
+func New(name string, cmd conf.CmdConf, views conf.ViewsConf) (Backend, error) {
+	if len(views) > 0 && name != "" && name != Bind {
+		return nil, fmt.Errorf("the %s backend does not support views", name)
+	}
+	switch name {
+	case "", Bind:
+		return &bindBackend{cmd: cmd, views: views}, nil
+	case Knot:
+		return &knotBackend{cmd: cmd}, nil
+	case Nsd:
+		return &nsdBackend{cmd: cmd}, nil
+	case PowerDns:
+		return &powerDnsBackend{cmd: cmd}, nil
+	default:
+		return nil, fmt.Errorf("unknown backend: %s", name)
+	}
+}
+
+func runCheckCmd(name string, cmd *exec.Cmd) error {
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		if logger.Logger.GetLevel() >= log.DebugLevel {
+			err = fmt.Errorf("%s failed with output: %w: %s", name, err, string(out))
+		}
+		return err
+	}
+	return nil
+}
+
+func runCmdDebugLog(title string, cmd *exec.Cmd) error {
+	if logger.Logger.GetLevel() > log.DebugLevel {
+		return cmd.Run()
+	}
+
+	raw, err := cmd.CombinedOutput()
+	if err != nil {
+		logger.Logger.Debug(title, "cmd", cmd.Args, "err", err, "raw", string(raw))
+	}
+	return err
+}
@@ -0,0 +1,110 @@
+package backend
+
+import (
+	"bytes"
+	_ "embed"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/1f349/verbena/conf"
+)
+
+//go:embed knot.conf.generated
+var knotConfGenerated string
+
+//go:embed nsd.conf.generated
+var nsdConfGenerated string
+
+func TestNew(t *testing.T) {
+	for _, name := range []string{"", Bind, Knot, Nsd, PowerDns} {
+		_, err := New(name, conf.CmdConf{}, nil)
+		if err != nil {
+			t.Fatal(name, err)
+		}
+	}
+	_, err := New("unbound", conf.CmdConf{}, nil)
+	if err == nil {
+		t.Fatal("expected error for unknown backend")
+	}
+
+	views := conf.ViewsConf{{Name: "internal", MatchClients: []string{"10.0.0.0/8"}}}
+	_, err = New(Bind, conf.CmdConf{}, views)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{Knot, Nsd, PowerDns} {
+		_, err = New(name, conf.CmdConf{}, views)
+		if err == nil {
+			t.Fatal("expected error for views with", name)
+		}
+	}
+}
+
+func TestWriteKnotConfig(t *testing.T) {
+	buf := new(bytes.Buffer)
+	err := WriteKnotConfig(buf, "/var/lib/knot/zones", []string{"example.com", "example.org", "example.net"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if buf.String() != knotConfGenerated {
+		t.Fatal("expected", knotConfGenerated, "actual", buf.String())
+	}
+}
+
+func TestWriteNsdConfig(t *testing.T) {
+	buf := new(bytes.Buffer)
+	err := WriteNsdConfig(buf, "/etc/nsd/zones", []string{"example.com", "example.org", "example.net"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if buf.String() != nsdConfGenerated {
+		t.Fatal("expected", nsdConfGenerated, "actual", buf.String())
+	}
+}
+
+func TestPowerDnsCheckZone(t *testing.T) {
+	dir := t.TempDir()
+	p := &powerDnsBackend{}
+
+	validPath := filepath.Join(dir, "valid.zone")
+	err := os.WriteFile(validPath, []byte(`$ORIGIN example.com.
+$TTL 300
+@	IN	SOA	ns1.example.com.	hostmaster.example.com. ( 1 2 3 4 5 )
+@	IN	NS	ns1.example.com.
+`), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = p.CheckZone(t.Context(), "example.com", validPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	missingSoaPath := filepath.Join(dir, "missing-soa.zone")
+	err = os.WriteFile(missingSoaPath, []byte(`$ORIGIN example.com.
+$TTL 300
+@	IN	NS	ns1.example.com.
+`), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = p.CheckZone(t.Context(), "example.com", missingSoaPath)
+	if err == nil {
+		t.Fatal("expected missing SOA error")
+	}
+
+	invalidPath := filepath.Join(dir, "invalid.zone")
+	err = os.WriteFile(invalidPath, []byte(`$ORIGIN example.com.
+@	IN	A	not-an-ip
+`), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = p.CheckZone(t.Context(), "example.com", invalidPath)
+	if err == nil {
+		t.Fatal("expected parse error")
+	}
+}
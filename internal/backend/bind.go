@@ -0,0 +1,61 @@
+package backend
+
+import (
+	"context"
+	"io"
+	"os/exec"
+
+	"github.com/1f349/verbena/conf"
+	"github.com/1f349/verbena/internal/bind"
+)
+
+type bindBackend struct {
+	cmd   conf.CmdConf
+	views conf.ViewsConf
+}
+
+func (b *bindBackend) CheckZone(ctx context.Context, zoneName string, path string) error {
+	return runCheckCmd("named-checkzone", exec.CommandContext(ctx, b.cmd.CheckZone, zoneName, path))
+}
+
+func (b *bindBackend) WriteConfig(w io.Writer, zonesPath string, zones []string) error {
+	return bind.WriteBindConfig(w, zonesPath, b.views, zones)
+}
+
+func (b *bindBackend) Reload(ctx context.Context) error {
+	return runCmdDebugLog("Full rndc log", exec.CommandContext(ctx, b.cmd.Rndc, "reload"))
+}
+
+func (b *bindBackend) ReloadZone(ctx context.Context, zoneName string) error {
+	for _, args := range b.zoneArgs("reload", zoneName) {
+		err := runCmdDebugLog("Full rndc log", exec.CommandContext(ctx, b.cmd.Rndc, args...))
+		if err != nil {
+			// If "rndc reload <zone>" fails then try "rndc reload" without the zone argument
+			return b.Reload(ctx)
+		}
+	}
+	return nil
+}
+
+func (b *bindBackend) Notify(ctx context.Context, zoneName string) error {
+	for _, args := range b.zoneArgs("notify", zoneName) {
+		err := exec.CommandContext(ctx, b.cmd.Rndc, args...).Run()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// zoneArgs returns the rndc arguments for a zone command, the zone exists once
+// in each view so the command is repeated for every view
+func (b *bindBackend) zoneArgs(command, zoneName string) [][]string {
+	if len(b.views) == 0 {
+		return [][]string{{command, zoneName}}
+	}
+	args := make([][]string, 0, len(b.views))
+	for _, view := range b.views {
+		args = append(args, []string{command, zoneName, "IN", view.Name})
+	}
+	return args
+}
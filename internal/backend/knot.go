@@ -0,0 +1,66 @@
+package backend
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os/exec"
+	"path"
+	"strconv"
+
+	"github.com/1f349/verbena/conf"
+)
+
+type knotBackend struct {
+	cmd conf.CmdConf
+}
+
+func (k *knotBackend) CheckZone(ctx context.Context, zoneName string, path string) error {
+	return runCheckCmd("kzonecheck", exec.CommandContext(ctx, k.cmd.KZoneCheck, "-o", zoneName, path))
+}
+
+func (k *knotBackend) WriteConfig(w io.Writer, zonesPath string, zones []string) error {
+	return WriteKnotConfig(w, zonesPath, zones)
+}
+
+func (k *knotBackend) Reload(ctx context.Context) error {
+	return runCmdDebugLog("Full knotc log", exec.CommandContext(ctx, k.cmd.Knotc, "reload"))
+}
+
+func (k *knotBackend) ReloadZone(ctx context.Context, zoneName string) error {
+	err := runCmdDebugLog("Full knotc log", exec.CommandContext(ctx, k.cmd.Knotc, "zone-reload", zoneName))
+	if err != nil {
+		// The zone might not be known to knot yet so reload everything
+		return k.Reload(ctx)
+	}
+	return nil
+}
+
+func (k *knotBackend) Notify(ctx context.Context, zoneName string) error {
+	return exec.CommandContext(ctx, k.cmd.Knotc, "zone-notify", zoneName).Run()
+}
+
+func WriteKnotConfig(w io.Writer, zonesPath string, origins []string) error {
+	if len(origins) == 0 {
+		return nil
+	}
+
+	// zone:
+	//   - domain: "example.com"
+	//     file: "/var/lib/knot/zones/example.com.zone"
+	_, err := fmt.Fprintf(w, "zone:\n")
+	if err != nil {
+		return err
+	}
+	for _, zone := range origins {
+		_, err = fmt.Fprintf(w, "  - domain: %s\n", strconv.Quote(zone))
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(w, "    file: %s\n", strconv.Quote(path.Join(zonesPath, zone+".zone")))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,66 @@
+package backend
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os/exec"
+	"path"
+	"strconv"
+
+	"github.com/1f349/verbena/conf"
+)
+
+type nsdBackend struct {
+	cmd conf.CmdConf
+}
+
+func (n *nsdBackend) CheckZone(ctx context.Context, zoneName string, path string) error {
+	return runCheckCmd("nsd-checkzone", exec.CommandContext(ctx, n.cmd.NsdCheckZone, zoneName, path))
+}
+
+func (n *nsdBackend) WriteConfig(w io.Writer, zonesPath string, zones []string) error {
+	return WriteNsdConfig(w, zonesPath, zones)
+}
+
+func (n *nsdBackend) Reload(ctx context.Context) error {
+	// "reconfig" picks up added and removed zones, "reload" then reads every zone file
+	err := runCmdDebugLog("Full nsd-control log", exec.CommandContext(ctx, n.cmd.NsdControl, "reconfig"))
+	if err != nil {
+		return err
+	}
+	return runCmdDebugLog("Full nsd-control log", exec.CommandContext(ctx, n.cmd.NsdControl, "reload"))
+}
+
+func (n *nsdBackend) ReloadZone(ctx context.Context, zoneName string) error {
+	err := runCmdDebugLog("Full nsd-control log", exec.CommandContext(ctx, n.cmd.NsdControl, "reload", zoneName))
+	if err != nil {
+		return n.Reload(ctx)
+	}
+	return nil
+}
+
+func (n *nsdBackend) Notify(ctx context.Context, zoneName string) error {
+	return exec.CommandContext(ctx, n.cmd.NsdControl, "notify", zoneName).Run()
+}
+
+func WriteNsdConfig(w io.Writer, zonesPath string, origins []string) error {
+	for _, zone := range origins {
+		// zone:
+		// <tab>name: "example.com"
+		// <tab>zonefile: "/etc/nsd/zones/example.com.zone"
+		_, err := fmt.Fprintf(w, "zone:\n")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(w, "\tname: %s\n", strconv.Quote(zone))
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(w, "\tzonefile: %s\n", strconv.Quote(path.Join(zonesPath, zone+".zone")))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
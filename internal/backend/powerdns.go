@@ -0,0 +1,63 @@
+package backend
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+
+	"github.com/1f349/verbena/conf"
+	"github.com/1f349/verbena/internal/bind"
+	"github.com/miekg/dns"
+)
+
+// powerDnsBackend drives PowerDNS using the bind backend, which reads the same
+// named.conf style zone list as BIND.
+type powerDnsBackend struct {
+	cmd conf.CmdConf
+}
+
+func (p *powerDnsBackend) CheckZone(ctx context.Context, zoneName string, path string) error {
+	// PowerDNS has no offline zone checker so parse the file instead
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	zp := dns.NewZoneParser(f, dns.Fqdn(zoneName), path)
+	hasSoa := false
+	for rr, ok := zp.Next(); ok; rr, ok = zp.Next() {
+		if rr.Header().Rrtype == dns.TypeSOA {
+			hasSoa = true
+		}
+	}
+	if err := zp.Err(); err != nil {
+		return err
+	}
+	if !hasSoa {
+		return fmt.Errorf("zone %s has no SOA record", zoneName)
+	}
+	return nil
+}
+
+func (p *powerDnsBackend) WriteConfig(w io.Writer, zonesPath string, zones []string) error {
+	return bind.WriteBindConfig(w, zonesPath, nil, zones)
+}
+
+func (p *powerDnsBackend) Reload(ctx context.Context) error {
+	return runCmdDebugLog("Full pdns_control log", exec.CommandContext(ctx, p.cmd.PdnsControl, "rediscover"))
+}
+
+func (p *powerDnsBackend) ReloadZone(ctx context.Context, zoneName string) error {
+	err := runCmdDebugLog("Full pdns_control log", exec.CommandContext(ctx, p.cmd.PdnsControl, "bind-reload-now", zoneName))
+	if err != nil {
+		return p.Reload(ctx)
+	}
+	return nil
+}
+
+func (p *powerDnsBackend) Notify(ctx context.Context, zoneName string) error {
+	return exec.CommandContext(ctx, p.cmd.PdnsControl, "notify", zoneName).Run()
+}
@@ -4,28 +4,78 @@ import (
 	"fmt"
 	"io"
 	"path"
+	"slices"
 	"strconv"
+	"strings"
+
+	"github.com/1f349/verbena/conf"
+	"github.com/1f349/verbena/internal/geoip"
 )
 
-func WriteBindConfig(w io.Writer, zonesPath string, origins []string) error {
+// WriteBindConfig writes a zone block for each origin, when views are
+// configured the zones are repeated inside a view block for each view and
+// load the zone file generated for that view. Geo views include the ACL file
+// written from the GeoIP database and match clients in the ACL.
+func WriteBindConfig(w io.Writer, zonesPath string, views conf.ViewsConf, origins []string) error {
+	if len(views) == 0 {
+		return writeZones(w, "", zonesPath, origins)
+	}
+	for _, view := range views {
+		// include "/etc/bind/zones/eu.acl";
+		// view "internal" {
+		// <tab>match-clients { 10.0.0.0/8; "geo-eu"; };
+		// <tab>zone "example.com" IN {
+		// <tab><tab>type master;
+		// <tab><tab>file "/etc/bind/zones/internal/example.com.zone";
+		// <tab>};
+		// };
+		matchClients := view.MatchClients
+		if len(view.Geo) > 0 {
+			_, err := fmt.Fprintf(w, "include %s;\n", strconv.Quote(geoip.AclFile(zonesPath, view.Name)))
+			if err != nil {
+				return err
+			}
+			matchClients = append(slices.Clip(matchClients), strconv.Quote(geoip.AclName(view.Name)))
+		}
+		_, err := fmt.Fprintf(w, "view %s {\n", strconv.Quote(view.Name))
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(w, "\tmatch-clients { %s; };\n", strings.Join(matchClients, "; "))
+		if err != nil {
+			return err
+		}
+		err = writeZones(w, "\t", path.Join(zonesPath, view.Name), origins)
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(w, "};\n")
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeZones(w io.Writer, indent, zonesPath string, origins []string) error {
 	for _, zone := range origins {
 		// zone "example.com" IN {
 		// <tab>type master;
 		// <tab>file "/etc/bind/zones/example.com.zone";
 		// };
-		_, err := fmt.Fprintf(w, "zone %s IN {\n", strconv.Quote(zone))
+		_, err := fmt.Fprintf(w, "%szone %s IN {\n", indent, strconv.Quote(zone))
 		if err != nil {
 			return err
 		}
-		_, err = fmt.Fprintf(w, "\ttype master;\n")
+		_, err = fmt.Fprintf(w, "%s\ttype master;\n", indent)
 		if err != nil {
 			return err
 		}
-		_, err = fmt.Fprintf(w, "\tfile %s;\n", strconv.Quote(path.Join(zonesPath, zone+".zone")))
+		_, err = fmt.Fprintf(w, "%s\tfile %s;\n", indent, strconv.Quote(path.Join(zonesPath, zone+".zone")))
 		if err != nil {
 			return err
 		}
-		_, err = fmt.Fprintf(w, "};\n")
+		_, err = fmt.Fprintf(w, "%s};\n", indent)
 		if err != nil {
 			return err
 		}
@@ -4,14 +4,19 @@ import (
 	"bytes"
 	_ "embed"
 	"testing"
+
+	"github.com/1f349/verbena/conf"
 )
 
 //go:embed named.conf.local.generated
 var namedConfLocalGenerated string
 
+//go:embed named.conf.views.generated
+var namedConfViewsGenerated string
+
 func TestWriteBindConfig(t *testing.T) {
 	buf := new(bytes.Buffer)
-	err := WriteBindConfig(buf, "/etc/bind/zones", []string{"example.com", "example.org", "example.net"})
+	err := WriteBindConfig(buf, "/etc/bind/zones", nil, []string{"example.com", "example.org", "example.net"})
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -20,3 +25,21 @@ func TestWriteBindConfig(t *testing.T) {
 		t.Fatal("expected", namedConfLocalGenerated, "actual", buf.String())
 	}
 }
+
+func TestWriteBindConfigViews(t *testing.T) {
+	views := conf.ViewsConf{
+		{Name: "internal", MatchClients: []string{"10.0.0.0/8", "!10.1.0.0/16", "localhost"}},
+		{Name: "eu", Geo: []string{"continent:EU", "country:GB"}},
+		{Name: "external", MatchClients: []string{"any"}},
+	}
+
+	buf := new(bytes.Buffer)
+	err := WriteBindConfig(buf, "/etc/bind/zones", views, []string{"example.com", "example.org"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if buf.String() != namedConfViewsGenerated {
+		t.Fatal("expected", namedConfViewsGenerated, "actual", buf.String())
+	}
+}
@@ -2,50 +2,114 @@ package builder
 
 import (
 	"context"
+	"crypto/sha256"
 	"fmt"
 	"io"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"slices"
 	"sync"
 	"time"
 
 	"github.com/1f349/verbena/conf"
-	"github.com/1f349/verbena/internal/bind"
+	"github.com/1f349/verbena/internal/alias"
+	"github.com/1f349/verbena/internal/backend"
 	"github.com/1f349/verbena/internal/database"
+	"github.com/1f349/verbena/internal/geoip"
+	"github.com/1f349/verbena/internal/pool"
+	"github.com/1f349/verbena/internal/utils"
+	"github.com/1f349/verbena/internal/webhook"
 	"github.com/1f349/verbena/internal/zone"
 	"github.com/1f349/verbena/logger"
-	"github.com/charmbracelet/log"
 	"github.com/gobuffalo/nulls"
 )
 
 type committerQueries interface {
 	GetZoneActiveRecords(ctx context.Context, zoneID int64) ([]database.Record, error)
 	GetActiveZones(ctx context.Context) ([]database.Zone, error)
+	GetZoneHealthChecks(ctx context.Context, zoneID int64) ([]database.HealthCheck, error)
+	GetZonePools(ctx context.Context, zoneID int64) ([]database.Pool, error)
+	GetZonePoolMembers(ctx context.Context, zoneID int64) ([]database.PoolMember, error)
+}
+
+type eventFirer interface {
+	Fire(zoneID int64, zoneName string, event webhook.Event, data any)
+}
+
+// publisher bumps the serial of a zone, rebuilds it and notifies the
+// secondaries, it is implemented by the committer
+type publisher interface {
+	Publish(ctx context.Context, zone database.Zone) error
+}
+
+type aliasResolver interface {
+	Lookup(ctx context.Context, target string) (alias.Answer, error)
 }
 
 type Builder struct {
 	db          committerQueries
 	genTick     time.Duration
 	dir         string
-	bindGenConf string
+	genConf     string
 	nameservers conf.NameserverConf
 	genLock     sync.Mutex
-	cmd         conf.CmdConf
+	backend     backend.Backend
+	events      eventFirer
+	aliases     aliasResolver
+	views       conf.ViewsConf
+
+	// geo writes the ACL files of geo views, nil when no view uses GeoIP
+	geo *geoip.AclWriter
+
+	// pools rotates the published members of record pools once per tick
+	pools *pool.Rotator
+
+	// failing holds the zones which failed to generate, so the failure event
+	// is only fired once per failure
+	failing map[int64]bool
+
+	// publisher is used when the generated records of a zone change without
+	// a commit, nil on secondaries which never bump the serial
+	publisher publisher
+
+	// generated holds a hash of the records last generated for each zone, it
+	// is kept when generating fails so a broken zone is not published again
+	// on every tick until its records change
+	generated map[int64][sha256.Size]byte
 }
 
-func New(db committerQueries, genTick time.Duration, dir string, bindGenConf string, nameservers conf.NameserverConf, cmd conf.CmdConf) (*Builder, error) {
+func New(db committerQueries, genTick time.Duration, dir string, genConf string, nameservers conf.NameserverConf, backend backend.Backend, events eventFirer, aliases aliasResolver, views conf.ViewsConf, geoDatabase string) (*Builder, error) {
+	var geo *geoip.AclWriter
+	if regions := views.GeoRegions(); len(regions) > 0 {
+		if geoDatabase == "" {
+			return nil, fmt.Errorf("geo views require the geoip database to be configured")
+		}
+		geo = geoip.NewAclWriter(geoDatabase, dir, regions)
+	}
+
 	return &Builder{
 		db:          db,
 		genTick:     genTick,
 		dir:         dir,
-		bindGenConf: bindGenConf,
+		genConf:     genConf,
 		nameservers: nameservers,
-		cmd:         cmd,
+		backend:     backend,
+		events:      events,
+		aliases:     aliases,
+		views:       views,
+		geo:         geo,
+		pools:       pool.NewRotator(),
+		failing:     make(map[int64]bool),
+		generated:   make(map[int64][sha256.Size]byte),
 	}, nil
 }
 
+// SetPublisher sets the committer used to publish zones whose generated
+// records changed without a commit, this must be called before Start
+func (b *Builder) SetPublisher(p publisher) {
+	b.publisher = p
+}
+
 func (b *Builder) Start() {
 	go b.internalTicker()
 }
@@ -67,6 +131,8 @@ func (b *Builder) internalTicker() {
 }
 
 func (b *Builder) generateZones(loadedZones *[]string) {
+	b.pools.Tick()
+
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
 	zones, err := b.db.GetActiveZones(ctx)
 	cancel()
@@ -78,7 +144,7 @@ func (b *Builder) generateZones(loadedZones *[]string) {
 	var newLoadedZones []string
 	for _, i := range zones {
 		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
-		err = b.Generate(ctx, i)
+		err = b.generateOrPublish(ctx, i)
 		cancel()
 		if err != nil {
 			logger.Logger.Error("Failed to generate a zone", "zone id", i.ID, "zone name", i.Name, "err", err)
@@ -88,8 +154,17 @@ func (b *Builder) generateZones(loadedZones *[]string) {
 
 	slices.Sort(newLoadedZones)
 
+	// The config is also reloaded when the ACL files of geo views change
+	aclsChanged := false
+	if b.geo != nil {
+		aclsChanged, err = b.geo.Update()
+		if err != nil {
+			logger.Logger.Error("Failed to update GeoIP ACL files", "err", err)
+		}
+	}
+
 	// If the currently loaded zones and new loaded zones
-	if !slices.Equal(newLoadedZones, *loadedZones) {
+	if aclsChanged || !slices.Equal(newLoadedZones, *loadedZones) {
 		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
 		err = b.generateLocalGeneratedConfig(ctx, newLoadedZones)
 		cancel()
@@ -101,12 +176,106 @@ func (b *Builder) generateZones(loadedZones *[]string) {
 	}
 }
 
+// generateOrPublish publishes the zone when the records generated for it have
+// changed since the last generation, such as a pool rotating or an ALIAS
+// target resolving to new addresses, so the secondaries transfer the change
+func (b *Builder) generateOrPublish(ctx context.Context, zoneInfo database.Zone) error {
+	if b.publisher != nil {
+		views, err := b.viewRecords(ctx, zoneInfo)
+		if err != nil {
+			return err
+		}
+		b.genLock.Lock()
+		last, ok := b.generated[zoneInfo.ID]
+		b.genLock.Unlock()
+		if ok && last != hashViewRecords(views) {
+			return b.publisher.Publish(ctx, zoneInfo)
+		}
+	}
+	return b.Generate(ctx, zoneInfo)
+}
+
 func (b *Builder) Generate(ctx context.Context, zoneInfo database.Zone) error {
 	b.genLock.Lock()
 	defer b.genLock.Unlock()
 
-	zoneFileName := filepath.Join(b.dir, zoneInfo.Name+".zone")
-	zoneFileTemp := filepath.Join(b.dir, zoneInfo.Name+".zone.temp")
+	err := b.generate(ctx, zoneInfo)
+	if err != nil {
+		if !b.failing[zoneInfo.ID] {
+			b.events.Fire(zoneInfo.ID, zoneInfo.Name, webhook.GenerateFailed, struct {
+				Error string `json:"error"`
+			}{Error: err.Error()})
+		}
+		b.failing[zoneInfo.ID] = true
+		return err
+	}
+	delete(b.failing, zoneInfo.ID)
+	return nil
+}
+
+func (b *Builder) generate(ctx context.Context, zoneInfo database.Zone) error {
+	views, err := b.viewRecords(ctx, zoneInfo)
+	if err != nil {
+		return err
+	}
+	b.generated[zoneInfo.ID] = hashViewRecords(views)
+	for _, i := range views {
+		err := b.generateView(ctx, zoneInfo, i.view, i.records)
+		if err != nil {
+			return err
+		}
+	}
+
+	return b.backend.ReloadZone(ctx, zoneInfo.Name)
+}
+
+type viewRecords struct {
+	view    string
+	records []zone.Record
+}
+
+// viewRecords returns the records published in each view, without views a
+// single zone file is generated for the empty view name
+func (b *Builder) viewRecords(ctx context.Context, zoneInfo database.Zone) ([]viewRecords, error) {
+	views := []string{""}
+	if len(b.views) > 0 {
+		views = b.views.Names()
+	}
+	out := make([]viewRecords, 0, len(views))
+	for _, view := range views {
+		records, err := b.zoneRecords(ctx, zoneInfo, view)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, viewRecords{view: view, records: records})
+	}
+	return out, nil
+}
+
+// hashViewRecords hashes the generated records, the SOA record is left out so
+// the hash only changes with the records
+func hashViewRecords(views []viewRecords) [sha256.Size]byte {
+	h := sha256.New()
+	for _, i := range views {
+		fmt.Fprintf(h, "view %s\n", i.view)
+		for _, r := range i.records {
+			fmt.Fprintf(h, "%s\t%v\t%s\t%s\n", r.Name, r.TimeToLive, r.Type, r.Value)
+		}
+	}
+	return [sha256.Size]byte(h.Sum(nil))
+}
+
+// generateView writes the zone file for a view, the files for each view are
+// stored in a directory named after the view
+func (b *Builder) generateView(ctx context.Context, zoneInfo database.Zone, view string, records []zone.Record) error {
+	dir := filepath.Join(b.dir, view)
+	err := os.MkdirAll(dir, 0700)
+	if err != nil {
+		return err
+	}
+
+	zoneFileName := filepath.Join(dir, zoneInfo.Name+".zone")
+	zoneFileTemp := filepath.Join(dir, zoneInfo.Name+".zone.temp")
 
 	zoneFile, err := os.Create(zoneFileTemp)
 	if err != nil {
@@ -115,7 +284,7 @@ func (b *Builder) Generate(ctx context.Context, zoneInfo database.Zone) error {
 	defer zoneFile.Close()
 	defer os.Remove(zoneFileTemp)
 
-	err = b.Preview(ctx, zoneFile, zoneInfo)
+	err = writeZone(zoneFile, zoneInfo, records)
 	if err != nil {
 		return err
 	}
@@ -125,27 +294,40 @@ func (b *Builder) Generate(ctx context.Context, zoneInfo database.Zone) error {
 		return err
 	}
 
-	cmd := exec.CommandContext(ctx, b.cmd.CheckZone, zoneInfo.Name, zoneFileTemp)
-	out, err := cmd.CombinedOutput()
+	err = b.backend.CheckZone(ctx, zoneInfo.Name, zoneFileTemp)
 	if err != nil {
-		if logger.Logger.GetLevel() >= log.DebugLevel {
-			err = fmt.Errorf("named-checkzone failed with output: %w: %s", err, string(out))
-		}
 		return err
 	}
 
-	err = os.Rename(zoneFileTemp, zoneFileName)
+	return os.Rename(zoneFileTemp, zoneFileName)
+}
+
+// Preview writes the zone file served in view
+func (b *Builder) Preview(ctx context.Context, w io.Writer, zoneInfo database.Zone, view string) error {
+	records, err := b.zoneRecords(ctx, zoneInfo, view)
 	if err != nil {
 		return err
 	}
-
-	return b.bindReloadZone(ctx, zoneInfo)
+	return writeZone(w, zoneInfo, records)
 }
 
-func (b *Builder) Preview(ctx context.Context, w io.Writer, zoneInfo database.Zone) error {
+// zoneRecords returns the records published in view, records tagged with
+// other views are left out
+func (b *Builder) zoneRecords(ctx context.Context, zoneInfo database.Zone, view string) ([]zone.Record, error) {
 	records, err := b.db.GetZoneActiveRecords(ctx, zoneInfo.ID)
 	if err != nil {
-		return err
+		return nil, err
+	}
+
+	checks, err := b.db.GetZoneHealthChecks(ctx, zoneInfo.ID)
+	if err != nil {
+		return nil, err
+	}
+	failing := make(map[int64]database.HealthCheck)
+	for _, i := range checks {
+		if !i.Healthy {
+			failing[i.RecordID] = i
+		}
 	}
 
 	nameservers := b.nameservers.GetNameserversForZone(zoneInfo)
@@ -161,9 +343,22 @@ func (b *Builder) Preview(ctx context.Context, w io.Writer, zoneInfo database.Zo
 	}
 
 	for _, i := range records {
+		if !utils.InView(utils.SplitViews(i.Views), view) {
+			continue
+		}
+		// failover records publish the backup value with a short TTL while
+		// the health check is failing
+		if check, ok := failing[i.ID]; ok {
+			i.Value = check.Backup
+			i.Ttl = nulls.NewInt32(check.BackupTtl)
+		}
 		ty := zone.RecordTypeFromString(i.Type)
 		if !ty.IsValid() {
-			return fmt.Errorf("unknown type: %s", i.Type)
+			return nil, fmt.Errorf("unknown type: %s", i.Type)
+		}
+		if ty == zone.ALIAS {
+			zoneRecords = b.flattenAlias(ctx, zoneRecords, zoneInfo, i)
+			continue
 		}
 		zoneRecords = append(zoneRecords, zone.Record{
 			Name: i.Name,
@@ -176,6 +371,10 @@ func (b *Builder) Preview(ctx context.Context, w io.Writer, zoneInfo database.Zo
 		})
 	}
 
+	return b.appendPools(ctx, zoneRecords, zoneInfo)
+}
+
+func writeZone(w io.Writer, zoneInfo database.Zone, zoneRecords []zone.Record) error {
 	return zone.WriteZone(w, zoneInfo.Name, uint32(zoneInfo.Ttl), zone.SoaRecord{
 		Nameserver: zoneInfo.Nameserver,
 		Admin:      zoneInfo.Admin,
@@ -187,51 +386,103 @@ func (b *Builder) Preview(ctx context.Context, w io.Writer, zoneInfo database.Zo
 	}, zoneRecords)
 }
 
-func (b *Builder) generateLocalGeneratedConfig(ctx context.Context, zones []string) error {
-	bindLocalTempPath := b.bindGenConf + ".temp"
-	bindLocalTemp, err := os.Create(bindLocalTempPath)
+// appendPools appends the members of each record pool which are published
+// during the current generation
+func (b *Builder) appendPools(ctx context.Context, zoneRecords []zone.Record, zoneInfo database.Zone) ([]zone.Record, error) {
+	pools, err := b.db.GetZonePools(ctx, zoneInfo.ID)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	defer bindLocalTemp.Close()
-	defer os.Remove(bindLocalTempPath)
-
-	err = bind.WriteBindConfig(bindLocalTemp, b.dir, zones)
+	if len(pools) == 0 {
+		return zoneRecords, nil
+	}
+	rows, err := b.db.GetZonePoolMembers(ctx, zoneInfo.ID)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	members := make(map[int64][]pool.Member)
+	for _, i := range rows {
+		members[i.PoolID] = append(members[i.PoolID], pool.Member{
+			ID:      i.ID,
+			Value:   i.Value,
+			Weight:  i.Weight,
+			Enabled: i.Enabled,
+		})
 	}
 
-	err = os.Rename(bindLocalTempPath, b.bindGenConf)
+	for _, p := range pools {
+		ty := zone.RecordTypeFromString(p.Type)
+		if !ty.IsValid() {
+			return nil, fmt.Errorf("unknown type: %s", p.Type)
+		}
+		for _, m := range b.pools.Selected(p.ID, int(p.Size), members[p.ID]) {
+			zoneRecords = append(zoneRecords, zone.Record{
+				Name: p.Name,
+				TimeToLive: nulls.UInt32{
+					UInt32: uint32(p.Ttl.Int32),
+					Valid:  p.Ttl.Valid,
+				},
+				Type:  ty,
+				Value: m.Value,
+			})
+		}
+	}
+	return zoneRecords, nil
+}
+
+// flattenAlias appends the resolved addresses of the ALIAS target, the TTL of
+// the target is used unless the ALIAS record has a lower TTL. A target which
+// has never resolved is left out so the rest of the zone is still generated.
+func (b *Builder) flattenAlias(ctx context.Context, zoneRecords []zone.Record, zoneInfo database.Zone, record database.Record) []zone.Record {
+	answer, err := b.aliases.Lookup(ctx, record.Value)
 	if err != nil {
-		return err
+		logger.Logger.Warn("Failed to resolve ALIAS record, leaving it out of the zone", "zone", zoneInfo.Name, "name", record.Name, "target", record.Value, "err", err)
+		return zoneRecords
 	}
 
-	return b.bindReload(ctx)
-}
+	ttl := uint32(zoneInfo.Ttl)
+	if record.Ttl.Valid {
+		ttl = uint32(record.Ttl.Int32)
+	}
+	ttl = min(ttl, answer.Ttl)
 
-func (b *Builder) bindReload(ctx context.Context) error {
-	cmd := exec.CommandContext(ctx, b.cmd.Rndc, "reload")
-	return runCmdDebugLog("Full rndc log", cmd)
+	for _, addr := range answer.A {
+		zoneRecords = append(zoneRecords, zone.Record{
+			Name:       record.Name,
+			TimeToLive: nulls.NewUInt32(ttl),
+			Type:       zone.A,
+			Value:      addr.String(),
+		})
+	}
+	for _, addr := range answer.AAAA {
+		zoneRecords = append(zoneRecords, zone.Record{
+			Name:       record.Name,
+			TimeToLive: nulls.NewUInt32(ttl),
+			Type:       zone.AAAA,
+			Value:      addr.String(),
+		})
+	}
+	return zoneRecords
 }
 
-func (b *Builder) bindReloadZone(ctx context.Context, zone database.Zone) error {
-	cmd := exec.CommandContext(ctx, b.cmd.Rndc, "reload", zone.Name)
-	err := runCmdDebugLog("Full rndc log", cmd)
+func (b *Builder) generateLocalGeneratedConfig(ctx context.Context, zones []string) error {
+	genConfTempPath := b.genConf + ".temp"
+	genConfTemp, err := os.Create(genConfTempPath)
 	if err != nil {
-		// If "rndc reload <zone>" fails then try "rndc reload" without the zone argument
-		return b.bindReload(ctx)
+		return err
 	}
-	return nil
-}
+	defer genConfTemp.Close()
+	defer os.Remove(genConfTempPath)
 
-func runCmdDebugLog(title string, cmd *exec.Cmd) error {
-	if logger.Logger.GetLevel() > log.DebugLevel {
-		return cmd.Run()
+	err = b.backend.WriteConfig(genConfTemp, b.dir, zones)
+	if err != nil {
+		return err
 	}
 
-	raw, err := cmd.CombinedOutput()
+	err = os.Rename(genConfTempPath, b.genConf)
 	if err != nil {
-		logger.Logger.Debug(title, "cmd", cmd.Args, "err", err, "raw", string(raw))
+		return err
 	}
-	return err
+
+	return b.backend.Reload(ctx)
 }
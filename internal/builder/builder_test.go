@@ -0,0 +1,113 @@
+package builder
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/1f349/verbena/conf"
+	"github.com/1f349/verbena/internal/database"
+	"github.com/1f349/verbena/internal/webhook"
+	"github.com/stretchr/testify/assert"
+)
+
+type builderTestQueries struct {
+	records []database.Record
+}
+
+func (b *builderTestQueries) GetZoneActiveRecords(ctx context.Context, zoneID int64) ([]database.Record, error) {
+	return b.records, nil
+}
+
+func (b *builderTestQueries) GetActiveZones(ctx context.Context) ([]database.Zone, error) {
+	return nil, nil
+}
+
+func (b *builderTestQueries) GetZoneHealthChecks(ctx context.Context, zoneID int64) ([]database.HealthCheck, error) {
+	return nil, nil
+}
+
+func (b *builderTestQueries) GetZonePools(ctx context.Context, zoneID int64) ([]database.Pool, error) {
+	return nil, nil
+}
+
+func (b *builderTestQueries) GetZonePoolMembers(ctx context.Context, zoneID int64) ([]database.PoolMember, error) {
+	return nil, nil
+}
+
+// builderTestBackend refuses every zone file while failing is set
+type builderTestBackend struct {
+	failing bool
+}
+
+func (b *builderTestBackend) CheckZone(ctx context.Context, zoneName string, path string) error {
+	if b.failing {
+		return errors.New("invalid zone")
+	}
+	return nil
+}
+
+func (b *builderTestBackend) WriteConfig(w io.Writer, zonesPath string, zones []string) error {
+	return nil
+}
+
+func (b *builderTestBackend) Reload(ctx context.Context) error { return nil }
+
+func (b *builderTestBackend) ReloadZone(ctx context.Context, zoneName string) error { return nil }
+
+func (b *builderTestBackend) Notify(ctx context.Context, zoneName string) error { return nil }
+
+type builderTestEvents struct {
+	events []webhook.Event
+}
+
+func (b *builderTestEvents) Fire(zoneID int64, zoneName string, event webhook.Event, data any) {
+	b.events = append(b.events, event)
+}
+
+// builderTestPublisher rebuilds the zone like the committer does
+type builderTestPublisher struct {
+	builder   *Builder
+	published int
+}
+
+func (b *builderTestPublisher) Publish(ctx context.Context, zone database.Zone) error {
+	b.published++
+	zone.Serial++
+	return b.builder.Generate(ctx, zone)
+}
+
+func TestBuilder_generateOrPublishFailing(t *testing.T) {
+	db := &builderTestQueries{records: []database.Record{
+		{ID: 1, ZoneID: 1, Name: "www", Type: "A", Value: "192.0.2.1", Active: true},
+	}}
+	backend := &builderTestBackend{}
+	events := &builderTestEvents{}
+	b, err := New(db, time.Minute, t.TempDir(), "", conf.NameserverConf{}, backend, events, nil, nil, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	publisher := &builderTestPublisher{builder: b}
+	b.SetPublisher(publisher)
+	zoneInfo := database.Zone{ID: 1, Name: "example.com", Active: true}
+
+	assert.NoError(t, b.generateOrPublish(t.Context(), zoneInfo))
+	assert.Zero(t, publisher.published)
+
+	// the changed records are published once, the zone then fails to
+	// generate and is not published again on the next tick
+	db.records[0].Value = "192.0.2.2"
+	backend.failing = true
+	for range 2 {
+		assert.Error(t, b.generateOrPublish(t.Context(), zoneInfo))
+	}
+	assert.Equal(t, 1, publisher.published)
+	assert.Equal(t, []webhook.Event{webhook.GenerateFailed}, events.events)
+
+	// the zone is generated again once fixed without another publish
+	backend.failing = false
+	assert.NoError(t, b.generateOrPublish(t.Context(), zoneInfo))
+	assert.Equal(t, 1, publisher.published)
+}
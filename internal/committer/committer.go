@@ -2,30 +2,45 @@ package committer
 
 import (
 	"context"
-	"os/exec"
+	"errors"
 	"time"
 
-	"github.com/1f349/verbena/conf"
+	"github.com/1f349/verbena/internal/backend"
 	"github.com/1f349/verbena/internal/builder"
 	"github.com/1f349/verbena/internal/database"
+	"github.com/1f349/verbena/internal/lint"
+	"github.com/1f349/verbena/internal/notify"
+	"github.com/1f349/verbena/internal/propagation"
+	"github.com/1f349/verbena/internal/webhook"
 	"github.com/1f349/verbena/logger"
 )
 
+type commitEvent struct {
+	Serial uint32 `json:"serial"`
+	Error  string `json:"error,omitempty"`
+}
+
 type Committer struct {
 	db      *database.Queries
 	tick    time.Duration
 	primary bool
 	b       *builder.Builder
-	cmd     conf.CmdConf
+	backend backend.Backend
+	notify  *notify.Notifier
+	tracker *propagation.Tracker
+	events  *webhook.Dispatcher
 }
 
-func New(db *database.Queries, tick time.Duration, primary bool, b *builder.Builder, cmd conf.CmdConf) *Committer {
+func New(db *database.Queries, tick time.Duration, primary bool, b *builder.Builder, backend backend.Backend, notifier *notify.Notifier, tracker *propagation.Tracker, events *webhook.Dispatcher) *Committer {
 	return &Committer{
 		db:      db,
 		tick:    tick,
 		primary: primary,
 		b:       b,
-		cmd:     cmd,
+		backend: backend,
+		notify:  notifier,
+		tracker: tracker,
+		events:  events,
 	}
 }
 
@@ -63,6 +78,10 @@ func (c *Committer) Commit(ctx context.Context, zone database.Zone) error {
 	shouldNotify := false
 
 	err := c.db.UseTx(ctx, func(tx *database.Queries) error {
+		before, err := tx.GetZoneActiveRecords(ctx, zone.ID)
+		if err != nil {
+			return err
+		}
 		rowsUpdated, err := tx.CommitZoneRecords(ctx, zone.ID)
 		if err != nil {
 			return err
@@ -72,6 +91,10 @@ func (c *Committer) Commit(ctx context.Context, zone database.Zone) error {
 			return err
 		}
 		if rowsUpdated+rowsDeleted > 0 {
+			err = lintCommit(ctx, tx, zone, before)
+			if err != nil {
+				return err
+			}
 			shouldNotify = true
 			err = tx.UpdateZoneSerial(ctx, zone.ID)
 			if err != nil {
@@ -80,21 +103,66 @@ func (c *Committer) Commit(ctx context.Context, zone database.Zone) error {
 		}
 		return nil
 	})
+	if err != nil {
+		c.events.Fire(zone.ID, zone.Name, webhook.CommitFailed, commitEvent{Serial: uint32(zone.Serial), Error: err.Error()})
+		return err
+	}
+
+	return c.publish(ctx, zone, shouldNotify)
+}
+
+// Publish records a change to the published zone which was made outside the
+// staged records, such as a failover record switching to the backup value.
+// The serial is bumped and the zone rebuilt and notified like a commit.
+func (c *Committer) Publish(ctx context.Context, zone database.Zone) error {
+	err := c.db.UpdateZoneSerial(ctx, zone.ID)
+	if err != nil {
+		c.events.Fire(zone.ID, zone.Name, webhook.CommitFailed, commitEvent{Serial: uint32(zone.Serial), Error: err.Error()})
+		return err
+	}
+	return c.publish(ctx, zone, true)
+}
+
+func (c *Committer) publish(ctx context.Context, zone database.Zone, shouldNotify bool) error {
+	// Reload the zone to pick up the new serial
+	zone, err := c.db.GetZone(ctx, zone.ID)
 	if err != nil {
 		return err
 	}
 
 	err = c.b.Generate(ctx, zone)
 	if err != nil {
+		if shouldNotify {
+			c.events.Fire(zone.ID, zone.Name, webhook.CommitFailed, commitEvent{Serial: uint32(zone.Serial), Error: err.Error()})
+		}
 		return err
 	}
 
-	if shouldNotify {
-		return c.bindNotify(ctx, zone)
+	if !shouldNotify {
+		return nil
 	}
-	return nil
+	c.events.Fire(zone.ID, zone.Name, webhook.CommitSucceeded, commitEvent{Serial: uint32(zone.Serial)})
+	c.tracker.Track(zone)
+	if c.notify.HasSecondaries(zone.Name) {
+		c.notify.Notify(zone.Name, uint32(zone.Serial))
+		return nil
+	}
+	return c.backend.Notify(ctx, zone.Name)
 }
 
-func (c *Committer) bindNotify(ctx context.Context, zone database.Zone) error {
-	return exec.CommandContext(ctx, c.cmd.Rndc, "notify", zone.Name).Run()
+// lintCommit stops the commit when the staged changes add lint errors, the
+// changes stay staged so they can be fixed through the API
+func lintCommit(ctx context.Context, tx *database.Queries, zone database.Zone, before []database.Record) error {
+	after, err := tx.GetZoneActiveRecords(ctx, zone.ID)
+	if err != nil {
+		return err
+	}
+	problems := lint.NewErrors(
+		lint.Check(zone.Name, lint.FromActiveRecords(before)),
+		lint.Check(zone.Name, lint.FromActiveRecords(after)),
+	)
+	if len(problems) > 0 {
+		return errors.New("zone lint failed: " + lint.Message(problems))
+	}
+	return nil
 }
@@ -0,0 +1,28 @@
+CREATE TABLE IF NOT EXISTS webhooks
+(
+    id      BIGINT  NOT NULL PRIMARY KEY AUTO_INCREMENT,
+    zone_id BIGINT  NOT NULL,
+    url     TEXT    NOT NULL,
+    secret  TEXT    NOT NULL,
+    events  TEXT    NOT NULL,
+    active  BOOLEAN NOT NULL DEFAULT 1,
+
+    FOREIGN KEY (zone_id) REFERENCES zones (id) ON DELETE RESTRICT ON UPDATE RESTRICT,
+    INDEX webhook_zone_id (zone_id)
+);
+
+CREATE TABLE IF NOT EXISTS webhook_deliveries
+(
+    id          BIGINT  NOT NULL PRIMARY KEY AUTO_INCREMENT,
+    webhook_id  BIGINT  NOT NULL,
+    event       TEXT    NOT NULL,
+    payload     TEXT    NOT NULL,
+    attempt     INTEGER NOT NULL,
+    status_code INTEGER NOT NULL,
+    error       TEXT    NOT NULL,
+    success     BOOLEAN NOT NULL,
+    created_at  BIGINT  NOT NULL,
+
+    FOREIGN KEY (webhook_id) REFERENCES webhooks (id) ON DELETE CASCADE ON UPDATE RESTRICT,
+    INDEX webhook_delivery_webhook_id (webhook_id)
+);
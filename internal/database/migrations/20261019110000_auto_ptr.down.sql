@@ -0,0 +1,2 @@
+ALTER TABLE records
+    DROP COLUMN auto_ptr;
@@ -0,0 +1,2 @@
+ALTER TABLE records
+    ADD COLUMN auto_ptr BOOLEAN NOT NULL DEFAULT 0;
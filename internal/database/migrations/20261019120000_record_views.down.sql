@@ -0,0 +1,4 @@
+ALTER TABLE records
+    DROP COLUMN views;
+ALTER TABLE records
+    DROP COLUMN pre_views;
@@ -0,0 +1,4 @@
+ALTER TABLE records
+    ADD COLUMN views TEXT NOT NULL DEFAULT '';
+ALTER TABLE records
+    ADD COLUMN pre_views TEXT NOT NULL DEFAULT '';
@@ -0,0 +1 @@
+DROP TABLE health_checks;
@@ -0,0 +1,22 @@
+CREATE TABLE IF NOT EXISTS health_checks
+(
+    id             BIGINT  NOT NULL PRIMARY KEY AUTO_INCREMENT,
+    record_id      BIGINT  NOT NULL UNIQUE,
+    zone_id        BIGINT  NOT NULL,
+    backup         TEXT    NOT NULL,
+    backup_ttl     INTEGER NOT NULL,
+    check_type     TEXT    NOT NULL,
+    target         TEXT    NOT NULL,
+    http_status    INTEGER NOT NULL,
+    query_name     TEXT    NOT NULL,
+    query_type     TEXT    NOT NULL,
+    check_interval INTEGER NOT NULL,
+    threshold      INTEGER NOT NULL,
+    healthy        BOOLEAN NOT NULL DEFAULT 1,
+    checked_at     BIGINT  NOT NULL DEFAULT 0,
+    last_error     TEXT    NOT NULL DEFAULT '',
+
+    FOREIGN KEY (record_id) REFERENCES records (id) ON DELETE CASCADE ON UPDATE RESTRICT,
+    FOREIGN KEY (zone_id) REFERENCES zones (id) ON DELETE RESTRICT ON UPDATE RESTRICT,
+    INDEX health_check_zone_id (zone_id)
+);
@@ -0,0 +1,2 @@
+DROP TABLE pool_members;
+DROP TABLE pools;
@@ -0,0 +1,24 @@
+CREATE TABLE IF NOT EXISTS pools
+(
+    id      BIGINT  NOT NULL PRIMARY KEY AUTO_INCREMENT,
+    zone_id BIGINT  NOT NULL,
+    name    TEXT    NOT NULL,
+    type    TEXT    NOT NULL,
+    ttl     INTEGER NULL,
+    size    INTEGER NOT NULL,
+
+    FOREIGN KEY (zone_id) REFERENCES zones (id) ON DELETE RESTRICT ON UPDATE RESTRICT,
+    INDEX pool_zone_id (zone_id)
+);
+
+CREATE TABLE IF NOT EXISTS pool_members
+(
+    id      BIGINT  NOT NULL PRIMARY KEY AUTO_INCREMENT,
+    pool_id BIGINT  NOT NULL,
+    value   TEXT    NOT NULL,
+    weight  INTEGER NOT NULL,
+    enabled BOOLEAN NOT NULL DEFAULT 1,
+
+    FOREIGN KEY (pool_id) REFERENCES pools (id) ON DELETE CASCADE ON UPDATE RESTRICT,
+    INDEX pool_member_pool_id (pool_id)
+);
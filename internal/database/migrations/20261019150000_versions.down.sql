@@ -0,0 +1,4 @@
+ALTER TABLE zones
+    DROP COLUMN version;
+ALTER TABLE records
+    DROP COLUMN version;
@@ -0,0 +1,4 @@
+ALTER TABLE zones
+    ADD COLUMN version BIGINT NOT NULL DEFAULT 1;
+ALTER TABLE records
+    ADD COLUMN version BIGINT NOT NULL DEFAULT 1;
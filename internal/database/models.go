@@ -14,12 +14,47 @@ type BotToken struct {
 	ZoneID  int64 `json:"zone_id"`
 }
 
+type HealthCheck struct {
+	ID            int64  `json:"id"`
+	RecordID      int64  `json:"record_id"`
+	ZoneID        int64  `json:"zone_id"`
+	Backup        string `json:"backup"`
+	BackupTtl     int32  `json:"backup_ttl"`
+	CheckType     string `json:"check_type"`
+	Target        string `json:"target"`
+	HttpStatus    int32  `json:"http_status"`
+	QueryName     string `json:"query_name"`
+	QueryType     string `json:"query_type"`
+	CheckInterval int32  `json:"check_interval"`
+	Threshold     int32  `json:"threshold"`
+	Healthy       bool   `json:"healthy"`
+	CheckedAt     int64  `json:"checked_at"`
+	LastError     string `json:"last_error"`
+}
+
 type Owner struct {
 	ID     int64  `json:"id"`
 	ZoneID int64  `json:"zone_id"`
 	UserID string `json:"user_id"`
 }
 
+type Pool struct {
+	ID     int64       `json:"id"`
+	ZoneID int64       `json:"zone_id"`
+	Name   string      `json:"name"`
+	Type   string      `json:"type"`
+	Ttl    nulls.Int32 `json:"ttl"`
+	Size   int32       `json:"size"`
+}
+
+type PoolMember struct {
+	ID      int64  `json:"id"`
+	PoolID  int64  `json:"pool_id"`
+	Value   string `json:"value"`
+	Weight  int32  `json:"weight"`
+	Enabled bool   `json:"enabled"`
+}
+
 type Record struct {
 	ID        int64       `json:"id"`
 	Name      string      `json:"name"`
@@ -32,6 +67,31 @@ type Record struct {
 	PreValue  string      `json:"pre_value"`
 	PreActive bool        `json:"pre_active"`
 	PreDelete bool        `json:"pre_delete"`
+	AutoPtr   bool        `json:"auto_ptr"`
+	Views     string      `json:"views"`
+	PreViews  string      `json:"pre_views"`
+	Version   int64       `json:"version"`
+}
+
+type Webhook struct {
+	ID     int64  `json:"id"`
+	ZoneID int64  `json:"zone_id"`
+	Url    string `json:"url"`
+	Secret string `json:"secret"`
+	Events string `json:"events"`
+	Active bool   `json:"active"`
+}
+
+type WebhookDelivery struct {
+	ID         int64  `json:"id"`
+	WebhookID  int64  `json:"webhook_id"`
+	Event      string `json:"event"`
+	Payload    string `json:"payload"`
+	Attempt    int32  `json:"attempt"`
+	StatusCode int32  `json:"status_code"`
+	Error      string `json:"error"`
+	Success    bool   `json:"success"`
+	CreatedAt  int64  `json:"created_at"`
 }
 
 type Zone struct {
@@ -45,4 +105,5 @@ type Zone struct {
 	Ttl        int32  `json:"ttl"`
 	Active     bool   `json:"active"`
 	Nameserver string `json:"nameserver"`
+	Version    int64  `json:"version"`
 }
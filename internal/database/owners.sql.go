@@ -10,7 +10,7 @@ import (
 )
 
 const getOwnerByUserIdAndZone = `-- name: GetOwnerByUserIdAndZone :one
-SELECT owners.id, owners.zone_id, owners.user_id, zones.id, zones.name, zones.serial, zones.admin, zones.refresh, zones.retry, zones.expire, zones.ttl, zones.active, zones.nameserver
+SELECT owners.id, owners.zone_id, owners.user_id, zones.id, zones.name, zones.serial, zones.admin, zones.refresh, zones.retry, zones.expire, zones.ttl, zones.active, zones.nameserver, zones.version
 FROM owners
          INNER JOIN zones ON owners.zone_id = zones.id
 WHERE user_id = ?
@@ -44,6 +44,22 @@ func (q *Queries) GetOwnerByUserIdAndZone(ctx context.Context, arg GetOwnerByUse
 		&i.Zone.Ttl,
 		&i.Zone.Active,
 		&i.Zone.Nameserver,
+		&i.Zone.Version,
 	)
 	return i, err
 }
+
+const insertOwner = `-- name: InsertOwner :exec
+INSERT INTO owners (zone_id, user_id)
+VALUES (?, ?)
+`
+
+type InsertOwnerParams struct {
+	ZoneID int64  `json:"zone_id"`
+	UserID string `json:"user_id"`
+}
+
+func (q *Queries) InsertOwner(ctx context.Context, arg InsertOwnerParams) error {
+	_, err := q.db.ExecContext(ctx, insertOwner, arg.ZoneID, arg.UserID)
+	return err
+}
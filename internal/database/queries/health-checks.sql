@@ -0,0 +1,41 @@
+-- name: GetHealthChecks :many
+SELECT *
+FROM health_checks;
+
+-- name: GetZoneHealthChecks :many
+SELECT *
+FROM health_checks
+WHERE zone_id = ?;
+
+-- name: GetRecordHealthCheck :one
+SELECT *
+FROM health_checks
+WHERE record_id = ?
+  AND zone_id = ?;
+
+-- name: UpsertHealthCheck :exec
+INSERT INTO health_checks (record_id, zone_id, backup, backup_ttl, check_type, target, http_status, query_name,
+                           query_type, check_interval, threshold)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+ON DUPLICATE KEY UPDATE backup         = VALUES(backup),
+                        backup_ttl     = VALUES(backup_ttl),
+                        check_type     = VALUES(check_type),
+                        target         = VALUES(target),
+                        http_status    = VALUES(http_status),
+                        query_name     = VALUES(query_name),
+                        query_type     = VALUES(query_type),
+                        check_interval = VALUES(check_interval),
+                        threshold      = VALUES(threshold);
+
+-- name: DeleteHealthCheck :exec
+DELETE
+FROM health_checks
+WHERE record_id = ?
+  AND zone_id = ?;
+
+-- name: SetHealthCheckResult :exec
+UPDATE health_checks
+SET healthy    = ?,
+    checked_at = ?,
+    last_error = ?
+WHERE id = ?;
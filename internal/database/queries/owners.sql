@@ -4,3 +4,7 @@ FROM owners
          INNER JOIN zones ON owners.zone_id = zones.id
 WHERE user_id = ?
   AND zones.name = ?;
+
+-- name: InsertOwner :exec
+INSERT INTO owners (zone_id, user_id)
+VALUES (?, ?);
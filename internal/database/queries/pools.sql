@@ -0,0 +1,45 @@
+-- name: GetZonePools :many
+SELECT *
+FROM pools
+WHERE zone_id = ?
+ORDER BY id;
+
+-- name: GetZonePool :one
+SELECT *
+FROM pools
+WHERE zone_id = ?
+  AND name = ?
+  AND type = ?;
+
+-- name: GetZonePoolMembers :many
+SELECT pool_members.*
+FROM pool_members
+         INNER JOIN pools ON pool_members.pool_id = pools.id
+WHERE pools.zone_id = ?
+ORDER BY pool_members.id;
+
+-- name: InsertPool :execlastid
+INSERT INTO pools (zone_id, name, type, ttl, size)
+VALUES (?, ?, ?, ?, ?);
+
+-- name: UpdatePool :exec
+UPDATE pools
+SET ttl  = ?,
+    size = ?
+WHERE id = ?
+  AND zone_id = ?;
+
+-- name: DeletePool :exec
+DELETE
+FROM pools
+WHERE id = ?
+  AND zone_id = ?;
+
+-- name: InsertPoolMember :exec
+INSERT INTO pool_members (pool_id, value, weight, enabled)
+VALUES (?, ?, ?, ?);
+
+-- name: DeletePoolMembers :exec
+DELETE
+FROM pool_members
+WHERE pool_id = ?;
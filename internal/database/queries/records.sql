@@ -21,14 +21,36 @@ WHERE records.id = sqlc.arg(record_id)
   AND pre_delete = false;
 
 -- name: InsertRecordFromApi :execlastid
-INSERT INTO records (name, zone_id, ttl, type, value, active, pre_ttl, pre_value, pre_active, pre_delete)
-VALUES (?, ?, 0, ?, "", 0, ?, ?, ?, 0);
+INSERT INTO records (name, zone_id, ttl, type, value, active, pre_ttl, pre_value, pre_active, pre_delete, pre_views, auto_ptr)
+VALUES (?, ?, 0, ?, "", 0, ?, ?, ?, 0, ?, ?);
 
 -- name: UpdateRecordFromApi :exec
 UPDATE records
 SET pre_ttl    = ?,
     pre_value  = ?,
-    pre_active = ?
+    pre_active = ?,
+    version    = version + 1
+WHERE id = ?
+  AND zone_id = ?
+  AND pre_delete = false;
+
+-- name: UpdateRecordIfVersion :execrows
+UPDATE records
+SET pre_ttl    = ?,
+    pre_value  = ?,
+    pre_active = ?,
+    auto_ptr   = ?,
+    pre_views  = ?,
+    version    = version + 1
+WHERE id = ?
+  AND zone_id = ?
+  AND version = ?
+  AND pre_delete = false;
+
+-- name: SetRecordViews :exec
+UPDATE records
+SET pre_views = ?,
+    version   = version + 1
 WHERE id = ?
   AND zone_id = ?
   AND pre_delete = false;
@@ -40,17 +62,27 @@ WHERE id = sqlc.arg(record_id)
   AND zone_id = sqlc.arg(zone_id)
   AND pre_delete = false;
 
+-- name: DeleteRecordIfVersion :execrows
+UPDATE records
+SET pre_delete = TRUE
+WHERE id = sqlc.arg(record_id)
+  AND zone_id = sqlc.arg(zone_id)
+  AND version = sqlc.arg(version)
+  AND pre_delete = false;
+
 -- name: CommitZoneRecords :execrows
 UPDATE records
 SET ttl    = pre_ttl,
     value  = pre_value,
-    active = pre_active
+    active = pre_active,
+    views  = pre_views
 WHERE zone_id = ?
   AND (
     ttl != pre_ttl
         OR (ttl IS NULL) != (pre_ttl IS NULL)
         OR (`value` != pre_value)
         OR (active != pre_active)
+        OR (views != pre_views)
     )
   AND pre_delete = false;
 
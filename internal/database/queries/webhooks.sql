@@ -0,0 +1,45 @@
+-- name: GetZoneWebhooks :many
+SELECT *
+FROM webhooks
+WHERE zone_id = ?;
+
+-- name: GetActiveZoneWebhooks :many
+SELECT *
+FROM webhooks
+WHERE zone_id = ?
+  AND active = 1;
+
+-- name: GetZoneWebhook :one
+SELECT *
+FROM webhooks
+WHERE id = sqlc.arg(webhook_id)
+  AND zone_id = sqlc.arg(zone_id);
+
+-- name: InsertWebhook :execlastid
+INSERT INTO webhooks (zone_id, url, secret, events, active)
+VALUES (?, ?, ?, ?, ?);
+
+-- name: UpdateWebhook :exec
+UPDATE webhooks
+SET url    = ?,
+    events = ?,
+    active = ?
+WHERE id = ?
+  AND zone_id = ?;
+
+-- name: DeleteWebhook :exec
+DELETE
+FROM webhooks
+WHERE id = sqlc.arg(webhook_id)
+  AND zone_id = sqlc.arg(zone_id);
+
+-- name: InsertWebhookDelivery :execlastid
+INSERT INTO webhook_deliveries (webhook_id, event, payload, attempt, status_code, error, success, created_at)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?);
+
+-- name: GetWebhookDeliveries :many
+SELECT *
+FROM webhook_deliveries
+WHERE webhook_id = ?
+ORDER BY id DESC
+LIMIT 100;
@@ -16,9 +16,10 @@ WHERE id = ?;
 
 -- name: UpdateZoneSerial :exec
 UPDATE zones
-SET serial =
+SET serial  =
         IF(LEFT(serial, 8) = DATE_FORMAT(CURDATE(), '%Y%m%d'), serial + 1,
-           CAST(DATE_FORMAT(CURDATE(), '%Y%m%d') AS UNSIGNED) * 100 + 1)
+           CAST(DATE_FORMAT(CURDATE(), '%Y%m%d') AS UNSIGNED) * 100 + 1),
+    version = version + 1
 WHERE id = ?;
 
 -- name: LookupZone :one
@@ -26,10 +27,22 @@ SELECT id
 FROM zones
 WHERE name = ?;
 
--- name: UpdateZoneConfig :exec
+-- name: UpdateZoneConfig :execrows
 UPDATE zones
 SET refresh = ?,
     retry   = ?,
     expire  = ?,
-    ttl     = ?
-WHERE id = ?;
+    ttl     = ?,
+    version = version + 1
+WHERE id = ?
+  AND version = ?;
+
+-- name: GetReverseZones :many
+SELECT *
+FROM zones
+WHERE name LIKE '%.in-addr.arpa'
+   OR name LIKE '%.ip6.arpa';
+
+-- name: InsertZone :execlastid
+INSERT INTO zones (name, serial, admin, refresh, retry, expire, ttl, active, nameserver)
+VALUES (?, ?, ?, ?, ?, ?, ?, 1, ?);
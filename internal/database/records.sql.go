@@ -30,13 +30,15 @@ const commitZoneRecords = `-- name: CommitZoneRecords :execrows
 UPDATE records
 SET ttl    = pre_ttl,
     value  = pre_value,
-    active = pre_active
+    active = pre_active,
+    views  = pre_views
 WHERE zone_id = ?
   AND (
     ttl != pre_ttl
         OR (ttl IS NULL) != (pre_ttl IS NULL)
         OR (` + "`" + `value` + "`" + ` != pre_value)
         OR (active != pre_active)
+        OR (views != pre_views)
     )
   AND pre_delete = false
 `
@@ -67,8 +69,31 @@ func (q *Queries) DeleteRecordFromApi(ctx context.Context, arg DeleteRecordFromA
 	return err
 }
 
+const deleteRecordIfVersion = `-- name: DeleteRecordIfVersion :execrows
+UPDATE records
+SET pre_delete = TRUE
+WHERE id = ?
+  AND zone_id = ?
+  AND version = ?
+  AND pre_delete = false
+`
+
+type DeleteRecordIfVersionParams struct {
+	RecordID int64 `json:"record_id"`
+	ZoneID   int64 `json:"zone_id"`
+	Version  int64 `json:"version"`
+}
+
+func (q *Queries) DeleteRecordIfVersion(ctx context.Context, arg DeleteRecordIfVersionParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteRecordIfVersion, arg.RecordID, arg.ZoneID, arg.Version)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
 const getZoneActiveRecords = `-- name: GetZoneActiveRecords :many
-SELECT id, name, zone_id, ttl, type, value, active, pre_ttl, pre_value, pre_active, pre_delete
+SELECT id, name, zone_id, ttl, type, value, active, pre_ttl, pre_value, pre_active, pre_delete, auto_ptr, views, pre_views, version
 FROM records
 WHERE active = 1
   AND zone_id = ?
@@ -96,6 +121,10 @@ func (q *Queries) GetZoneActiveRecords(ctx context.Context, zoneID int64) ([]Rec
 			&i.PreValue,
 			&i.PreActive,
 			&i.PreDelete,
+			&i.AutoPtr,
+			&i.Views,
+			&i.PreViews,
+			&i.Version,
 		); err != nil {
 			return nil, err
 		}
@@ -111,7 +140,7 @@ func (q *Queries) GetZoneActiveRecords(ctx context.Context, zoneID int64) ([]Rec
 }
 
 const getZoneRecord = `-- name: GetZoneRecord :one
-SELECT records.id, records.name, records.zone_id, records.ttl, records.type, records.value, records.active, records.pre_ttl, records.pre_value, records.pre_active, records.pre_delete, zones.name
+SELECT records.id, records.name, records.zone_id, records.ttl, records.type, records.value, records.active, records.pre_ttl, records.pre_value, records.pre_active, records.pre_delete, records.auto_ptr, records.views, records.pre_views, records.version, zones.name
 FROM records
          INNER JOIN zones ON records.zone_id = zones.id
 WHERE records.id = ?
@@ -144,13 +173,17 @@ func (q *Queries) GetZoneRecord(ctx context.Context, arg GetZoneRecordParams) (G
 		&i.Record.PreValue,
 		&i.Record.PreActive,
 		&i.Record.PreDelete,
+		&i.Record.AutoPtr,
+		&i.Record.Views,
+		&i.Record.PreViews,
+		&i.Record.Version,
 		&i.Name,
 	)
 	return i, err
 }
 
 const getZoneRecords = `-- name: GetZoneRecords :many
-SELECT records.id, records.name, records.zone_id, records.ttl, records.type, records.value, records.active, records.pre_ttl, records.pre_value, records.pre_active, records.pre_delete, zones.name
+SELECT records.id, records.name, records.zone_id, records.ttl, records.type, records.value, records.active, records.pre_ttl, records.pre_value, records.pre_active, records.pre_delete, records.auto_ptr, records.views, records.pre_views, records.version, zones.name
 FROM records
          INNER JOIN zones ON records.zone_id = zones.id
 WHERE zone_id = ?
@@ -183,6 +216,10 @@ func (q *Queries) GetZoneRecords(ctx context.Context, zoneID int64) ([]GetZoneRe
 			&i.Record.PreValue,
 			&i.Record.PreActive,
 			&i.Record.PreDelete,
+			&i.Record.AutoPtr,
+			&i.Record.Views,
+			&i.Record.PreViews,
+			&i.Record.Version,
 			&i.Name,
 		); err != nil {
 			return nil, err
@@ -199,8 +236,8 @@ func (q *Queries) GetZoneRecords(ctx context.Context, zoneID int64) ([]GetZoneRe
 }
 
 const insertRecordFromApi = `-- name: InsertRecordFromApi :execlastid
-INSERT INTO records (name, zone_id, ttl, type, value, active, pre_ttl, pre_value, pre_active, pre_delete)
-VALUES (?, ?, 0, ?, "", 0, ?, ?, ?, 0)
+INSERT INTO records (name, zone_id, ttl, type, value, active, pre_ttl, pre_value, pre_active, pre_delete, pre_views, auto_ptr)
+VALUES (?, ?, 0, ?, "", 0, ?, ?, ?, 0, ?, ?)
 `
 
 type InsertRecordFromApiParams struct {
@@ -210,6 +247,8 @@ type InsertRecordFromApiParams struct {
 	PreTtl    nulls.Int32 `json:"pre_ttl"`
 	PreValue  string      `json:"pre_value"`
 	PreActive bool        `json:"pre_active"`
+	PreViews  string      `json:"pre_views"`
+	AutoPtr   bool        `json:"auto_ptr"`
 }
 
 func (q *Queries) InsertRecordFromApi(ctx context.Context, arg InsertRecordFromApiParams) (int64, error) {
@@ -220,6 +259,8 @@ func (q *Queries) InsertRecordFromApi(ctx context.Context, arg InsertRecordFromA
 		arg.PreTtl,
 		arg.PreValue,
 		arg.PreActive,
+		arg.PreViews,
+		arg.AutoPtr,
 	)
 	if err != nil {
 		return 0, err
@@ -227,11 +268,32 @@ func (q *Queries) InsertRecordFromApi(ctx context.Context, arg InsertRecordFromA
 	return result.LastInsertId()
 }
 
+const setRecordViews = `-- name: SetRecordViews :exec
+UPDATE records
+SET pre_views = ?,
+    version   = version + 1
+WHERE id = ?
+  AND zone_id = ?
+  AND pre_delete = false
+`
+
+type SetRecordViewsParams struct {
+	PreViews string `json:"pre_views"`
+	ID       int64  `json:"id"`
+	ZoneID   int64  `json:"zone_id"`
+}
+
+func (q *Queries) SetRecordViews(ctx context.Context, arg SetRecordViewsParams) error {
+	_, err := q.db.ExecContext(ctx, setRecordViews, arg.PreViews, arg.ID, arg.ZoneID)
+	return err
+}
+
 const updateRecordFromApi = `-- name: UpdateRecordFromApi :exec
 UPDATE records
 SET pre_ttl    = ?,
     pre_value  = ?,
-    pre_active = ?
+    pre_active = ?,
+    version    = version + 1
 WHERE id = ?
   AND zone_id = ?
   AND pre_delete = false
@@ -255,3 +317,45 @@ func (q *Queries) UpdateRecordFromApi(ctx context.Context, arg UpdateRecordFromA
 	)
 	return err
 }
+
+const updateRecordIfVersion = `-- name: UpdateRecordIfVersion :execrows
+UPDATE records
+SET pre_ttl    = ?,
+    pre_value  = ?,
+    pre_active = ?,
+    auto_ptr   = ?,
+    pre_views  = ?,
+    version    = version + 1
+WHERE id = ?
+  AND zone_id = ?
+  AND version = ?
+  AND pre_delete = false
+`
+
+type UpdateRecordIfVersionParams struct {
+	PreTtl    nulls.Int32 `json:"pre_ttl"`
+	PreValue  string      `json:"pre_value"`
+	PreActive bool        `json:"pre_active"`
+	AutoPtr   bool        `json:"auto_ptr"`
+	PreViews  string      `json:"pre_views"`
+	ID        int64       `json:"id"`
+	ZoneID    int64       `json:"zone_id"`
+	Version   int64       `json:"version"`
+}
+
+func (q *Queries) UpdateRecordIfVersion(ctx context.Context, arg UpdateRecordIfVersionParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, updateRecordIfVersion,
+		arg.PreTtl,
+		arg.PreValue,
+		arg.PreActive,
+		arg.AutoPtr,
+		arg.PreViews,
+		arg.ID,
+		arg.ZoneID,
+		arg.Version,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
@@ -24,3 +24,51 @@ func (q *Queries) UseTx(ctx context.Context, cb func(tx *Queries) error) error {
 	}
 	return tx.Commit()
 }
+
+// RecordTx is the subset of queries used to stage several record changes at
+// once, this allows routes to accept a fake in tests.
+type RecordTx interface {
+	GetZoneRecords(ctx context.Context, zoneID int64) ([]GetZoneRecordsRow, error)
+	GetZoneRecord(ctx context.Context, arg GetZoneRecordParams) (GetZoneRecordRow, error)
+	InsertRecordFromApi(ctx context.Context, arg InsertRecordFromApiParams) (int64, error)
+	UpdateRecordFromApi(ctx context.Context, arg UpdateRecordFromApiParams) error
+	DeleteRecordFromApi(ctx context.Context, arg DeleteRecordFromApiParams) error
+	UpdateRecordIfVersion(ctx context.Context, arg UpdateRecordIfVersionParams) (int64, error)
+	DeleteRecordIfVersion(ctx context.Context, arg DeleteRecordIfVersionParams) (int64, error)
+	SetRecordViews(ctx context.Context, arg SetRecordViewsParams) error
+	GetReverseZones(ctx context.Context) ([]Zone, error)
+}
+
+func (q *Queries) UseRecordTx(ctx context.Context, cb func(tx RecordTx) error) error {
+	return q.UseTx(ctx, func(tx *Queries) error {
+		return cb(tx)
+	})
+}
+
+// ZoneTx is the subset of queries used to create zones along with their owner
+type ZoneTx interface {
+	LookupZone(ctx context.Context, name string) (int64, error)
+	InsertZone(ctx context.Context, arg InsertZoneParams) (int64, error)
+	InsertOwner(ctx context.Context, arg InsertOwnerParams) error
+}
+
+func (q *Queries) UseZoneTx(ctx context.Context, cb func(tx ZoneTx) error) error {
+	return q.UseTx(ctx, func(tx *Queries) error {
+		return cb(tx)
+	})
+}
+
+// PoolTx is the subset of queries used to replace a pool and its members
+type PoolTx interface {
+	GetZonePool(ctx context.Context, arg GetZonePoolParams) (Pool, error)
+	InsertPool(ctx context.Context, arg InsertPoolParams) (int64, error)
+	UpdatePool(ctx context.Context, arg UpdatePoolParams) error
+	DeletePoolMembers(ctx context.Context, poolID int64) error
+	InsertPoolMember(ctx context.Context, arg InsertPoolMemberParams) error
+}
+
+func (q *Queries) UsePoolTx(ctx context.Context, cb func(tx PoolTx) error) error {
+	return q.UseTx(ctx, func(tx *Queries) error {
+		return cb(tx)
+	})
+}
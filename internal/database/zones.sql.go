@@ -10,7 +10,7 @@ import (
 )
 
 const getActiveZones = `-- name: GetActiveZones :many
-SELECT id, name, serial, admin, refresh, retry, expire, ttl, active, nameserver
+SELECT id, name, serial, admin, refresh, retry, expire, ttl, active, nameserver, version
 FROM zones
 WHERE active = 1
 `
@@ -35,6 +35,7 @@ func (q *Queries) GetActiveZones(ctx context.Context) ([]Zone, error) {
 			&i.Ttl,
 			&i.Active,
 			&i.Nameserver,
+			&i.Version,
 		); err != nil {
 			return nil, err
 		}
@@ -50,7 +51,7 @@ func (q *Queries) GetActiveZones(ctx context.Context) ([]Zone, error) {
 }
 
 const getOwnedZones = `-- name: GetOwnedZones :many
-SELECT zones.id, zones.name, zones.serial, zones.admin, zones.refresh, zones.retry, zones.expire, zones.ttl, zones.active, zones.nameserver, owners.user_id
+SELECT zones.id, zones.name, zones.serial, zones.admin, zones.refresh, zones.retry, zones.expire, zones.ttl, zones.active, zones.nameserver, zones.version, owners.user_id
 FROM zones
          INNER JOIN owners ON zones.id = owners.zone_id
 WHERE owners.user_id = ?
@@ -81,6 +82,7 @@ func (q *Queries) GetOwnedZones(ctx context.Context, userID string) ([]GetOwnedZ
 			&i.Zone.Ttl,
 			&i.Zone.Active,
 			&i.Zone.Nameserver,
+			&i.Zone.Version,
 			&i.UserID,
 		); err != nil {
 			return nil, err
@@ -96,8 +98,50 @@ func (q *Queries) GetOwnedZones(ctx context.Context, userID string) ([]GetOwnedZ
 	return items, nil
 }
 
+const getReverseZones = `-- name: GetReverseZones :many
+SELECT id, name, serial, admin, refresh, retry, expire, ttl, active, nameserver, version
+FROM zones
+WHERE name LIKE '%.in-addr.arpa'
+   OR name LIKE '%.ip6.arpa'
+`
+
+func (q *Queries) GetReverseZones(ctx context.Context) ([]Zone, error) {
+	rows, err := q.db.QueryContext(ctx, getReverseZones)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Zone
+	for rows.Next() {
+		var i Zone
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.Serial,
+			&i.Admin,
+			&i.Refresh,
+			&i.Retry,
+			&i.Expire,
+			&i.Ttl,
+			&i.Active,
+			&i.Nameserver,
+			&i.Version,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getZone = `-- name: GetZone :one
-SELECT id, name, serial, admin, refresh, retry, expire, ttl, active, nameserver
+SELECT id, name, serial, admin, refresh, retry, expire, ttl, active, nameserver, version
 FROM zones
 WHERE id = ?
 `
@@ -116,10 +160,44 @@ func (q *Queries) GetZone(ctx context.Context, id int64) (Zone, error) {
 		&i.Ttl,
 		&i.Active,
 		&i.Nameserver,
+		&i.Version,
 	)
 	return i, err
 }
 
+const insertZone = `-- name: InsertZone :execlastid
+INSERT INTO zones (name, serial, admin, refresh, retry, expire, ttl, active, nameserver)
+VALUES (?, ?, ?, ?, ?, ?, ?, 1, ?)
+`
+
+type InsertZoneParams struct {
+	Name       string `json:"name"`
+	Serial     int64  `json:"serial"`
+	Admin      string `json:"admin"`
+	Refresh    int32  `json:"refresh"`
+	Retry      int32  `json:"retry"`
+	Expire     int32  `json:"expire"`
+	Ttl        int32  `json:"ttl"`
+	Nameserver string `json:"nameserver"`
+}
+
+func (q *Queries) InsertZone(ctx context.Context, arg InsertZoneParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, insertZone,
+		arg.Name,
+		arg.Serial,
+		arg.Admin,
+		arg.Refresh,
+		arg.Retry,
+		arg.Expire,
+		arg.Ttl,
+		arg.Nameserver,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.LastInsertId()
+}
+
 const lookupZone = `-- name: LookupZone :one
 SELECT id
 FROM zones
@@ -133,13 +211,15 @@ func (q *Queries) LookupZone(ctx context.Context, name string) (int64, error) {
 	return id, err
 }
 
-const updateZoneConfig = `-- name: UpdateZoneConfig :exec
+const updateZoneConfig = `-- name: UpdateZoneConfig :execrows
 UPDATE zones
 SET refresh = ?,
     retry   = ?,
     expire  = ?,
-    ttl     = ?
+    ttl     = ?,
+    version = version + 1
 WHERE id = ?
+  AND version = ?
 `
 
 type UpdateZoneConfigParams struct {
@@ -148,24 +228,30 @@ type UpdateZoneConfigParams struct {
 	Expire  int32 `json:"expire"`
 	Ttl     int32 `json:"ttl"`
 	ID      int64 `json:"id"`
+	Version int64 `json:"version"`
 }
 
-func (q *Queries) UpdateZoneConfig(ctx context.Context, arg UpdateZoneConfigParams) error {
-	_, err := q.db.ExecContext(ctx, updateZoneConfig,
+func (q *Queries) UpdateZoneConfig(ctx context.Context, arg UpdateZoneConfigParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, updateZoneConfig,
 		arg.Refresh,
 		arg.Retry,
 		arg.Expire,
 		arg.Ttl,
 		arg.ID,
+		arg.Version,
 	)
-	return err
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
 }
 
 const updateZoneSerial = `-- name: UpdateZoneSerial :exec
 UPDATE zones
-SET serial =
+SET serial  =
         IF(LEFT(serial, 8) = DATE_FORMAT(CURDATE(), '%Y%m%d'), serial + 1,
-           CAST(DATE_FORMAT(CURDATE(), '%Y%m%d') AS UNSIGNED) * 100 + 1)
+           CAST(DATE_FORMAT(CURDATE(), '%Y%m%d') AS UNSIGNED) * 100 + 1),
+    version = version + 1
 WHERE id = ?
 `
 
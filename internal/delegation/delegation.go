@@ -0,0 +1,238 @@
+package delegation
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/1f349/verbena/internal/utils"
+	"github.com/1f349/verbena/rest"
+)
+
+// FromRecords groups the NS records below the apex along with their DS and
+// glue records into delegations sorted by name.
+func FromRecords(zoneName string, records []rest.Record) []rest.Delegation {
+	byName := make(map[string]*rest.Delegation)
+	var names []string
+	for _, r := range records {
+		name := normalName(r.Name)
+		if r.Type != "NS" || name == "" {
+			continue
+		}
+		d, ok := byName[name]
+		if !ok {
+			d = &rest.Delegation{
+				Name:        name,
+				Nameservers: []string{},
+				Glue:        []rest.Glue{},
+				DS:          []rest.DelegationSigner{},
+			}
+			byName[name] = d
+			names = append(names, name)
+		}
+		d.Nameservers = append(d.Nameservers, normalHost(r.Value.Target))
+	}
+
+	out := make([]rest.Delegation, 0, len(names))
+	slices.Sort(names)
+	for _, name := range names {
+		d := byName[name]
+		for _, r := range Records(zoneName, name, records) {
+			switch r.Type {
+			case "DS":
+				d.DS = append(d.DS, rest.DelegationSigner{
+					KeyTag:     r.Value.KeyTag,
+					Algorithm:  r.Value.Algorithm,
+					DigestType: r.Value.DigestType,
+					Digest:     r.Value.Digest,
+				})
+			case "A", "AAAA":
+				if r.Value.IP != nil {
+					d.Glue = append(d.Glue, rest.Glue{
+						Nameserver: hostFqdn(zoneName, r.Name),
+						IP:         *r.Value.IP,
+					})
+				}
+			}
+		}
+		out = append(out, *d)
+	}
+	return out
+}
+
+// Records returns the NS, DS and glue records making up the delegation of name,
+// address records for nameservers outside the child are not glue so they are
+// left alone
+func Records(zoneName, name string, records []rest.Record) []rest.Record {
+	name = normalName(name)
+	child := hostFqdn(zoneName, name)
+	targets := make(map[string]bool)
+	for _, r := range records {
+		target := normalHost(r.Value.Target)
+		if r.Type == "NS" && normalName(r.Name) == name && isBelow(target, child) {
+			targets[target] = true
+		}
+	}
+
+	var out []rest.Record
+	for _, r := range records {
+		if normalName(r.Name) == name && (r.Type == "NS" || r.Type == "DS") {
+			out = append(out, r)
+			continue
+		}
+		if (r.Type == "A" || r.Type == "AAAA") && targets[hostFqdn(zoneName, r.Name)] {
+			out = append(out, r)
+		}
+	}
+	return out
+}
+
+// ToRecords converts a delegation into the records to store in the zone
+func ToRecords(zoneName, name string, d rest.PutDelegation) []rest.CreateRecord {
+	name = normalName(name)
+	records := make([]rest.CreateRecord, 0, len(d.Nameservers)+len(d.Glue)+len(d.DS))
+	for _, ns := range d.Nameservers {
+		records = append(records, rest.CreateRecord{
+			Name:   name,
+			Type:   "NS",
+			Value:  rest.RecordValue{Target: normalHost(ns)},
+			Active: true,
+		})
+	}
+	for _, ds := range d.DS {
+		records = append(records, rest.CreateRecord{
+			Name:   name,
+			Type:   "DS",
+			Value:  ds.RecordValue(),
+			Active: true,
+		})
+	}
+	for _, glue := range d.Glue {
+		ip := glue.IP
+		ty := "A"
+		if ip.Is6() {
+			ty = "AAAA"
+		}
+		records = append(records, rest.CreateRecord{
+			Name:   relativeName(zoneName, normalHost(glue.Nameserver)),
+			Type:   ty,
+			Value:  rest.RecordValue{IP: &ip},
+			Active: true,
+		})
+	}
+	return records
+}
+
+// Validate checks the delegation is well-formed, glue is only allowed for
+// nameservers inside the child zone and those nameservers must have glue.
+func Validate(zoneName, name string, d rest.PutDelegation) error {
+	name = normalName(name)
+	if name == "" || name == "*" || strings.HasPrefix(name, "*.") || !utils.ValidateDomainName(name) || strings.HasSuffix(name, ".") {
+		return errors.New("invalid delegation name")
+	}
+	if len(d.Nameservers) == 0 {
+		return errors.New("at least one nameserver is required")
+	}
+
+	child := hostFqdn(zoneName, name)
+	needsGlue := make(map[string]bool)
+	for _, ns := range d.Nameservers {
+		if !utils.ValidateDomainName(ns) {
+			return fmt.Errorf("invalid nameserver: %s", ns)
+		}
+		if isBelow(normalHost(ns), child) {
+			needsGlue[normalHost(ns)] = true
+		}
+	}
+
+	hasGlue := make(map[string]bool)
+	for _, glue := range d.Glue {
+		ns := normalHost(glue.Nameserver)
+		if !needsGlue[ns] {
+			return fmt.Errorf("glue for %s is not allowed as it is not a nameserver inside %s", glue.Nameserver, child)
+		}
+		if !glue.IP.IsValid() || glue.IP.Is4In6() {
+			return fmt.Errorf("invalid glue address for %s", glue.Nameserver)
+		}
+		hasGlue[ns] = true
+	}
+	for ns := range needsGlue {
+		if !hasGlue[ns] {
+			return fmt.Errorf("nameserver %s is inside %s and requires glue", ns, child)
+		}
+	}
+
+	for _, ds := range d.DS {
+		if !ds.RecordValue().IsValidForType("DS") {
+			return fmt.Errorf("invalid DS record for key tag %d", ds.KeyTag)
+		}
+	}
+	return nil
+}
+
+// Diff returns the records to create and the existing records to remove so
+// the existing records match the wanted records
+func Diff(existing []rest.Record, want []rest.CreateRecord) (create []rest.CreateRecord, remove []rest.Record) {
+	used := make(map[int]bool)
+outer:
+	for _, w := range want {
+		for i, e := range existing {
+			if !used[i] && recordKey(e.Name, e.Type, e.Value) == recordKey(w.Name, w.Type, w.Value) {
+				used[i] = true
+				continue outer
+			}
+		}
+		create = append(create, w)
+	}
+	for i, e := range existing {
+		if !used[i] {
+			remove = append(remove, e)
+		}
+	}
+	return create, remove
+}
+
+// ChildName returns the fully qualified name of the child zone without the
+// trailing dot, this matches how zone names are stored
+func ChildName(zoneName, name string) string {
+	return hostFqdn(zoneName, name)
+}
+
+func recordKey(name, ty string, value rest.RecordValue) string {
+	return normalName(name) + "\t" + ty + "\t" + strings.ToLower(value.ToValueString(ty))
+}
+
+func normalName(name string) string {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if name == "@" {
+		return ""
+	}
+	return name
+}
+
+func normalHost(host string) string {
+	return strings.TrimSuffix(strings.ToLower(strings.TrimSpace(host)), ".")
+}
+
+// hostFqdn converts a record name relative to the zone into a hostname
+func hostFqdn(zoneName, name string) string {
+	name = normalName(name)
+	if name == "" {
+		return normalHost(zoneName)
+	}
+	return name + "." + normalHost(zoneName)
+}
+
+// relativeName converts a hostname inside the zone into a record name
+func relativeName(zoneName, host string) string {
+	zoneName = normalHost(zoneName)
+	if host == zoneName {
+		return ""
+	}
+	return strings.TrimSuffix(host, "."+zoneName)
+}
+
+func isBelow(host, parent string) bool {
+	return host == parent || strings.HasSuffix(host, "."+parent)
+}
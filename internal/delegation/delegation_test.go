@@ -0,0 +1,165 @@
+package delegation
+
+import (
+	"context"
+	"database/sql"
+	"net/netip"
+	"strings"
+	"testing"
+
+	"github.com/1f349/verbena/conf"
+	"github.com/1f349/verbena/internal/database"
+	"github.com/1f349/verbena/rest"
+	"github.com/miekg/dns"
+	"github.com/stretchr/testify/assert"
+)
+
+func ipPtr(s string) *netip.Addr {
+	ip := netip.MustParseAddr(s)
+	return &ip
+}
+
+func testRecords() []rest.Record {
+	return []rest.Record{
+		{ID: 1, Name: "@", Type: "A", Value: rest.RecordValue{IP: ipPtr("192.0.2.1")}},
+		{ID: 2, Name: "child", Type: "NS", Value: rest.RecordValue{Target: "ns1.child.example.com"}},
+		{ID: 3, Name: "child", Type: "NS", Value: rest.RecordValue{Target: "ns.example.net."}},
+		{ID: 4, Name: "ns1.child", Type: "A", Value: rest.RecordValue{IP: ipPtr("192.0.2.53")}},
+		{ID: 5, Name: "child", Type: "DS", Value: rest.RecordValue{KeyTag: 12345, Algorithm: 13, DigestType: 2, Digest: strings.Repeat("3f", 32)}},
+		{ID: 6, Name: "other", Type: "NS", Value: rest.RecordValue{Target: "ns1.example.com"}},
+		{ID: 7, Name: "ns1", Type: "A", Value: rest.RecordValue{IP: ipPtr("192.0.2.2")}},
+	}
+}
+
+func TestFromRecords(t *testing.T) {
+	assert.Equal(t, []rest.Delegation{
+		{
+			Name:        "child",
+			Nameservers: []string{"ns1.child.example.com", "ns.example.net"},
+			Glue:        []rest.Glue{{Nameserver: "ns1.child.example.com", IP: netip.MustParseAddr("192.0.2.53")}},
+			DS:          []rest.DelegationSigner{{KeyTag: 12345, Algorithm: 13, DigestType: 2, Digest: strings.Repeat("3f", 32)}},
+		},
+		{
+			// ns1.example.com is outside the child so the address is not glue
+			Name:        "other",
+			Nameservers: []string{"ns1.example.com"},
+			Glue:        []rest.Glue{},
+			DS:          []rest.DelegationSigner{},
+		},
+	}, FromRecords("example.com", testRecords()))
+}
+
+func TestValidate(t *testing.T) {
+	glue := []rest.Glue{{Nameserver: "ns1.child.example.com", IP: netip.MustParseAddr("192.0.2.53")}}
+	for _, i := range []struct {
+		name string
+		d    rest.PutDelegation
+		err  string
+	}{
+		{"child", rest.PutDelegation{Nameservers: []string{"ns1.child.example.com"}, Glue: glue}, ""},
+		{"child", rest.PutDelegation{Nameservers: []string{"ns.example.net"}}, ""},
+		{"@", rest.PutDelegation{Nameservers: []string{"ns.example.net"}}, "invalid delegation name"},
+		{"*.child", rest.PutDelegation{Nameservers: []string{"ns.example.net"}}, "invalid delegation name"},
+		{"child", rest.PutDelegation{}, "at least one nameserver is required"},
+		{"child", rest.PutDelegation{Nameservers: []string{"ns1.child.example.com"}}, "nameserver ns1.child.example.com is inside child.example.com and requires glue"},
+		{"child", rest.PutDelegation{Nameservers: []string{"ns.example.net"}, Glue: []rest.Glue{{Nameserver: "ns.example.net", IP: netip.MustParseAddr("192.0.2.53")}}}, "glue for ns.example.net is not allowed as it is not a nameserver inside child.example.com"},
+		{"child", rest.PutDelegation{Nameservers: []string{"ns.example.net"}, DS: []rest.DelegationSigner{{KeyTag: 1, Algorithm: 13, DigestType: 2, Digest: "abcd"}}}, "invalid DS record for key tag 1"},
+	} {
+		err := Validate("example.com", i.name, i.d)
+		if i.err == "" {
+			assert.NoError(t, err)
+		} else {
+			assert.EqualError(t, err, i.err)
+		}
+	}
+}
+
+func TestDiff(t *testing.T) {
+	records := testRecords()
+	existing := Records("example.com", "child", records)
+	assert.Len(t, existing, 4)
+
+	create, remove := Diff(existing, ToRecords("example.com", "child", rest.PutDelegation{
+		Nameservers: []string{"ns1.child.example.com", "ns2.child.example.com"},
+		Glue: []rest.Glue{
+			{Nameserver: "ns1.child.example.com", IP: netip.MustParseAddr("192.0.2.53")},
+			{Nameserver: "ns2.child.example.com", IP: netip.MustParseAddr("2001:db8::53")},
+		},
+	}))
+	assert.Equal(t, []rest.CreateRecord{
+		{Name: "child", Type: "NS", Value: rest.RecordValue{Target: "ns2.child.example.com"}, Active: true},
+		{Name: "ns2.child", Type: "AAAA", Value: rest.RecordValue{IP: ipPtr("2001:db8::53")}, Active: true},
+	}, create)
+	assert.Equal(t, []rest.Record{records[2], records[4]}, remove)
+}
+
+type syncTestQueries struct {
+	rows     []database.GetZoneRecordsRow
+	inserted []database.InsertRecordFromApiParams
+	deleted  []int64
+}
+
+func (s *syncTestQueries) GetActiveZones(ctx context.Context) ([]database.Zone, error) {
+	return nil, nil
+}
+
+func (s *syncTestQueries) GetZone(ctx context.Context, id int64) (database.Zone, error) {
+	if id != 2 {
+		return database.Zone{}, sql.ErrNoRows
+	}
+	return database.Zone{ID: 2, Name: "child.example.com", Active: true}, nil
+}
+
+func (s *syncTestQueries) LookupZone(ctx context.Context, name string) (int64, error) {
+	if name != "child.example.com" {
+		return 0, sql.ErrNoRows
+	}
+	return 2, nil
+}
+
+func (s *syncTestQueries) GetZoneRecords(ctx context.Context, zoneID int64) ([]database.GetZoneRecordsRow, error) {
+	return s.rows, nil
+}
+
+func (s *syncTestQueries) InsertRecordFromApi(ctx context.Context, arg database.InsertRecordFromApiParams) (int64, error) {
+	s.inserted = append(s.inserted, arg)
+	return int64(100 + len(s.inserted)), nil
+}
+
+func (s *syncTestQueries) DeleteRecordFromApi(ctx context.Context, arg database.DeleteRecordFromApiParams) error {
+	s.deleted = append(s.deleted, arg.RecordID)
+	return nil
+}
+
+func TestSyncer_Sync(t *testing.T) {
+	ksk, err := dns.NewRR("child.example.com. 3600 IN DNSKEY 257 3 13 kXKkvWU3vGYfTJGl3qBd4qhiWp5aRs7YtkCJxD2d+t7KXqwahww5IgJtxJT2yFItlggazyfXqJEVOmMJ3qT0tQ==")
+	if err != nil {
+		t.Fatal(err)
+	}
+	zsk, err := dns.NewRR("child.example.com. 3600 IN DNSKEY 256 3 13 oJMRESz5E4gYzS/q6XDrvU1qMPYIjCWzJaOau8XNEZeqCYKD5ar0IRd8KqXXFJkqmVfRvMGPmM1x8fGAa2XhSA==")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := DelegationSigners([]*dns.DNSKEY{ksk.(*dns.DNSKEY), zsk.(*dns.DNSKEY)})
+	assert.Len(t, want, 1)
+
+	q := &syncTestQueries{
+		rows: []database.GetZoneRecordsRow{
+			{Record: database.Record{ID: 2, ZoneID: 1, Name: "child", Type: "NS", PreValue: "ns.example.net", PreActive: true}},
+			{Record: database.Record{ID: 3, ZoneID: 1, Name: "child", Type: "DS", PreValue: "1\t13\t2\t" + strings.Repeat("3f", 32), PreActive: true}},
+			{Record: database.Record{ID: 4, ZoneID: 1, Name: "other", Type: "NS", PreValue: "ns.example.net", PreActive: true}},
+		},
+	}
+	s := NewSyncer(q, 0, conf.NameserverConf{})
+	s.lookupKeys = func(ctx context.Context, child database.Zone) ([]*dns.DNSKEY, error) {
+		assert.Equal(t, "child.example.com", child.Name)
+		return []*dns.DNSKEY{ksk.(*dns.DNSKEY), zsk.(*dns.DNSKEY)}, nil
+	}
+
+	err = s.Sync(t.Context(), database.Zone{ID: 1, Name: "example.com"})
+	assert.NoError(t, err)
+	assert.Equal(t, []database.InsertRecordFromApiParams{
+		{Name: "child", ZoneID: 1, Type: "DS", PreValue: want[0].RecordValue().ToValueString("DS"), PreActive: true},
+	}, q.inserted)
+	assert.Equal(t, []int64{3}, q.deleted)
+}
@@ -0,0 +1,235 @@
+package delegation
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/1f349/verbena/conf"
+	"github.com/1f349/verbena/internal/database"
+	"github.com/1f349/verbena/logger"
+	"github.com/1f349/verbena/rest"
+	"github.com/miekg/dns"
+)
+
+type lookupQueries interface {
+	LookupZone(ctx context.Context, name string) (int64, error)
+}
+
+type syncQueries interface {
+	GetActiveZones(ctx context.Context) ([]database.Zone, error)
+	GetZone(ctx context.Context, id int64) (database.Zone, error)
+	LookupZone(ctx context.Context, name string) (int64, error)
+	GetZoneRecords(ctx context.Context, zoneID int64) ([]database.GetZoneRecordsRow, error)
+	InsertRecordFromApi(ctx context.Context, arg database.InsertRecordFromApiParams) (int64, error)
+	DeleteRecordFromApi(ctx context.Context, arg database.DeleteRecordFromApiParams) error
+}
+
+// Syncer keeps the DS records of delegations in sync with the DNSKEY records
+// published by child zones which are also hosted by Verbena. The changes are
+// staged like any other record change and picked up by the committer.
+type Syncer struct {
+	db          syncQueries
+	tick        time.Duration
+	nameservers conf.NameserverConf
+	client      *dns.Client
+	lookupKeys  func(ctx context.Context, child database.Zone) ([]*dns.DNSKEY, error)
+}
+
+func NewSyncer(db syncQueries, tick time.Duration, nameservers conf.NameserverConf) *Syncer {
+	s := &Syncer{
+		db:          db,
+		tick:        tick,
+		nameservers: nameservers,
+		client:      &dns.Client{Net: "tcp", Timeout: 10 * time.Second},
+	}
+	s.lookupKeys = s.queryKeys
+	return s
+}
+
+func (s *Syncer) Start() {
+	go s.internalTick()
+}
+
+func (s *Syncer) internalTick() {
+	t := time.NewTicker(s.tick)
+	for range t.C {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+		zones, err := s.db.GetActiveZones(ctx)
+		cancel()
+		if err != nil {
+			logger.Logger.Error("Failed to get list of active zones", "err", err)
+			continue
+		}
+		for _, i := range zones {
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+			err = s.Sync(ctx, i)
+			cancel()
+			if err != nil {
+				logger.Logger.Error("Failed to sync delegations", "zone id", i.ID, "zone name", i.Name, "err", err)
+			}
+		}
+	}
+}
+
+// IsVerbenaChild checks if the delegated child is also a Verbena zone
+func IsVerbenaChild(ctx context.Context, db lookupQueries, zoneName, name string) (int64, bool, error) {
+	childId, err := db.LookupZone(ctx, ChildName(zoneName, name))
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, err
+	}
+	return childId, true, nil
+}
+
+// Sync stages DS changes for each delegation of the zone with a Verbena child
+func (s *Syncer) Sync(ctx context.Context, zoneInfo database.Zone) error {
+	rows, err := s.db.GetZoneRecords(ctx, zoneInfo.ID)
+	if err != nil {
+		return err
+	}
+	records := make([]rest.Record, 0, len(rows))
+	for _, row := range rows {
+		v, err := rest.ParseRecordValue(row.Record.Type, row.Record.PreValue)
+		if err != nil {
+			continue
+		}
+		records = append(records, rest.Record{
+			ID:     row.Record.ID,
+			Name:   row.Record.Name,
+			ZoneID: row.Record.ZoneID,
+			Ttl:    row.Record.PreTtl,
+			Type:   row.Record.Type,
+			Value:  v,
+			Active: row.Record.PreActive,
+		})
+	}
+
+	for _, d := range FromRecords(zoneInfo.Name, records) {
+		childId, ok, err := IsVerbenaChild(ctx, s.db, zoneInfo.Name, d.Name)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			continue
+		}
+		child, err := s.db.GetZone(ctx, childId)
+		if err != nil {
+			return err
+		}
+		if !child.Active {
+			continue
+		}
+
+		keys, err := s.lookupKeys(ctx, child)
+		if err != nil {
+			// keep the current DS records until the child answers again
+			logger.Logger.Warn("Failed to lookup child DNSKEY records", "zone", zoneInfo.Name, "child", child.Name, "err", err)
+			continue
+		}
+
+		var existing []rest.Record
+		for _, r := range Records(zoneInfo.Name, d.Name, records) {
+			if r.Type == "DS" {
+				existing = append(existing, r)
+			}
+		}
+		var want []rest.CreateRecord
+		for _, ds := range DelegationSigners(keys) {
+			want = append(want, rest.CreateRecord{
+				Name:   d.Name,
+				Type:   "DS",
+				Value:  ds.RecordValue(),
+				Active: true,
+			})
+		}
+
+		err = s.apply(ctx, zoneInfo, existing, want)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *Syncer) apply(ctx context.Context, zoneInfo database.Zone, existing []rest.Record, want []rest.CreateRecord) error {
+	create, remove := Diff(existing, want)
+	for _, r := range create {
+		_, err := s.db.InsertRecordFromApi(ctx, database.InsertRecordFromApiParams{
+			Name:      r.Name,
+			ZoneID:    zoneInfo.ID,
+			Type:      r.Type,
+			PreValue:  r.Value.ToValueString(r.Type),
+			PreActive: true,
+		})
+		if err != nil {
+			return err
+		}
+		logger.Logger.Info("Staged DS record from child zone", "zone", zoneInfo.Name, "name", r.Name, "key tag", r.Value.KeyTag)
+	}
+	for _, r := range remove {
+		err := s.db.DeleteRecordFromApi(ctx, database.DeleteRecordFromApiParams{
+			RecordID: r.ID,
+			ZoneID:   zoneInfo.ID,
+		})
+		if err != nil {
+			return err
+		}
+		logger.Logger.Info("Staged removal of DS record no longer published by child zone", "zone", zoneInfo.Name, "name", r.Name, "key tag", r.Value.KeyTag)
+	}
+	return nil
+}
+
+// DelegationSigners converts the key signing keys into SHA-256 DS records
+func DelegationSigners(keys []*dns.DNSKEY) []rest.DelegationSigner {
+	var out []rest.DelegationSigner
+	for _, key := range keys {
+		if key.Flags&dns.SEP == 0 || key.Flags&dns.REVOKE != 0 {
+			continue
+		}
+		ds := key.ToDS(dns.SHA256)
+		if ds == nil {
+			continue
+		}
+		out = append(out, rest.DelegationSigner{
+			KeyTag:     ds.KeyTag,
+			Algorithm:  ds.Algorithm,
+			DigestType: ds.DigestType,
+			Digest:     ds.Digest,
+		})
+	}
+	return out
+}
+
+// queryKeys asks the nameservers of the child zone for its DNSKEY records
+func (s *Syncer) queryKeys(ctx context.Context, child database.Zone) ([]*dns.DNSKEY, error) {
+	m := new(dns.Msg)
+	m.SetQuestion(dns.Fqdn(child.Name), dns.TypeDNSKEY)
+	m.SetEdns0(4096, true)
+
+	err := errors.New("no nameservers")
+	for _, ns := range s.nameservers.GetNameserversForZone(child) {
+		var resp *dns.Msg
+		resp, _, err = s.client.ExchangeContext(ctx, m, net.JoinHostPort(ns, "53"))
+		if err != nil {
+			continue
+		}
+		if resp.Rcode != dns.RcodeSuccess {
+			err = fmt.Errorf("nameserver %s responded with %s", ns, dns.RcodeToString[resp.Rcode])
+			continue
+		}
+		var keys []*dns.DNSKEY
+		for _, rr := range resp.Answer {
+			if key, ok := rr.(*dns.DNSKEY); ok {
+				keys = append(keys, key)
+			}
+		}
+		return keys, nil
+	}
+	return nil, err
+}
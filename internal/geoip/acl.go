@@ -0,0 +1,190 @@
+package geoip
+
+import (
+	"fmt"
+	"io"
+	"iter"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/oschwald/maxminddb-golang/v2"
+)
+
+// Location is the part of a MaxMind country or city record used to match
+// regions, the registered country is used for networks without a location
+// such as anycast ranges
+type Location struct {
+	Country struct {
+		IsoCode string `maxminddb:"iso_code"`
+	} `maxminddb:"country"`
+	RegisteredCountry struct {
+		IsoCode string `maxminddb:"iso_code"`
+	} `maxminddb:"registered_country"`
+	Continent struct {
+		Code string `maxminddb:"code"`
+	} `maxminddb:"continent"`
+}
+
+// In reports whether the location is inside the region
+func (l Location) In(r Region) bool {
+	switch r.Kind {
+	case KindCountry:
+		if l.Country.IsoCode != "" {
+			return l.Country.IsoCode == r.Code
+		}
+		return l.RegisteredCountry.IsoCode == r.Code
+	case KindContinent:
+		return l.Continent.Code == r.Code
+	}
+	return false
+}
+
+// Network is a prefix from the database along with its location
+type Network struct {
+	Prefix   netip.Prefix
+	Location Location
+}
+
+// Networks reads every network from the MMDB file at path
+func Networks(path string) ([]Network, error) {
+	db, err := maxminddb.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	var out []Network
+	for result := range db.Networks() {
+		var loc Location
+		err := result.Decode(&loc)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode %s: %w", result.Prefix(), err)
+		}
+		out = append(out, Network{Prefix: result.Prefix(), Location: loc})
+	}
+	return out, nil
+}
+
+// Match returns the prefixes of the networks inside any of the regions
+func Match(networks iter.Seq[Network], regions []Region) []netip.Prefix {
+	var out []netip.Prefix
+	for n := range networks {
+		if slices.ContainsFunc(regions, n.Location.In) {
+			out = append(out, n.Prefix)
+		}
+	}
+	return out
+}
+
+// AclName is the name of the BIND ACL holding the networks of a geo view
+func AclName(view string) string {
+	return "geo-" + view
+}
+
+// AclFile is the path of the file defining the ACL of a geo view
+func AclFile(dir, view string) string {
+	return filepath.Join(dir, view+".acl")
+}
+
+// WriteAcl writes a BIND ACL statement containing the prefixes, an empty ACL
+// matches no clients
+func WriteAcl(w io.Writer, name string, prefixes []netip.Prefix) error {
+	// acl "geo-eu" {
+	// <tab>192.0.2.0/24;
+	// };
+	_, err := fmt.Fprintf(w, "acl %s {\n", strconv.Quote(name))
+	if err != nil {
+		return err
+	}
+	if len(prefixes) == 0 {
+		_, err = fmt.Fprintf(w, "\tnone;\n")
+		if err != nil {
+			return err
+		}
+	}
+	for _, prefix := range prefixes {
+		_, err = fmt.Fprintf(w, "\t%s;\n", prefix)
+		if err != nil {
+			return err
+		}
+	}
+	_, err = fmt.Fprintf(w, "};\n")
+	return err
+}
+
+// AclWriter keeps the ACL files of the geo views in sync with the database,
+// reading the whole database is slow so the files are only written again once
+// the database file has changed
+type AclWriter struct {
+	database string
+	dir      string
+	views    map[string][]Region
+
+	mu      sync.Mutex
+	modTime time.Time
+	size    int64
+}
+
+func NewAclWriter(database, dir string, views map[string][]Region) *AclWriter {
+	return &AclWriter{database: database, dir: dir, views: views}
+}
+
+// Update writes the ACL files when the database has changed since the last
+// call, true is returned when the files were written
+func (a *AclWriter) Update() (bool, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	stat, err := os.Stat(a.database)
+	if err != nil {
+		return false, err
+	}
+	if stat.ModTime().Equal(a.modTime) && stat.Size() == a.size {
+		return false, nil
+	}
+
+	networks, err := Networks(a.database)
+	if err != nil {
+		return false, err
+	}
+	for view, regions := range a.views {
+		err = a.writeFile(view, Match(slices.Values(networks), regions))
+		if err != nil {
+			return false, err
+		}
+	}
+
+	a.modTime = stat.ModTime()
+	a.size = stat.Size()
+	return true, nil
+}
+
+func (a *AclWriter) writeFile(view string, prefixes []netip.Prefix) error {
+	err := os.MkdirAll(a.dir, 0700)
+	if err != nil {
+		return err
+	}
+	name := AclFile(a.dir, view)
+	temp := name + ".temp"
+	f, err := os.Create(temp)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(temp)
+	defer f.Close()
+
+	err = WriteAcl(f, AclName(view), prefixes)
+	if err != nil {
+		return err
+	}
+	err = f.Close()
+	if err != nil {
+		return err
+	}
+	return os.Rename(temp, name)
+}
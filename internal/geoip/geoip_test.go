@@ -0,0 +1,77 @@
+package geoip
+
+import (
+	"bytes"
+	"net/netip"
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseRegion(t *testing.T) {
+	for _, i := range []struct {
+		in  string
+		out Region
+		err string
+	}{
+		{"country:GB", Region{KindCountry, "GB"}, ""},
+		{" Continent : eu", Region{KindContinent, "EU"}, ""},
+		{"GB", Region{}, "invalid region \"GB\", expected country:XX or continent:XX"},
+		{"country:GBR", Region{}, "invalid country code \"GBR\""},
+		{"continent:XX", Region{}, "invalid continent code \"XX\", expected one of AF, AN, AS, EU, NA, OC, SA"},
+		{"city:London", Region{}, "invalid region kind \"city\", expected country or continent"},
+	} {
+		t.Run(i.in, func(t *testing.T) {
+			r, err := ParseRegion(i.in)
+			if i.err != "" {
+				assert.EqualError(t, err, i.err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, i.out, r)
+		})
+	}
+}
+
+func location(country, registered, continent string) Location {
+	var l Location
+	l.Country.IsoCode = country
+	l.RegisteredCountry.IsoCode = registered
+	l.Continent.Code = continent
+	return l
+}
+
+func TestMatch(t *testing.T) {
+	networks := []Network{
+		{netip.MustParsePrefix("192.0.2.0/24"), location("GB", "GB", "EU")},
+		{netip.MustParsePrefix("198.51.100.0/24"), location("FR", "FR", "EU")},
+		{netip.MustParsePrefix("203.0.113.0/24"), location("US", "US", "NA")},
+		{netip.MustParsePrefix("2001:db8::/32"), location("", "GB", "")},
+	}
+	assert.Equal(t, []netip.Prefix{
+		netip.MustParsePrefix("192.0.2.0/24"),
+		netip.MustParsePrefix("2001:db8::/32"),
+	}, Match(slices.Values(networks), []Region{{KindCountry, "GB"}}))
+	assert.Equal(t, []netip.Prefix{
+		netip.MustParsePrefix("192.0.2.0/24"),
+		netip.MustParsePrefix("198.51.100.0/24"),
+		netip.MustParsePrefix("203.0.113.0/24"),
+	}, Match(slices.Values(networks), []Region{{KindContinent, "EU"}, {KindCountry, "US"}}))
+	assert.Nil(t, Match(slices.Values(networks), []Region{{KindContinent, "OC"}}))
+}
+
+func TestWriteAcl(t *testing.T) {
+	buf := new(bytes.Buffer)
+	err := WriteAcl(buf, AclName("eu"), []netip.Prefix{
+		netip.MustParsePrefix("192.0.2.0/24"),
+		netip.MustParsePrefix("2001:db8::/32"),
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "acl \"geo-eu\" {\n\t192.0.2.0/24;\n\t2001:db8::/32;\n};\n", buf.String())
+
+	buf.Reset()
+	err = WriteAcl(buf, AclName("oc"), nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "acl \"geo-oc\" {\n\tnone;\n};\n", buf.String())
+}
@@ -0,0 +1,48 @@
+package geoip
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+const (
+	KindCountry   = "country"
+	KindContinent = "continent"
+)
+
+var continents = []string{"AF", "AN", "AS", "EU", "NA", "OC", "SA"}
+
+// Region is a country, using the ISO 3166-1 alpha-2 code, or a continent, using
+// the two letter code from the MaxMind databases
+type Region struct {
+	Kind string
+	Code string
+}
+
+// ParseRegion reads a region in the form "country:GB" or "continent:EU"
+func ParseRegion(s string) (Region, error) {
+	kind, code, ok := strings.Cut(s, ":")
+	if !ok {
+		return Region{}, fmt.Errorf("invalid region %q, expected country:XX or continent:XX", s)
+	}
+	kind = strings.ToLower(strings.TrimSpace(kind))
+	code = strings.ToUpper(strings.TrimSpace(code))
+	switch kind {
+	case KindCountry:
+		if len(code) != 2 || strings.Trim(code, "ABCDEFGHIJKLMNOPQRSTUVWXYZ") != "" {
+			return Region{}, fmt.Errorf("invalid country code %q", code)
+		}
+	case KindContinent:
+		if !slices.Contains(continents, code) {
+			return Region{}, fmt.Errorf("invalid continent code %q, expected one of %s", code, strings.Join(continents, ", "))
+		}
+	default:
+		return Region{}, fmt.Errorf("invalid region kind %q, expected country or continent", kind)
+	}
+	return Region{Kind: kind, Code: code}, nil
+}
+
+func (r Region) String() string {
+	return r.Kind + ":" + r.Code
+}
@@ -0,0 +1,180 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/1f349/verbena/internal/database"
+	"github.com/1f349/verbena/internal/utils"
+	"github.com/1f349/verbena/rest"
+	"github.com/miekg/dns"
+)
+
+const (
+	DefaultBackupTtl = 60
+	defaultInterval  = 30
+	defaultThreshold = 3
+	defaultStatus    = http.StatusOK
+
+	minInterval  = 10
+	maxInterval  = 3600
+	maxThreshold = 10
+
+	// MaxBackupTtl keeps the backup TTL short so clients return to the primary
+	// value soon after it recovers
+	MaxBackupTtl = 3600
+
+	checkTimeout = 5 * time.Second
+)
+
+// Normalize fills in the defaults of a health check and validates it, targets
+// with an address which is not public are refused unless allowPrivate is set
+func Normalize(check rest.HealthCheck, allowPrivate bool) (rest.HealthCheck, error) {
+	if check.Interval == 0 {
+		check.Interval = defaultInterval
+	}
+	if check.Interval < minInterval || check.Interval > maxInterval {
+		return rest.HealthCheck{}, fmt.Errorf("interval must be between %d and %d seconds", minInterval, maxInterval)
+	}
+	if check.Threshold == 0 {
+		check.Threshold = defaultThreshold
+	}
+	if check.Threshold < 1 || check.Threshold > maxThreshold {
+		return rest.HealthCheck{}, fmt.Errorf("threshold must be between 1 and %d", maxThreshold)
+	}
+
+	switch check.Type {
+	case rest.HealthCheckTCP:
+		if !validHostPort(check.Target) {
+			return rest.HealthCheck{}, errors.New("tcp target must be host:port")
+		}
+		host, _, _ := net.SplitHostPort(check.Target)
+		if !allowPrivate && !utils.IsPublicHost(host) {
+			return rest.HealthCheck{}, errors.New("tcp target must be a public address")
+		}
+		check.HttpStatus, check.QueryName, check.QueryType = 0, "", ""
+	case rest.HealthCheckHTTP:
+		u, err := url.Parse(check.Target)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return rest.HealthCheck{}, errors.New("http target must be an http or https URL")
+		}
+		if !allowPrivate && !utils.IsPublicHost(u.Hostname()) {
+			return rest.HealthCheck{}, errors.New("http target must be a public address")
+		}
+		if check.HttpStatus == 0 {
+			check.HttpStatus = defaultStatus
+		}
+		if check.HttpStatus < 100 || check.HttpStatus > 599 {
+			return rest.HealthCheck{}, fmt.Errorf("invalid http status %d", check.HttpStatus)
+		}
+		check.QueryName, check.QueryType = "", ""
+	case rest.HealthCheckDNS:
+		if !validHostPort(check.Target) {
+			return rest.HealthCheck{}, errors.New("dns target must be the host:port of a nameserver")
+		}
+		host, _, _ := net.SplitHostPort(check.Target)
+		if !allowPrivate && !utils.IsPublicHost(host) {
+			return rest.HealthCheck{}, errors.New("dns target must be a public address")
+		}
+		if _, ok := dns.IsDomainName(check.QueryName); !ok || check.QueryName == "" {
+			return rest.HealthCheck{}, fmt.Errorf("invalid query name %q", check.QueryName)
+		}
+		check.QueryName = strings.ToLower(strings.TrimSuffix(check.QueryName, "."))
+		if check.QueryType == "" {
+			check.QueryType = "A"
+		}
+		check.QueryType = strings.ToUpper(check.QueryType)
+		if _, ok := dns.StringToType[check.QueryType]; !ok {
+			return rest.HealthCheck{}, fmt.Errorf("invalid query type %q", check.QueryType)
+		}
+		check.HttpStatus = 0
+	default:
+		return rest.HealthCheck{}, fmt.Errorf("unknown check type %q, expected tcp, http or dns", check.Type)
+	}
+	return check, nil
+}
+
+func validHostPort(target string) bool {
+	host, port, err := net.SplitHostPort(target)
+	return err == nil && host != "" && port != ""
+}
+
+// Runner performs health checks, connections to addresses which are not
+// public are refused unless private targets are allowed
+type Runner struct {
+	dialer     *net.Dialer
+	httpClient *http.Client
+}
+
+func NewRunner(allowPrivate bool) *Runner {
+	dialer := &net.Dialer{}
+	if !allowPrivate {
+		dialer.Control = utils.PublicDialControl
+	}
+	return &Runner{
+		dialer: dialer,
+		// redirects are not followed so the status of the target itself is
+		// compared
+		httpClient: &http.Client{
+			Transport: &http.Transport{
+				DialContext: dialer.DialContext,
+			},
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+	}
+}
+
+// Run performs a single health check, a nil error means the check passed
+func (r *Runner) Run(ctx context.Context, check database.HealthCheck) error {
+	ctx, cancel := context.WithTimeout(ctx, checkTimeout)
+	defer cancel()
+
+	switch check.CheckType {
+	case rest.HealthCheckTCP:
+		conn, err := r.dialer.DialContext(ctx, "tcp", check.Target)
+		if err != nil {
+			return err
+		}
+		return conn.Close()
+	case rest.HealthCheckHTTP:
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, check.Target, nil)
+		if err != nil {
+			return err
+		}
+		req.Header.Set("User-Agent", "Verbena health check")
+		resp, err := r.httpClient.Do(req)
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+		if int32(resp.StatusCode) != check.HttpStatus {
+			return fmt.Errorf("unexpected status: %s", resp.Status)
+		}
+		return nil
+	case rest.HealthCheckDNS:
+		msg := new(dns.Msg)
+		msg.SetQuestion(dns.Fqdn(check.QueryName), dns.StringToType[check.QueryType])
+		client := &dns.Client{Net: "udp", Dialer: r.dialer}
+		resp, _, err := client.ExchangeContext(ctx, msg, check.Target)
+		if err != nil {
+			return err
+		}
+		if resp.Rcode != dns.RcodeSuccess {
+			return fmt.Errorf("unexpected rcode: %s", dns.RcodeToString[resp.Rcode])
+		}
+		if len(resp.Answer) == 0 {
+			return errors.New("empty answer")
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown check type: %s", check.CheckType)
+	}
+}
@@ -0,0 +1,159 @@
+package health
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/1f349/verbena/internal/database"
+	"github.com/1f349/verbena/internal/webhook"
+	"github.com/1f349/verbena/logger"
+)
+
+const defaultTick = 10 * time.Second
+
+type checkerQueries interface {
+	GetHealthChecks(ctx context.Context) ([]database.HealthCheck, error)
+	SetHealthCheckResult(ctx context.Context, arg database.SetHealthCheckResultParams) error
+	GetZone(ctx context.Context, id int64) (database.Zone, error)
+}
+
+// publisher bumps the serial and rebuilds a zone after the published value of
+// a failover record changes
+type publisher interface {
+	Publish(ctx context.Context, zone database.Zone) error
+}
+
+type eventFirer interface {
+	Fire(zoneID int64, zoneName string, event webhook.Event, data any)
+}
+
+type runFunc func(ctx context.Context, check database.HealthCheck) error
+
+// FailoverEvent is the webhook payload sent when a record switches between
+// the primary and backup values
+type FailoverEvent struct {
+	RecordID int64  `json:"record_id"`
+	Healthy  bool   `json:"healthy"`
+	Error    string `json:"error,omitempty"`
+}
+
+// Checker runs the health checks of failover records. A record switches to
+// the backup value after Threshold consecutive failures and back to the
+// primary value after Threshold consecutive successes.
+type Checker struct {
+	db        checkerQueries
+	tick      time.Duration
+	publisher publisher
+	events    eventFirer
+	run       runFunc
+	now       func() time.Time
+
+	mu sync.Mutex
+	// streak counts the consecutive results which disagree with the stored
+	// health of each check
+	streak map[int64]int32
+}
+
+func New(db checkerQueries, tick time.Duration, publisher publisher, events eventFirer, allowPrivateTargets bool) *Checker {
+	if tick <= 0 {
+		tick = defaultTick
+	}
+	return &Checker{
+		db:        db,
+		tick:      tick,
+		publisher: publisher,
+		events:    events,
+		run:       NewRunner(allowPrivateTargets).Run,
+		now:       time.Now,
+		streak:    make(map[int64]int32),
+	}
+}
+
+func (c *Checker) Start() {
+	go c.internalTicker()
+}
+
+func (c *Checker) internalTicker() {
+	t := time.NewTicker(c.tick)
+	for range t.C {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+		c.RunDue(ctx)
+		cancel()
+	}
+}
+
+// RunDue runs the checks whose interval has passed since they were last run
+func (c *Checker) RunDue(ctx context.Context) {
+	checks, err := c.db.GetHealthChecks(ctx)
+	if err != nil {
+		logger.Logger.Error("Failed to get health checks", "err", err)
+		return
+	}
+
+	now := c.now()
+	var wg sync.WaitGroup
+	for _, check := range checks {
+		if now.Unix() < check.CheckedAt+int64(check.CheckInterval) {
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := c.run(ctx, check)
+			c.record(ctx, check, now, err)
+		}()
+	}
+	wg.Wait()
+}
+
+func (c *Checker) record(ctx context.Context, check database.HealthCheck, now time.Time, checkErr error) {
+	passed := checkErr == nil
+
+	c.mu.Lock()
+	healthy := check.Healthy
+	if passed == check.Healthy {
+		delete(c.streak, check.ID)
+	} else {
+		c.streak[check.ID]++
+		if c.streak[check.ID] >= check.Threshold {
+			healthy = passed
+			delete(c.streak, check.ID)
+		}
+	}
+	c.mu.Unlock()
+
+	lastError := ""
+	if checkErr != nil {
+		lastError = checkErr.Error()
+	}
+	err := c.db.SetHealthCheckResult(ctx, database.SetHealthCheckResultParams{
+		Healthy:   healthy,
+		CheckedAt: now.Unix(),
+		LastError: lastError,
+		ID:        check.ID,
+	})
+	if err != nil {
+		logger.Logger.Error("Failed to save health check result", "record id", check.RecordID, "err", err)
+		return
+	}
+	if healthy == check.Healthy {
+		return
+	}
+
+	logger.Logger.Info("Failover record changed", "record id", check.RecordID, "healthy", healthy, "err", checkErr)
+	zone, err := c.db.GetZone(ctx, check.ZoneID)
+	if err != nil {
+		logger.Logger.Error("Failed to get zone", "zone id", check.ZoneID, "err", err)
+		return
+	}
+	c.events.Fire(zone.ID, zone.Name, webhook.FailoverChanged, FailoverEvent{
+		RecordID: check.RecordID,
+		Healthy:  healthy,
+		Error:    lastError,
+	})
+	err = c.publisher.Publish(ctx, zone)
+	if err != nil {
+		logger.Logger.Error("Failed to publish failover change", "zone id", zone.ID, "zone name", zone.Name, "err", err)
+	}
+}
@@ -0,0 +1,166 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/1f349/verbena/internal/database"
+	"github.com/1f349/verbena/internal/utils"
+	"github.com/1f349/verbena/internal/webhook"
+	"github.com/1f349/verbena/rest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalize(t *testing.T) {
+	check, err := Normalize(rest.HealthCheck{Type: rest.HealthCheckHTTP, Target: "https://example.com/health"}, false)
+	assert.NoError(t, err)
+	assert.Equal(t, rest.HealthCheck{Type: rest.HealthCheckHTTP, Target: "https://example.com/health", HttpStatus: 200, Interval: 30, Threshold: 3}, check)
+
+	check, err = Normalize(rest.HealthCheck{Type: rest.HealthCheckDNS, Target: "192.0.2.53:53", QueryName: "Example.com.", QueryType: "aaaa", HttpStatus: 200}, false)
+	assert.NoError(t, err)
+	assert.Equal(t, rest.HealthCheck{Type: rest.HealthCheckDNS, Target: "192.0.2.53:53", QueryName: "example.com", QueryType: "AAAA", Interval: 30, Threshold: 3}, check)
+
+	for _, i := range []rest.HealthCheck{
+		{Type: "icmp", Target: "192.0.2.1"},
+		{Type: rest.HealthCheckTCP, Target: "192.0.2.1"},
+		{Type: rest.HealthCheckTCP, Target: "192.0.2.1:443", Interval: 5},
+		{Type: rest.HealthCheckTCP, Target: "192.0.2.1:443", Threshold: 11},
+		{Type: rest.HealthCheckHTTP, Target: "ftp://example.com"},
+		{Type: rest.HealthCheckHTTP, Target: "https://example.com", HttpStatus: 700},
+		{Type: rest.HealthCheckDNS, Target: "192.0.2.53:53"},
+		{Type: rest.HealthCheckDNS, Target: "192.0.2.53:53", QueryName: "example.com", QueryType: "NOPE"},
+		{Type: rest.HealthCheckTCP, Target: "127.0.0.1:443"},
+		{Type: rest.HealthCheckTCP, Target: "localhost:443"},
+		{Type: rest.HealthCheckHTTP, Target: "http://169.254.169.254/latest/meta-data"},
+		{Type: rest.HealthCheckHTTP, Target: "https://[fd00::1]/health"},
+		{Type: rest.HealthCheckDNS, Target: "10.0.0.53:53", QueryName: "example.com"},
+	} {
+		_, err := Normalize(i, false)
+		assert.Error(t, err, i)
+	}
+
+	// internal targets are accepted when allowed
+	_, err = Normalize(rest.HealthCheck{Type: rest.HealthCheckTCP, Target: "127.0.0.1:443"}, true)
+	assert.NoError(t, err)
+}
+
+func TestRun(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		if req.URL.Path == "/down" {
+			rw.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer srv.Close()
+
+	// the test server is on loopback which is refused by default
+	assert.ErrorIs(t, NewRunner(false).Run(t.Context(), database.HealthCheck{CheckType: rest.HealthCheckHTTP, Target: srv.URL + "/up", HttpStatus: 200}), utils.ErrNotPublicAddr)
+	assert.ErrorIs(t, NewRunner(false).Run(t.Context(), database.HealthCheck{CheckType: rest.HealthCheckTCP, Target: srv.Listener.Addr().String()}), utils.ErrNotPublicAddr)
+
+	runner := NewRunner(true)
+	assert.NoError(t, runner.Run(t.Context(), database.HealthCheck{CheckType: rest.HealthCheckHTTP, Target: srv.URL + "/up", HttpStatus: 200}))
+	assert.Error(t, runner.Run(t.Context(), database.HealthCheck{CheckType: rest.HealthCheckHTTP, Target: srv.URL + "/down", HttpStatus: 200}))
+	assert.NoError(t, runner.Run(t.Context(), database.HealthCheck{CheckType: rest.HealthCheckTCP, Target: srv.Listener.Addr().String()}))
+
+	// find a port with nothing listening
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	closedAddr := ln.Addr().String()
+	ln.Close()
+	assert.Error(t, runner.Run(t.Context(), database.HealthCheck{CheckType: rest.HealthCheckTCP, Target: closedAddr}))
+}
+
+type checkerTestQueries struct {
+	checks []database.HealthCheck
+}
+
+func (c *checkerTestQueries) GetHealthChecks(ctx context.Context) ([]database.HealthCheck, error) {
+	return c.checks, nil
+}
+
+func (c *checkerTestQueries) SetHealthCheckResult(ctx context.Context, arg database.SetHealthCheckResultParams) error {
+	for i := range c.checks {
+		if c.checks[i].ID == arg.ID {
+			c.checks[i].Healthy = arg.Healthy
+			c.checks[i].CheckedAt = arg.CheckedAt
+			c.checks[i].LastError = arg.LastError
+		}
+	}
+	return nil
+}
+
+func (c *checkerTestQueries) GetZone(ctx context.Context, id int64) (database.Zone, error) {
+	return database.Zone{ID: id, Name: "example.com"}, nil
+}
+
+type checkerTestPublisher struct {
+	published []int64
+}
+
+func (c *checkerTestPublisher) Publish(ctx context.Context, zone database.Zone) error {
+	c.published = append(c.published, zone.ID)
+	return nil
+}
+
+type checkerTestEvents struct {
+	events []FailoverEvent
+}
+
+func (c *checkerTestEvents) Fire(zoneID int64, zoneName string, event webhook.Event, data any) {
+	c.events = append(c.events, data.(FailoverEvent))
+}
+
+func TestChecker(t *testing.T) {
+	q := &checkerTestQueries{checks: []database.HealthCheck{
+		{ID: 1, RecordID: 10, ZoneID: 3456, CheckInterval: 30, Threshold: 2, Healthy: true},
+	}}
+	publisher := &checkerTestPublisher{}
+	events := &checkerTestEvents{}
+	c := New(q, 0, publisher, events, false)
+
+	now := time.Unix(1000, 0)
+	c.now = func() time.Time { return now }
+	var checkErr error
+	c.run = func(ctx context.Context, check database.HealthCheck) error { return checkErr }
+
+	step := func() {
+		c.RunDue(t.Context())
+		now = now.Add(30 * time.Second)
+	}
+
+	// the first failure stays on the primary value
+	checkErr = errors.New("connection refused")
+	step()
+	assert.True(t, q.checks[0].Healthy)
+	assert.Equal(t, "connection refused", q.checks[0].LastError)
+	assert.Equal(t, int64(1000), q.checks[0].CheckedAt)
+
+	// the check is not due until the interval has passed
+	c.now = func() time.Time { return now.Add(-time.Second) }
+	c.RunDue(t.Context())
+	assert.Equal(t, int64(1000), q.checks[0].CheckedAt)
+	c.now = func() time.Time { return now }
+
+	step()
+	assert.False(t, q.checks[0].Healthy)
+	assert.Equal(t, []int64{3456}, publisher.published)
+
+	// a single success does not switch back
+	checkErr = nil
+	step()
+	assert.False(t, q.checks[0].Healthy)
+	assert.Equal(t, "", q.checks[0].LastError)
+	step()
+	assert.True(t, q.checks[0].Healthy)
+	assert.Equal(t, []int64{3456, 3456}, publisher.published)
+	assert.Equal(t, []FailoverEvent{
+		{RecordID: 10, Healthy: false, Error: "connection refused"},
+		{RecordID: 10, Healthy: true},
+	}, events.events)
+}
@@ -0,0 +1,281 @@
+package lint
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/1f349/verbena/internal/database"
+	"github.com/1f349/verbena/internal/mailauth"
+	"github.com/1f349/verbena/internal/utils"
+	"github.com/1f349/verbena/rest"
+)
+
+// Check finds the cross-record problems in the active records of a zone which
+// the value validation of each record cannot catch. The problems are sorted by
+// name and type. Records tagged with views are only checked against the other
+// records served in the same view.
+func Check(zoneName string, records []rest.Record) []rest.LintProblem {
+	zoneName = normalHost(zoneName)
+
+	var views []string
+	for _, r := range records {
+		views = append(views, r.Views...)
+	}
+	slices.Sort(views)
+	views = slices.Compact(views)
+	if len(views) == 0 {
+		views = []string{""}
+	}
+
+	l := &linter{zoneName: zoneName, seen: make(map[rest.LintProblem]bool)}
+	for _, view := range views {
+		l.checkView(view, records)
+	}
+
+	slices.SortFunc(l.problems, func(a, b rest.LintProblem) int {
+		return cmp.Or(
+			cmp.Compare(a.Name, b.Name),
+			cmp.Compare(a.Type, b.Type),
+			cmp.Compare(a.Severity, b.Severity),
+			cmp.Compare(a.Message, b.Message),
+		)
+	})
+	return l.problems
+}
+
+// FromActiveRecords converts the committed records used by the builder, records
+// which fail to parse are skipped as the builder reports them instead
+func FromActiveRecords(records []database.Record) []rest.Record {
+	out := make([]rest.Record, 0, len(records))
+	for _, r := range records {
+		v, err := rest.ParseRecordValue(r.Type, r.Value)
+		if err != nil {
+			continue
+		}
+		out = append(out, rest.Record{
+			ID:     r.ID,
+			Name:   r.Name,
+			ZoneID: r.ZoneID,
+			Ttl:    r.Ttl,
+			Type:   r.Type,
+			Value:  v,
+			Active: r.Active,
+			Views:  utils.SplitViews(r.Views),
+		})
+	}
+	return out
+}
+
+// Errors filters the problems with error severity
+func Errors(problems []rest.LintProblem) []rest.LintProblem {
+	var out []rest.LintProblem
+	for _, p := range problems {
+		if p.Severity == rest.LintError {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+// NewErrors returns the errors in after which are missing from before, this
+// allows changes to zones which already contain errors
+func NewErrors(before, after []rest.LintProblem) []rest.LintProblem {
+	existing := make(map[rest.LintProblem]bool, len(before))
+	for _, p := range before {
+		existing[p] = true
+	}
+	var out []rest.LintProblem
+	for _, p := range Errors(after) {
+		if !existing[p] {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+// Message joins the problems into a single line
+func Message(problems []rest.LintProblem) string {
+	parts := make([]string, 0, len(problems))
+	for _, p := range problems {
+		parts = append(parts, fmt.Sprintf("%s %s: %s", p.Name, p.Type, p.Message))
+	}
+	return strings.Join(parts, "; ")
+}
+
+type linter struct {
+	zoneName string
+	byName   map[string][]rest.Record
+	seen     map[rest.LintProblem]bool
+	problems []rest.LintProblem
+}
+
+// checkView lints the active records served in view, problems found in more
+// than one view are only reported once
+func (l *linter) checkView(view string, records []rest.Record) {
+	var active []rest.Record
+	l.byName = make(map[string][]rest.Record)
+	for _, r := range records {
+		if !r.Active || !utils.InView(r.Views, view) {
+			continue
+		}
+		active = append(active, r)
+		name := ownerName(l.zoneName, r.Name)
+		l.byName[name] = append(l.byName[name], r)
+	}
+
+	for name, set := range l.byName {
+		l.checkName(name, set)
+	}
+	for _, r := range active {
+		l.checkTarget(ownerName(l.zoneName, r.Name), r)
+	}
+}
+
+func (l *linter) report(severity, name, recordType, format string, a ...any) {
+	p := rest.LintProblem{
+		Severity: severity,
+		Name:     name,
+		Type:     recordType,
+		Message:  fmt.Sprintf(format, a...),
+	}
+	if l.seen[p] {
+		return
+	}
+	l.seen[p] = true
+	l.problems = append(l.problems, p)
+}
+
+// checkName looks for conflicts between the records of a single owner name
+func (l *linter) checkName(name string, set []rest.Record) {
+	types := make(map[string]int)
+	values := make(map[string]bool)
+	ttls := make(map[string]rest.Record)
+	spf, dmarc := 0, 0
+	for _, r := range set {
+		types[r.Type]++
+		if r.Type == "TXT" && mailauth.IsSPF(r.Value.Text) {
+			spf++
+		}
+		if r.Type == "TXT" && mailauth.IsDMARC(r.Value.Text) {
+			dmarc++
+		}
+
+		value := r.Type + "\t" + r.Value.ToValueString(r.Type)
+		if values[value] {
+			l.report(rest.LintError, name, r.Type, "duplicate record")
+		}
+		values[value] = true
+
+		if first, ok := ttls[r.Type]; !ok {
+			ttls[r.Type] = r
+		} else if first.Ttl != r.Ttl {
+			l.report(rest.LintWarning, name, r.Type, "records in the RRset have different TTLs")
+		}
+	}
+
+	if types["CNAME"] > 0 {
+		if name == l.zoneName {
+			l.report(rest.LintError, name, "CNAME", "CNAME records are not allowed at the zone apex")
+		}
+		if types["CNAME"] > 1 {
+			l.report(rest.LintError, name, "CNAME", "only one CNAME record is allowed for a name")
+		}
+		for ty := range types {
+			if ty != "CNAME" {
+				l.report(rest.LintError, name, "CNAME", "CNAME conflicts with the %s record", ty)
+			}
+		}
+	}
+	// receivers treat more than one policy as an error
+	if spf > 1 {
+		l.report(rest.LintError, name, "TXT", "only one SPF record is allowed for a name")
+	}
+	if dmarc > 1 {
+		l.report(rest.LintError, name, "TXT", "only one DMARC record is allowed for a name")
+	}
+	if types["ALIAS"] > 0 {
+		for _, ty := range []string{"A", "AAAA"} {
+			if types[ty] > 0 {
+				l.report(rest.LintError, name, "ALIAS", "ALIAS conflicts with the %s record", ty)
+			}
+		}
+	}
+}
+
+// checkTarget looks for targets inside the zone which are CNAMEs or missing
+func (l *linter) checkTarget(name string, r rest.Record) {
+	var target string
+	switch r.Type {
+	case "CNAME", "ALIAS", "NS", "MX", "PTR", "SRV", "SVCB", "HTTPS":
+		target = normalHost(r.Value.Target)
+	default:
+		return
+	}
+	// "." means no target for SRV and the owner name for SVCB and HTTPS
+	if target == "" || !isBelow(target, l.zoneName) || l.isDelegated(target, name, r.Type) {
+		return
+	}
+
+	set, ok := l.byName[target]
+	if !ok && target != l.zoneName && !l.hasWildcard(target) {
+		l.report(rest.LintWarning, name, r.Type, "target %s does not exist in the zone", target)
+		return
+	}
+	if r.Type == "MX" || r.Type == "NS" {
+		for _, t := range set {
+			if t.Type == "CNAME" {
+				l.report(rest.LintError, name, r.Type, "target %s is a CNAME", target)
+				break
+			}
+		}
+	}
+}
+
+// isDelegated reports whether the target is at or below a delegation point,
+// the records there belong to the child zone
+func (l *linter) isDelegated(target, owner, recordType string) bool {
+	for name, set := range l.byName {
+		if name == l.zoneName || !isBelow(target, name) {
+			continue
+		}
+		for _, r := range set {
+			// glue for the NS records of the delegation is expected to exist
+			if r.Type == "NS" && !(recordType == "NS" && owner == name) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (l *linter) hasWildcard(target string) bool {
+	for parent := target; parent != l.zoneName; {
+		_, after, ok := strings.Cut(parent, ".")
+		if !ok {
+			return false
+		}
+		parent = after
+		if _, ok := l.byName["*."+parent]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+func ownerName(zoneName, name string) string {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if name == "" || name == "@" {
+		return zoneName
+	}
+	return name + "." + zoneName
+}
+
+func normalHost(host string) string {
+	return strings.TrimSuffix(strings.ToLower(strings.TrimSpace(host)), ".")
+}
+
+func isBelow(host, parent string) bool {
+	return host == parent || strings.HasSuffix(host, "."+parent)
+}
@@ -0,0 +1,85 @@
+package lint
+
+import (
+	"net/netip"
+	"testing"
+
+	"github.com/1f349/verbena/rest"
+	"github.com/gobuffalo/nulls"
+	"github.com/stretchr/testify/assert"
+)
+
+func ipPtr(s string) *netip.Addr {
+	ip := netip.MustParseAddr(s)
+	return &ip
+}
+
+func TestCheck(t *testing.T) {
+	records := []rest.Record{
+		{ID: 1, Name: "@", Type: "A", Value: rest.RecordValue{IP: ipPtr("192.0.2.1")}, Active: true},
+		{ID: 2, Name: "@", Type: "MX", Value: rest.RecordValue{Preference: 10, Target: "mail.example.com"}, Active: true},
+		{ID: 3, Name: "mail", Type: "CNAME", Value: rest.RecordValue{Target: "mx.example.net"}, Active: true},
+		{ID: 4, Name: "www", Type: "CNAME", Value: rest.RecordValue{Target: "example.com"}, Active: true},
+		{ID: 5, Name: "www", Type: "A", Value: rest.RecordValue{IP: ipPtr("192.0.2.1")}, Active: true},
+		{ID: 6, Name: "api", Type: "A", Ttl: nulls.NewInt32(300), Value: rest.RecordValue{IP: ipPtr("192.0.2.2")}, Active: true},
+		{ID: 7, Name: "api", Type: "A", Value: rest.RecordValue{IP: ipPtr("192.0.2.3")}, Active: true},
+		{ID: 8, Name: "api", Type: "A", Value: rest.RecordValue{IP: ipPtr("192.0.2.3")}, Active: true},
+		{ID: 9, Name: "docs", Type: "CNAME", Value: rest.RecordValue{Target: "missing.example.com"}, Active: true},
+		{ID: 10, Name: "@", Type: "CNAME", Value: rest.RecordValue{Target: "example.net"}, Active: true},
+		// inactive records are not published
+		{ID: 11, Name: "old", Type: "CNAME", Value: rest.RecordValue{Target: "gone.example.com"}, Active: false},
+		// delegated and wildcard targets are not dangling
+		{ID: 12, Name: "child", Type: "NS", Value: rest.RecordValue{Target: "ns1.child.example.com"}, Active: true},
+		{ID: 13, Name: "shop", Type: "CNAME", Value: rest.RecordValue{Target: "store.child.example.com"}, Active: true},
+		{ID: 14, Name: "*.cdn", Type: "A", Value: rest.RecordValue{IP: ipPtr("192.0.2.4")}, Active: true},
+		{ID: 15, Name: "static", Type: "CNAME", Value: rest.RecordValue{Target: "edge.cdn.example.com"}, Active: true},
+		{ID: 16, Name: "mail", Type: "TXT", Value: rest.RecordValue{Text: "v=spf1 mx -all"}, Active: true},
+		{ID: 17, Name: "mail", Type: "TXT", Value: rest.RecordValue{Text: "v=spf1 -all"}, Active: true},
+	}
+
+	assert.Equal(t, []rest.LintProblem{
+		{Severity: rest.LintError, Name: "api.example.com", Type: "A", Message: "duplicate record"},
+		{Severity: rest.LintWarning, Name: "api.example.com", Type: "A", Message: "records in the RRset have different TTLs"},
+		{Severity: rest.LintWarning, Name: "child.example.com", Type: "NS", Message: "target ns1.child.example.com does not exist in the zone"},
+		{Severity: rest.LintWarning, Name: "docs.example.com", Type: "CNAME", Message: "target missing.example.com does not exist in the zone"},
+		{Severity: rest.LintError, Name: "example.com", Type: "CNAME", Message: "CNAME conflicts with the A record"},
+		{Severity: rest.LintError, Name: "example.com", Type: "CNAME", Message: "CNAME conflicts with the MX record"},
+		{Severity: rest.LintError, Name: "example.com", Type: "CNAME", Message: "CNAME records are not allowed at the zone apex"},
+		{Severity: rest.LintError, Name: "example.com", Type: "MX", Message: "target mail.example.com is a CNAME"},
+		{Severity: rest.LintError, Name: "mail.example.com", Type: "CNAME", Message: "CNAME conflicts with the TXT record"},
+		{Severity: rest.LintError, Name: "mail.example.com", Type: "TXT", Message: "only one SPF record is allowed for a name"},
+		{Severity: rest.LintError, Name: "www.example.com", Type: "CNAME", Message: "CNAME conflicts with the A record"},
+	}, Check("example.com", records))
+}
+
+func TestNewErrors(t *testing.T) {
+	before := Check("example.com", []rest.Record{
+		{Name: "www", Type: "CNAME", Value: rest.RecordValue{Target: "example.net"}, Active: true},
+		{Name: "www", Type: "A", Value: rest.RecordValue{IP: ipPtr("192.0.2.1")}, Active: true},
+	})
+	after := Check("example.com", []rest.Record{
+		{Name: "www", Type: "CNAME", Value: rest.RecordValue{Target: "example.net"}, Active: true},
+		{Name: "www", Type: "A", Value: rest.RecordValue{IP: ipPtr("192.0.2.1")}, Active: true},
+		{Name: "api", Type: "CNAME", Value: rest.RecordValue{Target: "example.net"}, Active: true},
+		{Name: "api", Type: "TXT", Value: rest.RecordValue{Text: "hello"}, Active: true},
+	})
+	problems := NewErrors(before, after)
+	assert.Equal(t, []rest.LintProblem{
+		{Severity: rest.LintError, Name: "api.example.com", Type: "CNAME", Message: "CNAME conflicts with the TXT record"},
+	}, problems)
+	assert.Equal(t, "api.example.com CNAME: CNAME conflicts with the TXT record", Message(problems))
+}
+
+func TestCheckViews(t *testing.T) {
+	records := []rest.Record{
+		{ID: 1, Name: "www", Type: "CNAME", Value: rest.RecordValue{Target: "internal.example.net"}, Active: true, Views: []string{"internal"}},
+		{ID: 2, Name: "www", Type: "A", Value: rest.RecordValue{IP: ipPtr("192.0.2.1")}, Active: true, Views: []string{"external"}},
+		// records without views are checked in every view
+		{ID: 3, Name: "api", Type: "CNAME", Value: rest.RecordValue{Target: "example.net"}, Active: true},
+		{ID: 4, Name: "api", Type: "TXT", Value: rest.RecordValue{Text: "hello"}, Active: true, Views: []string{"internal"}},
+	}
+
+	assert.Equal(t, []rest.LintProblem{
+		{Severity: rest.LintError, Name: "api.example.com", Type: "CNAME", Message: "CNAME conflicts with the TXT record"},
+	}, Check("example.com", records))
+}
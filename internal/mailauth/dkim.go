@@ -0,0 +1,174 @@
+package mailauth
+
+import (
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/1f349/verbena/rest"
+)
+
+// MinRSAKeyBits is the smallest RSA key allowed by RFC 8301
+const MinRSAKeyBits = 1024
+
+// MaxRSAKeyBits keeps the record a reasonable size
+const MaxRSAKeyBits = 4096
+
+// DKIMName returns the owner name of the DKIM key for a selector of the mail
+// domain, both names are relative to the zone
+func DKIMName(name, selector string) string {
+	if name == "" || name == "@" {
+		return selector + "._domainkey"
+	}
+	return selector + "._domainkey." + name
+}
+
+// IsDKIM reports whether the TXT record text is a DKIM key, the version tag is
+// optional so any record with a p= tag is treated as a key
+func IsDKIM(text string) bool {
+	tags, err := parseTags(text)
+	if err != nil {
+		return false
+	}
+	_, ok := tags["p"]
+	return ok
+}
+
+// BuildDKIM validates the key and returns the record text and the key size
+func BuildDKIM(key rest.DKIMKey) (string, int, error) {
+	bits, err := validateDKIMKey(key)
+	if err != nil {
+		return "", 0, err
+	}
+
+	parts := []string{"v=DKIM1", "k=" + key.KeyType}
+	if len(key.HashAlgorithms) > 0 {
+		parts = append(parts, "h="+strings.Join(key.HashAlgorithms, ":"))
+	}
+	if len(key.Flags) > 0 {
+		parts = append(parts, "t="+strings.Join(key.Flags, ":"))
+	}
+	parts = append(parts, "p="+key.PublicKey)
+	return strings.Join(parts, "; "), bits, nil
+}
+
+// ParseDKIM reads the key from the record text and returns the key size
+func ParseDKIM(text string) (rest.DKIMKey, int, error) {
+	tags, err := parseTags(text)
+	if err != nil {
+		return rest.DKIMKey{}, 0, err
+	}
+	if v, ok := tags["v"]; ok && v != "DKIM1" {
+		return rest.DKIMKey{}, 0, fmt.Errorf("invalid version %s", v)
+	}
+	key := rest.DKIMKey{
+		KeyType:   "rsa",
+		PublicKey: strings.Join(strings.Fields(tags["p"]), ""),
+	}
+	if k, ok := tags["k"]; ok {
+		key.KeyType = k
+	}
+	if h, ok := tags["h"]; ok {
+		key.HashAlgorithms = splitList(h)
+	}
+	if t, ok := tags["t"]; ok {
+		key.Flags = splitList(t)
+	}
+	bits, err := validateDKIMKey(key)
+	if err != nil {
+		return rest.DKIMKey{}, 0, err
+	}
+	return key, bits, nil
+}
+
+func validateDKIMKey(key rest.DKIMKey) (int, error) {
+	for _, h := range key.HashAlgorithms {
+		// RFC 8301 removed sha1
+		if h != "sha256" {
+			return 0, fmt.Errorf("unsupported hash algorithm %s", h)
+		}
+	}
+	for _, t := range key.Flags {
+		if t != "y" && t != "s" {
+			return 0, fmt.Errorf("unknown flag %s", t)
+		}
+	}
+	raw, err := base64.StdEncoding.DecodeString(key.PublicKey)
+	if err != nil {
+		return 0, errors.New("public key is not valid base64")
+	}
+
+	switch key.KeyType {
+	case "rsa":
+		pub, err := parseRSAKey(raw)
+		if err != nil {
+			return 0, err
+		}
+		bits := pub.N.BitLen()
+		if bits < MinRSAKeyBits || bits > MaxRSAKeyBits {
+			return 0, fmt.Errorf("RSA key is %d bits, expected between %d and %d bits", bits, MinRSAKeyBits, MaxRSAKeyBits)
+		}
+		return bits, nil
+	case "ed25519":
+		if len(raw) != ed25519.PublicKeySize {
+			return 0, fmt.Errorf("Ed25519 key is %d bytes, expected %d bytes", len(raw), ed25519.PublicKeySize)
+		}
+		return ed25519.PublicKeySize * 8, nil
+	default:
+		return 0, fmt.Errorf("unsupported key type %s", key.KeyType)
+	}
+}
+
+// parseRSAKey accepts a SubjectPublicKeyInfo and falls back to the bare
+// RSAPublicKey which some signers publish
+func parseRSAKey(raw []byte) (*rsa.PublicKey, error) {
+	pub, err := x509.ParsePKIXPublicKey(raw)
+	if err == nil {
+		rsaPub, ok := pub.(*rsa.PublicKey)
+		if !ok {
+			return nil, errors.New("public key is not an RSA key")
+		}
+		return rsaPub, nil
+	}
+	rsaPub, err := x509.ParsePKCS1PublicKey(raw)
+	if err != nil {
+		return nil, errors.New("public key is not a valid RSA key")
+	}
+	return rsaPub, nil
+}
+
+// parseTags reads the tag=value list used by DKIM and DMARC records
+func parseTags(text string) (map[string]string, error) {
+	tags := make(map[string]string)
+	for _, part := range strings.Split(text, ";") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		name, value, ok := strings.Cut(part, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid tag %s", part)
+		}
+		name = strings.TrimSpace(name)
+		if _, ok := tags[name]; ok {
+			return nil, fmt.Errorf("duplicate tag %s", name)
+		}
+		tags[name] = strings.TrimSpace(value)
+	}
+	return tags, nil
+}
+
+func splitList(s string) []string {
+	var out []string
+	for _, v := range strings.Split(s, ":") {
+		v = strings.TrimSpace(v)
+		if v != "" {
+			out = append(out, v)
+		}
+	}
+	return out
+}
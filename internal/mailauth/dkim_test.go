@@ -0,0 +1,80 @@
+package mailauth
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"math/big"
+	"testing"
+
+	"github.com/1f349/verbena/rest"
+	"github.com/stretchr/testify/assert"
+)
+
+func rsaKey(t *testing.T, bits int) string {
+	key, err := rsa.GenerateKey(rand.Reader, bits)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return base64.StdEncoding.EncodeToString(der)
+}
+
+// shortRSAKey builds a public key which is too small to be generated
+func shortRSAKey(t *testing.T) string {
+	n := new(big.Int).Lsh(big.NewInt(1), 511)
+	n.Add(n, big.NewInt(1))
+	der, err := x509.MarshalPKIXPublicKey(&rsa.PublicKey{N: n, E: 65537})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return base64.StdEncoding.EncodeToString(der)
+}
+
+func TestBuildDKIM(t *testing.T) {
+	pub := rsaKey(t, 2048)
+	text, bits, err := BuildDKIM(rest.DKIMKey{KeyType: "rsa", PublicKey: pub, HashAlgorithms: []string{"sha256"}})
+	assert.NoError(t, err)
+	assert.Equal(t, 2048, bits)
+	assert.Equal(t, "v=DKIM1; k=rsa; h=sha256; p="+pub, text)
+	assert.True(t, IsDKIM(text))
+
+	key, bits, err := ParseDKIM(text)
+	assert.NoError(t, err)
+	assert.Equal(t, 2048, bits)
+	assert.Equal(t, rest.DKIMKey{KeyType: "rsa", PublicKey: pub, HashAlgorithms: []string{"sha256"}}, key)
+
+	// the key type defaults to rsa and whitespace in the key is ignored
+	key, _, err = ParseDKIM("p=" + pub[:20] + " " + pub[20:])
+	assert.NoError(t, err)
+	assert.Equal(t, rest.DKIMKey{KeyType: "rsa", PublicKey: pub}, key)
+
+	edPub, _, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, bits, err = BuildDKIM(rest.DKIMKey{KeyType: "ed25519", PublicKey: base64.StdEncoding.EncodeToString(edPub), Flags: []string{"s"}})
+	assert.NoError(t, err)
+	assert.Equal(t, 256, bits)
+
+	for _, key := range []rest.DKIMKey{
+		{KeyType: "rsa", PublicKey: shortRSAKey(t)},
+		{KeyType: "rsa", PublicKey: "not base64!"},
+		{KeyType: "rsa", PublicKey: base64.StdEncoding.EncodeToString(edPub)},
+		{KeyType: "ed25519", PublicKey: pub},
+		{KeyType: "dsa", PublicKey: pub},
+		{KeyType: "rsa", PublicKey: pub, HashAlgorithms: []string{"sha1"}},
+		{KeyType: "rsa", PublicKey: pub, Flags: []string{"x"}},
+	} {
+		_, _, err = BuildDKIM(key)
+		assert.Error(t, err, key)
+	}
+
+	assert.Equal(t, "s1._domainkey", DKIMName("@", "s1"))
+	assert.Equal(t, "s1._domainkey.mail", DKIMName("mail", "s1"))
+}
@@ -0,0 +1,193 @@
+package mailauth
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/1f349/verbena/rest"
+	"github.com/gobuffalo/nulls"
+)
+
+const dmarcVersion = "DMARC1"
+
+var dmarcPolicies = []string{"none", "quarantine", "reject"}
+
+// DMARCName returns the owner name of the DMARC policy of the mail domain,
+// both names are relative to the zone
+func DMARCName(name string) string {
+	if name == "" || name == "@" {
+		return "_dmarc"
+	}
+	return "_dmarc." + name
+}
+
+// IsDMARC reports whether the TXT record text is a DMARC policy
+func IsDMARC(text string) bool {
+	v, _, _ := strings.Cut(text, ";")
+	name, value, ok := strings.Cut(v, "=")
+	return ok && strings.TrimSpace(name) == "v" && strings.TrimSpace(value) == dmarcVersion
+}
+
+// BuildDMARC validates the policy and returns the record text
+func BuildDMARC(p rest.DMARCPolicy) (string, error) {
+	err := validateDMARC(p)
+	if err != nil {
+		return "", err
+	}
+
+	parts := []string{"v=" + dmarcVersion, "p=" + p.Policy}
+	if p.SubdomainPolicy != "" {
+		parts = append(parts, "sp="+p.SubdomainPolicy)
+	}
+	if p.Percent.Valid {
+		parts = append(parts, "pct="+strconv.Itoa(int(p.Percent.Int32)))
+	}
+	if len(p.AggregateReports) > 0 {
+		parts = append(parts, "rua="+strings.Join(p.AggregateReports, ","))
+	}
+	if len(p.FailureReports) > 0 {
+		parts = append(parts, "ruf="+strings.Join(p.FailureReports, ","))
+	}
+	if p.DkimAlignment != "" {
+		parts = append(parts, "adkim="+p.DkimAlignment)
+	}
+	if p.SpfAlignment != "" {
+		parts = append(parts, "aspf="+p.SpfAlignment)
+	}
+	if p.FailureOptions != "" {
+		parts = append(parts, "fo="+p.FailureOptions)
+	}
+	if p.ReportInterval.Valid {
+		parts = append(parts, "ri="+strconv.Itoa(int(p.ReportInterval.Int32)))
+	}
+	return strings.Join(parts, "; "), nil
+}
+
+// ParseDMARC reads the tags of a DMARC record, v=DMARC1 must be the first tag
+func ParseDMARC(text string) (rest.DMARCPolicy, error) {
+	if !IsDMARC(text) {
+		return rest.DMARCPolicy{}, errors.New("v=DMARC1 must be the first tag")
+	}
+	tags, err := parseTags(text)
+	if err != nil {
+		return rest.DMARCPolicy{}, err
+	}
+
+	var p rest.DMARCPolicy
+	for name, value := range tags {
+		switch name {
+		case "v":
+		case "p":
+			p.Policy = value
+		case "sp":
+			p.SubdomainPolicy = value
+		case "pct":
+			p.Percent, err = parseDMARCInt(name, value)
+		case "rua":
+			p.AggregateReports = splitURIs(value)
+		case "ruf":
+			p.FailureReports = splitURIs(value)
+		case "adkim":
+			p.DkimAlignment = value
+		case "aspf":
+			p.SpfAlignment = value
+		case "fo":
+			p.FailureOptions = value
+		case "ri":
+			p.ReportInterval, err = parseDMARCInt(name, value)
+		case "rf":
+			// afrf is the only report format
+			if value != "afrf" {
+				return rest.DMARCPolicy{}, fmt.Errorf("invalid report format %s", value)
+			}
+		default:
+			return rest.DMARCPolicy{}, fmt.Errorf("unknown tag %s", name)
+		}
+		if err != nil {
+			return rest.DMARCPolicy{}, err
+		}
+	}
+
+	err = validateDMARC(p)
+	if err != nil {
+		return rest.DMARCPolicy{}, err
+	}
+	return p, nil
+}
+
+func validateDMARC(p rest.DMARCPolicy) error {
+	if !slices.Contains(dmarcPolicies, p.Policy) {
+		return fmt.Errorf("invalid policy %q, expected none, quarantine or reject", p.Policy)
+	}
+	if p.SubdomainPolicy != "" && !slices.Contains(dmarcPolicies, p.SubdomainPolicy) {
+		return fmt.Errorf("invalid subdomain policy %q, expected none, quarantine or reject", p.SubdomainPolicy)
+	}
+	if p.Percent.Valid && (p.Percent.Int32 < 0 || p.Percent.Int32 > 100) {
+		return errors.New("percent must be between 0 and 100")
+	}
+	if p.ReportInterval.Valid && p.ReportInterval.Int32 < 0 {
+		return errors.New("report interval cannot be negative")
+	}
+	for _, alignment := range []string{p.DkimAlignment, p.SpfAlignment} {
+		if alignment != "" && alignment != "r" && alignment != "s" {
+			return fmt.Errorf("invalid alignment %q, expected r or s", alignment)
+		}
+	}
+	if p.FailureOptions != "" {
+		for _, fo := range strings.Split(p.FailureOptions, ":") {
+			if fo != "0" && fo != "1" && fo != "d" && fo != "s" {
+				return fmt.Errorf("invalid failure option %q", fo)
+			}
+		}
+	}
+	for _, uri := range slices.Concat(p.AggregateReports, p.FailureReports) {
+		err := validateReportURI(uri)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateReportURI checks a mailto: report address, an optional size limit
+// such as "!10m" can follow the address
+func validateReportURI(uri string) error {
+	if strings.ContainsAny(uri, ",; \t") {
+		return fmt.Errorf("invalid report URI %q", uri)
+	}
+	address, limit, hasLimit := strings.Cut(uri, "!")
+	if hasLimit {
+		n := strings.TrimRight(limit, "kmgt")
+		if len(limit)-len(n) > 1 || !isPrefixLength(n, 1<<31-1) {
+			return fmt.Errorf("invalid size limit in report URI %q", uri)
+		}
+	}
+	u, err := url.Parse(address)
+	if err != nil || u.Scheme != "mailto" || !strings.Contains(u.Opaque, "@") {
+		return fmt.Errorf("invalid report URI %q, expected a mailto: address", uri)
+	}
+	return nil
+}
+
+func parseDMARCInt(name, value string) (nulls.Int32, error) {
+	n, err := strconv.ParseInt(value, 10, 32)
+	if err != nil {
+		return nulls.Int32{}, fmt.Errorf("invalid %s value %s", name, value)
+	}
+	return nulls.NewInt32(int32(n)), nil
+}
+
+func splitURIs(s string) []string {
+	var out []string
+	for _, v := range strings.Split(s, ",") {
+		v = strings.TrimSpace(v)
+		if v != "" {
+			out = append(out, v)
+		}
+	}
+	return out
+}
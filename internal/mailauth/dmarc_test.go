@@ -0,0 +1,59 @@
+package mailauth
+
+import (
+	"testing"
+
+	"github.com/1f349/verbena/rest"
+	"github.com/gobuffalo/nulls"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuildDMARC(t *testing.T) {
+	policy := rest.DMARCPolicy{
+		Policy:           "quarantine",
+		SubdomainPolicy:  "reject",
+		Percent:          nulls.NewInt32(50),
+		AggregateReports: []string{"mailto:dmarc@example.com", "mailto:reports@example.net!10m"},
+		DkimAlignment:    "s",
+		FailureOptions:   "0:d",
+	}
+	text, err := BuildDMARC(policy)
+	assert.NoError(t, err)
+	assert.Equal(t, "v=DMARC1; p=quarantine; sp=reject; pct=50; rua=mailto:dmarc@example.com,mailto:reports@example.net!10m; adkim=s; fo=0:d", text)
+	assert.True(t, IsDMARC(text))
+
+	parsed, err := ParseDMARC(text)
+	assert.NoError(t, err)
+	assert.Equal(t, policy, parsed)
+
+	parsed, err = ParseDMARC("v=DMARC1;p=none;rf=afrf;ri=3600")
+	assert.NoError(t, err)
+	assert.Equal(t, rest.DMARCPolicy{Policy: "none", ReportInterval: nulls.NewInt32(3600)}, parsed)
+
+	for _, p := range []rest.DMARCPolicy{
+		{},
+		{Policy: "block"},
+		{Policy: "none", SubdomainPolicy: "allow"},
+		{Policy: "none", Percent: nulls.NewInt32(101)},
+		{Policy: "none", AggregateReports: []string{"https://example.com"}},
+		{Policy: "none", FailureReports: []string{"mailto:example.com"}},
+		{Policy: "none", AggregateReports: []string{"mailto:a@example.com!10x"}},
+		{Policy: "none", SpfAlignment: "x"},
+		{Policy: "none", FailureOptions: "2"},
+		{Policy: "none", ReportInterval: nulls.NewInt32(-1)},
+	} {
+		_, err = BuildDMARC(p)
+		assert.Error(t, err, p)
+	}
+
+	for _, text := range []string{
+		"p=none; v=DMARC1",
+		"v=DMARC1; p=none; p=reject",
+		"v=DMARC1; p=none; unknown=1",
+		"v=DMARC1; p=none; pct=abc",
+		"v=DMARC1; p=none; rf=iodef",
+	} {
+		_, err = ParseDMARC(text)
+		assert.Error(t, err, text)
+	}
+}
@@ -0,0 +1,272 @@
+package mailauth
+
+import (
+	"errors"
+	"fmt"
+	"net/netip"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/1f349/verbena/rest"
+	"github.com/miekg/dns"
+)
+
+// MaxSPFLookups is the limit on mechanisms and modifiers causing DNS lookups
+// from RFC 7208 section 4.6.4
+const MaxSPFLookups = 10
+
+const spfVersion = "v=spf1"
+
+// spfDomain allows underscore labels as includes are often below _spf
+var spfDomain = regexp.MustCompile(`^[a-z0-9_-]+(\.[a-z0-9_-]+)*\.?$`)
+
+// IsSPF reports whether the TXT record text is an SPF record
+func IsSPF(text string) bool {
+	text = strings.ToLower(text)
+	return text == spfVersion || strings.HasPrefix(text, spfVersion+" ")
+}
+
+// ParseSPF splits an SPF record into its terms and validates each of them
+func ParseSPF(text string) ([]string, error) {
+	if !IsSPF(text) {
+		return nil, errors.New("missing v=spf1 version")
+	}
+	terms := strings.Fields(text)[1:]
+	err := validateSPFTerms(terms)
+	if err != nil {
+		return nil, err
+	}
+	return terms, nil
+}
+
+// BuildSPF validates the terms and joins them into the SPF record text
+func BuildSPF(terms []string) (string, error) {
+	if len(terms) == 0 {
+		return "", errors.New("at least one mechanism is required")
+	}
+	for _, term := range terms {
+		if term == "" || strings.ContainsAny(term, " \t") {
+			return "", fmt.Errorf("invalid term %q", term)
+		}
+	}
+	err := validateSPFTerms(terms)
+	if err != nil {
+		return "", err
+	}
+	return spfVersion + " " + strings.Join(terms, " "), nil
+}
+
+type spfTerm struct {
+	name     string
+	value    string
+	modifier bool
+}
+
+func validateSPFTerms(terms []string) error {
+	seenAll := false
+	modifiers := make(map[string]bool)
+	for _, raw := range terms {
+		term, err := parseSPFTerm(raw)
+		if err != nil {
+			return err
+		}
+		// modifiers are not evaluated in order so they may follow all
+		if seenAll && !term.modifier {
+			return fmt.Errorf("%s is never evaluated as it is after the all mechanism", raw)
+		}
+		if term.modifier {
+			if (term.name == "redirect" || term.name == "exp") && modifiers[term.name] {
+				return fmt.Errorf("the %s modifier can only be used once", term.name)
+			}
+			modifiers[term.name] = true
+			continue
+		}
+		if term.name == "all" {
+			seenAll = true
+		}
+	}
+	return nil
+}
+
+func parseSPFTerm(raw string) (spfTerm, error) {
+	term := strings.ToLower(raw)
+
+	// modifiers are name=value, the name cannot contain ':' or '/'
+	if name, value, ok := strings.Cut(term, "="); ok && !strings.ContainsAny(name, ":/") {
+		if !isSPFModifierName(name) {
+			return spfTerm{}, fmt.Errorf("invalid modifier %s", raw)
+		}
+		if (name == "redirect" || name == "exp") && !isSPFDomainSpec(value) {
+			return spfTerm{}, fmt.Errorf("invalid domain in %s", raw)
+		}
+		return spfTerm{name: name, value: value, modifier: true}, nil
+	}
+
+	term = strings.TrimLeft(term, "+-~?")
+	if len(raw)-len(term) > 1 {
+		return spfTerm{}, fmt.Errorf("invalid qualifier in %s", raw)
+	}
+	name, value, hasValue := strings.Cut(term, ":")
+
+	switch name {
+	case "all":
+		if hasValue {
+			return spfTerm{}, fmt.Errorf("invalid mechanism %s", raw)
+		}
+	case "include", "exists":
+		if !hasValue || !isSPFDomainSpec(value) {
+			return spfTerm{}, fmt.Errorf("invalid domain in %s", raw)
+		}
+	case "ptr":
+		if hasValue && !isSPFDomainSpec(value) {
+			return spfTerm{}, fmt.Errorf("invalid domain in %s", raw)
+		}
+	case "ip4", "ip6":
+		if !hasValue || !isSPFNetwork(value, name == "ip6") {
+			return spfTerm{}, fmt.Errorf("invalid network in %s", raw)
+		}
+	default:
+		// a and mx can have an optional domain followed by the dual cidr length
+		base, cidr, hasCidr := strings.Cut(term, "/")
+		if hasCidr && (cidr == "" || !isSPFDualCidr(cidr)) {
+			return spfTerm{}, fmt.Errorf("invalid prefix length in %s", raw)
+		}
+		name, value, hasValue = strings.Cut(base, ":")
+		if name != "a" && name != "mx" {
+			return spfTerm{}, fmt.Errorf("unknown mechanism %s", raw)
+		}
+		if hasValue && !isSPFDomainSpec(value) {
+			return spfTerm{}, fmt.Errorf("invalid domain in %s", raw)
+		}
+	}
+	return spfTerm{name: name, value: value}, nil
+}
+
+func isSPFModifierName(name string) bool {
+	if name == "" || name[0] < 'a' || name[0] > 'z' {
+		return false
+	}
+	for i := 0; i < len(name); i++ {
+		c := name[i]
+		if !(c >= 'a' && c <= 'z' || c >= '0' && c <= '9' || c == '-' || c == '_' || c == '.') {
+			return false
+		}
+	}
+	return true
+}
+
+// isSPFDomainSpec checks the domain, names containing macros are expanded
+// while evaluating so only the characters are checked
+func isSPFDomainSpec(domain string) bool {
+	if strings.Contains(domain, "%") {
+		for i := 0; i < len(domain); i++ {
+			if domain[i] < 0x21 || domain[i] > 0x7e {
+				return false
+			}
+		}
+		return true
+	}
+	_, ok := dns.IsDomainName(domain)
+	return ok && spfDomain.MatchString(domain)
+}
+
+func isSPFNetwork(value string, ipv6 bool) bool {
+	prefix, err := netip.ParsePrefix(value)
+	if err == nil {
+		return prefix.Addr().Is6() == ipv6 && !prefix.Addr().Is4In6()
+	}
+	addr, err := netip.ParseAddr(value)
+	return err == nil && addr.Is6() == ipv6 && !addr.Is4In6()
+}
+
+// isSPFDualCidr checks "n", "n//m" or "/m" after the first slash
+func isSPFDualCidr(cidr string) bool {
+	ip4, ip6, dual := strings.Cut(cidr, "//")
+	if cidr[0] == '/' {
+		ip4, ip6, dual = "", cidr[1:], true
+	}
+	if ip4 != "" && !isPrefixLength(ip4, 32) {
+		return false
+	}
+	return !dual || isPrefixLength(ip6, 128)
+}
+
+func isPrefixLength(s string, max int) bool {
+	n, err := strconv.Atoi(s)
+	return err == nil && n >= 0 && n <= max && strconv.Itoa(n) == s
+}
+
+// CountSPFLookups counts the mechanisms and modifiers causing DNS lookups in
+// the SPF record of the name. Includes and redirects to names inside the zone
+// are followed using the zone records, other domains are counted once as their
+// records are not known.
+func CountSPFLookups(zoneName, name string, terms []string, records []rest.Record) (int, error) {
+	zoneName = strings.TrimSuffix(strings.ToLower(zoneName), ".")
+	visiting := map[string]bool{ownerName(zoneName, name): true}
+	return countSPFLookups(zoneName, terms, records, visiting)
+}
+
+func countSPFLookups(zoneName string, terms []string, records []rest.Record, visiting map[string]bool) (int, error) {
+	count := 0
+	for _, raw := range terms {
+		term, err := parseSPFTerm(raw)
+		if err != nil {
+			return 0, err
+		}
+		switch term.name {
+		case "a", "mx", "ptr", "exists":
+			count++
+		case "include", "redirect":
+			count++
+			domain := strings.TrimSuffix(term.value, ".")
+			if strings.Contains(domain, "%") || !(domain == zoneName || strings.HasSuffix(domain, "."+zoneName)) {
+				continue
+			}
+			if visiting[domain] {
+				return 0, fmt.Errorf("%s includes itself", domain)
+			}
+			text, ok := findSPF(zoneName, domain, records)
+			if !ok {
+				return 0, fmt.Errorf("%s has no SPF record in the zone", domain)
+			}
+			nested, err := ParseSPF(text)
+			if err != nil {
+				return 0, fmt.Errorf("%s: %w", domain, err)
+			}
+			visiting[domain] = true
+			n, err := countSPFLookups(zoneName, nested, records, visiting)
+			if err != nil {
+				return 0, err
+			}
+			delete(visiting, domain)
+			count += n
+		}
+	}
+	return count, nil
+}
+
+// ValidateSPFLookups returns an error when the lookup limit is exceeded
+func ValidateSPFLookups(lookups int) error {
+	if lookups > MaxSPFLookups {
+		return fmt.Errorf("SPF record needs %d DNS lookups, the limit is %d", lookups, MaxSPFLookups)
+	}
+	return nil
+}
+
+func findSPF(zoneName, domain string, records []rest.Record) (string, bool) {
+	for _, r := range records {
+		if r.Type == "TXT" && r.Active && ownerName(zoneName, r.Name) == domain && IsSPF(r.Value.Text) {
+			return r.Value.Text, true
+		}
+	}
+	return "", false
+}
+
+func ownerName(zoneName, name string) string {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if name == "" || name == "@" {
+		return zoneName
+	}
+	return name + "." + zoneName
+}
@@ -0,0 +1,90 @@
+package mailauth
+
+import (
+	"testing"
+
+	"github.com/1f349/verbena/rest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuildSPF(t *testing.T) {
+	for _, terms := range [][]string{
+		{"-all"},
+		{"mx", "a:mail.example.com/24", "a//64", "mx:example.net/24//64", "~all"},
+		{"ip4:192.0.2.0/24", "ip4:192.0.2.1", "ip6:2001:db8::/32", "?all"},
+		{"include:_spf.google.com", "exists:%{i}._spf.example.com", "ptr", "-all"},
+		{"redirect=_spf.example.com"},
+		{"mx", "exp=explain._spf.example.com", "-all"},
+		{"mx", "-all", "exp=explain._spf.example.com"},
+	} {
+		text, err := BuildSPF(terms)
+		assert.NoError(t, err, terms)
+		parsed, err := ParseSPF(text)
+		assert.NoError(t, err, terms)
+		assert.Equal(t, terms, parsed)
+	}
+
+	for _, terms := range [][]string{
+		{},
+		{"mx -all"},
+		{"mx", "all:example.com"},
+		{"include"},
+		{"include:not a domain"},
+		{"ip4:2001:db8::1"},
+		{"ip6:192.0.2.1"},
+		{"a/33"},
+		{"a//129"},
+		{"a/"},
+		{"+-mx"},
+		{"mailbox"},
+		{"-all", "mx"},
+		{"-all", "exp=explain._spf.example.com", "mx"},
+		{"-all", "exp=a.example.com", "exp=b.example.com"},
+		{"redirect=a.example.com", "redirect=b.example.com"},
+		{"1bad=value"},
+	} {
+		_, err := BuildSPF(terms)
+		assert.Error(t, err, terms)
+	}
+}
+
+func TestIsSPF(t *testing.T) {
+	assert.True(t, IsSPF("v=spf1 -all"))
+	assert.True(t, IsSPF("V=SPF1"))
+	assert.False(t, IsSPF("v=spf10 -all"))
+	assert.False(t, IsSPF("google-site-verification=abc"))
+}
+
+func TestCountSPFLookups(t *testing.T) {
+	records := []rest.Record{
+		{Name: "@", Type: "TXT", Value: rest.RecordValue{Text: "v=spf1 mx include:_spf.example.com -all"}, Active: true},
+		{Name: "_spf", Type: "TXT", Value: rest.RecordValue{Text: "v=spf1 a include:_spf.google.com include:_spf2.example.com ip4:192.0.2.0/24 -all"}, Active: true},
+		{Name: "_spf2", Type: "TXT", Value: rest.RecordValue{Text: "v=spf1 exists:%{i}.example.net ptr ?all"}, Active: true},
+		{Name: "loop", Type: "TXT", Value: rest.RecordValue{Text: "v=spf1 include:loop2.example.com -all"}, Active: true},
+		{Name: "loop2", Type: "TXT", Value: rest.RecordValue{Text: "v=spf1 redirect=loop.example.com"}, Active: true},
+	}
+
+	terms, err := ParseSPF(records[0].Value.Text)
+	assert.NoError(t, err)
+	lookups, err := CountSPFLookups("example.com", "@", terms, records)
+	assert.NoError(t, err)
+	// mx, include, a, include, include, exists, ptr
+	assert.Equal(t, 7, lookups)
+	assert.NoError(t, ValidateSPFLookups(lookups))
+
+	_, err = CountSPFLookups("example.com", "loop", []string{"include:loop2.example.com", "-all"}, records)
+	assert.EqualError(t, err, "loop.example.com includes itself")
+
+	_, err = CountSPFLookups("example.com", "@", []string{"include:missing.example.com", "-all"}, records)
+	assert.EqualError(t, err, "missing.example.com has no SPF record in the zone")
+
+	terms = []string{"a", "mx", "ptr", "exists:example.net", "include:a.example.net", "include:b.example.net", "include:c.example.net", "include:d.example.net", "include:e.example.net", "include:f.example.net", "-all"}
+	lookups, err = CountSPFLookups("example.com", "@", terms, records)
+	assert.NoError(t, err)
+	assert.Equal(t, 10, lookups)
+	assert.NoError(t, ValidateSPFLookups(lookups))
+
+	lookups, err = CountSPFLookups("example.com", "@", append([]string{"a:example.org"}, terms...), records)
+	assert.NoError(t, err)
+	assert.EqualError(t, ValidateSPFLookups(lookups), "SPF record needs 11 DNS lookups, the limit is 10")
+}
@@ -0,0 +1,172 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/1f349/verbena/conf"
+	"github.com/1f349/verbena/logger"
+	"github.com/miekg/dns"
+)
+
+const (
+	defaultInitialBackoff = 2 * time.Second
+	defaultMaxBackoff     = 5 * time.Minute
+)
+
+// SecondaryStatus is the state of the most recent NOTIFY sent to a secondary.
+type SecondaryStatus struct {
+	Secondary    string    `json:"secondary"`
+	Serial       uint32    `json:"serial"`
+	Acknowledged bool      `json:"acknowledged"`
+	Attempts     int       `json:"attempts"`
+	LastAttempt  time.Time `json:"last_attempt"`
+	LastError    string    `json:"last_error,omitempty"`
+}
+
+type pending struct {
+	status SecondaryStatus
+	cancel context.CancelFunc
+}
+
+// Notifier sends RFC 1996 NOTIFY messages to the secondaries of a zone and
+// retries with backoff until each secondary acknowledges the message.
+type Notifier struct {
+	conf           conf.NotifyConf
+	client         *dns.Client
+	initialBackoff time.Duration
+	maxBackoff     time.Duration
+
+	mu     sync.Mutex
+	status map[string]map[string]*pending
+}
+
+func New(notifyConf conf.NotifyConf) *Notifier {
+	return &Notifier{
+		conf:           notifyConf,
+		client:         &dns.Client{Net: "udp", Timeout: 5 * time.Second},
+		initialBackoff: defaultInitialBackoff,
+		maxBackoff:     defaultMaxBackoff,
+		status:         make(map[string]map[string]*pending),
+	}
+}
+
+// HasSecondaries reports whether any secondaries are configured for the zone.
+func (n *Notifier) HasSecondaries(zoneName string) bool {
+	return len(n.conf.GetSecondariesForZone(zoneName)) > 0
+}
+
+// Notify starts sending NOTIFY messages for the zone serial to every configured
+// secondary. Any unacknowledged NOTIFY for an older serial is abandoned.
+func (n *Notifier) Notify(zoneName string, serial uint32) {
+	secondaries := n.conf.GetSecondariesForZone(zoneName)
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	zoneStatus := n.status[zoneName]
+	if zoneStatus == nil {
+		zoneStatus = make(map[string]*pending)
+		n.status[zoneName] = zoneStatus
+	}
+
+	for _, secondary := range secondaries {
+		if p := zoneStatus[secondary]; p != nil {
+			p.cancel()
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		p := &pending{
+			status: SecondaryStatus{Secondary: secondary, Serial: serial},
+			cancel: cancel,
+		}
+		zoneStatus[secondary] = p
+		go n.sendUntilAcknowledged(ctx, zoneName, secondary, p)
+	}
+}
+
+// Status returns the NOTIFY status of each secondary for the zone.
+func (n *Notifier) Status(zoneName string) []SecondaryStatus {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	out := make([]SecondaryStatus, 0, len(n.status[zoneName]))
+	for _, p := range n.status[zoneName] {
+		out = append(out, p.status)
+	}
+	slices.SortFunc(out, func(a, b SecondaryStatus) int {
+		return strings.Compare(a.Secondary, b.Secondary)
+	})
+	return out
+}
+
+func (n *Notifier) sendUntilAcknowledged(ctx context.Context, zoneName, secondary string, p *pending) {
+	backoff := n.initialBackoff
+	for {
+		err := n.send(ctx, zoneName, secondary, p.status.Serial)
+
+		n.mu.Lock()
+		if ctx.Err() != nil {
+			// A newer NOTIFY has replaced this one
+			n.mu.Unlock()
+			return
+		}
+		p.status.Attempts++
+		p.status.LastAttempt = time.Now()
+		if err == nil {
+			p.status.Acknowledged = true
+			p.status.LastError = ""
+			p.cancel()
+			n.mu.Unlock()
+			return
+		}
+		p.status.LastError = err.Error()
+		n.mu.Unlock()
+
+		logger.Logger.Debug("Failed to send NOTIFY", "zone", zoneName, "secondary", secondary, "err", err, "retry", backoff)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, n.maxBackoff)
+	}
+}
+
+func (n *Notifier) send(ctx context.Context, zoneName, secondary string, serial uint32) error {
+	m := new(dns.Msg)
+	m.SetNotify(dns.Fqdn(zoneName))
+	// Only the serial is meaningful to the secondary, see RFC 1996 section 3.7
+	m.Answer = append(m.Answer, &dns.SOA{
+		Hdr:    dns.RR_Header{Name: dns.Fqdn(zoneName), Rrtype: dns.TypeSOA, Class: dns.ClassINET},
+		Ns:     ".",
+		Mbox:   ".",
+		Serial: serial,
+	})
+
+	resp, _, err := n.client.ExchangeContext(ctx, m, secondaryAddr(secondary))
+	if err != nil {
+		return err
+	}
+	if resp.Opcode != dns.OpcodeNotify || !resp.Response {
+		return errors.New("invalid NOTIFY response")
+	}
+	if resp.Rcode != dns.RcodeSuccess {
+		return fmt.Errorf("NOTIFY rejected: %s", dns.RcodeToString[resp.Rcode])
+	}
+	return nil
+}
+
+func secondaryAddr(secondary string) string {
+	if _, _, err := net.SplitHostPort(secondary); err == nil {
+		return secondary
+	}
+	return net.JoinHostPort(secondary, "53")
+}
@@ -0,0 +1,104 @@
+package notify
+
+import (
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/1f349/verbena/conf"
+	"github.com/miekg/dns"
+)
+
+func startSecondary(t *testing.T, handler dns.HandlerFunc) string {
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	started := make(chan struct{})
+	srv := &dns.Server{PacketConn: pc, Handler: handler, NotifyStartedFunc: func() { close(started) }}
+	go srv.ActivateAndServe()
+	<-started
+	t.Cleanup(func() { _ = srv.Shutdown() })
+	return pc.LocalAddr().String()
+}
+
+func waitForAck(t *testing.T, n *Notifier, zoneName string) SecondaryStatus {
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		status := n.Status(zoneName)
+		if len(status) == 1 && status[0].Acknowledged {
+			return status[0]
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal("NOTIFY was never acknowledged", n.Status(zoneName))
+	return SecondaryStatus{}
+}
+
+func TestNotifier_Notify(t *testing.T) {
+	var serial atomic.Uint32
+	addr := startSecondary(t, func(w dns.ResponseWriter, r *dns.Msg) {
+		if r.Opcode != dns.OpcodeNotify || r.Question[0].Name != "example.com." {
+			t.Error("unexpected message", r)
+			return
+		}
+		serial.Store(r.Answer[0].(*dns.SOA).Serial)
+		m := new(dns.Msg)
+		m.SetReply(r)
+		_ = w.WriteMsg(m)
+	})
+
+	n := New(conf.NotifyConf{Zones: map[string][]string{"example.com": {addr}}})
+	if !n.HasSecondaries("example.com") {
+		t.Fatal("expected secondaries for example.com")
+	}
+	if n.HasSecondaries("example.org") {
+		t.Fatal("expected no secondaries for example.org")
+	}
+
+	n.Notify("example.com", 2025010101)
+	status := waitForAck(t, n, "example.com")
+	if status.Secondary != addr || status.Serial != 2025010101 || status.Attempts != 1 {
+		t.Fatal("unexpected status", status)
+	}
+	if serial.Load() != 2025010101 {
+		t.Fatal("secondary received the wrong serial", serial.Load())
+	}
+}
+
+func TestNotifier_Retry(t *testing.T) {
+	var calls atomic.Int32
+	addr := startSecondary(t, func(w dns.ResponseWriter, r *dns.Msg) {
+		m := new(dns.Msg)
+		m.SetReply(r)
+		if calls.Add(1) < 3 {
+			m.Rcode = dns.RcodeServerFailure
+		}
+		_ = w.WriteMsg(m)
+	})
+
+	n := New(conf.NotifyConf{Default: []string{addr}})
+	n.initialBackoff = 10 * time.Millisecond
+
+	n.Notify("example.com", 2025010102)
+	status := waitForAck(t, n, "example.com")
+	if status.Attempts != 3 {
+		t.Fatal("expected 3 attempts, got", status.Attempts)
+	}
+	if status.LastError != "" {
+		t.Fatal("expected last error to be cleared", status.LastError)
+	}
+}
+
+func TestSecondaryAddr(t *testing.T) {
+	if secondaryAddr("192.0.2.1") != "192.0.2.1:53" {
+		t.Fatal("expected default port")
+	}
+	if secondaryAddr("192.0.2.1:5353") != "192.0.2.1:5353" {
+		t.Fatal("expected port to be kept")
+	}
+	if secondaryAddr("2001:db8::1") != "[2001:db8::1]:53" {
+		t.Fatal("expected bracketed IPv6 address")
+	}
+}
@@ -0,0 +1,122 @@
+package pool
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// Member is a value which can be published for a pool, members with a zero
+// weight or which are disabled are never published
+type Member struct {
+	ID      int64
+	Value   string
+	Weight  int32
+	Enabled bool
+}
+
+type state struct {
+	gen         uint64
+	fingerprint string
+	selected    []int64
+	// current is the smooth weighted round robin counter of each member
+	current map[int64]int64
+}
+
+// Rotator chooses the published members of each pool. The selection rotates
+// once per generation using smooth weighted round robin, so over time each
+// member is published in proportion to its weight.
+type Rotator struct {
+	mu    sync.Mutex
+	gen   uint64
+	pools map[int64]*state
+}
+
+func NewRotator() *Rotator {
+	return &Rotator{pools: make(map[int64]*state)}
+}
+
+// Tick starts a new generation, the next call to Selected for each pool
+// rotates the members
+func (r *Rotator) Tick() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.gen++
+
+	// forget pools which were not used in the last generation
+	for id, s := range r.pools {
+		if s.gen+1 < r.gen {
+			delete(r.pools, id)
+		}
+	}
+}
+
+// Selected returns up to size members of the pool, the selection is kept for
+// the rest of the generation unless the members of the pool change
+func (r *Rotator) Selected(poolID int64, size int, members []Member) []Member {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	fingerprint := memberFingerprint(size, members)
+	s := r.pools[poolID]
+	if s == nil {
+		s = &state{current: make(map[int64]int64)}
+		r.pools[poolID] = s
+	}
+	if s.selected == nil || s.gen != r.gen || s.fingerprint != fingerprint {
+		s.selected = s.rotate(size, members)
+		s.gen = r.gen
+		s.fingerprint = fingerprint
+	}
+
+	out := make([]Member, 0, len(s.selected))
+	for _, m := range members {
+		if slices.Contains(s.selected, m.ID) {
+			out = append(out, m)
+		}
+	}
+	return out
+}
+
+func (s *state) rotate(size int, members []Member) []int64 {
+	var eligible []Member
+	for _, m := range members {
+		if m.Enabled && m.Weight > 0 {
+			eligible = append(eligible, m)
+		}
+	}
+
+	var total int64
+	for _, m := range eligible {
+		total += int64(m.Weight)
+	}
+
+	// each pick is a step of smooth weighted round robin over every eligible
+	// member, members already picked this generation are skipped
+	selected := make([]int64, 0, min(size, len(eligible)))
+	for len(selected) < size && len(selected) < len(eligible) {
+		best := -1
+		for i, m := range eligible {
+			s.current[m.ID] += int64(m.Weight)
+			if slices.Contains(selected, m.ID) {
+				continue
+			}
+			if best == -1 || s.current[m.ID] > s.current[eligible[best].ID] {
+				best = i
+			}
+		}
+		s.current[eligible[best].ID] -= total
+		selected = append(selected, eligible[best].ID)
+	}
+	return selected
+}
+
+func memberFingerprint(size int, members []Member) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%d", size)
+	for _, m := range members {
+		fmt.Fprintf(&sb, ";%d,%d,%t", m.ID, m.Weight, m.Enabled)
+	}
+	return sb.String()
+}
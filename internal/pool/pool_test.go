@@ -0,0 +1,66 @@
+package pool
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func memberIDs(members []Member) []int64 {
+	ids := make([]int64, 0, len(members))
+	for _, m := range members {
+		ids = append(ids, m.ID)
+	}
+	return ids
+}
+
+func TestRotatorWeights(t *testing.T) {
+	r := NewRotator()
+	members := []Member{
+		{ID: 1, Value: "192.0.2.1", Weight: 3, Enabled: true},
+		{ID: 2, Value: "192.0.2.2", Weight: 1, Enabled: true},
+		{ID: 3, Value: "192.0.2.3", Weight: 5, Enabled: false},
+		{ID: 4, Value: "192.0.2.4", Weight: 0, Enabled: true},
+	}
+
+	counts := make(map[int64]int)
+	for range 8 {
+		r.Tick()
+		selected := r.Selected(10, 1, members)
+		assert.Len(t, selected, 1)
+		counts[selected[0].ID]++
+
+		// the selection is kept for the rest of the generation
+		assert.Equal(t, selected, r.Selected(10, 1, members))
+	}
+	assert.Equal(t, map[int64]int{1: 6, 2: 2}, counts)
+}
+
+func TestRotatorSubset(t *testing.T) {
+	r := NewRotator()
+	members := []Member{
+		{ID: 1, Weight: 1, Enabled: true},
+		{ID: 2, Weight: 1, Enabled: true},
+		{ID: 3, Weight: 1, Enabled: true},
+	}
+
+	seen := make(map[int64]int)
+	for range 3 {
+		r.Tick()
+		selected := r.Selected(10, 2, members)
+		assert.Len(t, selected, 2)
+		for _, m := range selected {
+			seen[m.ID]++
+		}
+	}
+	assert.Equal(t, map[int64]int{1: 2, 2: 2, 3: 2}, seen)
+
+	// a pool larger than the enabled members publishes every enabled member
+	assert.Equal(t, []int64{1, 2, 3}, memberIDs(r.Selected(10, 5, members)))
+
+	// disabling a selected member changes the selection straight away
+	r.Tick()
+	selected := r.Selected(10, 1, members)
+	members[selected[0].ID-1].Enabled = false
+	assert.NotEqual(t, selected, r.Selected(10, 1, members))
+}
@@ -0,0 +1,260 @@
+package propagation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/1f349/verbena/conf"
+	"github.com/1f349/verbena/internal/database"
+	"github.com/1f349/verbena/logger"
+	"github.com/miekg/dns"
+)
+
+type Status string
+
+const (
+	Pending  Status = "pending"
+	Partial  Status = "partial"
+	Complete Status = "complete"
+)
+
+const (
+	defaultPollInterval = 5 * time.Second
+	defaultPollTimeout  = time.Hour
+
+	// maxCommitsPerZone limits the number of remembered commits for each zone
+	maxCommitsPerZone = 10
+)
+
+type NameserverStatus struct {
+	Nameserver string `json:"nameserver"`
+	Serial     uint32 `json:"serial"`
+	Updated    bool   `json:"updated"`
+	LastError  string `json:"last_error,omitempty"`
+}
+
+// Commit is the propagation state of a single zone serial.
+type Commit struct {
+	Serial      uint32             `json:"serial"`
+	Committed   time.Time          `json:"committed"`
+	Status      Status             `json:"status"`
+	Nameservers []NameserverStatus `json:"nameservers"`
+}
+
+// Tracker polls the nameservers of a zone after each commit until they all
+// serve the new serial.
+type Tracker struct {
+	nameservers  conf.NameserverConf
+	client       *dns.Client
+	pollInterval time.Duration
+	pollTimeout  time.Duration
+	resolveAddr  func(nameserver string) string
+
+	mu      sync.Mutex
+	commits map[string][]*Commit
+	changed chan struct{}
+}
+
+func New(nameservers conf.NameserverConf) *Tracker {
+	return &Tracker{
+		nameservers:  nameservers,
+		client:       &dns.Client{Net: "udp", Timeout: 5 * time.Second},
+		pollInterval: defaultPollInterval,
+		pollTimeout:  defaultPollTimeout,
+		resolveAddr: func(nameserver string) string {
+			return net.JoinHostPort(nameserver, "53")
+		},
+		commits: make(map[string][]*Commit),
+		changed: make(chan struct{}),
+	}
+}
+
+// Track records a new serial for the zone and starts polling its nameservers.
+func (t *Tracker) Track(zoneInfo database.Zone) {
+	serial := uint32(zoneInfo.Serial)
+	nameservers := t.nameservers.GetNameserversForZone(zoneInfo)
+
+	c := &Commit{
+		Serial:      serial,
+		Committed:   time.Now(),
+		Status:      Pending,
+		Nameservers: make([]NameserverStatus, len(nameservers)),
+	}
+	for i, ns := range nameservers {
+		c.Nameservers[i].Nameserver = ns
+	}
+
+	t.mu.Lock()
+	commits := append(t.commits[zoneInfo.Name], c)
+	if len(commits) > maxCommitsPerZone {
+		commits = commits[len(commits)-maxCommitsPerZone:]
+	}
+	t.commits[zoneInfo.Name] = commits
+	t.broadcastLocked()
+	t.mu.Unlock()
+
+	go t.poll(zoneInfo.Name, c)
+}
+
+// Commits returns the remembered commits for the zone, newest first.
+func (t *Tracker) Commits(zoneName string) []Commit {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	out := make([]Commit, 0, len(t.commits[zoneName]))
+	for _, c := range slices.Backward(t.commits[zoneName]) {
+		out = append(out, c.clone())
+	}
+	return out
+}
+
+// Commit returns the propagation state of a single serial.
+func (t *Tracker) Commit(zoneName string, serial uint32) (Commit, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for _, c := range t.commits[zoneName] {
+		if c.Serial == serial {
+			return c.clone(), true
+		}
+	}
+	return Commit{}, false
+}
+
+// WaitPropagated blocks until a commit newer than afterSerial has propagated to
+// every nameserver or the context is done. The status of the newest commit is
+// returned, or Pending if no newer commit has been made yet.
+func (t *Tracker) WaitPropagated(ctx context.Context, zoneName string, afterSerial uint32) (Status, error) {
+	for {
+		t.mu.Lock()
+		status := Pending
+		for _, c := range t.commits[zoneName] {
+			if serialAfter(c.Serial, afterSerial) {
+				status = c.Status
+			}
+		}
+		changed := t.changed
+		t.mu.Unlock()
+
+		if status == Complete {
+			return status, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return status, ctx.Err()
+		case <-changed:
+		}
+	}
+}
+
+func (t *Tracker) poll(zoneName string, c *Commit) {
+	ctx, cancel := context.WithTimeout(context.Background(), t.pollTimeout)
+	defer cancel()
+
+	for {
+		t.mu.Lock()
+		nameservers := make([]string, 0, len(c.Nameservers))
+		for _, ns := range c.Nameservers {
+			if !ns.Updated {
+				nameservers = append(nameservers, ns.Nameserver)
+			}
+		}
+		t.mu.Unlock()
+
+		for _, ns := range nameservers {
+			serial, err := t.querySerial(ctx, zoneName, ns)
+			t.update(c, ns, serial, err)
+		}
+
+		t.mu.Lock()
+		status := c.Status
+		t.mu.Unlock()
+		if status == Complete {
+			return
+		}
+
+		select {
+		case <-ctx.Done():
+			logger.Logger.Warn("Gave up waiting for zone propagation", "zone", zoneName, "serial", c.Serial)
+			return
+		case <-time.After(t.pollInterval):
+		}
+	}
+}
+
+func (t *Tracker) update(c *Commit, nameserver string, serial uint32, err error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	updated := 0
+	for i := range c.Nameservers {
+		ns := &c.Nameservers[i]
+		if ns.Nameserver == nameserver {
+			if err != nil {
+				ns.LastError = err.Error()
+			} else {
+				ns.Serial = serial
+				ns.Updated = !serialAfter(c.Serial, serial)
+				ns.LastError = ""
+			}
+		}
+		if ns.Updated {
+			updated++
+		}
+	}
+
+	status := Pending
+	switch {
+	case updated == len(c.Nameservers):
+		status = Complete
+	case updated > 0:
+		status = Partial
+	}
+	if status != c.Status {
+		c.Status = status
+		t.broadcastLocked()
+	}
+}
+
+func (t *Tracker) querySerial(ctx context.Context, zoneName, nameserver string) (uint32, error) {
+	m := new(dns.Msg)
+	m.SetQuestion(dns.Fqdn(zoneName), dns.TypeSOA)
+	m.RecursionDesired = false
+
+	resp, _, err := t.client.ExchangeContext(ctx, m, t.resolveAddr(nameserver))
+	if err != nil {
+		return 0, err
+	}
+	if resp.Rcode != dns.RcodeSuccess {
+		return 0, fmt.Errorf("SOA query failed: %s", dns.RcodeToString[resp.Rcode])
+	}
+	for _, rr := range resp.Answer {
+		if soa, ok := rr.(*dns.SOA); ok {
+			return soa.Serial, nil
+		}
+	}
+	return 0, errors.New("SOA query returned no SOA record")
+}
+
+// broadcastLocked wakes every WaitPropagated call, t.mu must be held
+func (t *Tracker) broadcastLocked() {
+	close(t.changed)
+	t.changed = make(chan struct{})
+}
+
+func (c *Commit) clone() Commit {
+	c2 := *c
+	c2.Nameservers = slices.Clone(c.Nameservers)
+	return c2
+}
+
+// serialAfter compares serials using RFC 1982 serial number arithmetic
+func serialAfter(a, b uint32) bool {
+	return a != b && int32(a-b) > 0
+}
@@ -0,0 +1,103 @@
+package propagation
+
+import (
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/1f349/verbena/conf"
+	"github.com/1f349/verbena/internal/database"
+	"github.com/miekg/dns"
+)
+
+func startNameserver(t *testing.T, serial *atomic.Uint32) string {
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	started := make(chan struct{})
+	srv := &dns.Server{PacketConn: pc, NotifyStartedFunc: func() { close(started) }, Handler: dns.HandlerFunc(func(w dns.ResponseWriter, r *dns.Msg) {
+		m := new(dns.Msg)
+		m.SetReply(r)
+		m.Answer = append(m.Answer, &dns.SOA{
+			Hdr:    dns.RR_Header{Name: r.Question[0].Name, Rrtype: dns.TypeSOA, Class: dns.ClassINET},
+			Ns:     "ns1.example.com.",
+			Mbox:   "hostmaster.example.com.",
+			Serial: serial.Load(),
+		})
+		_ = w.WriteMsg(m)
+	})}
+	go srv.ActivateAndServe()
+	<-started
+	t.Cleanup(func() { _ = srv.Shutdown() })
+	return pc.LocalAddr().String()
+}
+
+func TestTracker(t *testing.T) {
+	var serial1, serial2 atomic.Uint32
+	serial1.Store(2025010101)
+	serial2.Store(2025010101)
+	addrs := map[string]string{
+		"ns1.example.com": startNameserver(t, &serial1),
+		"ns2.example.com": startNameserver(t, &serial2),
+	}
+
+	tracker := New(conf.MustNameserverConf([][]string{{"ns1.example.com", "ns2.example.com"}}))
+	tracker.pollInterval = 10 * time.Millisecond
+	tracker.resolveAddr = func(nameserver string) string {
+		return addrs[nameserver]
+	}
+
+	zoneInfo := database.Zone{Name: "example.com", Serial: 2025010102}
+	tracker.Track(zoneInfo)
+
+	commit, ok := tracker.Commit("example.com", 2025010102)
+	if !ok {
+		t.Fatal("expected commit to be tracked")
+	}
+	if commit.Status != Pending {
+		t.Fatal("expected pending status, got", commit.Status)
+	}
+
+	serial1.Store(2025010102)
+	waitForStatus(t, tracker, Partial)
+
+	serial2.Store(2025010103)
+	status, err := tracker.WaitPropagated(t.Context(), "example.com", 2025010101)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status != Complete {
+		t.Fatal("expected complete status, got", status)
+	}
+
+	commits := tracker.Commits("example.com")
+	if len(commits) != 1 || commits[0].Nameservers[1].Serial != 2025010103 {
+		t.Fatal("unexpected commits", commits)
+	}
+}
+
+func waitForStatus(t *testing.T, tracker *Tracker, status Status) {
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		commits := tracker.Commits("example.com")
+		if len(commits) > 0 && commits[0].Status == status {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal("timed out waiting for status", status)
+}
+
+func TestSerialAfter(t *testing.T) {
+	if !serialAfter(2, 1) {
+		t.Fatal("2 is after 1")
+	}
+	if serialAfter(1, 1) {
+		t.Fatal("1 is not after 1")
+	}
+	if !serialAfter(1, 0xffffffff) {
+		t.Fatal("1 is after the wrapped serial")
+	}
+}
@@ -0,0 +1,91 @@
+package reverse
+
+import (
+	"errors"
+	"fmt"
+	"net/netip"
+	"strconv"
+	"strings"
+)
+
+// Classless is an IPv4 block smaller than a /24, the parent /24 zone delegates
+// it by pointing a CNAME record for each address into the child zone as
+// described in RFC 2317.
+type Classless struct {
+	Prefix netip.Prefix
+}
+
+// Alias is a CNAME record in the parent zone, Name is relative to the parent
+// zone and Target is fully qualified without the trailing dot
+type Alias struct {
+	Name   string
+	Target string
+}
+
+func NewClassless(prefix netip.Prefix) (Classless, error) {
+	if !prefix.Addr().Is4() || prefix.Bits() <= 24 {
+		return Classless{}, errors.New("classless delegation requires an IPv4 prefix longer than /24")
+	}
+	return Classless{Prefix: prefix.Masked()}, nil
+}
+
+// ParseClassless reads a label such as "64-26" from the parent /24 zone, the
+// dash form is used as a slash is not allowed in record names
+func ParseClassless(parent netip.Prefix, label string) (Classless, error) {
+	if !parent.Addr().Is4() || parent.Bits() != 24 {
+		return Classless{}, errors.New("classless delegation is only possible from a /24 zone")
+	}
+	start, bitsRaw, ok := strings.Cut(label, "-")
+	if !ok {
+		return Classless{}, fmt.Errorf("invalid classless label %q, expected the first octet and the prefix length such as 64-26", label)
+	}
+	octet, ok := parseOctet(start)
+	if !ok {
+		return Classless{}, fmt.Errorf("invalid octet %q", start)
+	}
+	bits, err := strconv.Atoi(bitsRaw)
+	if err != nil || strconv.Itoa(bits) != bitsRaw || bits <= 24 || bits > 32 {
+		return Classless{}, fmt.Errorf("invalid prefix length %q, expected between 25 and 32", bitsRaw)
+	}
+
+	a := parent.Masked().Addr().As4()
+	a[3] = octet
+	prefix := netip.PrefixFrom(netip.AddrFrom4(a), bits)
+	if prefix.Masked() != prefix {
+		return Classless{}, fmt.Errorf("%s is not the start of a /%d block", prefix.Addr(), bits)
+	}
+	return Classless{Prefix: prefix}, nil
+}
+
+// Label returns the name of the delegation relative to the parent zone
+func (c Classless) Label() string {
+	return strconv.Itoa(int(c.Prefix.Addr().As4()[3])) + "-" + strconv.Itoa(c.Prefix.Bits())
+}
+
+// ParentName returns the name of the /24 zone containing the block
+func (c Classless) ParentName() string {
+	return prefixName(c.Prefix.Addr(), 24)
+}
+
+// ZoneName returns the name of the child zone holding the PTR records
+func (c Classless) ZoneName() string {
+	return c.Label() + "." + c.ParentName()
+}
+
+// Aliases returns the CNAME records needed in the parent zone, one for each
+// address of the block
+func (c Classless) Aliases() []Alias {
+	first := int(c.Prefix.Addr().As4()[3])
+	count := 1 << (32 - c.Prefix.Bits())
+	zoneName := c.ZoneName()
+
+	aliases := make([]Alias, 0, count)
+	for i := first; i < first+count; i++ {
+		octet := strconv.Itoa(i)
+		aliases = append(aliases, Alias{
+			Name:   octet,
+			Target: octet + "." + zoneName,
+		})
+	}
+	return aliases
+}
@@ -0,0 +1,213 @@
+// Package reverse maps addresses and prefixes to the names used below
+// in-addr.arpa and ip6.arpa, including the RFC 2317 names used to delegate
+// IPv4 blocks smaller than a /24.
+package reverse
+
+import (
+	"errors"
+	"fmt"
+	"net/netip"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+const (
+	ipv4Suffix = "in-addr.arpa"
+	ipv6Suffix = "ip6.arpa"
+)
+
+// ZoneNames returns the reverse zones covering the prefix. IPv4 prefixes are
+// rounded to octet boundaries and IPv6 prefixes to nibble boundaries so a /22
+// returns four /24 zones, an IPv4 prefix longer than /24 returns the RFC 2317
+// zone of the classless block.
+func ZoneNames(prefix netip.Prefix) ([]string, error) {
+	if !prefix.IsValid() || prefix.Addr().Is4In6() || prefix.Addr().Zone() != "" {
+		return nil, errors.New("invalid prefix")
+	}
+	prefix = prefix.Masked()
+	bits := prefix.Bits()
+	if prefix.Addr().Is4() {
+		if bits < 8 {
+			return nil, errors.New("IPv4 prefixes must be /8 or longer")
+		}
+		if bits > 24 {
+			c, err := NewClassless(prefix)
+			if err != nil {
+				return nil, err
+			}
+			return []string{c.ZoneName()}, nil
+		}
+		return expand(prefix, 8), nil
+	}
+	if bits < 16 {
+		return nil, errors.New("IPv6 prefixes must be /16 or longer")
+	}
+	if bits > 124 {
+		return nil, errors.New("IPv6 prefixes must be /124 or shorter")
+	}
+	return expand(prefix, 4), nil
+}
+
+// expand splits the prefix into the prefixes at the next label boundary
+func expand(prefix netip.Prefix, step int) []string {
+	bits := prefix.Bits()
+	rounded := (bits + step - 1) / step * step
+	count := 1 << (rounded - bits)
+
+	names := make([]string, 0, count)
+	addr := prefix.Addr()
+	for range count {
+		names = append(names, prefixName(addr, rounded))
+		addr = nextBlock(addr, rounded)
+	}
+	return names
+}
+
+// nextBlock returns the first address of the following block of the given size
+func nextBlock(addr netip.Addr, bits int) netip.Addr {
+	b := addr.AsSlice()
+	inc := 1 << (7 - (bits-1)%8)
+	for i := (bits - 1) / 8; i >= 0 && inc > 0; i-- {
+		sum := int(b[i]) + inc
+		b[i] = byte(sum)
+		inc = sum >> 8
+	}
+	next, _ := netip.AddrFromSlice(b)
+	return next
+}
+
+// prefixName returns the reverse name of the first bits of the address, bits
+// must be a multiple of 8 for IPv4 and 4 for IPv6
+func prefixName(addr netip.Addr, bits int) string {
+	var labels []string
+	if addr.Is4() {
+		a := addr.As4()
+		for i := 0; i < bits/8; i++ {
+			labels = append(labels, strconv.Itoa(int(a[i])))
+		}
+		slices.Reverse(labels)
+		return strings.Join(append(labels, ipv4Suffix), ".")
+	}
+	a := addr.As16()
+	for i := 0; i < bits/4; i++ {
+		nibble := a[i/2] >> 4
+		if i%2 == 1 {
+			nibble = a[i/2] & 0xf
+		}
+		labels = append(labels, strconv.FormatUint(uint64(nibble), 16))
+	}
+	slices.Reverse(labels)
+	return strings.Join(append(labels, ipv6Suffix), ".")
+}
+
+// PTRName returns the fully qualified name of the PTR record for the address
+// without the trailing dot
+func PTRName(addr netip.Addr) string {
+	addr = addr.Unmap()
+	return prefixName(addr, addr.BitLen())
+}
+
+// ParseZoneName returns the prefix covered by a reverse zone, RFC 2317 zones
+// such as "64-26.2.0.192.in-addr.arpa" return the classless block
+func ParseZoneName(name string) (netip.Prefix, error) {
+	name = strings.TrimSuffix(strings.ToLower(name), ".")
+	if rest, ok := strings.CutSuffix(name, "."+ipv4Suffix); ok {
+		return parseIPv4ZoneName(strings.Split(rest, "."))
+	}
+	if rest, ok := strings.CutSuffix(name, "."+ipv6Suffix); ok {
+		return parseIPv6ZoneName(strings.Split(rest, "."))
+	}
+	return netip.Prefix{}, fmt.Errorf("%s is not a reverse zone", name)
+}
+
+func parseIPv4ZoneName(labels []string) (netip.Prefix, error) {
+	if len(labels) == 4 {
+		parent, err := parseIPv4ZoneName(labels[1:])
+		if err != nil {
+			return netip.Prefix{}, err
+		}
+		c, err := ParseClassless(parent, labels[0])
+		if err != nil {
+			return netip.Prefix{}, err
+		}
+		return c.Prefix, nil
+	}
+	if len(labels) > 4 {
+		return netip.Prefix{}, errors.New("too many labels for an IPv4 reverse zone")
+	}
+
+	var a [4]byte
+	for i, label := range labels {
+		n, ok := parseOctet(label)
+		if !ok {
+			return netip.Prefix{}, fmt.Errorf("invalid octet %q", label)
+		}
+		a[len(labels)-1-i] = n
+	}
+	return netip.PrefixFrom(netip.AddrFrom4(a), len(labels)*8), nil
+}
+
+func parseIPv6ZoneName(labels []string) (netip.Prefix, error) {
+	if len(labels) > 31 {
+		return netip.Prefix{}, errors.New("too many labels for an IPv6 reverse zone")
+	}
+
+	var a [16]byte
+	for i, label := range labels {
+		n, err := strconv.ParseUint(label, 16, 8)
+		if err != nil || len(label) != 1 {
+			return netip.Prefix{}, fmt.Errorf("invalid nibble %q", label)
+		}
+		pos := len(labels) - 1 - i
+		if pos%2 == 0 {
+			a[pos/2] |= byte(n) << 4
+		} else {
+			a[pos/2] |= byte(n)
+		}
+	}
+	return netip.PrefixFrom(netip.AddrFrom16(a), len(labels)*4), nil
+}
+
+func parseOctet(s string) (byte, bool) {
+	n, err := strconv.Atoi(s)
+	if err != nil || n < 0 || n > 255 || strconv.Itoa(n) != s {
+		return 0, false
+	}
+	return byte(n), true
+}
+
+// RecordName returns the name of the PTR record for the address relative to
+// the reverse zone, false is returned when the zone does not contain the address
+func RecordName(zoneName string, addr netip.Addr) (string, bool) {
+	prefix, err := ParseZoneName(zoneName)
+	addr = addr.Unmap()
+	if err != nil || !prefix.Contains(addr) {
+		return "", false
+	}
+	if addr.Is4() && prefix.Bits() > 24 {
+		// the records of a classless zone are named after the last octet
+		return strconv.Itoa(int(addr.As4()[3])), true
+	}
+	ptr := PTRName(addr)
+	zoneName = strings.TrimSuffix(strings.ToLower(zoneName), ".")
+	return strings.TrimSuffix(ptr, "."+zoneName), true
+}
+
+// FindZone returns the most specific reverse zone containing the address
+func FindZone(addr netip.Addr, zoneNames []string) (string, bool) {
+	addr = addr.Unmap()
+	best := ""
+	bestBits := -1
+	for _, name := range zoneNames {
+		prefix, err := ParseZoneName(name)
+		if err != nil || !prefix.Contains(addr) {
+			continue
+		}
+		if prefix.Bits() > bestBits {
+			best = name
+			bestBits = prefix.Bits()
+		}
+	}
+	return best, bestBits >= 0
+}
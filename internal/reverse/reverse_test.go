@@ -0,0 +1,127 @@
+package reverse
+
+import (
+	"net/netip"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestZoneNames(t *testing.T) {
+	for _, i := range []struct {
+		prefix string
+		names  []string
+	}{
+		{"10.0.0.0/8", []string{"10.in-addr.arpa"}},
+		{"172.16.0.0/16", []string{"16.172.in-addr.arpa"}},
+		{"192.0.2.0/24", []string{"2.0.192.in-addr.arpa"}},
+		{"192.0.2.77/24", []string{"2.0.192.in-addr.arpa"}},
+		{"198.51.100.0/22", []string{"100.51.198.in-addr.arpa", "101.51.198.in-addr.arpa", "102.51.198.in-addr.arpa", "103.51.198.in-addr.arpa"}},
+		{"10.255.0.0/15", []string{"254.10.in-addr.arpa", "255.10.in-addr.arpa"}},
+		{"192.0.2.64/26", []string{"64-26.2.0.192.in-addr.arpa"}},
+		{"2001:db8::/32", []string{"8.b.d.0.1.0.0.2.ip6.arpa"}},
+		{"2001:db8:ab00::/40", []string{"b.a.8.b.d.0.1.0.0.2.ip6.arpa"}},
+		{"2001:db8::/31", []string{"8.b.d.0.1.0.0.2.ip6.arpa", "9.b.d.0.1.0.0.2.ip6.arpa"}},
+	} {
+		names, err := ZoneNames(netip.MustParsePrefix(i.prefix))
+		assert.NoError(t, err, i.prefix)
+		assert.Equal(t, i.names, names, i.prefix)
+	}
+
+	for _, prefix := range []string{"0.0.0.0/0", "10.0.0.0/7", "2001::/8", "2001:db8::/126", "::ffff:192.0.2.0/120"} {
+		_, err := ZoneNames(netip.MustParsePrefix(prefix))
+		assert.Error(t, err, prefix)
+	}
+}
+
+func TestParseZoneName(t *testing.T) {
+	for _, i := range []struct {
+		name   string
+		prefix string
+	}{
+		{"10.in-addr.arpa", "10.0.0.0/8"},
+		{"2.0.192.in-addr.arpa.", "192.0.2.0/24"},
+		{"64-26.2.0.192.in-addr.arpa", "192.0.2.64/26"},
+		{"8.b.d.0.1.0.0.2.ip6.arpa", "2001:db8::/32"},
+		{"B.A.8.b.d.0.1.0.0.2.ip6.arpa", "2001:db8:ab00::/40"},
+	} {
+		prefix, err := ParseZoneName(i.name)
+		assert.NoError(t, err, i.name)
+		assert.Equal(t, netip.MustParsePrefix(i.prefix), prefix, i.name)
+	}
+
+	for _, name := range []string{"example.com", "in-addr.arpa", "256.in-addr.arpa", "01.in-addr.arpa", "65-26.2.0.192.in-addr.arpa", "0-24.2.0.192.in-addr.arpa", "1.2.0.192.in-addr.arpa", "10.8.b.d.0.1.0.0.2.ip6.arpa"} {
+		_, err := ParseZoneName(name)
+		assert.Error(t, err, name)
+	}
+}
+
+func TestPTRName(t *testing.T) {
+	assert.Equal(t, "10.2.0.192.in-addr.arpa", PTRName(netip.MustParseAddr("192.0.2.10")))
+	assert.Equal(t, "10.2.0.192.in-addr.arpa", PTRName(netip.MustParseAddr("::ffff:192.0.2.10")))
+	assert.Equal(t, "1.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.8.b.d.0.1.0.0.2.ip6.arpa", PTRName(netip.MustParseAddr("2001:db8::1")))
+}
+
+func TestRecordName(t *testing.T) {
+	for _, i := range []struct {
+		zone string
+		addr string
+		name string
+		ok   bool
+	}{
+		{"2.0.192.in-addr.arpa", "192.0.2.10", "10", true},
+		{"0.192.in-addr.arpa", "192.0.2.10", "10.2", true},
+		{"64-26.2.0.192.in-addr.arpa", "192.0.2.65", "65", true},
+		{"64-26.2.0.192.in-addr.arpa", "192.0.2.10", "", false},
+		{"8.b.d.0.1.0.0.2.ip6.arpa", "2001:db8::1", "1.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0", true},
+		{"8.b.d.0.1.0.0.2.ip6.arpa", "192.0.2.10", "", false},
+		{"example.com", "192.0.2.10", "", false},
+	} {
+		name, ok := RecordName(i.zone, netip.MustParseAddr(i.addr))
+		assert.Equal(t, i.ok, ok, i.zone+" "+i.addr)
+		assert.Equal(t, i.name, name, i.zone+" "+i.addr)
+	}
+}
+
+func TestFindZone(t *testing.T) {
+	zones := []string{"example.com", "0.192.in-addr.arpa", "2.0.192.in-addr.arpa", "64-26.2.0.192.in-addr.arpa", "8.b.d.0.1.0.0.2.ip6.arpa"}
+	for _, i := range []struct {
+		addr string
+		zone string
+		ok   bool
+	}{
+		{"192.0.2.65", "64-26.2.0.192.in-addr.arpa", true},
+		{"192.0.2.10", "2.0.192.in-addr.arpa", true},
+		{"192.0.3.10", "0.192.in-addr.arpa", true},
+		{"198.51.100.1", "", false},
+		{"2001:db8::1", "8.b.d.0.1.0.0.2.ip6.arpa", true},
+	} {
+		zone, ok := FindZone(netip.MustParseAddr(i.addr), zones)
+		assert.Equal(t, i.ok, ok, i.addr)
+		assert.Equal(t, i.zone, zone, i.addr)
+	}
+}
+
+func TestClassless(t *testing.T) {
+	c, err := ParseClassless(netip.MustParsePrefix("192.0.2.0/24"), "128-30")
+	assert.NoError(t, err)
+	assert.Equal(t, netip.MustParsePrefix("192.0.2.128/30"), c.Prefix)
+	assert.Equal(t, "128-30", c.Label())
+	assert.Equal(t, "2.0.192.in-addr.arpa", c.ParentName())
+	assert.Equal(t, "128-30.2.0.192.in-addr.arpa", c.ZoneName())
+	assert.Equal(t, []Alias{
+		{"128", "128.128-30.2.0.192.in-addr.arpa"},
+		{"129", "129.128-30.2.0.192.in-addr.arpa"},
+		{"130", "130.128-30.2.0.192.in-addr.arpa"},
+		{"131", "131.128-30.2.0.192.in-addr.arpa"},
+	}, c.Aliases())
+
+	for _, label := range []string{"128", "128-24", "128-33", "129-30", "a-30", "128-030"} {
+		_, err := ParseClassless(netip.MustParsePrefix("192.0.2.0/24"), label)
+		assert.Error(t, err, label)
+	}
+	_, err = ParseClassless(netip.MustParsePrefix("192.0.0.0/16"), "128-30")
+	assert.Error(t, err)
+	_, err = NewClassless(netip.MustParsePrefix("192.0.2.0/24"))
+	assert.Error(t, err)
+}
@@ -13,6 +13,7 @@ import (
 	"github.com/1f349/mjwt"
 	"github.com/1f349/mjwt/auth"
 	"github.com/1f349/verbena/internal/database"
+	"github.com/1f349/verbena/internal/utils"
 	"github.com/1f349/verbena/logger"
 	"github.com/go-chi/chi/v5"
 	"github.com/golang-jwt/jwt/v4"
@@ -35,13 +36,19 @@ func AddAuthRoutes(r *chi.Mux, db authQueries, userKeystore *mjwt.KeyStore, apiI
 		dec.DisallowUnknownFields()
 		err := dec.Decode(&createBody)
 		if err != nil {
-			http.Error(rw, "Failed to decode body", http.StatusBadRequest)
+			writeError(rw, http.StatusBadRequest, "Failed to decode body")
+			return
+		}
+
+		createBody.Zone, err = utils.ToASCIIName(strings.ToLower(createBody.Zone))
+		if err != nil {
+			writeFieldError(rw, "zone", "Invalid zone")
 			return
 		}
 
 		_, isDomain := dns.IsDomainName(createBody.Zone)
 		if !isDomain {
-			http.Error(rw, "Invalid zone", http.StatusBadRequest)
+			writeFieldError(rw, "zone", "Invalid zone")
 			return
 		}
 
@@ -51,7 +58,7 @@ func AddAuthRoutes(r *chi.Mux, db authQueries, userKeystore *mjwt.KeyStore, apiI
 		})
 		if err != nil {
 			logger.Logger.Debug("Failed to get owner by user id and zone", "err", err)
-			http.Error(rw, "Database error", http.StatusInternalServerError)
+			writeError(rw, http.StatusInternalServerError, "Database error")
 			return
 		}
 
@@ -61,7 +68,7 @@ func AddAuthRoutes(r *chi.Mux, db authQueries, userKeystore *mjwt.KeyStore, apiI
 		})
 		if err != nil {
 			logger.Logger.Debug("Failed to register bot token", "err", err)
-			http.Error(rw, "Database error", http.StatusInternalServerError)
+			writeError(rw, http.StatusInternalServerError, "Database error")
 			return
 		}
 
@@ -70,7 +77,7 @@ func AddAuthRoutes(r *chi.Mux, db authQueries, userKeystore *mjwt.KeyStore, apiI
 		botToken, err := auth.CreateRefreshTokenWithDuration(apiIssuer, 87600*time.Hour, createBody.Zone, tokenAti, tokenAti, jwt.ClaimStrings{})
 		if err != nil {
 			logger.Logger.Debug("Failed to create refresh token", "err", err)
-			http.Error(rw, "Failed to create refresh token", http.StatusInternalServerError)
+			writeError(rw, http.StatusInternalServerError, "Failed to create refresh token")
 			return
 		}
 
@@ -84,13 +91,13 @@ func AddAuthRoutes(r *chi.Mux, db authQueries, userKeystore *mjwt.KeyStore, apiI
 	r.Post("/refresh-bot-token", validateAuthToken[auth.RefreshTokenClaims](apiIssuer.KeyStore(), func(rw http.ResponseWriter, req *http.Request, b mjwt.BaseTypeClaims[auth.RefreshTokenClaims]) {
 		zone := b.Subject
 		if _, isDomain := dns.IsDomainName(zone); !isDomain {
-			http.Error(rw, "Invalid token", http.StatusBadRequest)
+			writeError(rw, http.StatusBadRequest, "Invalid token")
 			return
 		}
 
 		accessTokenId, err := strconv.ParseInt(b.Claims.AccessTokenId, 16, 64)
 		if err != nil {
-			http.Error(rw, "Invalid token", http.StatusBadRequest)
+			writeError(rw, http.StatusBadRequest, "Invalid token")
 			return
 		}
 		logger.Logger.Debug("Refreshing bot token", "id", accessTokenId, "hex", b.Claims.AccessTokenId, "zone", zone)
@@ -100,11 +107,11 @@ func AddAuthRoutes(r *chi.Mux, db authQueries, userKeystore *mjwt.KeyStore, apiI
 		case err == nil:
 			break
 		case errors.Is(err, sql.ErrNoRows):
-			http.Error(rw, "Invalid token", http.StatusUnauthorized)
+			writeError(rw, http.StatusUnauthorized, "Invalid token")
 			return
 		case err != nil:
 			logger.Logger.Debug("Failed to refresh bot token", "err", err)
-			http.Error(rw, "Database error", http.StatusInternalServerError)
+			writeError(rw, http.StatusInternalServerError, "Database error")
 			return
 		}
 
@@ -114,7 +121,7 @@ func AddAuthRoutes(r *chi.Mux, db authQueries, userKeystore *mjwt.KeyStore, apiI
 			"verbena-bot-token",
 		}, ps)
 		if err != nil {
-			http.Error(rw, "Failed to create token", http.StatusInternalServerError)
+			writeError(rw, http.StatusInternalServerError, "Failed to create token")
 			return
 		}
 
@@ -133,13 +140,13 @@ func validateAuthToken[T mjwt.Claims](keystore *mjwt.KeyStore, next authHandler[
 		rawAuth := req.Header.Get("Authorization")
 		token, found := strings.CutPrefix(rawAuth, "Bearer ")
 		if !found {
-			http.Error(rw, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+			writeError(rw, http.StatusUnauthorized, http.StatusText(http.StatusUnauthorized))
 			return
 		}
 
 		_, b, err := mjwt.ExtractClaims[T](keystore, token)
 		if err != nil {
-			http.Error(rw, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+			writeError(rw, http.StatusUnauthorized, http.StatusText(http.StatusUnauthorized))
 			return
 		}
 
@@ -0,0 +1,310 @@
+package routes
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/1f349/mjwt"
+	"github.com/1f349/mjwt/auth"
+	"github.com/1f349/verbena/conf"
+	"github.com/1f349/verbena/internal/database"
+	"github.com/1f349/verbena/internal/utils"
+	"github.com/1f349/verbena/internal/webhook"
+	"github.com/1f349/verbena/logger"
+	"github.com/1f349/verbena/rest"
+	"github.com/go-chi/chi/v5"
+)
+
+const maxBatchOperations = 1000
+
+type batchQueries interface {
+	GetZone(ctx context.Context, zoneId int64) (database.Zone, error)
+	UseRecordTx(ctx context.Context, cb func(tx database.RecordTx) error) error
+	poolLister
+}
+
+// batchError is returned from the transaction when an operation is invalid so
+// it can be reported as a bad request
+type batchError struct {
+	index int
+	msg   string
+}
+
+func (e batchError) Error() string {
+	return fmt.Sprintf("operation %d: %s", e.index, e.msg)
+}
+
+// batchModified is returned from the transaction when a record was changed
+// since it was read, precondition is set when the operation gave the version
+type batchModified struct {
+	index        int
+	id           int64
+	precondition bool
+}
+
+func (e batchModified) Error() string {
+	if e.precondition {
+		return fmt.Sprintf("operation %d: the record %d has been modified", e.index, e.id)
+	}
+	return fmt.Sprintf("operation %d: the record %d was modified by another request", e.index, e.id)
+}
+
+func AddBatchRoutes(r chi.Router, db batchQueries, keystore *mjwt.KeyStore, views conf.ViewsConf, events eventFirer) {
+	r.Post("/zones/{zone_id:[0-9]+}/batch", validateAuthToken(keystore, func(rw http.ResponseWriter, req *http.Request, b mjwt.BaseTypeClaims[auth.AccessTokenClaims]) {
+		var operations []rest.BatchOperation
+		err := json.NewDecoder(req.Body).Decode(&operations)
+		if err != nil {
+			writeError(rw, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+
+		if len(operations) == 0 {
+			writeFieldError(rw, "operations", "At least one operation is required")
+			return
+		}
+		if len(operations) > maxBatchOperations {
+			writeFieldError(rw, "operations", fmt.Sprintf("Too many operations, expected at most %d", maxBatchOperations))
+			return
+		}
+
+		err = validateBatch(operations, views)
+		if err != nil {
+			writeError(rw, http.StatusBadRequest, "Invalid batch: "+err.Error())
+			return
+		}
+
+		zoneInfo, ok := getOwnedZone(rw, req, db, b)
+		if !ok {
+			return
+		}
+
+		pools, err := db.GetZonePools(req.Context(), zoneInfo.ID)
+		if err != nil {
+			logger.Logger.Error("Failed to get zone pools", "err", err)
+			writeError(rw, http.StatusInternalServerError, "Database error occurred")
+			return
+		}
+
+		var results []rest.BatchResult
+		var changes []recordChange
+		err = db.UseRecordTx(req.Context(), func(tx database.RecordTx) error {
+			before, err := zoneRecordsInTx(req.Context(), tx, zoneInfo.ID)
+			if err != nil {
+				return err
+			}
+			results = make([]rest.BatchResult, 0, len(operations))
+			changes = make([]recordChange, 0, len(operations))
+			for i, op := range operations {
+				change, err := applyBatchOperation(req.Context(), tx, zoneInfo, pools, i, op)
+				if err != nil {
+					return err
+				}
+				results = append(results, rest.BatchResult{Action: op.Action, Record: change.record})
+				changes = append(changes, change)
+			}
+			return lintTx(req.Context(), tx, zoneInfo, before)
+		})
+		var bErr batchError
+		if errors.As(err, &bErr) {
+			writeError(rw, http.StatusBadRequest, "Invalid batch: "+bErr.Error())
+			return
+		}
+		var modErr batchModified
+		if errors.As(err, &modErr) {
+			if modErr.precondition {
+				writeError(rw, http.StatusPreconditionFailed, "Precondition failed: "+modErr.Error())
+			} else {
+				writeError(rw, http.StatusConflict, modErr.Error())
+			}
+			return
+		}
+		var poolErr recordPoolConflict
+		if errors.As(err, &poolErr) {
+			writeError(rw, http.StatusConflict, poolErr.Error())
+			return
+		}
+		var lintErr lintFailure
+		if errors.As(err, &lintErr) {
+			writeLintFailure(rw, lintErr)
+			return
+		}
+		if err != nil {
+			logger.Logger.Debug("Failed to apply batch", "err", err)
+			writeError(rw, http.StatusInternalServerError, "Database error occurred")
+			return
+		}
+
+		for _, change := range changes {
+			events.Fire(zoneInfo.ID, zoneInfo.Name, change.event, change.record)
+		}
+
+		json.NewEncoder(rw).Encode(results)
+	}))
+}
+
+// validateBatch checks everything which does not need the existing records,
+// Unicode targets of created records are converted to punycode and the views
+// are normalized in place
+func validateBatch(operations []rest.BatchOperation, views conf.ViewsConf) error {
+	changed := make(map[int64]bool)
+	for i, op := range operations {
+		if op.Ttl.Valid && op.Ttl.Int32 > ttlMaxOneWeek {
+			return batchError{i, fmt.Sprintf("invalid time to live, expected 'null or ttl <= %d seconds'", ttlMaxOneWeek)}
+		}
+		if op.AutoPTR {
+			return batchError{i, "auto PTR records cannot be changed in a batch, use the record routes"}
+		}
+		if op.Views != nil {
+			normalized, err := views.Normalize(*op.Views)
+			if err != nil {
+				return batchError{i, "invalid views: " + err.Error()}
+			}
+			operations[i].Views = &normalized
+		}
+
+		switch op.Action {
+		case rest.BatchCreate:
+			if op.Version != 0 {
+				return batchError{i, "version is only used to update or delete a record"}
+			}
+			value, err := op.Value.ToASCII(op.Type)
+			if err != nil {
+				return batchError{i, "invalid value: " + err.Error()}
+			}
+			if !value.IsValidForType(op.Type) {
+				return batchError{i, "invalid value for type"}
+			}
+			operations[i].Value = value
+			continue
+		case rest.BatchUpdate, rest.BatchDelete:
+			if op.ID <= 0 {
+				return batchError{i, "invalid record ID"}
+			}
+		default:
+			return batchError{i, "unknown action: " + op.Action}
+		}
+
+		if changed[op.ID] {
+			return batchError{i, fmt.Sprintf("record %d is changed more than once", op.ID)}
+		}
+		changed[op.ID] = true
+	}
+	return nil
+}
+
+func applyBatchOperation(ctx context.Context, tx database.RecordTx, zoneInfo database.Zone, pools []database.Pool, index int, op rest.BatchOperation) (recordChange, error) {
+	if op.Action == rest.BatchCreate {
+		name, err := utils.NormalizeRecordName(zoneInfo.Name, op.Type, op.Name)
+		if err != nil {
+			return recordChange{}, batchError{index, "invalid record name: " + err.Error()}
+		}
+		err = findRecordPoolConflict(name, op.Type, pools)
+		if err != nil {
+			return recordChange{}, err
+		}
+		var views []string
+		if op.Views != nil {
+			views = *op.Views
+		}
+		id, err := tx.InsertRecordFromApi(ctx, database.InsertRecordFromApiParams{
+			Name:      name,
+			ZoneID:    zoneInfo.ID,
+			Type:      op.Type,
+			PreTtl:    op.Ttl,
+			PreValue:  op.Value.ToValueString(op.Type),
+			PreActive: op.Active,
+			PreViews:  utils.JoinViews(views),
+		})
+		if err != nil {
+			return recordChange{}, err
+		}
+		return recordChange{webhook.RecordCreated, rest.Record{
+			ID:     id,
+			Name:   name,
+			ZoneID: zoneInfo.ID,
+			Ttl:    op.Ttl,
+			Type:   op.Type,
+			Value:  op.Value,
+			Active: op.Active,
+			Views:  views,
+		}.WithUnicode()}, nil
+	}
+
+	row, err := tx.GetZoneRecord(ctx, database.GetZoneRecordParams{
+		RecordID: op.ID,
+		ZoneID:   zoneInfo.ID,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return recordChange{}, batchError{index, fmt.Sprintf("record %d not found", op.ID)}
+	}
+	if err != nil {
+		return recordChange{}, err
+	}
+	// the PTR record would be left behind in the reverse zone
+	if row.Record.AutoPtr {
+		return recordChange{}, batchError{index, fmt.Sprintf("record %d keeps an auto PTR record, use the record routes", op.ID)}
+	}
+	if op.Version != 0 && op.Version != row.Record.Version {
+		return recordChange{}, batchModified{index, op.ID, true}
+	}
+
+	if op.Action == rest.BatchDelete {
+		original, err := RecordToRestRecord(row.Record)
+		if err != nil {
+			return recordChange{}, err
+		}
+		changed, err := tx.DeleteRecordIfVersion(ctx, database.DeleteRecordIfVersionParams{
+			RecordID: op.ID,
+			ZoneID:   zoneInfo.ID,
+			Version:  row.Record.Version,
+		})
+		if err != nil {
+			return recordChange{}, err
+		}
+		if changed == 0 {
+			return recordChange{}, batchModified{index, op.ID, op.Version != 0}
+		}
+		return recordChange{webhook.RecordDeleted, original}, nil
+	}
+
+	value, err := op.Value.ToASCII(row.Record.Type)
+	if err != nil {
+		return recordChange{}, batchError{index, "invalid value: " + err.Error()}
+	}
+	if !value.IsValidForType(row.Record.Type) {
+		return recordChange{}, batchError{index, "invalid value for type"}
+	}
+	views := utils.SplitViews(row.Record.PreViews)
+	if op.Views != nil {
+		views = *op.Views
+	}
+	changed, err := tx.UpdateRecordIfVersion(ctx, database.UpdateRecordIfVersionParams{
+		PreTtl:    op.Ttl,
+		PreValue:  value.ToValueString(row.Record.Type),
+		PreActive: op.Active,
+		PreViews:  utils.JoinViews(views),
+		ID:        op.ID,
+		ZoneID:    zoneInfo.ID,
+		Version:   row.Record.Version,
+	})
+	if err != nil {
+		return recordChange{}, err
+	}
+	if changed == 0 {
+		return recordChange{}, batchModified{index, op.ID, op.Version != 0}
+	}
+	return recordChange{webhook.RecordUpdated, rest.Record{
+		ID:     op.ID,
+		Name:   row.Record.Name,
+		ZoneID: zoneInfo.ID,
+		Ttl:    op.Ttl,
+		Type:   row.Record.Type,
+		Value:  value,
+		Active: op.Active,
+		Views:  views,
+	}.WithUnicode()}, nil
+}
@@ -0,0 +1,153 @@
+package routes
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/1f349/mjwt"
+	"github.com/1f349/mjwt/auth"
+	"github.com/1f349/verbena/conf"
+	"github.com/1f349/verbena/internal/database"
+	"github.com/1f349/verbena/internal/webhook"
+	"github.com/go-chi/chi/v5"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAddBatchRoutes(t *testing.T) {
+	r := chi.NewRouter()
+	issuer, err := mjwt.NewIssuer("hello world", "1", jwt.SigningMethodRS256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	q := &rrsetTestQueries{recordTestQueries: recordTestQueries{
+		records: make(map[int64]database.Record),
+	}}
+	events := &recordTestEvents{}
+	views := conf.ViewsConf{
+		{Name: "internal", MatchClients: []string{"10.0.0.0/8"}},
+		{Name: "external", MatchClients: []string{"any"}},
+	}
+	AddBatchRoutes(r, q, issuer.KeyStore(), views, events)
+	for _, i := range []database.InsertRecordFromApiParams{
+		{Name: "www", ZoneID: 3456, Type: "A", PreValue: "192.0.2.1", PreActive: true, PreViews: "external"},
+		{Name: "old", ZoneID: 3456, Type: "A", PreValue: "192.0.2.2", PreActive: true},
+	} {
+		_, err = q.InsertRecordFromApi(t.Context(), i)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ps := auth.NewPermStorage()
+	ps.Set("domain:owns=example.org")
+	wrongToken, err := issuer.GenerateJwt("1234", "", jwt.ClaimStrings{}, time.Hour, auth.AccessTokenClaims{Perms: ps})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ps = auth.NewPermStorage()
+	ps.Set("domain:owns=example.com")
+	token, err := issuer.GenerateJwt("1234", "", jwt.ClaimStrings{}, time.Hour, auth.AccessTokenClaims{Perms: ps})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	body := `[
+{"action":"create","name":"api","type":"A","ttl":300,"value":{"ip":"192.0.2.3"},"active":true,"views":["Internal"]},
+{"action":"update","id":1,"ttl":null,"value":{"ip":"192.0.2.10"},"active":true},
+{"action":"delete","id":2,"version":1}
+]`
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/zones/3456/batch", strings.NewReader(body))
+	r.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+
+	rec = httptest.NewRecorder()
+	req = httptest.NewRequest(http.MethodPost, "/zones/3456/batch", strings.NewReader(body))
+	req.Header.Set("Authorization", "Bearer "+wrongToken)
+	r.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	for _, i := range []struct {
+		body string
+		err  string
+	}{
+		{`[]`, "{\"code\":\"validation_failed\",\"message\":\"At least one operation is required\",\"field\":\"operations\"}\n"},
+		{`[{"action":"rename","id":1}]`, "{\"code\":\"validation_failed\",\"message\":\"Invalid batch: operation 0: unknown action: rename\"}\n"},
+		{`[{"action":"create","name":"api","type":"A","value":{"ip":"2001:db8::1"}}]`, "{\"code\":\"validation_failed\",\"message\":\"Invalid batch: operation 0: invalid value for type\"}\n"},
+		{`[{"action":"update","id":1,"value":{"ip":"192.0.2.10"}},{"action":"delete","id":1}]`, "{\"code\":\"validation_failed\",\"message\":\"Invalid batch: operation 1: record 1 is changed more than once\"}\n"},
+		// found while applying so the create is rolled back
+		{`[{"action":"create","name":"api","type":"A","value":{"ip":"192.0.2.3"}},{"action":"delete","id":99}]`, "{\"code\":\"validation_failed\",\"message\":\"Invalid batch: operation 1: record 99 not found\"}\n"},
+		{`[{"action":"update","id":1,"value":{"target":"example.net"}}]`, "{\"code\":\"validation_failed\",\"message\":\"Invalid batch: operation 0: invalid value for type\"}\n"},
+		{`[{"action":"create","name":"api","type":"A","value":{"ip":"192.0.2.3"},"auto_ptr":true}]`, "{\"code\":\"validation_failed\",\"message\":\"Invalid batch: operation 0: auto PTR records cannot be changed in a batch, use the record routes\"}\n"},
+		{`[{"action":"create","name":"api","type":"A","value":{"ip":"192.0.2.3"},"views":["office"]}]`, "{\"code\":\"validation_failed\",\"message\":\"Invalid batch: operation 0: invalid views: unknown view \\\"office\\\"\"}\n"},
+		{`[{"action":"create","name":"api","type":"A","value":{"ip":"192.0.2.3"},"version":1}]`, "{\"code\":\"validation_failed\",\"message\":\"Invalid batch: operation 0: version is only used to update or delete a record\"}\n"},
+	} {
+		rec = httptest.NewRecorder()
+		req = httptest.NewRequest(http.MethodPost, "/zones/3456/batch", strings.NewReader(i.body))
+		req.Header.Set("Authorization", "Bearer "+token)
+		r.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.Equal(t, i.err, rec.Body.String())
+	}
+	assert.Len(t, q.records, 2)
+	assert.Empty(t, events.events)
+
+	// records cannot be created at a pooled name, the apex is "@" in the pool
+	q.zonePools = []database.Pool{{ID: 1, ZoneID: 3456, Name: "@", Type: "A", Size: 1}}
+	rec = httptest.NewRecorder()
+	req = httptest.NewRequest(http.MethodPost, "/zones/3456/batch", strings.NewReader(`[{"action":"create","name":"@","type":"CNAME","value":{"target":"example.net"}}]`))
+	req.Header.Set("Authorization", "Bearer "+token)
+	r.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusConflict, rec.Code)
+	assert.Equal(t, "{\"code\":\"conflict\",\"message\":\"Record conflicts with the existing A pool\"}\n", rec.Body.String())
+	assert.Len(t, q.records, 2)
+	q.zonePools = nil
+
+	// a stale version fails the whole batch
+	rec = httptest.NewRecorder()
+	req = httptest.NewRequest(http.MethodPost, "/zones/3456/batch", strings.NewReader(`[{"action":"create","name":"api","type":"A","value":{"ip":"192.0.2.3"},"active":true},{"action":"update","id":1,"value":{"ip":"192.0.2.10"},"active":true,"version":7}]`))
+	req.Header.Set("Authorization", "Bearer "+token)
+	r.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusPreconditionFailed, rec.Code)
+	assert.Equal(t, "{\"code\":\"precondition_failed\",\"message\":\"Precondition failed: operation 1: the record 1 has been modified\"}\n", rec.Body.String())
+	assert.Len(t, q.records, 2)
+
+	rec = httptest.NewRecorder()
+	req = httptest.NewRequest(http.MethodPost, "/zones/3456/batch", strings.NewReader(body))
+	req.Header.Set("Authorization", "Bearer "+token)
+	r.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "[{\"action\":\"create\",\"record\":{\"id\":5,\"name\":\"api\",\"zone_id\":3456,\"ttl\":300,\"type\":\"A\",\"value\":{\"ip\":\"192.0.2.3\"},\"active\":true,\"views\":[\"internal\"]}},{\"action\":\"update\",\"record\":{\"id\":1,\"name\":\"www\",\"zone_id\":3456,\"ttl\":null,\"type\":\"A\",\"value\":{\"ip\":\"192.0.2.10\"},\"active\":true,\"views\":[\"external\"]}},{\"action\":\"delete\",\"record\":{\"id\":2,\"name\":\"old\",\"zone_id\":3456,\"ttl\":null,\"type\":\"A\",\"value\":{\"ip\":\"192.0.2.2\"},\"active\":true}}]\n", rec.Body.String())
+	assert.Equal(t, []webhook.Event{webhook.RecordCreated, webhook.RecordUpdated, webhook.RecordDeleted}, events.events)
+	assert.Equal(t, "192.0.2.10", q.records[1].PreValue)
+	assert.Equal(t, "external", q.records[1].PreViews)
+	assert.Equal(t, "internal", q.records[5].PreViews)
+	assert.True(t, q.records[2].PreDelete)
+
+	// inactive records can be created
+	rec = httptest.NewRecorder()
+	req = httptest.NewRequest(http.MethodPost, "/zones/3456/batch", strings.NewReader(`[{"action":"create","name":"spare","type":"A","value":{"ip":"192.0.2.4"},"active":false}]`))
+	req.Header.Set("Authorization", "Bearer "+token)
+	r.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.False(t, q.records[6].PreActive)
+
+	// records keeping a PTR record are left to the record routes
+	id, err := q.InsertRecordFromApi(t.Context(), database.InsertRecordFromApiParams{Name: "mail", ZoneID: 3456, Type: "A", PreValue: "192.0.2.25", PreActive: true, AutoPtr: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rec = httptest.NewRecorder()
+	req = httptest.NewRequest(http.MethodPost, "/zones/3456/batch", strings.NewReader(fmt.Sprintf(`[{"action":"delete","id":%d}]`, id)))
+	req.Header.Set("Authorization", "Bearer "+token)
+	r.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Equal(t, fmt.Sprintf("{\"code\":\"validation_failed\",\"message\":\"Invalid batch: operation 0: record %d keeps an auto PTR record, use the record routes\"}\n", id), rec.Body.String())
+	assert.False(t, q.records[id].PreDelete)
+}
@@ -0,0 +1,303 @@
+package routes
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/1f349/mjwt"
+	"github.com/1f349/mjwt/auth"
+	"github.com/1f349/verbena/internal/database"
+	"github.com/1f349/verbena/internal/delegation"
+	"github.com/1f349/verbena/internal/utils"
+	"github.com/1f349/verbena/internal/webhook"
+	"github.com/1f349/verbena/logger"
+	"github.com/1f349/verbena/rest"
+	"github.com/go-chi/chi/v5"
+)
+
+type delegationQueries interface {
+	GetZone(ctx context.Context, zoneId int64) (database.Zone, error)
+	LookupZone(ctx context.Context, name string) (int64, error)
+	GetZoneRecords(ctx context.Context, zoneId int64) ([]database.GetZoneRecordsRow, error)
+	UseRecordTx(ctx context.Context, cb func(tx database.RecordTx) error) error
+}
+
+// delegationConflictError is returned from the transaction when a record
+// would be occluded by the delegation
+type delegationConflictError struct {
+	name string
+}
+
+func (e delegationConflictError) Error() string {
+	return "Delegation conflicts with the existing record " + e.name
+}
+
+type zoneRecordsQueries interface {
+	GetZoneRecords(ctx context.Context, zoneId int64) ([]database.GetZoneRecordsRow, error)
+}
+
+func AddDelegationRoutes(r chi.Router, db delegationQueries, keystore *mjwt.KeyStore, events eventFirer) {
+	r.Route("/zones/{zone_id:[0-9]+}/delegations", func(r chi.Router) {
+		// List all delegations
+		r.Get("/", validateAuthToken(keystore, func(rw http.ResponseWriter, req *http.Request, b mjwt.BaseTypeClaims[auth.AccessTokenClaims]) {
+			zone, ok := getOwnedZone(rw, req, db, b)
+			if !ok {
+				return
+			}
+
+			records, ok := getRestZoneRecords(rw, req, db, zone.ID)
+			if !ok {
+				return
+			}
+
+			delegations := delegation.FromRecords(zone.Name, records)
+			for i := range delegations {
+				_, delegations[i].Automatic, _ = delegation.IsVerbenaChild(req.Context(), db, zone.Name, delegations[i].Name)
+			}
+			json.NewEncoder(rw).Encode(delegations)
+		}))
+
+		// Create or replace a delegation
+		r.Put("/{name}", validateAuthToken(keystore, func(rw http.ResponseWriter, req *http.Request, b mjwt.BaseTypeClaims[auth.AccessTokenClaims]) {
+			var putDelegation rest.PutDelegation
+			err := json.NewDecoder(req.Body).Decode(&putDelegation)
+			if err != nil {
+				writeError(rw, http.StatusBadRequest, "Invalid request body")
+				return
+			}
+
+			zone, ok := getOwnedZone(rw, req, db, b)
+			if !ok {
+				return
+			}
+
+			name, err := utils.ToASCIIName(strings.ToLower(chi.URLParam(req, "name")))
+			if err != nil {
+				writeError(rw, http.StatusBadRequest, "Invalid delegation: "+err.Error())
+				return
+			}
+			putDelegation, err = putDelegation.ToASCII()
+			if err != nil {
+				writeError(rw, http.StatusBadRequest, "Invalid delegation: "+err.Error())
+				return
+			}
+			err = delegation.Validate(zone.Name, name, putDelegation)
+			if err != nil {
+				writeError(rw, http.StatusBadRequest, "Invalid delegation: "+err.Error())
+				return
+			}
+
+			_, automatic, err := delegation.IsVerbenaChild(req.Context(), db, zone.Name, name)
+			if err != nil {
+				logger.Logger.Error("Failed to lookup child zone", "err", err)
+				writeError(rw, http.StatusInternalServerError, "Database error occurred")
+				return
+			}
+			if automatic && len(putDelegation.DS) > 0 {
+				writeError(rw, http.StatusBadRequest, "Invalid delegation: DS records are synced automatically from the child zone")
+				return
+			}
+
+			out := rest.Delegation{
+				Name:        name,
+				Nameservers: []string{},
+				Glue:        []rest.Glue{},
+				DS:          []rest.DelegationSigner{},
+				Automatic:   automatic,
+			}
+			var changes []recordChange
+			err = db.UseRecordTx(req.Context(), func(tx database.RecordTx) error {
+				records, err := zoneRecordsInTx(req.Context(), tx, zone.ID)
+				if err != nil {
+					return err
+				}
+
+				existing := delegation.Records(zone.Name, name, records)
+				if occluded := occludedRecords(zone.Name, name, records, existing, putDelegation); occluded != "" {
+					return delegationConflictError{occluded}
+				}
+
+				if automatic {
+					// leave the synced DS records alone
+					var kept []rest.Record
+					for _, r := range existing {
+						if r.Type == "DS" {
+							out.DS = append(out.DS, rest.DelegationSigner{
+								KeyTag:     r.Value.KeyTag,
+								Algorithm:  r.Value.Algorithm,
+								DigestType: r.Value.DigestType,
+								Digest:     r.Value.Digest,
+							})
+							continue
+						}
+						kept = append(kept, r)
+					}
+					existing = kept
+				}
+
+				create, remove := delegation.Diff(existing, delegation.ToRecords(zone.Name, name, putDelegation))
+				changes = make([]recordChange, 0, len(create)+len(remove))
+				for _, record := range create {
+					id, err := tx.InsertRecordFromApi(req.Context(), database.InsertRecordFromApiParams{
+						Name:      record.Name,
+						ZoneID:    zone.ID,
+						Type:      record.Type,
+						PreTtl:    record.Ttl,
+						PreValue:  record.Value.ToValueString(record.Type),
+						PreActive: true,
+					})
+					if err != nil {
+						return err
+					}
+					changes = append(changes, recordChange{webhook.RecordCreated, rest.Record{
+						ID:     id,
+						Name:   record.Name,
+						ZoneID: zone.ID,
+						Ttl:    record.Ttl,
+						Type:   record.Type,
+						Value:  record.Value,
+						Active: true,
+					}})
+				}
+				for _, record := range remove {
+					err = tx.DeleteRecordFromApi(req.Context(), database.DeleteRecordFromApiParams{
+						RecordID: record.ID,
+						ZoneID:   zone.ID,
+					})
+					if err != nil {
+						return err
+					}
+					changes = append(changes, recordChange{webhook.RecordDeleted, record})
+				}
+				return lintTx(req.Context(), tx, zone, records)
+			})
+			var conflictErr delegationConflictError
+			if errors.As(err, &conflictErr) {
+				writeError(rw, http.StatusConflict, conflictErr.Error())
+				return
+			}
+			var lintErr lintFailure
+			if errors.As(err, &lintErr) {
+				writeLintFailure(rw, lintErr)
+				return
+			}
+			if err != nil {
+				logger.Logger.Debug("Failed to update delegation", "err", err)
+				writeError(rw, http.StatusInternalServerError, "Database error occurred")
+				return
+			}
+
+			for _, change := range changes {
+				events.Fire(zone.ID, zone.Name, change.event, change.record)
+			}
+
+			for _, ns := range putDelegation.Nameservers {
+				out.Nameservers = append(out.Nameservers, strings.TrimSuffix(strings.ToLower(ns), "."))
+			}
+			out.Glue = append(out.Glue, putDelegation.Glue...)
+			out.DS = append(out.DS, putDelegation.DS...)
+			json.NewEncoder(rw).Encode(out)
+		}))
+
+		// Delete a delegation
+		r.Delete("/{name}", validateAuthToken(keystore, func(rw http.ResponseWriter, req *http.Request, b mjwt.BaseTypeClaims[auth.AccessTokenClaims]) {
+			zone, ok := getOwnedZone(rw, req, db, b)
+			if !ok {
+				return
+			}
+
+			name, err := utils.ToASCIIName(strings.ToLower(chi.URLParam(req, "name")))
+			if err != nil {
+				writeError(rw, http.StatusBadRequest, "Invalid delegation: "+err.Error())
+				return
+			}
+
+			var existing []rest.Record
+			err = db.UseRecordTx(req.Context(), func(tx database.RecordTx) error {
+				records, err := zoneRecordsInTx(req.Context(), tx, zone.ID)
+				if err != nil {
+					return err
+				}
+				existing = delegation.Records(zone.Name, name, records)
+				for _, record := range existing {
+					err = tx.DeleteRecordFromApi(req.Context(), database.DeleteRecordFromApiParams{
+						RecordID: record.ID,
+						ZoneID:   zone.ID,
+					})
+					if err != nil {
+						return err
+					}
+				}
+				return nil
+			})
+			if err != nil {
+				logger.Logger.Debug("Failed to delete delegation", "err", err)
+				writeError(rw, http.StatusInternalServerError, "Database error occurred")
+				return
+			}
+
+			if len(existing) == 0 {
+				writeNotFound(rw)
+				return
+			}
+
+			for _, record := range existing {
+				events.Fire(zone.ID, zone.Name, webhook.RecordDeleted, record)
+			}
+
+			rw.WriteHeader(http.StatusOK)
+		}))
+	})
+}
+
+// getRestZoneRecords loads the staged records of a zone
+func getRestZoneRecords(rw http.ResponseWriter, req *http.Request, db zoneRecordsQueries, zoneId int64) ([]rest.Record, bool) {
+	rows, err := db.GetZoneRecords(req.Context(), zoneId)
+	if err != nil {
+		logger.Logger.Error("Failed to get zone records", "err", err)
+		writeError(rw, http.StatusInternalServerError, "Database error occurred")
+		return nil, false
+	}
+
+	records := make([]rest.Record, 0, len(rows))
+	for _, row := range rows {
+		if row.Record.PreDelete {
+			continue
+		}
+		records = appendRecord(records, row.Record)
+	}
+	return records, true
+}
+
+// occludedRecords returns the name of the first record which would be hidden
+// by the delegation, these are any records at or below the delegation point
+// which are not part of the delegation
+func occludedRecords(zoneName, name string, records, existing []rest.Record, d rest.PutDelegation) string {
+	part := make(map[int64]bool, len(existing))
+	for _, r := range existing {
+		part[r.ID] = true
+	}
+	glue := make(map[string]bool, len(d.Glue))
+	for _, g := range d.Glue {
+		glue[strings.TrimSuffix(strings.ToLower(g.Nameserver), ".")] = true
+	}
+
+	child := delegation.ChildName(zoneName, name)
+	for _, r := range records {
+		if part[r.ID] {
+			continue
+		}
+		host := delegation.ChildName(zoneName, strings.ToLower(r.Name))
+		if host != child && !strings.HasSuffix(host, "."+child) {
+			continue
+		}
+		if (r.Type == "A" || r.Type == "AAAA") && glue[host] {
+			continue
+		}
+		return host
+	}
+	return ""
+}
@@ -0,0 +1,170 @@
+package routes
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/1f349/mjwt"
+	"github.com/1f349/mjwt/auth"
+	"github.com/1f349/verbena/internal/database"
+	"github.com/1f349/verbena/internal/webhook"
+	"github.com/go-chi/chi/v5"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+type delegationTestQueries struct {
+	rrsetTestQueries
+}
+
+func (d *delegationTestQueries) LookupZone(ctx context.Context, name string) (int64, error) {
+	if name == "signed.example.com" {
+		return 3457, nil
+	}
+	return 0, sql.ErrNoRows
+}
+
+func TestAddDelegationRoutes(t *testing.T) {
+	r := chi.NewRouter()
+	issuer, err := mjwt.NewIssuer("hello world", "1", jwt.SigningMethodRS256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	q := &delegationTestQueries{rrsetTestQueries{recordTestQueries: recordTestQueries{
+		records: make(map[int64]database.Record),
+	}}}
+	events := &recordTestEvents{}
+	AddDelegationRoutes(r, q, issuer.KeyStore(), events)
+	_, err = q.InsertRecordFromApi(t.Context(), database.InsertRecordFromApiParams{
+		Name:      "www.taken",
+		ZoneID:    3456,
+		Type:      "A",
+		PreValue:  "192.0.2.1",
+		PreActive: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ps := auth.NewPermStorage()
+	ps.Set("domain:owns=example.org")
+	wrongToken, err := issuer.GenerateJwt("1234", "", jwt.ClaimStrings{}, time.Hour, auth.AccessTokenClaims{Perms: ps})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ps = auth.NewPermStorage()
+	ps.Set("domain:owns=example.com")
+	token, err := issuer.GenerateJwt("1234", "", jwt.ClaimStrings{}, time.Hour, auth.AccessTokenClaims{Perms: ps})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("PUT /zones/3456/delegations/child", func(t *testing.T) {
+		body := `{"nameservers":["ns1.child.example.com","ns.example.net"],"glue":[{"nameserver":"ns1.child.example.com","ip":"192.0.2.53"}],"ds":[{"key_tag":12345,"algorithm":13,"digest_type":2,"digest":"` + strings.Repeat("3f", 32) + `"}]}`
+
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPut, "/zones/3456/delegations/child", strings.NewReader(body))
+		r.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+
+		rec = httptest.NewRecorder()
+		req = httptest.NewRequest(http.MethodPut, "/zones/3456/delegations/child", strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+wrongToken)
+		r.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+
+		rec = httptest.NewRecorder()
+		req = httptest.NewRequest(http.MethodPut, "/zones/3456/delegations/child", strings.NewReader(`{"nameservers":["ns1.child.example.com"]}`))
+		req.Header.Set("Authorization", "Bearer "+token)
+		r.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.Equal(t, "{\"code\":\"validation_failed\",\"message\":\"Invalid delegation: nameserver ns1.child.example.com is inside child.example.com and requires glue\"}\n", rec.Body.String())
+
+		rec = httptest.NewRecorder()
+		req = httptest.NewRequest(http.MethodPut, "/zones/3456/delegations/taken", strings.NewReader(`{"nameservers":["ns.example.net"]}`))
+		req.Header.Set("Authorization", "Bearer "+token)
+		r.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusConflict, rec.Code)
+		assert.Equal(t, "{\"code\":\"conflict\",\"message\":\"Delegation conflicts with the existing record www.taken.example.com\"}\n", rec.Body.String())
+
+		rec = httptest.NewRecorder()
+		req = httptest.NewRequest(http.MethodPut, "/zones/3456/delegations/signed", strings.NewReader(`{"nameservers":["ns.example.net"],"ds":[{"key_tag":12345,"algorithm":13,"digest_type":2,"digest":"`+strings.Repeat("3f", 32)+`"}]}`))
+		req.Header.Set("Authorization", "Bearer "+token)
+		r.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.Equal(t, "{\"code\":\"validation_failed\",\"message\":\"Invalid delegation: DS records are synced automatically from the child zone\"}\n", rec.Body.String())
+
+		// a failure part way through leaves the records untouched
+		q.failInsert = true
+		rec = httptest.NewRecorder()
+		req = httptest.NewRequest(http.MethodPut, "/zones/3456/delegations/child", strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+token)
+		r.ServeHTTP(rec, req)
+		q.failInsert = false
+		assert.Equal(t, http.StatusInternalServerError, rec.Code)
+		assert.Len(t, q.records, 1)
+		assert.Empty(t, events.events)
+
+		rec = httptest.NewRecorder()
+		req = httptest.NewRequest(http.MethodPut, "/zones/3456/delegations/child", strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+token)
+		r.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "{\"name\":\"child\",\"nameservers\":[\"ns1.child.example.com\",\"ns.example.net\"],\"glue\":[{\"nameserver\":\"ns1.child.example.com\",\"ip\":\"192.0.2.53\"}],\"ds\":[{\"key_tag\":12345,\"algorithm\":13,\"digest_type\":2,\"digest\":\""+strings.Repeat("3f", 32)+"\"}],\"automatic\":false}\n", rec.Body.String())
+		assert.Len(t, q.records, 5)
+		assert.Equal(t, []webhook.Event{webhook.RecordCreated, webhook.RecordCreated, webhook.RecordCreated, webhook.RecordCreated}, events.events)
+
+		// Replacing the delegation only changes the differences
+		rec = httptest.NewRecorder()
+		req = httptest.NewRequest(http.MethodPut, "/zones/3456/delegations/child", strings.NewReader(`{"nameservers":["ns.example.net"]}`))
+		req.Header.Set("Authorization", "Bearer "+token)
+		r.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, []webhook.Event{webhook.RecordDeleted, webhook.RecordDeleted, webhook.RecordDeleted}, events.events[4:])
+	})
+
+	t.Run("GET /zones/3456/delegations", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/zones/3456/delegations", nil)
+		r.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+
+		rec = httptest.NewRecorder()
+		req = httptest.NewRequest(http.MethodGet, "/zones/3456/delegations", nil)
+		req.Header.Set("Authorization", "Bearer "+wrongToken)
+		r.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+
+		rec = httptest.NewRecorder()
+		req = httptest.NewRequest(http.MethodGet, "/zones/3456/delegations", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		r.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "[{\"name\":\"child\",\"nameservers\":[\"ns.example.net\"],\"glue\":[],\"ds\":[],\"automatic\":false}]\n", rec.Body.String())
+	})
+
+	t.Run("DELETE /zones/3456/delegations/child", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodDelete, "/zones/3456/delegations/child", nil)
+		req.Header.Set("Authorization", "Bearer "+wrongToken)
+		r.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+
+		rec = httptest.NewRecorder()
+		req = httptest.NewRequest(http.MethodDelete, "/zones/3456/delegations/child", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		r.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusOK, rec.Code)
+
+		rec = httptest.NewRecorder()
+		req = httptest.NewRequest(http.MethodDelete, "/zones/3456/delegations/child", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		r.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+	})
+}
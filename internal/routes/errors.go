@@ -0,0 +1,48 @@
+package routes
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/1f349/verbena/rest"
+)
+
+// writeErrorBody writes the JSON error body with the status
+func writeErrorBody(rw http.ResponseWriter, status int, body rest.ErrorBody) {
+	rw.Header().Set("Content-Type", "application/json")
+	rw.Header().Set("X-Content-Type-Options", "nosniff")
+	rw.WriteHeader(status)
+	json.NewEncoder(rw).Encode(body)
+}
+
+// writeError writes an error response with the code matching the status
+func writeError(rw http.ResponseWriter, status int, message string) {
+	writeErrorBody(rw, status, rest.ErrorBody{
+		Code:    rest.CodeForStatus(status),
+		Message: message,
+	})
+}
+
+// writeFieldError writes a validation error for a single request field or URL
+// parameter
+func writeFieldError(rw http.ResponseWriter, field, message string) {
+	writeErrorBody(rw, http.StatusBadRequest, rest.ErrorBody{
+		Code:    rest.CodeValidation,
+		Message: message,
+		Field:   field,
+	})
+}
+
+// writeNotFound is used for missing resources and zones the token does not own
+func writeNotFound(rw http.ResponseWriter) {
+	writeError(rw, http.StatusNotFound, "Not found")
+}
+
+// writeLintFailure writes the conflict caused by lint errors with the problems
+func writeLintFailure(rw http.ResponseWriter, l lintFailure) {
+	writeErrorBody(rw, http.StatusConflict, rest.ErrorBody{
+		Code:    rest.CodeLintFailed,
+		Message: l.Error(),
+		Lint:    l.problems,
+	})
+}
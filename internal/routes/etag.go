@@ -0,0 +1,45 @@
+package routes
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// etag formats the version of a zone or record as a strong entity tag
+func etag(version int64) string {
+	return strconv.Quote(strconv.FormatInt(version, 10))
+}
+
+func setETag(rw http.ResponseWriter, version int64) {
+	rw.Header().Set("ETag", etag(version))
+}
+
+// checkIfMatch compares the If-Match header with the current version, a
+// missing header or "*" matches any version. A 412 response is written when
+// false is returned.
+func checkIfMatch(rw http.ResponseWriter, req *http.Request, current int64, what string) bool {
+	header := req.Header.Get("If-Match")
+	if header == "" {
+		return true
+	}
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		// weak tags never match with the strong comparison used by If-Match
+		if tag == "*" || tag == etag(current) {
+			return true
+		}
+	}
+	writeError(rw, http.StatusPreconditionFailed, "Precondition failed: the "+what+" has been modified")
+	return false
+}
+
+// writeModified is used when a conditional update matched no rows because the
+// version changed after it was read
+func writeModified(rw http.ResponseWriter, req *http.Request, what string) {
+	if req.Header.Get("If-Match") != "" {
+		writeError(rw, http.StatusPreconditionFailed, "Precondition failed: the "+what+" has been modified")
+		return
+	}
+	writeError(rw, http.StatusConflict, "The "+what+" was modified by another request")
+}
@@ -0,0 +1,281 @@
+package routes
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/1f349/mjwt"
+	"github.com/1f349/mjwt/auth"
+	"github.com/1f349/verbena/internal/database"
+	"github.com/1f349/verbena/internal/health"
+	"github.com/1f349/verbena/logger"
+	"github.com/1f349/verbena/rest"
+	"github.com/go-chi/chi/v5"
+)
+
+type failoverQueries interface {
+	GetZone(ctx context.Context, id int64) (database.Zone, error)
+	GetZoneRecord(ctx context.Context, row database.GetZoneRecordParams) (database.GetZoneRecordRow, error)
+	GetRecordHealthCheck(ctx context.Context, arg database.GetRecordHealthCheckParams) (database.HealthCheck, error)
+	UpsertHealthCheck(ctx context.Context, arg database.UpsertHealthCheckParams) error
+	DeleteHealthCheck(ctx context.Context, arg database.DeleteHealthCheckParams) error
+}
+
+func HealthCheckToRestFailover(check database.HealthCheck, recordType string) (rest.Failover, error) {
+	backup, err := rest.ParseRecordValue(recordType, check.Backup)
+	if err != nil {
+		return rest.Failover{}, err
+	}
+	return rest.Failover{
+		RecordID:  check.RecordID,
+		Backup:    backup.WithUnicode(recordType),
+		BackupTtl: check.BackupTtl,
+		Check: rest.HealthCheck{
+			Type:       check.CheckType,
+			Target:     check.Target,
+			HttpStatus: check.HttpStatus,
+			QueryName:  check.QueryName,
+			QueryType:  check.QueryType,
+			Interval:   check.CheckInterval,
+			Threshold:  check.Threshold,
+		},
+		Status: healthCheckStatus(check),
+	}, nil
+}
+
+func healthCheckStatus(check database.HealthCheck) rest.HealthStatus {
+	status := rest.HealthStatus{
+		Healthy:   check.Healthy,
+		LastError: check.LastError,
+	}
+	if check.CheckedAt != 0 {
+		checkedAt := time.Unix(check.CheckedAt, 0).UTC()
+		status.CheckedAt = &checkedAt
+	}
+	return status
+}
+
+// AddFailoverRoutes adds the failover routes, the zone is published when a
+// change affects a record which is currently serving its backup value
+func AddFailoverRoutes(r chi.Router, db failoverQueries, keystore *mjwt.KeyStore, publisher zonePublisher, allowPrivateTargets bool) {
+	r.Route("/zones/{zone_id:[0-9]+}/records/{record_id:[0-9]+}/failover", func(r chi.Router) {
+		r.Get("/", validateAuthToken(keystore, func(rw http.ResponseWriter, req *http.Request, b mjwt.BaseTypeClaims[auth.AccessTokenClaims]) {
+			record, ok := getOwnedRecord(rw, req, db, b)
+			if !ok {
+				return
+			}
+
+			check, err := db.GetRecordHealthCheck(req.Context(), database.GetRecordHealthCheckParams{
+				RecordID: record.Record.ID,
+				ZoneID:   record.Record.ZoneID,
+			})
+			switch {
+			case errors.Is(err, sql.ErrNoRows):
+				writeNotFound(rw)
+				return
+			case err != nil:
+				logger.Logger.Error("Failed to get health check", "err", err)
+				writeError(rw, http.StatusInternalServerError, "Database error occurred")
+				return
+			}
+
+			failover, err := HealthCheckToRestFailover(check, record.Record.Type)
+			if err != nil {
+				writeError(rw, http.StatusInternalServerError, "Server error occurred")
+				return
+			}
+			json.NewEncoder(rw).Encode(failover)
+		}))
+
+		// Create or replace the failover of a record
+		r.Put("/", validateAuthToken(keystore, func(rw http.ResponseWriter, req *http.Request, b mjwt.BaseTypeClaims[auth.AccessTokenClaims]) {
+			var putFailover rest.PutFailover
+			err := json.NewDecoder(req.Body).Decode(&putFailover)
+			if err != nil {
+				writeError(rw, http.StatusBadRequest, "Invalid request body")
+				return
+			}
+
+			record, ok := getOwnedRecord(rw, req, db, b)
+			if !ok {
+				return
+			}
+			recordType := record.Record.Type
+
+			if recordType != "A" && recordType != "AAAA" && recordType != "CNAME" {
+				writeFieldError(rw, "type", "Invalid failover: only A, AAAA and CNAME records can fail over")
+				return
+			}
+
+			backupTtl := int32(health.DefaultBackupTtl)
+			if putFailover.BackupTtl.Valid {
+				backupTtl = putFailover.BackupTtl.Int32
+			}
+			if backupTtl < 1 || backupTtl > health.MaxBackupTtl {
+				writeFieldError(rw, "backup_ttl", fmt.Sprintf("Invalid backup time to live, expected 'null or 1 <= ttl <= %d seconds'", health.MaxBackupTtl))
+				return
+			}
+
+			backup, err := putFailover.Backup.ToASCII(recordType)
+			if err != nil {
+				writeFieldError(rw, "backup", "Invalid backup value: "+err.Error())
+				return
+			}
+			if !backup.IsValidForType(recordType) {
+				writeFieldError(rw, "backup", "Invalid backup value for type")
+				return
+			}
+
+			check, err := health.Normalize(putFailover.Check, allowPrivateTargets)
+			if err != nil {
+				writeFieldError(rw, "check", "Invalid health check: "+err.Error())
+				return
+			}
+
+			servingBackup, err := isServingBackup(req.Context(), db, record.Record)
+			if err != nil {
+				logger.Logger.Error("Failed to get health check", "err", err)
+				writeError(rw, http.StatusInternalServerError, "Database error occurred")
+				return
+			}
+
+			err = db.UpsertHealthCheck(req.Context(), database.UpsertHealthCheckParams{
+				RecordID:      record.Record.ID,
+				ZoneID:        record.Record.ZoneID,
+				Backup:        backup.ToValueString(recordType),
+				BackupTtl:     backupTtl,
+				CheckType:     check.Type,
+				Target:        check.Target,
+				HttpStatus:    check.HttpStatus,
+				QueryName:     check.QueryName,
+				QueryType:     check.QueryType,
+				CheckInterval: check.Interval,
+				Threshold:     check.Threshold,
+			})
+			if err != nil {
+				logger.Logger.Error("Failed to save health check", "err", err)
+				writeError(rw, http.StatusInternalServerError, "Database error occurred")
+				return
+			}
+
+			saved, err := db.GetRecordHealthCheck(req.Context(), database.GetRecordHealthCheckParams{
+				RecordID: record.Record.ID,
+				ZoneID:   record.Record.ZoneID,
+			})
+			if err != nil {
+				logger.Logger.Error("Failed to get health check", "err", err)
+				writeError(rw, http.StatusInternalServerError, "Database error occurred")
+				return
+			}
+
+			if servingBackup {
+				publishRecordZone(req.Context(), db, publisher, record.Record.ZoneID)
+			}
+
+			failover, err := HealthCheckToRestFailover(saved, recordType)
+			if err != nil {
+				writeError(rw, http.StatusInternalServerError, "Server error occurred")
+				return
+			}
+			json.NewEncoder(rw).Encode(failover)
+		}))
+
+		r.Delete("/", validateAuthToken(keystore, func(rw http.ResponseWriter, req *http.Request, b mjwt.BaseTypeClaims[auth.AccessTokenClaims]) {
+			record, ok := getOwnedRecord(rw, req, db, b)
+			if !ok {
+				return
+			}
+
+			servingBackup, err := isServingBackup(req.Context(), db, record.Record)
+			if err != nil {
+				logger.Logger.Error("Failed to get health check", "err", err)
+				writeError(rw, http.StatusInternalServerError, "Database error occurred")
+				return
+			}
+
+			err = db.DeleteHealthCheck(req.Context(), database.DeleteHealthCheckParams{
+				RecordID: record.Record.ID,
+				ZoneID:   record.Record.ZoneID,
+			})
+			if err != nil {
+				logger.Logger.Error("Failed to delete health check", "err", err)
+				writeError(rw, http.StatusInternalServerError, "Database error occurred")
+				return
+			}
+
+			// the primary value is served again once the check is removed
+			if servingBackup {
+				publishRecordZone(req.Context(), db, publisher, record.Record.ZoneID)
+			}
+			rw.WriteHeader(http.StatusOK)
+		}))
+	})
+}
+
+// isServingBackup reports if the record has a failing health check, the backup
+// value is published in place of the record until the check recovers
+func isServingBackup(ctx context.Context, db failoverQueries, record database.Record) (bool, error) {
+	check, err := db.GetRecordHealthCheck(ctx, database.GetRecordHealthCheckParams{
+		RecordID: record.ID,
+		ZoneID:   record.ZoneID,
+	})
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return false, nil
+	case err != nil:
+		return false, err
+	}
+	return !check.Healthy, nil
+}
+
+// publishRecordZone publishes the zone of a record after a change to the value
+// being served, failures are logged as the change is already saved
+func publishRecordZone(ctx context.Context, db failoverQueries, publisher zonePublisher, zoneId int64) {
+	zoneInfo, err := db.GetZone(ctx, zoneId)
+	if err != nil {
+		logger.Logger.Error("Failed to get zone", "zone", zoneId, "err", err)
+		return
+	}
+	publishZone(ctx, publisher, zoneInfo)
+}
+
+// getOwnedRecord loads the record from the URL, a 404 is returned when the
+// token does not own the record name
+func getOwnedRecord(rw http.ResponseWriter, req *http.Request, db failoverQueries, b mjwt.BaseTypeClaims[auth.AccessTokenClaims]) (database.GetZoneRecordRow, bool) {
+	zoneId, err := getZoneId(req)
+	if err != nil {
+		writeFieldError(rw, "zone_id", "Invalid zone ID")
+		return database.GetZoneRecordRow{}, false
+	}
+
+	recordId, err := getRecordId(req)
+	if err != nil {
+		writeFieldError(rw, "record_id", "Invalid record ID")
+		return database.GetZoneRecordRow{}, false
+	}
+
+	row, err := db.GetZoneRecord(req.Context(), database.GetZoneRecordParams{
+		RecordID: recordId,
+		ZoneID:   zoneId,
+	})
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		writeNotFound(rw)
+		return database.GetZoneRecordRow{}, false
+	case err != nil:
+		logger.Logger.Error("Failed to get zone record", "err", err)
+		writeError(rw, http.StatusInternalServerError, "Database error occurred")
+		return database.GetZoneRecordRow{}, false
+	}
+
+	if !b.Claims.Perms.Has("domain:owns=" + row.Name) {
+		writeNotFound(rw)
+		return database.GetZoneRecordRow{}, false
+	}
+	return row, true
+}
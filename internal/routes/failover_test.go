@@ -0,0 +1,173 @@
+package routes
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/1f349/mjwt"
+	"github.com/1f349/mjwt/auth"
+	"github.com/1f349/verbena/conf"
+	"github.com/1f349/verbena/internal/database"
+	"github.com/go-chi/chi/v5"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+type failoverTestQueries struct {
+	recordTestQueries
+	checks map[int64]database.HealthCheck
+}
+
+func (f *failoverTestQueries) GetRecordHealthCheck(ctx context.Context, arg database.GetRecordHealthCheckParams) (database.HealthCheck, error) {
+	check, ok := f.checks[arg.RecordID]
+	if !ok || check.ZoneID != arg.ZoneID {
+		return database.HealthCheck{}, sql.ErrNoRows
+	}
+	return check, nil
+}
+
+func (f *failoverTestQueries) GetZoneHealthChecks(ctx context.Context, zoneID int64) ([]database.HealthCheck, error) {
+	var checks []database.HealthCheck
+	for _, check := range f.checks {
+		if check.ZoneID == zoneID {
+			checks = append(checks, check)
+		}
+	}
+	return checks, nil
+}
+
+func (f *failoverTestQueries) UpsertHealthCheck(ctx context.Context, arg database.UpsertHealthCheckParams) error {
+	check, ok := f.checks[arg.RecordID]
+	if !ok {
+		check = database.HealthCheck{ID: int64(len(f.checks) + 1), Healthy: true}
+	}
+	check.RecordID = arg.RecordID
+	check.ZoneID = arg.ZoneID
+	check.Backup = arg.Backup
+	check.BackupTtl = arg.BackupTtl
+	check.CheckType = arg.CheckType
+	check.Target = arg.Target
+	check.HttpStatus = arg.HttpStatus
+	check.QueryName = arg.QueryName
+	check.QueryType = arg.QueryType
+	check.CheckInterval = arg.CheckInterval
+	check.Threshold = arg.Threshold
+	f.checks[arg.RecordID] = check
+	return nil
+}
+
+func (f *failoverTestQueries) DeleteHealthCheck(ctx context.Context, arg database.DeleteHealthCheckParams) error {
+	delete(f.checks, arg.RecordID)
+	return nil
+}
+
+func TestAddFailoverRoutes(t *testing.T) {
+	r := chi.NewRouter()
+	issuer, err := mjwt.NewIssuer("hello world", "1", jwt.SigningMethodRS256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	q := &failoverTestQueries{
+		recordTestQueries: recordTestQueries{records: make(map[int64]database.Record)},
+		checks:            make(map[int64]database.HealthCheck),
+	}
+	AddRecordRoutes(r, q, issuer.KeyStore(), conf.NameserverConf{}, nil, &propagationTestTracker{}, &recordTestEvents{})
+	publisher := &poolTestPublisher{}
+	AddFailoverRoutes(r, q, issuer.KeyStore(), publisher, false)
+	for _, i := range []database.InsertRecordFromApiParams{
+		{Name: "www", ZoneID: 3456, Type: "A", PreValue: "192.0.2.1", PreActive: true},
+		{Name: "@", ZoneID: 3456, Type: "MX", PreValue: "10 mail.example.com", PreActive: true},
+	} {
+		_, err = q.InsertRecordFromApi(t.Context(), i)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ps := auth.NewPermStorage()
+	ps.Set("domain:owns=example.com")
+	token, err := issuer.GenerateJwt("1234", "", jwt.ClaimStrings{}, time.Hour, auth.AccessTokenClaims{Perms: ps})
+	if err != nil {
+		t.Fatal(err)
+	}
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+token)
+		r.ServeHTTP(rec, req)
+		return rec
+	}
+
+	t.Run("GET /zones/3456/records/1/failover", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/zones/3456/records/1/failover", nil)
+		r.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+
+		rec = do(http.MethodGet, "/zones/3456/records/1/failover", "")
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+		rec = do(http.MethodGet, "/zones/3456/records/9/failover", "")
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+	})
+
+	t.Run("PUT /zones/3456/records/1/failover", func(t *testing.T) {
+		for _, i := range []struct {
+			path string
+			body string
+			err  string
+		}{
+			{"/zones/3456/records/2/failover", `{"backup":{"preference":10,"target":"backup.example.com"},"check":{"type":"tcp","target":"192.0.2.1:25"}}`, "{\"code\":\"validation_failed\",\"message\":\"Invalid failover: only A, AAAA and CNAME records can fail over\",\"field\":\"type\"}\n"},
+			{"/zones/3456/records/1/failover", `{"backup":{"ip":"2001:db8::1"},"check":{"type":"tcp","target":"192.0.2.1:443"}}`, "{\"code\":\"validation_failed\",\"message\":\"Invalid backup value for type\",\"field\":\"backup\"}\n"},
+			{"/zones/3456/records/1/failover", `{"backup":{"ip":"198.51.100.1"},"backup_ttl":86400,"check":{"type":"tcp","target":"192.0.2.1:443"}}`, "{\"code\":\"validation_failed\",\"message\":\"Invalid backup time to live, expected 'null or 1 \\u003c= ttl \\u003c= 3600 seconds'\",\"field\":\"backup_ttl\"}\n"},
+			{"/zones/3456/records/1/failover", `{"backup":{"ip":"198.51.100.1"},"check":{"type":"icmp","target":"192.0.2.1"}}`, "{\"code\":\"validation_failed\",\"message\":\"Invalid health check: unknown check type \\\"icmp\\\", expected tcp, http or dns\",\"field\":\"check\"}\n"},
+			{"/zones/3456/records/1/failover", `{"backup":{"ip":"198.51.100.1"},"check":{"type":"http","target":"http://169.254.169.254/latest/meta-data"}}`, "{\"code\":\"validation_failed\",\"message\":\"Invalid health check: http target must be a public address\",\"field\":\"check\"}\n"},
+		} {
+			rec := do(http.MethodPut, i.path, i.body)
+			assert.Equal(t, http.StatusBadRequest, rec.Code)
+			assert.Equal(t, i.err, rec.Body.String())
+		}
+
+		rec := do(http.MethodPut, "/zones/3456/records/1/failover", `{"backup":{"ip":"198.51.100.1"},"check":{"type":"http","target":"https://192.0.2.1/health"}}`)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "{\"record_id\":1,\"backup\":{\"ip\":\"198.51.100.1\"},\"backup_ttl\":60,\"check\":{\"type\":\"http\",\"target\":\"https://192.0.2.1/health\",\"http_status\":200,\"interval\":30,\"threshold\":3},\"status\":{\"healthy\":true}}\n", rec.Body.String())
+		assert.Equal(t, "198.51.100.1", q.checks[1].Backup)
+
+		// the primary value is still served so nothing is published
+		assert.Empty(t, publisher.published)
+	})
+
+	t.Run("GET /zones/3456/records/1 health", func(t *testing.T) {
+		check := q.checks[1]
+		check.Healthy = false
+		check.CheckedAt = 1760000000
+		check.LastError = "unexpected status: 503 Service Unavailable"
+		q.checks[1] = check
+
+		rec := do(http.MethodGet, "/zones/3456/records/1", "")
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "{\"id\":1,\"name\":\"www\",\"zone_id\":3456,\"ttl\":null,\"type\":\"A\",\"value\":{\"ip\":\"192.0.2.1\"},\"active\":true,\"health\":{\"healthy\":false,\"checked_at\":\"2025-10-09T08:53:20Z\",\"last_error\":\"unexpected status: 503 Service Unavailable\"}}\n", rec.Body.String())
+	})
+
+	t.Run("PUT /zones/3456/records/1/failover unhealthy", func(t *testing.T) {
+		// the backup value is being served so changing it publishes the zone
+		rec := do(http.MethodPut, "/zones/3456/records/1/failover", `{"backup":{"ip":"198.51.100.2"},"check":{"type":"http","target":"https://192.0.2.1/health"}}`)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "198.51.100.2", q.checks[1].Backup)
+		assert.Equal(t, []string{"example.com"}, publisher.published)
+	})
+
+	t.Run("DELETE /zones/3456/records/1/failover", func(t *testing.T) {
+		rec := do(http.MethodDelete, "/zones/3456/records/1/failover", "")
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Empty(t, q.checks)
+		assert.Equal(t, []string{"example.com", "example.com"}, publisher.published)
+
+		rec = do(http.MethodGet, "/zones/3456/records/1/failover", "")
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+	})
+}
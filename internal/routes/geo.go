@@ -0,0 +1,334 @@
+package routes
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+
+	"github.com/1f349/mjwt"
+	"github.com/1f349/mjwt/auth"
+	"github.com/1f349/verbena/conf"
+	"github.com/1f349/verbena/internal/database"
+	"github.com/1f349/verbena/internal/geoip"
+	"github.com/1f349/verbena/internal/utils"
+	"github.com/1f349/verbena/internal/webhook"
+	"github.com/1f349/verbena/logger"
+	"github.com/1f349/verbena/rest"
+	"github.com/go-chi/chi/v5"
+)
+
+type geoQueries interface {
+	GetZone(ctx context.Context, zoneId int64) (database.Zone, error)
+	GetZoneRecords(ctx context.Context, zoneId int64) ([]database.GetZoneRecordsRow, error)
+	UseRecordTx(ctx context.Context, cb func(tx database.RecordTx) error) error
+}
+
+// geoValue is a record value along with the views it is published in
+type geoValue struct {
+	record rest.RRsetRecord
+	views  []string
+}
+
+func AddGeoRoutes(r chi.Router, db geoQueries, keystore *mjwt.KeyStore, views conf.ViewsConf, events eventFirer) {
+	r.Route("/zones/{zone_id:[0-9]+}/geo", func(r chi.Router) {
+		// List the record sets with records targeting a geo view
+		r.Get("/", validateAuthToken(keystore, func(rw http.ResponseWriter, req *http.Request, b mjwt.BaseTypeClaims[auth.AccessTokenClaims]) {
+			zoneInfo, ok := getOwnedZone(rw, req, db, b)
+			if !ok {
+				return
+			}
+
+			records, ok := getRestZoneRecords(rw, req, db, zoneInfo.ID)
+			if !ok {
+				return
+			}
+
+			geoViews := views.GeoRegions()
+			var sets []rest.GeoRecordSet
+			for _, record := range records {
+				if !slices.ContainsFunc(record.Views, func(view string) bool { return len(geoViews[view]) > 0 }) {
+					continue
+				}
+				if slices.ContainsFunc(sets, func(set rest.GeoRecordSet) bool {
+					return set.Type == record.Type && sameRecordName(set.Name, record.Name)
+				}) {
+					continue
+				}
+				sets = append(sets, toRestGeoRecordSet(record.Name, record.Type, views, rrsetRecords(records, record.Name, record.Type)))
+			}
+			if sets == nil {
+				sets = []rest.GeoRecordSet{}
+			}
+			slices.SortFunc(sets, func(a, b rest.GeoRecordSet) int {
+				return cmp.Or(cmp.Compare(a.Name, b.Name), cmp.Compare(a.Type, b.Type))
+			})
+			json.NewEncoder(rw).Encode(sets)
+		}))
+
+		r.Route("/{name}/{type}", func(r chi.Router) {
+			r.Get("/", validateAuthToken(keystore, func(rw http.ResponseWriter, req *http.Request, b mjwt.BaseTypeClaims[auth.AccessTokenClaims]) {
+				zoneInfo, ok := getOwnedZone(rw, req, db, b)
+				if !ok {
+					return
+				}
+
+				name, recordType, ok := getRRsetKey(rw, req, zoneInfo.Name)
+				if !ok {
+					return
+				}
+
+				records, ok := getRestZoneRecords(rw, req, db, zoneInfo.ID)
+				if !ok {
+					return
+				}
+
+				set := rrsetRecords(records, name, recordType)
+				if len(set) == 0 {
+					writeNotFound(rw)
+					return
+				}
+
+				json.NewEncoder(rw).Encode(toRestGeoRecordSet(name, recordType, views, set))
+			}))
+
+			// Replace all records of a name and type with an answer per view
+			r.Put("/", validateAuthToken(keystore, func(rw http.ResponseWriter, req *http.Request, b mjwt.BaseTypeClaims[auth.AccessTokenClaims]) {
+				var putSet rest.PutGeoRecordSet
+				err := json.NewDecoder(req.Body).Decode(&putSet)
+				if err != nil {
+					writeError(rw, http.StatusBadRequest, "Invalid request body")
+					return
+				}
+
+				if putSet.Ttl.Valid && putSet.Ttl.Int32 > ttlMaxOneWeek {
+					writeFieldError(rw, "ttl", fmt.Sprintf("Invalid time to live, expected 'null or ttl <= %d seconds'", ttlMaxOneWeek))
+					return
+				}
+
+				zoneInfo, ok := getOwnedZone(rw, req, db, b)
+				if !ok {
+					return
+				}
+
+				name, recordType, ok := getRRsetKey(rw, req, zoneInfo.Name)
+				if !ok {
+					return
+				}
+
+				values, err := geoValues(recordType, views, putSet)
+				if err != nil {
+					writeError(rw, http.StatusBadRequest, "Invalid geo record set: "+err.Error())
+					return
+				}
+
+				putRRset := rest.PutRRset{Ttl: putSet.Ttl, Records: make([]rest.RRsetRecord, len(values))}
+				for i, v := range values {
+					putRRset.Records[i] = v.record
+				}
+
+				var out []rest.Record
+				var changes []recordChange
+				err = db.UseRecordTx(req.Context(), func(tx database.RecordTx) error {
+					before, err := zoneRecordsInTx(req.Context(), tx, zoneInfo.ID)
+					if err != nil {
+						return err
+					}
+					existing := rrsetRecords(before, name, recordType)
+					out, changes, err = replaceRRset(req.Context(), tx, zoneInfo, name, recordType, putRRset, existing)
+					if err != nil {
+						return err
+					}
+					changes, err = setGeoViews(req.Context(), tx, zoneInfo.ID, out, values, existing, changes)
+					if err != nil {
+						return err
+					}
+					return lintTx(req.Context(), tx, zoneInfo, before)
+				})
+				var lintErr lintFailure
+				if errors.As(err, &lintErr) {
+					writeLintFailure(rw, lintErr)
+					return
+				}
+				if err != nil {
+					logger.Logger.Debug("Failed to replace geo record set", "err", err)
+					writeError(rw, http.StatusInternalServerError, "Database error occurred")
+					return
+				}
+
+				for _, change := range changes {
+					events.Fire(zoneInfo.ID, zoneInfo.Name, change.event, change.record)
+				}
+
+				json.NewEncoder(rw).Encode(toRestGeoRecordSet(name, recordType, views, out))
+			}))
+		})
+	})
+}
+
+// geoValues checks the answers and merges them into one entry per value with
+// the views the value is published in. A value in every view is published
+// without a view list. The active flag of a value is taken from the first
+// answer containing it.
+func geoValues(recordType string, views conf.ViewsConf, putSet rest.PutGeoRecordSet) ([]geoValue, error) {
+	if len(putSet.Answers) == 0 {
+		return nil, errors.New("at least one answer is required")
+	}
+
+	// the default answer is published in every view without its own answer
+	remaining := views.Names()
+	hasDefault := false
+	for i, answer := range putSet.Answers {
+		if answer.View == "" {
+			if hasDefault {
+				return nil, fmt.Errorf("duplicate default answer %d", i)
+			}
+			hasDefault = true
+			continue
+		}
+		names, err := views.Normalize([]string{answer.View})
+		if err != nil {
+			return nil, fmt.Errorf("answer %d: %w", i, err)
+		}
+		idx := slices.Index(remaining, names[0])
+		if idx == -1 {
+			return nil, fmt.Errorf("duplicate answer %d for view %s", i, names[0])
+		}
+		remaining = slices.Delete(remaining, idx, idx+1)
+		putSet.Answers[i].View = names[0]
+	}
+	if hasDefault && len(remaining) == 0 {
+		// the values would otherwise have no views and be published in every view
+		return nil, fmt.Errorf("default answer is unused, every view has its own answer")
+	}
+
+	var values []geoValue
+	for i, answer := range putSet.Answers {
+		for j := range answer.Records {
+			v, err := answer.Records[j].Value.ToASCII(recordType)
+			if err != nil {
+				return nil, fmt.Errorf("answer %d: invalid value for record %d: %w", i, j, err)
+			}
+			answer.Records[j].Value = v
+		}
+		err := validateRRset(recordType, rest.PutRRset{Records: answer.Records})
+		if err != nil {
+			return nil, fmt.Errorf("answer %d: %w", i, err)
+		}
+
+		answerViews := []string{answer.View}
+		if answer.View == "" {
+			answerViews = remaining
+		}
+		for _, record := range answer.Records {
+			value := record.Value.ToValueString(recordType)
+			idx := slices.IndexFunc(values, func(v geoValue) bool {
+				return v.record.Value.ToValueString(recordType) == value
+			})
+			if idx == -1 {
+				values = append(values, geoValue{record: rest.RRsetRecord{Value: record.Value, Active: record.Active}})
+				idx = len(values) - 1
+			}
+			values[idx].views = append(values[idx].views, answerViews...)
+		}
+	}
+
+	for i := range values {
+		slices.Sort(values[i].views)
+		values[i].views = slices.Compact(values[i].views)
+		// a value published in every view needs no view list
+		if len(values[i].views) == len(views) {
+			values[i].views = nil
+		}
+	}
+	return values, nil
+}
+
+// setGeoViews stages the views of the replaced records, records which only
+// changed their views are reported as updated
+func setGeoViews(ctx context.Context, tx database.RecordTx, zoneId int64, out []rest.Record, values []geoValue, existing []rest.Record, changes []recordChange) ([]recordChange, error) {
+	for i := range out {
+		want := values[i].views
+		out[i].Views = want
+
+		var previous []string
+		for _, e := range existing {
+			if e.ID == out[i].ID {
+				previous = e.Views
+			}
+		}
+		if slices.Equal(previous, want) {
+			continue
+		}
+		err := tx.SetRecordViews(ctx, database.SetRecordViewsParams{
+			PreViews: utils.JoinViews(want),
+			ID:       out[i].ID,
+			ZoneID:   zoneId,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		changed := false
+		for j := range changes {
+			if changes[j].record.ID == out[i].ID && changes[j].event != webhook.RecordDeleted {
+				changes[j].record.Views = want
+				changed = true
+			}
+		}
+		if !changed {
+			changes = append(changes, recordChange{webhook.RecordUpdated, out[i]})
+		}
+	}
+	return changes, nil
+}
+
+func toRestGeoRecordSet(name, recordType string, views conf.ViewsConf, records []rest.Record) rest.GeoRecordSet {
+	set := rest.GeoRecordSet{
+		Name:        name,
+		NameUnicode: utils.UnicodeNameIfDifferent(name),
+		Type:        recordType,
+		Answers:     []rest.GeoAnswer{},
+	}
+	if len(records) > 0 {
+		set.Ttl = records[0].Ttl
+	}
+
+	// without views every record is in the default answer
+	if len(views) == 0 {
+		set.Answers = append(set.Answers, geoAnswer("", nil, records))
+		return set
+	}
+	for _, view := range views {
+		var regions []string
+		for _, i := range view.Geo {
+			if region, err := geoip.ParseRegion(i); err == nil {
+				regions = append(regions, region.String())
+			}
+		}
+		set.Answers = append(set.Answers, geoAnswer(view.Name, regions, records))
+	}
+	return set
+}
+
+func geoAnswer(view string, regions []string, records []rest.Record) rest.GeoAnswer {
+	answer := rest.GeoAnswer{
+		View:    view,
+		Regions: regions,
+		Records: []rest.RRsetRecord{},
+	}
+	for _, r := range records {
+		if view != "" && !utils.InView(r.Views, view) {
+			continue
+		}
+		answer.Records = append(answer.Records, rest.RRsetRecord{
+			ID:     r.ID,
+			Value:  r.Value,
+			Active: r.Active,
+		})
+	}
+	return answer
+}
@@ -0,0 +1,115 @@
+package routes
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/1f349/mjwt"
+	"github.com/1f349/mjwt/auth"
+	"github.com/1f349/verbena/conf"
+	"github.com/1f349/verbena/internal/database"
+	"github.com/1f349/verbena/internal/webhook"
+	"github.com/go-chi/chi/v5"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAddGeoRoutes(t *testing.T) {
+	r := chi.NewRouter()
+	issuer, err := mjwt.NewIssuer("hello world", "1", jwt.SigningMethodRS256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	q := &rrsetTestQueries{recordTestQueries: recordTestQueries{
+		records: make(map[int64]database.Record),
+	}}
+	events := &recordTestEvents{}
+	views := conf.ViewsConf{
+		{Name: "eu", Geo: []string{"continent:eu"}},
+		{Name: "us", Geo: []string{"country:US"}},
+		{Name: "external", MatchClients: []string{"any"}},
+	}
+	AddGeoRoutes(r, q, issuer.KeyStore(), views, events)
+	_, err = q.InsertRecordFromApi(t.Context(), database.InsertRecordFromApiParams{Name: "www", ZoneID: 3456, Type: "A", PreValue: "192.0.2.1", PreActive: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ps := auth.NewPermStorage()
+	ps.Set("domain:owns=example.com")
+	token, err := issuer.GenerateJwt("1234", "", jwt.ClaimStrings{}, time.Hour, auth.AccessTokenClaims{Perms: ps})
+	if err != nil {
+		t.Fatal(err)
+	}
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+token)
+		r.ServeHTTP(rec, req)
+		return rec
+	}
+
+	t.Run("GET /zones/3456/geo", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/zones/3456/geo", nil)
+		r.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+
+		// records without views are not geo targeted
+		rec = do(http.MethodGet, "/zones/3456/geo", "")
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "[]\n", rec.Body.String())
+
+		rec = do(http.MethodGet, "/zones/3456/geo/www/A", "")
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "{\"name\":\"www\",\"type\":\"A\",\"ttl\":null,\"answers\":[{\"view\":\"eu\",\"regions\":[\"continent:EU\"],\"records\":[{\"id\":1,\"value\":{\"ip\":\"192.0.2.1\"},\"active\":true}]},{\"view\":\"us\",\"regions\":[\"country:US\"],\"records\":[{\"id\":1,\"value\":{\"ip\":\"192.0.2.1\"},\"active\":true}]},{\"view\":\"external\",\"records\":[{\"id\":1,\"value\":{\"ip\":\"192.0.2.1\"},\"active\":true}]}]}\n", rec.Body.String())
+
+		rec = do(http.MethodGet, "/zones/3456/geo/api/A", "")
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+	})
+
+	t.Run("PUT /zones/3456/geo/www/A", func(t *testing.T) {
+		for _, i := range []struct {
+			body string
+			err  string
+		}{
+			{`{"answers":[]}`, "{\"code\":\"validation_failed\",\"message\":\"Invalid geo record set: at least one answer is required\"}\n"},
+			{`{"answers":[{"view":"asia","records":[{"value":{"ip":"192.0.2.1"}}]}]}`, "{\"code\":\"validation_failed\",\"message\":\"Invalid geo record set: answer 0: unknown view \\\"asia\\\"\"}\n"},
+			{`{"answers":[{"view":"eu","records":[{"value":{"ip":"192.0.2.1"}}]},{"view":"EU","records":[{"value":{"ip":"192.0.2.2"}}]}]}`, "{\"code\":\"validation_failed\",\"message\":\"Invalid geo record set: duplicate answer 1 for view eu\"}\n"},
+			{`{"answers":[{"records":[{"value":{"ip":"192.0.2.1"}}]},{"records":[{"value":{"ip":"192.0.2.2"}}]}]}`, "{\"code\":\"validation_failed\",\"message\":\"Invalid geo record set: duplicate default answer 1\"}\n"},
+			// every view has its own answer so the default answer is never used
+			{`{"answers":[{"view":"eu","records":[{"value":{"ip":"192.0.2.1"}}]},{"view":"us","records":[{"value":{"ip":"192.0.2.2"}}]},{"view":"external","records":[{"value":{"ip":"192.0.2.3"}}]},{"records":[{"value":{"ip":"192.0.2.4"}}]}]}`, "{\"code\":\"validation_failed\",\"message\":\"Invalid geo record set: default answer is unused, every view has its own answer\"}\n"},
+			{`{"answers":[{"view":"eu","records":[{"value":{"ip":"2001:db8::1"}}]}]}`, "{\"code\":\"validation_failed\",\"message\":\"Invalid geo record set: answer 0: invalid value for record 0\"}\n"},
+		} {
+			rec := do(http.MethodPut, "/zones/3456/geo/www/A", i.body)
+			assert.Equal(t, http.StatusBadRequest, rec.Code)
+			assert.Equal(t, i.err, rec.Body.String())
+		}
+		assert.Empty(t, events.events)
+
+		rec := do(http.MethodPut, "/zones/3456/geo/www/A", `{"ttl":300,"answers":[{"view":"eu","records":[{"value":{"ip":"192.0.2.10"},"active":true}]},{"records":[{"value":{"ip":"192.0.2.1"},"active":true}]}]}`)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "{\"name\":\"www\",\"type\":\"A\",\"ttl\":300,\"answers\":[{\"view\":\"eu\",\"regions\":[\"continent:EU\"],\"records\":[{\"id\":2,\"value\":{\"ip\":\"192.0.2.10\"},\"active\":true}]},{\"view\":\"us\",\"regions\":[\"country:US\"],\"records\":[{\"id\":1,\"value\":{\"ip\":\"192.0.2.1\"},\"active\":true}]},{\"view\":\"external\",\"records\":[{\"id\":1,\"value\":{\"ip\":\"192.0.2.1\"},\"active\":true}]}]}\n", rec.Body.String())
+		assert.Equal(t, "external,us", q.records[1].PreViews)
+		assert.Equal(t, "eu", q.records[2].PreViews)
+		assert.Equal(t, []webhook.Event{webhook.RecordCreated, webhook.RecordUpdated}, events.events)
+
+		// a value answered in every view is stored without views
+		rec = do(http.MethodPut, "/zones/3456/geo/www/A", `{"ttl":300,"answers":[{"view":"eu","records":[{"value":{"ip":"192.0.2.1"},"active":true}]},{"records":[{"value":{"ip":"192.0.2.1"},"active":true}]}]}`)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "", q.records[1].PreViews)
+		assert.True(t, q.records[2].PreDelete)
+	})
+
+	t.Run("GET /zones/3456/geo after PUT", func(t *testing.T) {
+		rec := do(http.MethodPut, "/zones/3456/geo/@/A", `{"answers":[{"view":"us","records":[{"value":{"ip":"203.0.113.1"},"active":true}]}]}`)
+		assert.Equal(t, http.StatusOK, rec.Code)
+
+		rec = do(http.MethodGet, "/zones/3456/geo", "")
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "[{\"name\":\"@\",\"type\":\"A\",\"ttl\":null,\"answers\":[{\"view\":\"eu\",\"regions\":[\"continent:EU\"],\"records\":[]},{\"view\":\"us\",\"regions\":[\"country:US\"],\"records\":[{\"id\":3,\"value\":{\"ip\":\"203.0.113.1\"},\"active\":true}]},{\"view\":\"external\",\"records\":[]}]}]\n", rec.Body.String())
+	})
+}
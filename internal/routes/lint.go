@@ -0,0 +1,107 @@
+package routes
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/1f349/mjwt"
+	"github.com/1f349/mjwt/auth"
+	"github.com/1f349/verbena/internal/database"
+	"github.com/1f349/verbena/internal/lint"
+	"github.com/1f349/verbena/rest"
+	"github.com/go-chi/chi/v5"
+)
+
+type lintQueries interface {
+	GetZone(ctx context.Context, zoneId int64) (database.Zone, error)
+	GetZoneRecords(ctx context.Context, zoneId int64) ([]database.GetZoneRecordsRow, error)
+}
+
+// lintFailure is returned from a transaction when the changes add lint errors
+type lintFailure struct {
+	problems []rest.LintProblem
+}
+
+func (l lintFailure) Error() string {
+	return "Zone lint failed: " + lint.Message(l.problems)
+}
+
+func AddLintRoutes(r chi.Router, db lintQueries, keystore *mjwt.KeyStore) {
+	r.Get("/zones/{zone_id:[0-9]+}/lint", validateAuthToken(keystore, func(rw http.ResponseWriter, req *http.Request, b mjwt.BaseTypeClaims[auth.AccessTokenClaims]) {
+		zoneInfo, ok := getOwnedZone(rw, req, db, b)
+		if !ok {
+			return
+		}
+
+		records, ok := getRestZoneRecords(rw, req, db, zoneInfo.ID)
+		if !ok {
+			return
+		}
+
+		problems := lint.Check(zoneInfo.Name, records)
+		if problems == nil {
+			problems = []rest.LintProblem{}
+		}
+		json.NewEncoder(rw).Encode(problems)
+	}))
+}
+
+// lintRecordChange checks the created or updated record does not add lint
+// errors to the zone, an error response is written when false is returned
+func lintRecordChange(rw http.ResponseWriter, req *http.Request, db zoneRecordsQueries, zoneId int64, zoneName string, changed rest.Record) bool {
+	before, ok := getRestZoneRecords(rw, req, db, zoneId)
+	if !ok {
+		return false
+	}
+
+	after := make([]rest.Record, 0, len(before)+1)
+	replaced := false
+	for _, r := range before {
+		if changed.ID != 0 && r.ID == changed.ID {
+			after = append(after, changed)
+			replaced = true
+			continue
+		}
+		after = append(after, r)
+	}
+	if !replaced {
+		after = append(after, changed)
+	}
+
+	problems := lint.NewErrors(lint.Check(zoneName, before), lint.Check(zoneName, after))
+	if len(problems) > 0 {
+		writeLintFailure(rw, lintFailure{problems})
+		return false
+	}
+	return true
+}
+
+// lintTx compares the records staged in the transaction with the records from
+// before the changes
+func lintTx(ctx context.Context, tx database.RecordTx, zoneInfo database.Zone, before []rest.Record) error {
+	after, err := zoneRecordsInTx(ctx, tx, zoneInfo.ID)
+	if err != nil {
+		return err
+	}
+	problems := lint.NewErrors(lint.Check(zoneInfo.Name, before), lint.Check(zoneInfo.Name, after))
+	if len(problems) > 0 {
+		return lintFailure{problems}
+	}
+	return nil
+}
+
+func zoneRecordsInTx(ctx context.Context, tx zoneRecordsQueries, zoneId int64) ([]rest.Record, error) {
+	rows, err := tx.GetZoneRecords(ctx, zoneId)
+	if err != nil {
+		return nil, err
+	}
+	records := make([]rest.Record, 0, len(rows))
+	for _, row := range rows {
+		if row.Record.PreDelete {
+			continue
+		}
+		records = appendRecord(records, row.Record)
+	}
+	return records, nil
+}
@@ -0,0 +1,101 @@
+package routes
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/1f349/mjwt"
+	"github.com/1f349/mjwt/auth"
+	"github.com/1f349/verbena/conf"
+	"github.com/1f349/verbena/internal/database"
+	"github.com/go-chi/chi/v5"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAddLintRoutes(t *testing.T) {
+	r := chi.NewRouter()
+	issuer, err := mjwt.NewIssuer("hello world", "1", jwt.SigningMethodRS256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	q := &rrsetTestQueries{recordTestQueries: recordTestQueries{
+		records: make(map[int64]database.Record),
+	}}
+	events := &recordTestEvents{}
+	AddLintRoutes(r, q, issuer.KeyStore())
+	AddRecordRoutes(r, q, issuer.KeyStore(), conf.NameserverConf{}, nil, &propagationTestTracker{}, events)
+	AddRRsetRoutes(r, q, issuer.KeyStore(), events)
+	for _, i := range []database.InsertRecordFromApiParams{
+		{Name: "www", ZoneID: 3456, Type: "A", PreValue: "192.0.2.1", PreActive: true},
+		{Name: "docs", ZoneID: 3456, Type: "CNAME", PreValue: "missing.example.com", PreActive: true},
+	} {
+		_, err = q.InsertRecordFromApi(t.Context(), i)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ps := auth.NewPermStorage()
+	ps.Set("domain:owns=example.org")
+	wrongToken, err := issuer.GenerateJwt("1234", "", jwt.ClaimStrings{}, time.Hour, auth.AccessTokenClaims{Perms: ps})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ps = auth.NewPermStorage()
+	ps.Set("domain:owns=example.com")
+	token, err := issuer.GenerateJwt("1234", "", jwt.ClaimStrings{}, time.Hour, auth.AccessTokenClaims{Perms: ps})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("GET /zones/3456/lint", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/zones/3456/lint", nil)
+		r.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+
+		rec = httptest.NewRecorder()
+		req = httptest.NewRequest(http.MethodGet, "/zones/3456/lint", nil)
+		req.Header.Set("Authorization", "Bearer "+wrongToken)
+		r.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+
+		rec = httptest.NewRecorder()
+		req = httptest.NewRequest(http.MethodGet, "/zones/3456/lint", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		r.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "[{\"severity\":\"warning\",\"name\":\"docs.example.com\",\"type\":\"CNAME\",\"message\":\"target missing.example.com does not exist in the zone\"}]\n", rec.Body.String())
+	})
+
+	t.Run("POST /zones/3456/records", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/zones/3456/records", strings.NewReader(`{"name":"www","type":"CNAME","value":{"target":"example.net"}}`))
+		req.Header.Set("Authorization", "Bearer "+token)
+		r.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusConflict, rec.Code)
+		assert.Equal(t, "{\"code\":\"lint_failed\",\"message\":\"Zone lint failed: www.example.com CNAME: CNAME conflicts with the A record\",\"lint\":[{\"severity\":\"error\",\"name\":\"www.example.com\",\"type\":\"CNAME\",\"message\":\"CNAME conflicts with the A record\"}]}\n", rec.Body.String())
+		assert.Len(t, q.records, 2)
+
+		// warnings do not block changes
+		rec = httptest.NewRecorder()
+		req = httptest.NewRequest(http.MethodPost, "/zones/3456/records", strings.NewReader(`{"name":"blog","type":"CNAME","value":{"target":"gone.example.com"}}`))
+		req.Header.Set("Authorization", "Bearer "+token)
+		r.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusOK, rec.Code)
+	})
+
+	t.Run("PUT /zones/3456/rrsets/docs/TXT", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPut, "/zones/3456/rrsets/docs/TXT", strings.NewReader(`{"ttl":null,"records":[{"value":{"text":"hello"},"active":true}]}`))
+		req.Header.Set("Authorization", "Bearer "+token)
+		r.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusConflict, rec.Code)
+		assert.Equal(t, "{\"code\":\"lint_failed\",\"message\":\"Zone lint failed: docs.example.com CNAME: CNAME conflicts with the TXT record\",\"lint\":[{\"severity\":\"error\",\"name\":\"docs.example.com\",\"type\":\"CNAME\",\"message\":\"CNAME conflicts with the TXT record\"}]}\n", rec.Body.String())
+		assert.Len(t, q.records, 3)
+	})
+}
@@ -0,0 +1,419 @@
+package routes
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/1f349/mjwt"
+	"github.com/1f349/mjwt/auth"
+	"github.com/1f349/verbena/internal/database"
+	"github.com/1f349/verbena/internal/mailauth"
+	"github.com/1f349/verbena/internal/utils"
+	"github.com/1f349/verbena/internal/webhook"
+	"github.com/1f349/verbena/logger"
+	"github.com/1f349/verbena/rest"
+	"github.com/go-chi/chi/v5"
+	"github.com/gobuffalo/nulls"
+)
+
+type mailAuthQueries interface {
+	GetZone(ctx context.Context, zoneId int64) (database.Zone, error)
+	GetZoneRecords(ctx context.Context, zoneId int64) ([]database.GetZoneRecordsRow, error)
+	UseRecordTx(ctx context.Context, cb func(tx database.RecordTx) error) error
+}
+
+// mailAuthKind describes how one kind of mail authentication record is stored
+// in a TXT record. Other TXT records at the same name are left alone.
+type mailAuthKind struct {
+	label string
+	// owner returns the name of the TXT record for the mail domain
+	owner   func(name, selector string) string
+	matches func(text string) bool
+	// build decodes and validates the request body into the record text
+	build func(body io.Reader) (nulls.Int32, string, error)
+	// check validates the record text against the other zone records
+	check func(zoneName, owner, text string, records []rest.Record) error
+	// response converts the stored record into the response body
+	response func(zoneName, name, selector string, records []rest.Record, record rest.Record) (any, error)
+}
+
+// mailAuthError is returned from the transaction when the record is invalid
+type mailAuthError struct {
+	err error
+}
+
+func (e mailAuthError) Error() string {
+	return e.err.Error()
+}
+
+var spfKind = mailAuthKind{
+	label: "SPF",
+	owner: func(name, _ string) string {
+		return name
+	},
+	matches: mailauth.IsSPF,
+	build: func(body io.Reader) (nulls.Int32, string, error) {
+		var putSPF rest.PutSPF
+		err := json.NewDecoder(body).Decode(&putSPF)
+		if err != nil {
+			return nulls.Int32{}, "", errInvalidBody
+		}
+		text, err := mailauth.BuildSPF(putSPF.Mechanisms)
+		return putSPF.Ttl, text, err
+	},
+	check: func(zoneName, owner, text string, records []rest.Record) error {
+		terms, err := mailauth.ParseSPF(text)
+		if err != nil {
+			return err
+		}
+		lookups, err := mailauth.CountSPFLookups(zoneName, owner, terms, records)
+		if err != nil {
+			return err
+		}
+		return mailauth.ValidateSPFLookups(lookups)
+	},
+	response: func(zoneName, name, _ string, records []rest.Record, record rest.Record) (any, error) {
+		terms, err := mailauth.ParseSPF(record.Value.Text)
+		if err != nil {
+			return nil, err
+		}
+		lookups, err := mailauth.CountSPFLookups(zoneName, name, terms, records)
+		if err != nil {
+			return nil, err
+		}
+		return rest.SPF{
+			Name:       name,
+			Ttl:        record.Ttl,
+			Mechanisms: terms,
+			Lookups:    lookups,
+			Text:       record.Value.Text,
+		}, nil
+	},
+}
+
+var dkimKind = mailAuthKind{
+	label:   "DKIM",
+	owner:   mailauth.DKIMName,
+	matches: mailauth.IsDKIM,
+	build: func(body io.Reader) (nulls.Int32, string, error) {
+		var putDKIM rest.PutDKIM
+		err := json.NewDecoder(body).Decode(&putDKIM)
+		if err != nil {
+			return nulls.Int32{}, "", errInvalidBody
+		}
+		text, _, err := mailauth.BuildDKIM(putDKIM.DKIMKey)
+		return putDKIM.Ttl, text, err
+	},
+	response: func(_, name, selector string, _ []rest.Record, record rest.Record) (any, error) {
+		key, bits, err := mailauth.ParseDKIM(record.Value.Text)
+		if err != nil {
+			return nil, err
+		}
+		return rest.DKIM{
+			Name:     name,
+			Selector: selector,
+			Ttl:      record.Ttl,
+			DKIMKey:  key,
+			KeyBits:  bits,
+			Text:     record.Value.Text,
+		}, nil
+	},
+}
+
+var dmarcKind = mailAuthKind{
+	label: "DMARC",
+	owner: func(name, _ string) string {
+		return mailauth.DMARCName(name)
+	},
+	matches: mailauth.IsDMARC,
+	build: func(body io.Reader) (nulls.Int32, string, error) {
+		var putDMARC rest.PutDMARC
+		err := json.NewDecoder(body).Decode(&putDMARC)
+		if err != nil {
+			return nulls.Int32{}, "", errInvalidBody
+		}
+		text, err := mailauth.BuildDMARC(putDMARC.DMARCPolicy)
+		return putDMARC.Ttl, text, err
+	},
+	response: func(_, name, _ string, _ []rest.Record, record rest.Record) (any, error) {
+		policy, err := mailauth.ParseDMARC(record.Value.Text)
+		if err != nil {
+			return nil, err
+		}
+		return rest.DMARC{
+			Name:        name,
+			Ttl:         record.Ttl,
+			DMARCPolicy: policy,
+			Text:        record.Value.Text,
+		}, nil
+	},
+}
+
+var errInvalidBody = errors.New("invalid request body")
+
+func AddMailAuthRoutes(r chi.Router, db mailAuthQueries, keystore *mjwt.KeyStore, events eventFirer) {
+	r.Route("/zones/{zone_id:[0-9]+}/mail/{name}", func(r chi.Router) {
+		addMailAuthRoutes(r, "/spf", db, keystore, events, spfKind)
+		addMailAuthRoutes(r, "/dkim/{selector}", db, keystore, events, dkimKind)
+		addMailAuthRoutes(r, "/dmarc", db, keystore, events, dmarcKind)
+	})
+}
+
+func addMailAuthRoutes(r chi.Router, pattern string, db mailAuthQueries, keystore *mjwt.KeyStore, events eventFirer, kind mailAuthKind) {
+	// Get the record of the mail domain
+	r.Get(pattern, validateAuthToken(keystore, func(rw http.ResponseWriter, req *http.Request, b mjwt.BaseTypeClaims[auth.AccessTokenClaims]) {
+		zoneInfo, ok := getOwnedZone(rw, req, db, b)
+		if !ok {
+			return
+		}
+
+		name, selector, owner, ok := getMailAuthOwner(rw, req, zoneInfo.Name, kind)
+		if !ok {
+			return
+		}
+
+		records, ok := getRestZoneRecords(rw, req, db, zoneInfo.ID)
+		if !ok {
+			return
+		}
+
+		existing := mailAuthRecords(records, owner, kind)
+		if len(existing) == 0 {
+			writeNotFound(rw)
+			return
+		}
+
+		out, err := kind.response(zoneInfo.Name, name, selector, records, existing[0])
+		if err != nil {
+			writeError(rw, http.StatusConflict, fmt.Sprintf("Existing %s record is invalid: %s", kind.label, err))
+			return
+		}
+		json.NewEncoder(rw).Encode(out)
+	}))
+
+	// Create or replace the record of the mail domain
+	r.Put(pattern, validateAuthToken(keystore, func(rw http.ResponseWriter, req *http.Request, b mjwt.BaseTypeClaims[auth.AccessTokenClaims]) {
+		ttl, text, err := kind.build(req.Body)
+		if errors.Is(err, errInvalidBody) {
+			writeError(rw, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+		if err != nil {
+			writeError(rw, http.StatusBadRequest, fmt.Sprintf("Invalid %s: %s", kind.label, err))
+			return
+		}
+
+		if ttl.Valid && ttl.Int32 > ttlMaxOneWeek {
+			writeFieldError(rw, "ttl", fmt.Sprintf("Invalid time to live, expected 'null or ttl <= %d seconds'", ttlMaxOneWeek))
+			return
+		}
+
+		zoneInfo, ok := getOwnedZone(rw, req, db, b)
+		if !ok {
+			return
+		}
+
+		name, selector, owner, ok := getMailAuthOwner(rw, req, zoneInfo.Name, kind)
+		if !ok {
+			return
+		}
+
+		var out any
+		var changes []recordChange
+		err = db.UseRecordTx(req.Context(), func(tx database.RecordTx) error {
+			before, err := zoneRecordsInTx(req.Context(), tx, zoneInfo.ID)
+			if err != nil {
+				return err
+			}
+			if kind.check != nil {
+				err = kind.check(zoneInfo.Name, owner, text, before)
+				if err != nil {
+					return mailAuthError{err}
+				}
+			}
+
+			var record rest.Record
+			record, changes, err = replaceMailAuthRecord(req.Context(), tx, zoneInfo, owner, ttl, text, mailAuthRecords(before, owner, kind))
+			if err != nil {
+				return err
+			}
+			err = lintTx(req.Context(), tx, zoneInfo, before)
+			if err != nil {
+				return err
+			}
+
+			after, err := zoneRecordsInTx(req.Context(), tx, zoneInfo.ID)
+			if err != nil {
+				return err
+			}
+			out, err = kind.response(zoneInfo.Name, name, selector, after, record)
+			return err
+		})
+		var authErr mailAuthError
+		if errors.As(err, &authErr) {
+			writeError(rw, http.StatusBadRequest, fmt.Sprintf("Invalid %s: %s", kind.label, authErr))
+			return
+		}
+		var lintErr lintFailure
+		if errors.As(err, &lintErr) {
+			writeLintFailure(rw, lintErr)
+			return
+		}
+		if err != nil {
+			logger.Logger.Debug("Failed to replace mail authentication record", "type", kind.label, "err", err)
+			writeError(rw, http.StatusInternalServerError, "Database error occurred")
+			return
+		}
+
+		for _, change := range changes {
+			events.Fire(zoneInfo.ID, zoneInfo.Name, change.event, change.record)
+		}
+
+		json.NewEncoder(rw).Encode(out)
+	}))
+
+	// Delete the record of the mail domain
+	r.Delete(pattern, validateAuthToken(keystore, func(rw http.ResponseWriter, req *http.Request, b mjwt.BaseTypeClaims[auth.AccessTokenClaims]) {
+		zoneInfo, ok := getOwnedZone(rw, req, db, b)
+		if !ok {
+			return
+		}
+
+		_, _, owner, ok := getMailAuthOwner(rw, req, zoneInfo.Name, kind)
+		if !ok {
+			return
+		}
+
+		var existing []rest.Record
+		err := db.UseRecordTx(req.Context(), func(tx database.RecordTx) error {
+			records, err := zoneRecordsInTx(req.Context(), tx, zoneInfo.ID)
+			if err != nil {
+				return err
+			}
+			existing = mailAuthRecords(records, owner, kind)
+			for _, record := range existing {
+				err = tx.DeleteRecordFromApi(req.Context(), database.DeleteRecordFromApiParams{
+					RecordID: record.ID,
+					ZoneID:   zoneInfo.ID,
+				})
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			logger.Logger.Debug("Failed to delete mail authentication record", "type", kind.label, "err", err)
+			writeError(rw, http.StatusInternalServerError, "Database error occurred")
+			return
+		}
+
+		if len(existing) == 0 {
+			writeNotFound(rw)
+			return
+		}
+
+		for _, record := range existing {
+			events.Fire(zoneInfo.ID, zoneInfo.Name, webhook.RecordDeleted, record)
+		}
+
+		rw.WriteHeader(http.StatusOK)
+	}))
+}
+
+// getMailAuthOwner reads the mail domain and selector from the URL and returns
+// the name of the TXT record, an error response is written when false is
+// returned
+func getMailAuthOwner(rw http.ResponseWriter, req *http.Request, zoneName string, kind mailAuthKind) (string, string, string, bool) {
+	name, err := utils.NormalizeRecordName(zoneName, "TXT", chi.URLParam(req, "name"))
+	if err != nil {
+		writeFieldError(rw, "name", "Invalid record name: "+err.Error())
+		return "", "", "", false
+	}
+	selector, err := utils.ToASCIIName(chi.URLParam(req, "selector"))
+	if err != nil {
+		writeFieldError(rw, "selector", "Invalid selector: "+err.Error())
+		return "", "", "", false
+	}
+	owner, err := utils.NormalizeRecordName(zoneName, "TXT", kind.owner(name, selector))
+	if err != nil {
+		writeFieldError(rw, "name", "Invalid record name: "+err.Error())
+		return "", "", "", false
+	}
+	return name, selector, owner, true
+}
+
+// mailAuthRecords filters the TXT records of the owner name matching the kind
+// sorted by ID
+func mailAuthRecords(records []rest.Record, owner string, kind mailAuthKind) []rest.Record {
+	var out []rest.Record
+	for _, r := range rrsetRecords(records, owner, "TXT") {
+		if kind.matches(r.Value.Text) {
+			out = append(out, r)
+		}
+	}
+	return out
+}
+
+// replaceMailAuthRecord keeps the first existing record and removes any other
+// records of the same kind, publishing more than one is an error for receivers
+func replaceMailAuthRecord(ctx context.Context, tx database.RecordTx, zoneInfo database.Zone, owner string, ttl nulls.Int32, text string, existing []rest.Record) (rest.Record, []recordChange, error) {
+	var changes []recordChange
+	record := rest.Record{
+		Name:   owner,
+		ZoneID: zoneInfo.ID,
+		Ttl:    ttl,
+		Type:   "TXT",
+		Value:  rest.RecordValue{Text: text},
+		Active: true,
+	}.WithUnicode()
+
+	if len(existing) == 0 {
+		id, err := tx.InsertRecordFromApi(ctx, database.InsertRecordFromApiParams{
+			Name:      owner,
+			ZoneID:    zoneInfo.ID,
+			Type:      "TXT",
+			PreTtl:    ttl,
+			PreValue:  text,
+			PreActive: true,
+		})
+		if err != nil {
+			return rest.Record{}, nil, err
+		}
+		record.ID = id
+		return record, []recordChange{{webhook.RecordCreated, record}}, nil
+	}
+
+	first := existing[0]
+	record.ID = first.ID
+	record.Name = first.Name
+	if first.Ttl != ttl || first.Value.Text != text || !first.Active {
+		err := tx.UpdateRecordFromApi(ctx, database.UpdateRecordFromApiParams{
+			PreTtl:    ttl,
+			PreValue:  text,
+			PreActive: true,
+			ID:        first.ID,
+			ZoneID:    zoneInfo.ID,
+		})
+		if err != nil {
+			return rest.Record{}, nil, err
+		}
+		changes = append(changes, recordChange{webhook.RecordUpdated, record})
+	}
+
+	for _, e := range existing[1:] {
+		err := tx.DeleteRecordFromApi(ctx, database.DeleteRecordFromApiParams{
+			RecordID: e.ID,
+			ZoneID:   zoneInfo.ID,
+		})
+		if err != nil {
+			return rest.Record{}, nil, err
+		}
+		changes = append(changes, recordChange{webhook.RecordDeleted, e})
+	}
+	return record, changes, nil
+}
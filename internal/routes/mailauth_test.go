@@ -0,0 +1,134 @@
+package routes
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/1f349/mjwt"
+	"github.com/1f349/mjwt/auth"
+	"github.com/1f349/verbena/internal/database"
+	"github.com/1f349/verbena/internal/webhook"
+	"github.com/go-chi/chi/v5"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAddMailAuthRoutes(t *testing.T) {
+	r := chi.NewRouter()
+	issuer, err := mjwt.NewIssuer("hello world", "1", jwt.SigningMethodRS256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	q := &rrsetTestQueries{recordTestQueries: recordTestQueries{
+		records: make(map[int64]database.Record),
+	}}
+	events := &recordTestEvents{}
+	AddMailAuthRoutes(r, q, issuer.KeyStore(), events)
+	for _, i := range []database.InsertRecordFromApiParams{
+		{Name: "@", ZoneID: 3456, Type: "TXT", PreValue: "v=spf1 -all", PreActive: true},
+		{Name: "@", ZoneID: 3456, Type: "TXT", PreValue: "v=spf1 mx -all", PreActive: true},
+		{Name: "@", ZoneID: 3456, Type: "TXT", PreValue: "google-site-verification=abc", PreActive: true},
+		{Name: "_spf", ZoneID: 3456, Type: "TXT", PreValue: "v=spf1 a ip4:192.0.2.0/24 -all", PreActive: true},
+	} {
+		_, err = q.InsertRecordFromApi(t.Context(), i)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ps := auth.NewPermStorage()
+	ps.Set("domain:owns=example.org")
+	wrongToken, err := issuer.GenerateJwt("1234", "", jwt.ClaimStrings{}, time.Hour, auth.AccessTokenClaims{Perms: ps})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ps = auth.NewPermStorage()
+	ps.Set("domain:owns=example.com")
+	token, err := issuer.GenerateJwt("1234", "", jwt.ClaimStrings{}, time.Hour, auth.AccessTokenClaims{Perms: ps})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	do := func(method, path, body, token string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		r.ServeHTTP(rec, req)
+		return rec
+	}
+
+	t.Run("GET /zones/3456/mail/@/spf", func(t *testing.T) {
+		rec := do(http.MethodGet, "/zones/3456/mail/@/spf", "", "")
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+
+		rec = do(http.MethodGet, "/zones/3456/mail/@/spf", "", wrongToken)
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+
+		rec = do(http.MethodGet, "/zones/3456/mail/@/spf", "", token)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "{\"name\":\"@\",\"ttl\":null,\"mechanisms\":[\"-all\"],\"lookups\":0,\"text\":\"v=spf1 -all\"}\n", rec.Body.String())
+
+		rec = do(http.MethodGet, "/zones/3456/mail/www/spf", "", token)
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+	})
+
+	t.Run("PUT /zones/3456/mail/@/spf", func(t *testing.T) {
+		rec := do(http.MethodPut, "/zones/3456/mail/@/spf", `{"ttl":null,"mechanisms":["mx","+mx:"]}`, token)
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.Equal(t, "{\"code\":\"validation_failed\",\"message\":\"Invalid SPF: invalid domain in +mx:\"}\n", rec.Body.String())
+
+		rec = do(http.MethodPut, "/zones/3456/mail/@/spf", `{"ttl":null,"mechanisms":["include:missing.example.com","-all"]}`, token)
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.Equal(t, "{\"code\":\"validation_failed\",\"message\":\"Invalid SPF: missing.example.com has no SPF record in the zone\"}\n", rec.Body.String())
+		assert.Len(t, q.records, 4)
+
+		events.events = nil
+		rec = do(http.MethodPut, "/zones/3456/mail/@/spf", `{"ttl":3600,"mechanisms":["mx","include:_spf.example.com","~all"]}`, token)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "{\"name\":\"@\",\"ttl\":3600,\"mechanisms\":[\"mx\",\"include:_spf.example.com\",\"~all\"],\"lookups\":3,\"text\":\"v=spf1 mx include:_spf.example.com ~all\"}\n", rec.Body.String())
+
+		// the duplicate SPF record is removed and other TXT records are kept
+		assert.Equal(t, []webhook.Event{webhook.RecordUpdated, webhook.RecordDeleted}, events.events)
+		assert.Equal(t, "v=spf1 mx include:_spf.example.com ~all", q.records[1].PreValue)
+		assert.True(t, q.records[2].PreDelete)
+		assert.False(t, q.records[3].PreDelete)
+	})
+
+	t.Run("PUT /zones/3456/mail/@/dkim/s1", func(t *testing.T) {
+		rec := do(http.MethodPut, "/zones/3456/mail/@/dkim/s1", `{"ttl":null,"key_type":"ed25519","public_key":"AAAA"}`, token)
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.Equal(t, "{\"code\":\"validation_failed\",\"message\":\"Invalid DKIM: Ed25519 key is 3 bytes, expected 32 bytes\"}\n", rec.Body.String())
+
+		rec = do(http.MethodPut, "/zones/3456/mail/@/dkim/s1", `{"ttl":null,"key_type":"ed25519","public_key":"11qYAYKxCrfVS/7TyWQHOg7hcvPapiMlrwIaaPcHURo="}`, token)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "{\"name\":\"@\",\"selector\":\"s1\",\"ttl\":null,\"key_type\":\"ed25519\",\"public_key\":\"11qYAYKxCrfVS/7TyWQHOg7hcvPapiMlrwIaaPcHURo=\",\"key_bits\":256,\"text\":\"v=DKIM1; k=ed25519; p=11qYAYKxCrfVS/7TyWQHOg7hcvPapiMlrwIaaPcHURo=\"}\n", rec.Body.String())
+		assert.Equal(t, "s1._domainkey", q.records[5].Name)
+
+		rec = do(http.MethodGet, "/zones/3456/mail/@/dkim/s1", "", token)
+		assert.Equal(t, http.StatusOK, rec.Code)
+	})
+
+	t.Run("PUT /zones/3456/mail/@/dmarc", func(t *testing.T) {
+		rec := do(http.MethodPut, "/zones/3456/mail/@/dmarc", `{"ttl":null,"policy":"block"}`, token)
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.Equal(t, "{\"code\":\"validation_failed\",\"message\":\"Invalid DMARC: invalid policy \\\"block\\\", expected none, quarantine or reject\"}\n", rec.Body.String())
+
+		rec = do(http.MethodPut, "/zones/3456/mail/@/dmarc", `{"ttl":null,"policy":"reject","aggregate_reports":["mailto:dmarc@example.com"]}`, token)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "{\"name\":\"@\",\"ttl\":null,\"policy\":\"reject\",\"percent\":null,\"aggregate_reports\":[\"mailto:dmarc@example.com\"],\"report_interval\":null,\"text\":\"v=DMARC1; p=reject; rua=mailto:dmarc@example.com\"}\n", rec.Body.String())
+		assert.Equal(t, "_dmarc", q.records[6].Name)
+	})
+
+	t.Run("DELETE /zones/3456/mail/@/dmarc", func(t *testing.T) {
+		rec := do(http.MethodDelete, "/zones/3456/mail/@/dmarc", "", token)
+		assert.Equal(t, http.StatusOK, rec.Code)
+
+		rec = do(http.MethodDelete, "/zones/3456/mail/@/dmarc", "", token)
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+	})
+}
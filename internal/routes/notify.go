@@ -0,0 +1,44 @@
+package routes
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/1f349/mjwt"
+	"github.com/1f349/mjwt/auth"
+	"github.com/1f349/verbena/internal/database"
+	"github.com/1f349/verbena/internal/notify"
+	"github.com/1f349/verbena/logger"
+	"github.com/go-chi/chi/v5"
+)
+
+type notifyQueries interface {
+	GetZone(ctx context.Context, zoneId int64) (database.Zone, error)
+}
+
+type notifyStatusFunc func(zoneName string) []notify.SecondaryStatus
+
+func AddNotifyRoutes(r chi.Router, db notifyQueries, keystore *mjwt.KeyStore, status notifyStatusFunc) {
+	r.Get("/zones/{zone_id:[0-9]+}/notify", validateAuthToken(keystore, func(rw http.ResponseWriter, req *http.Request, b mjwt.BaseTypeClaims[auth.AccessTokenClaims]) {
+		zoneId, err := getZoneId(req)
+		if err != nil {
+			writeFieldError(rw, "zone_id", "Invalid zone ID")
+			return
+		}
+
+		zone, err := db.GetZone(req.Context(), zoneId)
+		if err != nil {
+			logger.Logger.Error("Failed to get zone", "err", err)
+			writeError(rw, http.StatusInternalServerError, "Database error occurred")
+			return
+		}
+
+		if !b.Claims.Perms.Has("domain:owns=" + zone.Name) {
+			writeNotFound(rw)
+			return
+		}
+
+		json.NewEncoder(rw).Encode(status(zone.Name))
+	}))
+}
@@ -0,0 +1,66 @@
+package routes
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/1f349/mjwt"
+	"github.com/1f349/mjwt/auth"
+	"github.com/1f349/verbena/internal/notify"
+	"github.com/go-chi/chi/v5"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAddNotifyRoutes(t *testing.T) {
+	r := chi.NewRouter()
+	issuer, err := mjwt.NewIssuer("hello world", "1", jwt.SigningMethodRS256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	AddNotifyRoutes(r, &zoneFileTestQueries{}, issuer.KeyStore(), func(zoneName string) []notify.SecondaryStatus {
+		return []notify.SecondaryStatus{
+			{
+				Secondary:    "192.0.2.1:53",
+				Serial:       2025062801,
+				Acknowledged: true,
+				Attempts:     1,
+				LastAttempt:  time.Date(2025, 6, 28, 12, 0, 0, 0, time.UTC),
+			},
+		}
+	})
+
+	t.Run("GET /zones/3456/notify", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/zones/3456/notify", nil)
+		r.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+
+		rec = httptest.NewRecorder()
+		req = httptest.NewRequest(http.MethodGet, "/zones/3456/notify", nil)
+		ps := auth.NewPermStorage()
+		ps.Set("domain:owns=example.org")
+		token, err := issuer.GenerateJwt("1234", "", jwt.ClaimStrings{}, time.Hour, auth.AccessTokenClaims{Perms: ps})
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+		r.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+
+		rec = httptest.NewRecorder()
+		req = httptest.NewRequest(http.MethodGet, "/zones/3456/notify", nil)
+		ps = auth.NewPermStorage()
+		ps.Set("domain:owns=example.com")
+		token, err = issuer.GenerateJwt("1234", "", jwt.ClaimStrings{}, time.Hour, auth.AccessTokenClaims{Perms: ps})
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+		r.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "[{\"secondary\":\"192.0.2.1:53\",\"serial\":2025062801,\"acknowledged\":true,\"attempts\":1,\"last_attempt\":\"2025-06-28T12:00:00Z\"}]\n", rec.Body.String())
+	})
+}
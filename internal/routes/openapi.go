@@ -0,0 +1,302 @@
+package routes
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/netip"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/1f349/verbena/internal/zone"
+	"github.com/1f349/verbena/rest"
+	"github.com/go-chi/chi/v5"
+	"github.com/gobuffalo/nulls"
+)
+
+// openapiOperation documents a route registered by AddZoneRoutes,
+// AddRecordRoutes, AddZoneFileRoutes or AddAuthRoutes. A nil Response is an
+// empty body and ResponseText is used for plain text bodies.
+type openapiOperation struct {
+	Method       string
+	Path         string
+	Summary      string
+	Params       []string
+	Request      any
+	Response     any
+	ResponseText string
+
+	// ETag adds the ETag response header and IfMatch the If-Match request
+	// header used for conditional updates
+	ETag    bool
+	IfMatch bool
+}
+
+type tokenResponse struct {
+	Token string `json:"token"`
+}
+
+var openapiOperations = []openapiOperation{
+	{Method: http.MethodGet, Path: "/zones", Summary: "List the zones owned by the token", Response: []rest.Zone{}},
+	{Method: http.MethodGet, Path: "/zones/{zone_id}", Summary: "Get a zone", Params: []string{"zone_id"}, Response: rest.Zone{}, ETag: true},
+	{Method: http.MethodPut, Path: "/zones/{zone_id}", Summary: "Update the SOA timers of a zone", Params: []string{"zone_id"}, Request: rest.PutZone{}, ResponseText: "OK", ETag: true, IfMatch: true},
+	{Method: http.MethodGet, Path: "/zones/lookup/{zone_name}", Summary: "Find the ID of a zone by name", Params: []string{"zone_name"}, Response: struct {
+		ID int64 `json:"id"`
+	}{}},
+
+	{Method: http.MethodGet, Path: "/zones/{zone_id}/records", Summary: "List the records of a zone", Params: []string{"zone_id"}, Response: []rest.Record{}},
+	{Method: http.MethodPost, Path: "/zones/{zone_id}/records", Summary: "Create a record", Params: []string{"zone_id", "wait"}, Request: rest.CreateRecord{}, Response: rest.Record{}},
+	{Method: http.MethodGet, Path: "/zones/{zone_id}/records/{record_id}", Summary: "Get a record", Params: []string{"zone_id", "record_id"}, Response: rest.Record{}, ETag: true},
+	{Method: http.MethodPut, Path: "/zones/{zone_id}/records/{record_id}", Summary: "Update a record", Params: []string{"zone_id", "record_id", "wait"}, Request: rest.PutRecord{}, Response: rest.Record{}, ETag: true, IfMatch: true},
+	{Method: http.MethodDelete, Path: "/zones/{zone_id}/records/{record_id}", Summary: "Delete a record", Params: []string{"zone_id", "record_id", "wait"}, IfMatch: true},
+
+	{Method: http.MethodPost, Path: "/zones/{zone_id}/zone-file", Summary: "Preview the zone file generated for a zone", Params: []string{"zone_id", "view"}, ResponseText: "The zone file in RFC 1035 master file format"},
+
+	{Method: http.MethodPost, Path: "/bot-token", Summary: "Create a refresh token for a bot managing one zone, requires a user access token", Request: struct {
+		Zone string `json:"zone"`
+	}{}, Response: tokenResponse{}},
+	{Method: http.MethodPost, Path: "/refresh-bot-token", Summary: "Exchange a bot refresh token for an access token", Response: tokenResponse{}},
+}
+
+var openapiParams = map[string]map[string]any{
+	"zone_id":   {"name": "zone_id", "in": "path", "required": true, "schema": map[string]any{"type": "integer", "format": "int64"}},
+	"record_id": {"name": "record_id", "in": "path", "required": true, "schema": map[string]any{"type": "integer", "format": "int64"}},
+	"zone_name": {"name": "zone_name", "in": "path", "required": true, "description": "The zone name, Unicode names are converted to punycode", "schema": map[string]any{"type": "string"}},
+	"view":      {"name": "view", "in": "query", "description": "The split-horizon view to generate, required when views are configured", "schema": map[string]any{"type": "string"}},
+	"wait":      {"name": "wait", "in": "query", "description": "Wait until the change has propagated to the secondaries, the outcome is reported in the Verbena-Propagation header", "schema": map[string]any{"type": "string", "enum": []string{"propagated"}}},
+}
+
+// recordValueFields lists the RecordValue fields used by each record type
+var recordValueFields = map[zone.RecordType][]string{
+	zone.NS:     {"target", "target_unicode"},
+	zone.MX:     {"preference", "target", "target_unicode"},
+	zone.A:      {"ip"},
+	zone.AAAA:   {"ip"},
+	zone.CNAME:  {"target", "target_unicode"},
+	zone.TXT:    {"text"},
+	zone.SRV:    {"priority", "weight", "port", "target", "target_unicode"},
+	zone.CAA:    {"flags", "tag", "value"},
+	zone.PTR:    {"target", "target_unicode"},
+	zone.TLSA:   {"usage", "selector", "matching_type", "certificate"},
+	zone.SSHFP:  {"algorithm", "fingerprint_type", "fingerprint"},
+	zone.SMIMEA: {"usage", "selector", "matching_type", "certificate"},
+	zone.SVCB:   {"priority", "target", "target_unicode", "params"},
+	zone.HTTPS:  {"priority", "target", "target_unicode", "params"},
+	zone.NAPTR:  {"order", "preference", "naptr_flags", "service", "regexp", "replacement"},
+	zone.URI:    {"priority", "weight", "target"},
+	zone.LOC:    {"latitude", "longitude", "altitude", "size", "horizontal_precision", "vertical_precision"},
+	zone.HINFO:  {"cpu", "os"},
+	zone.DS:     {"key_tag", "algorithm", "digest_type", "digest"},
+	zone.ALIAS:  {"target", "target_unicode"},
+}
+
+func AddOpenAPIRoutes(r chi.Router) {
+	spec, err := json.Marshal(openapiSpec())
+	if err != nil {
+		panic(err)
+	}
+	r.Get("/openapi.json", func(rw http.ResponseWriter, req *http.Request) {
+		rw.Header().Set("Content-Type", "application/json")
+		rw.Write(spec)
+	})
+}
+
+// openapiSpec builds the OpenAPI 3 document, the schemas are generated from
+// the rest types so they follow changes to the JSON fields
+func openapiSpec() map[string]any {
+	s := &schemaBuilder{components: make(map[string]any)}
+
+	paths := make(map[string]map[string]any)
+	for _, op := range openapiOperations {
+		if paths[op.Path] == nil {
+			paths[op.Path] = make(map[string]any)
+		}
+		paths[op.Path][strings.ToLower(op.Method)] = s.operation(op)
+	}
+
+	// Error bodies are referenced by every operation
+	s.schema(reflect.TypeFor[rest.ErrorBody]())
+
+	return map[string]any{
+		"openapi": "3.0.3",
+		"info": map[string]any{
+			"title":   "Verbena API",
+			"version": "1",
+		},
+		"paths": paths,
+		"components": map[string]any{
+			"schemas": s.components,
+			"securitySchemes": map[string]any{
+				"bearerAuth": map[string]any{"type": "http", "scheme": "bearer", "bearerFormat": "JWT"},
+			},
+		},
+		"security": []any{map[string]any{"bearerAuth": []string{}}},
+	}
+}
+
+type schemaBuilder struct {
+	components map[string]any
+}
+
+func (s *schemaBuilder) operation(op openapiOperation) map[string]any {
+	params := make([]any, 0, len(op.Params)+1)
+	for _, i := range op.Params {
+		params = append(params, openapiParams[i])
+	}
+	if op.IfMatch {
+		params = append(params, map[string]any{
+			"name":        "If-Match",
+			"in":          "header",
+			"description": "Only apply the change when the ETag still matches, otherwise 412 is returned",
+			"schema":      map[string]any{"type": "string"},
+		})
+	}
+
+	ok := map[string]any{"description": "OK"}
+	switch {
+	case op.Response != nil:
+		ok["content"] = map[string]any{"application/json": map[string]any{"schema": s.schema(reflect.TypeOf(op.Response))}}
+	case op.ResponseText != "":
+		ok["description"] = op.ResponseText
+		ok["content"] = map[string]any{"text/plain": map[string]any{"schema": map[string]any{"type": "string"}}}
+	}
+	if op.ETag {
+		ok["headers"] = map[string]any{
+			"ETag": map[string]any{"description": "The version of the resource", "schema": map[string]any{"type": "string"}},
+		}
+	}
+
+	out := map[string]any{
+		"summary": op.Summary,
+		"responses": map[string]any{
+			"200": ok,
+			"default": map[string]any{
+				"description": "Error",
+				"content":     map[string]any{"application/json": map[string]any{"schema": componentRef("ErrorBody")}},
+			},
+		},
+	}
+	if len(params) > 0 {
+		out["parameters"] = params
+	}
+	if op.Request != nil {
+		out["requestBody"] = map[string]any{
+			"required": true,
+			"content":  map[string]any{"application/json": map[string]any{"schema": s.schema(reflect.TypeOf(op.Request))}},
+		}
+	}
+	return out
+}
+
+func componentRef(name string) map[string]any {
+	return map[string]any{"$ref": "#/components/schemas/" + name}
+}
+
+var (
+	nullsInt32Type  = reflect.TypeFor[nulls.Int32]()
+	addrType        = reflect.TypeFor[netip.Addr]()
+	prefixType      = reflect.TypeFor[netip.Prefix]()
+	timeType        = reflect.TypeFor[time.Time]()
+	rawMessageType  = reflect.TypeFor[json.RawMessage]()
+	recordValueType = reflect.TypeFor[rest.RecordValue]()
+)
+
+func (s *schemaBuilder) schema(t reflect.Type) map[string]any {
+	switch t {
+	case nullsInt32Type:
+		return map[string]any{"type": "integer", "format": "int32", "nullable": true}
+	case addrType:
+		return map[string]any{"type": "string", "description": "IPv4 or IPv6 address"}
+	case prefixType:
+		return map[string]any{"type": "string", "description": "IP prefix in CIDR notation"}
+	case timeType:
+		return map[string]any{"type": "string", "format": "date-time"}
+	case rawMessageType:
+		return map[string]any{}
+	case recordValueType:
+		s.recordValue()
+		return componentRef("RecordValue")
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		return s.schema(t.Elem())
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int32:
+		return map[string]any{"type": "integer", "format": "int32"}
+	case reflect.Int, reflect.Int64:
+		return map[string]any{"type": "integer", "format": "int64"}
+	case reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return map[string]any{"type": "integer", "minimum": 0, "maximum": uint64(1)<<(t.Bits()) - 1}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Slice:
+		return map[string]any{"type": "array", "items": s.schema(t.Elem())}
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": s.schema(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return s.object(t)
+		}
+		if _, ok := s.components[t.Name()]; !ok {
+			// claim the name first so recursive types terminate
+			s.components[t.Name()] = nil
+			s.components[t.Name()] = s.object(t)
+		}
+		return componentRef(t.Name())
+	}
+	panic("openapi: unsupported type " + t.String())
+}
+
+func (s *schemaBuilder) object(t reflect.Type) map[string]any {
+	props := make(map[string]any)
+	for _, f := range reflect.VisibleFields(t) {
+		if !f.IsExported() || f.Anonymous {
+			continue
+		}
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		props[name] = s.schema(f.Type)
+	}
+	return map[string]any{"type": "object", "properties": props}
+}
+
+// recordValue adds a schema for the value of each record type, the type is a
+// field of the record so anyOf is used without a discriminator
+func (s *schemaBuilder) recordValue() {
+	if _, ok := s.components["RecordValue"]; ok {
+		return
+	}
+	s.components["RecordValue"] = nil
+
+	all := s.object(recordValueType)["properties"].(map[string]any)
+	refs := make([]any, 0, len(recordValueFields))
+	for ty := zone.NS; ty.IsValid(); ty++ {
+		props := make(map[string]any)
+		for _, field := range recordValueFields[ty] {
+			props[field] = all[field]
+		}
+		if _, ok := props["target_unicode"]; ok {
+			props["target_unicode"] = map[string]any{"type": "string", "readOnly": true, "description": "The Unicode form of a punycode target"}
+		}
+		name := "RecordValue" + ty.String()
+		s.components[name] = map[string]any{
+			"type":        "object",
+			"description": "The value of " + ty.String() + " records",
+			"properties":  props,
+		}
+		refs = append(refs, componentRef(name))
+	}
+	s.components["RecordValue"] = map[string]any{
+		"description": "The value of a record, the fields depend on the record type",
+		"anyOf":       refs,
+	}
+}
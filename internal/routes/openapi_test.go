@@ -0,0 +1,114 @@
+package routes
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/1f349/mjwt"
+	"github.com/1f349/verbena/conf"
+	"github.com/1f349/verbena/internal/zone"
+	"github.com/1f349/verbena/rest"
+	"github.com/go-chi/chi/v5"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+var chiParamPattern = regexp.MustCompile(`\{([a-z_]+):[^}]+}`)
+
+func TestOpenAPIRoutes(t *testing.T) {
+	r := chi.NewRouter()
+	issuer, err := mjwt.NewIssuer("hello world", "1", jwt.SigningMethodRS256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	AddZoneRoutes(r, nil, issuer.KeyStore(), conf.NameserverConf{})
+	AddRecordRoutes(r, nil, issuer.KeyStore(), conf.NameserverConf{}, nil, nil, nil)
+	AddZoneFileRoutes(r, nil, issuer.KeyStore(), nil, nil)
+	AddAuthRoutes(r, nil, issuer.KeyStore(), issuer)
+
+	documented := make(map[string]bool)
+	for _, op := range openapiOperations {
+		documented[op.Method+" "+op.Path] = false
+	}
+
+	err = chi.Walk(r, func(method string, route string, handler http.Handler, middlewares ...func(http.Handler) http.Handler) error {
+		route = chiParamPattern.ReplaceAllString(route, "{$1}")
+		if route != "/" {
+			route = strings.TrimSuffix(route, "/")
+		}
+		key := method + " " + route
+		if _, ok := documented[key]; !ok {
+			t.Errorf("route %s is missing from openapiOperations", key)
+		}
+		documented[key] = true
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for key, found := range documented {
+		if !found {
+			t.Errorf("openapiOperations documents %s which is not registered", key)
+		}
+	}
+}
+
+func TestOpenAPIRecordValue(t *testing.T) {
+	fields := make(map[string]bool)
+	for _, f := range reflect.VisibleFields(reflect.TypeFor[rest.RecordValue]()) {
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		fields[name] = true
+	}
+	for ty := zone.NS; ty.IsValid(); ty++ {
+		assert.NotEmpty(t, recordValueFields[ty], ty.String())
+		for _, i := range recordValueFields[ty] {
+			assert.True(t, fields[i], "%s uses unknown field %s", ty, i)
+		}
+	}
+}
+
+func TestAddOpenAPIRoutes(t *testing.T) {
+	r := chi.NewRouter()
+	AddOpenAPIRoutes(r)
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/openapi.json", nil)
+	r.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+
+	var spec struct {
+		OpenAPI    string                               `json:"openapi"`
+		Paths      map[string]map[string]map[string]any `json:"paths"`
+		Components struct {
+			Schemas map[string]map[string]any `json:"schemas"`
+		} `json:"components"`
+	}
+	err := json.Unmarshal(rec.Body.Bytes(), &spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "3.0.3", spec.OpenAPI)
+	assert.Contains(t, spec.Paths["/zones/{zone_id}/records/{record_id}"], "put")
+	assert.Len(t, spec.Components.Schemas["RecordValue"]["anyOf"], 20)
+	assert.Equal(t, map[string]any{
+		"type":        "object",
+		"description": "The value of MX records",
+		"properties": map[string]any{
+			"preference":     map[string]any{"type": "integer", "format": "int32"},
+			"target":         map[string]any{"type": "string"},
+			"target_unicode": map[string]any{"type": "string", "readOnly": true, "description": "The Unicode form of a punycode target"},
+		},
+	}, spec.Components.Schemas["RecordValueMX"])
+	assert.Equal(t, map[string]any{"$ref": "#/components/schemas/RecordValue"}, spec.Components.Schemas["Record"]["properties"].(map[string]any)["value"])
+
+	// every reference must point at a schema
+	for _, ref := range regexp.MustCompile(`"#/components/schemas/([A-Za-z0-9]+)"`).FindAllStringSubmatch(rec.Body.String(), -1) {
+		assert.Contains(t, spec.Components.Schemas, ref[1])
+	}
+}
@@ -0,0 +1,405 @@
+package routes
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/1f349/mjwt"
+	"github.com/1f349/mjwt/auth"
+	"github.com/1f349/verbena/internal/database"
+	"github.com/1f349/verbena/logger"
+	"github.com/1f349/verbena/rest"
+	"github.com/go-chi/chi/v5"
+)
+
+const (
+	poolMaxMembers = 64
+	poolMaxWeight  = 1000
+)
+
+type poolQueries interface {
+	GetZone(ctx context.Context, zoneId int64) (database.Zone, error)
+	GetZoneRecords(ctx context.Context, zoneId int64) ([]database.GetZoneRecordsRow, error)
+	GetZonePools(ctx context.Context, zoneID int64) ([]database.Pool, error)
+	GetZonePool(ctx context.Context, arg database.GetZonePoolParams) (database.Pool, error)
+	GetZonePoolMembers(ctx context.Context, zoneID int64) ([]database.PoolMember, error)
+	DeletePool(ctx context.Context, arg database.DeletePoolParams) error
+	UsePoolTx(ctx context.Context, cb func(tx database.PoolTx) error) error
+}
+
+type poolLister interface {
+	GetZonePools(ctx context.Context, zoneID int64) ([]database.Pool, error)
+}
+
+// zonePublisher bumps the serial of a zone and publishes it, this is used for
+// changes which do not go through the staged records
+type zonePublisher interface {
+	Publish(ctx context.Context, zone database.Zone) error
+}
+
+// poolConflict is returned when the pool would share a name with records
+// which cannot exist alongside it
+type poolConflict string
+
+func (p poolConflict) Error() string {
+	return "Pool conflicts with the existing " + string(p)
+}
+
+// recordPoolConflict is returned when a record would share a name with a pool
+// which it cannot exist alongside
+type recordPoolConflict string
+
+func (p recordPoolConflict) Error() string {
+	return "Record conflicts with the existing " + string(p) + " pool"
+}
+
+func AddPoolRoutes(r chi.Router, db poolQueries, keystore *mjwt.KeyStore, publisher zonePublisher) {
+	r.Route("/zones/{zone_id:[0-9]+}/pools", func(r chi.Router) {
+		// List every pool of the zone
+		r.Get("/", validateAuthToken(keystore, func(rw http.ResponseWriter, req *http.Request, b mjwt.BaseTypeClaims[auth.AccessTokenClaims]) {
+			zoneInfo, ok := getOwnedZone(rw, req, db, b)
+			if !ok {
+				return
+			}
+
+			pools, err := db.GetZonePools(req.Context(), zoneInfo.ID)
+			if err != nil {
+				logger.Logger.Error("Failed to get zone pools", "err", err)
+				writeError(rw, http.StatusInternalServerError, "Database error occurred")
+				return
+			}
+			members, err := db.GetZonePoolMembers(req.Context(), zoneInfo.ID)
+			if err != nil {
+				logger.Logger.Error("Failed to get zone pool members", "err", err)
+				writeError(rw, http.StatusInternalServerError, "Database error occurred")
+				return
+			}
+
+			out := make([]rest.Pool, 0, len(pools))
+			for _, p := range pools {
+				out = append(out, toRestPool(p, members))
+			}
+			json.NewEncoder(rw).Encode(out)
+		}))
+
+		r.Route("/{name}/{type}", func(r chi.Router) {
+			r.Get("/", validateAuthToken(keystore, func(rw http.ResponseWriter, req *http.Request, b mjwt.BaseTypeClaims[auth.AccessTokenClaims]) {
+				zoneInfo, ok := getOwnedZone(rw, req, db, b)
+				if !ok {
+					return
+				}
+
+				p, ok := getZonePool(rw, req, db, zoneInfo)
+				if !ok {
+					return
+				}
+
+				members, err := db.GetZonePoolMembers(req.Context(), zoneInfo.ID)
+				if err != nil {
+					logger.Logger.Error("Failed to get zone pool members", "err", err)
+					writeError(rw, http.StatusInternalServerError, "Database error occurred")
+					return
+				}
+				json.NewEncoder(rw).Encode(toRestPool(p, members))
+			}))
+
+			// Create or replace the pool of a name and type
+			r.Put("/", validateAuthToken(keystore, func(rw http.ResponseWriter, req *http.Request, b mjwt.BaseTypeClaims[auth.AccessTokenClaims]) {
+				var putPool rest.PutPool
+				err := json.NewDecoder(req.Body).Decode(&putPool)
+				if err != nil {
+					writeError(rw, http.StatusBadRequest, "Invalid request body")
+					return
+				}
+
+				if putPool.Ttl.Valid && putPool.Ttl.Int32 > ttlMaxOneWeek {
+					writeFieldError(rw, "ttl", fmt.Sprintf("Invalid time to live, expected 'null or ttl <= %d seconds'", ttlMaxOneWeek))
+					return
+				}
+
+				zoneInfo, ok := getOwnedZone(rw, req, db, b)
+				if !ok {
+					return
+				}
+
+				name, recordType, ok := getRRsetKey(rw, req, zoneInfo.Name)
+				if !ok {
+					return
+				}
+
+				putPool, err = validatePool(recordType, putPool)
+				if err != nil {
+					writeError(rw, http.StatusBadRequest, "Invalid pool: "+err.Error())
+					return
+				}
+
+				records, ok := getRestZoneRecords(rw, req, db, zoneInfo.ID)
+				if !ok {
+					return
+				}
+				pools, err := db.GetZonePools(req.Context(), zoneInfo.ID)
+				if err != nil {
+					logger.Logger.Error("Failed to get zone pools", "err", err)
+					writeError(rw, http.StatusInternalServerError, "Database error occurred")
+					return
+				}
+				err = checkPoolConflict(name, recordType, records, pools)
+				if err != nil {
+					writeError(rw, http.StatusConflict, err.Error())
+					return
+				}
+
+				err = db.UsePoolTx(req.Context(), func(tx database.PoolTx) error {
+					return replacePool(req.Context(), tx, zoneInfo.ID, name, recordType, putPool)
+				})
+				if err != nil {
+					logger.Logger.Debug("Failed to replace pool", "err", err)
+					writeError(rw, http.StatusInternalServerError, "Database error occurred")
+					return
+				}
+				publishZone(req.Context(), publisher, zoneInfo)
+
+				json.NewEncoder(rw).Encode(rest.Pool{
+					Name:    name,
+					Type:    recordType,
+					Ttl:     putPool.Ttl,
+					Size:    putPool.Size,
+					Members: putPool.Members,
+				}.WithUnicode())
+			}))
+
+			r.Delete("/", validateAuthToken(keystore, func(rw http.ResponseWriter, req *http.Request, b mjwt.BaseTypeClaims[auth.AccessTokenClaims]) {
+				zoneInfo, ok := getOwnedZone(rw, req, db, b)
+				if !ok {
+					return
+				}
+
+				p, ok := getZonePool(rw, req, db, zoneInfo)
+				if !ok {
+					return
+				}
+
+				err := db.DeletePool(req.Context(), database.DeletePoolParams{
+					ID:     p.ID,
+					ZoneID: zoneInfo.ID,
+				})
+				if err != nil {
+					logger.Logger.Error("Failed to delete pool", "err", err)
+					writeError(rw, http.StatusInternalServerError, "Database error occurred")
+					return
+				}
+				publishZone(req.Context(), publisher, zoneInfo)
+				rw.WriteHeader(http.StatusOK)
+			}))
+		})
+	})
+}
+
+// getZonePool loads the pool for the name and type in the URL, an error
+// response is written when false is returned
+func getZonePool(rw http.ResponseWriter, req *http.Request, db poolQueries, zoneInfo database.Zone) (database.Pool, bool) {
+	name, recordType, ok := getRRsetKey(rw, req, zoneInfo.Name)
+	if !ok {
+		return database.Pool{}, false
+	}
+
+	p, err := db.GetZonePool(req.Context(), database.GetZonePoolParams{
+		ZoneID: zoneInfo.ID,
+		Name:   name,
+		Type:   recordType,
+	})
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		writeNotFound(rw)
+		return database.Pool{}, false
+	case err != nil:
+		logger.Logger.Error("Failed to get pool", "err", err)
+		writeError(rw, http.StatusInternalServerError, "Database error occurred")
+		return database.Pool{}, false
+	}
+	return p, true
+}
+
+// validatePool converts the member values to punycode and checks the pool
+// can be published
+func validatePool(recordType string, putPool rest.PutPool) (rest.PutPool, error) {
+	if recordType != "A" && recordType != "AAAA" && recordType != "CNAME" {
+		return rest.PutPool{}, errors.New("only A, AAAA and CNAME pools are supported")
+	}
+	if len(putPool.Members) == 0 {
+		return rest.PutPool{}, errors.New("at least one member is required")
+	}
+	if len(putPool.Members) > poolMaxMembers {
+		return rest.PutPool{}, fmt.Errorf("at most %d members are allowed", poolMaxMembers)
+	}
+	if putPool.Size == 0 {
+		putPool.Size = 1
+	}
+	if putPool.Size < 1 || int(putPool.Size) > len(putPool.Members) {
+		return rest.PutPool{}, fmt.Errorf("size must be between 1 and the number of members")
+	}
+	if recordType == "CNAME" && putPool.Size != 1 {
+		return rest.PutPool{}, errors.New("CNAME pools must publish a single member")
+	}
+
+	members := make([]rest.PoolMember, len(putPool.Members))
+	values := make(map[string]bool, len(putPool.Members))
+	for i, m := range putPool.Members {
+		v, err := m.Value.ToASCII(recordType)
+		if err != nil {
+			return rest.PutPool{}, fmt.Errorf("invalid value for member %d: %w", i, err)
+		}
+		if !v.IsValidForType(recordType) {
+			return rest.PutPool{}, fmt.Errorf("invalid value for member %d", i)
+		}
+		value := v.ToValueString(recordType)
+		if values[value] {
+			return rest.PutPool{}, fmt.Errorf("duplicate value for member %d", i)
+		}
+		values[value] = true
+		if m.Weight < 0 || m.Weight > poolMaxWeight {
+			return rest.PutPool{}, fmt.Errorf("weight for member %d must be between 0 and %d", i, poolMaxWeight)
+		}
+		members[i] = rest.PoolMember{Value: v, Weight: m.Weight, Enabled: m.Enabled}
+	}
+	putPool.Members = members
+	return putPool, nil
+}
+
+// checkPoolConflict stops a pool being published alongside records of the same
+// type, which would make the weights meaningless, or alongside a CNAME
+func checkPoolConflict(name, recordType string, records []rest.Record, pools []database.Pool) error {
+	for _, r := range records {
+		if !sameRecordName(r.Name, name) {
+			continue
+		}
+		if r.Type == recordType || r.Type == "CNAME" || recordType == "CNAME" {
+			return poolConflict(r.Type + " record")
+		}
+	}
+	for _, p := range pools {
+		if !sameRecordName(p.Name, name) || p.Type == recordType {
+			continue
+		}
+		if p.Type == "CNAME" || recordType == "CNAME" {
+			return poolConflict(p.Type + " pool")
+		}
+	}
+	return nil
+}
+
+// checkRecordPools is the reverse of checkPoolConflict for records created
+// through the other routes, an error response is written when false is
+// returned
+func checkRecordPools(rw http.ResponseWriter, req *http.Request, db poolLister, zoneId int64, name, recordType string) bool {
+	pools, err := db.GetZonePools(req.Context(), zoneId)
+	if err != nil {
+		logger.Logger.Error("Failed to get zone pools", "err", err)
+		writeError(rw, http.StatusInternalServerError, "Database error occurred")
+		return false
+	}
+	err = findRecordPoolConflict(name, recordType, pools)
+	if err != nil {
+		writeError(rw, http.StatusConflict, err.Error())
+		return false
+	}
+	return true
+}
+
+func findRecordPoolConflict(name, recordType string, pools []database.Pool) error {
+	for _, p := range pools {
+		if !sameRecordName(p.Name, name) {
+			continue
+		}
+		if p.Type == recordType || p.Type == "CNAME" || recordType == "CNAME" {
+			return recordPoolConflict(p.Type)
+		}
+	}
+	return nil
+}
+
+// publishZone publishes a pool change, a failure is only logged as the change
+// is stored and the generator publishes the zone again on its next tick
+func publishZone(ctx context.Context, publisher zonePublisher, zone database.Zone) {
+	err := publisher.Publish(ctx, zone)
+	if err != nil {
+		logger.Logger.Error("Failed to publish zone", "zone", zone.Name, "err", err)
+	}
+}
+
+func replacePool(ctx context.Context, tx database.PoolTx, zoneId int64, name, recordType string, putPool rest.PutPool) error {
+	existing, err := tx.GetZonePool(ctx, database.GetZonePoolParams{
+		ZoneID: zoneId,
+		Name:   name,
+		Type:   recordType,
+	})
+	var poolId int64
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		poolId, err = tx.InsertPool(ctx, database.InsertPoolParams{
+			ZoneID: zoneId,
+			Name:   name,
+			Type:   recordType,
+			Ttl:    putPool.Ttl,
+			Size:   putPool.Size,
+		})
+		if err != nil {
+			return err
+		}
+	case err != nil:
+		return err
+	default:
+		poolId = existing.ID
+		err = tx.UpdatePool(ctx, database.UpdatePoolParams{
+			Ttl:    putPool.Ttl,
+			Size:   putPool.Size,
+			ID:     poolId,
+			ZoneID: zoneId,
+		})
+		if err != nil {
+			return err
+		}
+		err = tx.DeletePoolMembers(ctx, poolId)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, m := range putPool.Members {
+		err = tx.InsertPoolMember(ctx, database.InsertPoolMemberParams{
+			PoolID:  poolId,
+			Value:   m.Value.ToValueString(recordType),
+			Weight:  m.Weight,
+			Enabled: m.Enabled,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func toRestPool(p database.Pool, members []database.PoolMember) rest.Pool {
+	out := rest.Pool{
+		Name:    p.Name,
+		Type:    p.Type,
+		Ttl:     p.Ttl,
+		Size:    p.Size,
+		Members: []rest.PoolMember{},
+	}
+	for _, m := range members {
+		if m.PoolID != p.ID {
+			continue
+		}
+		v, err := rest.ParseRecordValue(p.Type, m.Value)
+		if err != nil {
+			continue
+		}
+		out.Members = append(out.Members, rest.PoolMember{Value: v, Weight: m.Weight, Enabled: m.Enabled})
+	}
+	return out.WithUnicode()
+}
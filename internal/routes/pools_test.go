@@ -0,0 +1,241 @@
+package routes
+
+import (
+	"cmp"
+	"context"
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/1f349/mjwt"
+	"github.com/1f349/mjwt/auth"
+	"github.com/1f349/verbena/conf"
+	"github.com/1f349/verbena/internal/database"
+	"github.com/go-chi/chi/v5"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+type poolTestQueries struct {
+	recordTestQueries
+	pools   map[int64]database.Pool
+	members map[int64]database.PoolMember
+}
+
+func (p *poolTestQueries) GetZonePools(ctx context.Context, zoneID int64) ([]database.Pool, error) {
+	var pools []database.Pool
+	for _, i := range p.pools {
+		if i.ZoneID == zoneID {
+			pools = append(pools, i)
+		}
+	}
+	slices.SortFunc(pools, func(a, b database.Pool) int { return cmp.Compare(a.ID, b.ID) })
+	return pools, nil
+}
+
+func (p *poolTestQueries) GetZonePool(ctx context.Context, arg database.GetZonePoolParams) (database.Pool, error) {
+	for _, i := range p.pools {
+		if i.ZoneID == arg.ZoneID && i.Name == arg.Name && i.Type == arg.Type {
+			return i, nil
+		}
+	}
+	return database.Pool{}, sql.ErrNoRows
+}
+
+func (p *poolTestQueries) GetZonePoolMembers(ctx context.Context, zoneID int64) ([]database.PoolMember, error) {
+	var members []database.PoolMember
+	for _, i := range p.members {
+		if p.pools[i.PoolID].ZoneID == zoneID {
+			members = append(members, i)
+		}
+	}
+	slices.SortFunc(members, func(a, b database.PoolMember) int { return cmp.Compare(a.ID, b.ID) })
+	return members, nil
+}
+
+func (p *poolTestQueries) DeletePool(ctx context.Context, arg database.DeletePoolParams) error {
+	if p.pools[arg.ID].ZoneID != arg.ZoneID {
+		return nil
+	}
+	delete(p.pools, arg.ID)
+	return p.DeletePoolMembers(ctx, arg.ID)
+}
+
+func (p *poolTestQueries) UsePoolTx(ctx context.Context, cb func(tx database.PoolTx) error) error {
+	return cb(p)
+}
+
+func (p *poolTestQueries) InsertPool(ctx context.Context, arg database.InsertPoolParams) (int64, error) {
+	id := int64(len(p.pools) + 1)
+	p.pools[id] = database.Pool{
+		ID:     id,
+		ZoneID: arg.ZoneID,
+		Name:   arg.Name,
+		Type:   arg.Type,
+		Ttl:    arg.Ttl,
+		Size:   arg.Size,
+	}
+	return id, nil
+}
+
+func (p *poolTestQueries) UpdatePool(ctx context.Context, arg database.UpdatePoolParams) error {
+	pool := p.pools[arg.ID]
+	pool.Ttl = arg.Ttl
+	pool.Size = arg.Size
+	p.pools[arg.ID] = pool
+	return nil
+}
+
+func (p *poolTestQueries) DeletePoolMembers(ctx context.Context, poolID int64) error {
+	for id, i := range p.members {
+		if i.PoolID == poolID {
+			delete(p.members, id)
+		}
+	}
+	return nil
+}
+
+func (p *poolTestQueries) InsertPoolMember(ctx context.Context, arg database.InsertPoolMemberParams) error {
+	id := int64(len(p.members) + 1)
+	for p.members[id].ID != 0 {
+		id++
+	}
+	p.members[id] = database.PoolMember{
+		ID:      id,
+		PoolID:  arg.PoolID,
+		Value:   arg.Value,
+		Weight:  arg.Weight,
+		Enabled: arg.Enabled,
+	}
+	return nil
+}
+
+type poolTestPublisher struct {
+	published []string
+}
+
+func (p *poolTestPublisher) Publish(ctx context.Context, zone database.Zone) error {
+	p.published = append(p.published, zone.Name)
+	return nil
+}
+
+func TestAddPoolRoutes(t *testing.T) {
+	r := chi.NewRouter()
+	issuer, err := mjwt.NewIssuer("hello world", "1", jwt.SigningMethodRS256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	q := &poolTestQueries{
+		recordTestQueries: recordTestQueries{records: make(map[int64]database.Record)},
+		pools:             make(map[int64]database.Pool),
+		members:           make(map[int64]database.PoolMember),
+	}
+	publisher := &poolTestPublisher{}
+	AddPoolRoutes(r, q, issuer.KeyStore(), publisher)
+	AddRecordRoutes(r, q, issuer.KeyStore(), conf.NameserverConf{}, nil, &propagationTestTracker{}, &recordTestEvents{})
+	_, err = q.InsertRecordFromApi(t.Context(), database.InsertRecordFromApiParams{Name: "mail", ZoneID: 3456, Type: "A", PreValue: "192.0.2.25", PreActive: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ps := auth.NewPermStorage()
+	ps.Set("domain:owns=example.com")
+	token, err := issuer.GenerateJwt("1234", "", jwt.ClaimStrings{}, time.Hour, auth.AccessTokenClaims{Perms: ps})
+	if err != nil {
+		t.Fatal(err)
+	}
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+token)
+		r.ServeHTTP(rec, req)
+		return rec
+	}
+
+	t.Run("GET /zones/3456/pools", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/zones/3456/pools", nil)
+		r.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+
+		rec = do(http.MethodGet, "/zones/3456/pools", "")
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "[]\n", rec.Body.String())
+
+		rec = do(http.MethodGet, "/zones/3456/pools/www/A", "")
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+	})
+
+	t.Run("PUT /zones/3456/pools/www/A", func(t *testing.T) {
+		for _, i := range []struct {
+			path   string
+			body   string
+			status int
+			err    string
+		}{
+			{"/zones/3456/pools/www/MX", `{"members":[{"value":{"preference":10,"target":"mail.example.com"}}]}`, http.StatusBadRequest, "{\"code\":\"validation_failed\",\"message\":\"Invalid pool: only A, AAAA and CNAME pools are supported\"}\n"},
+			{"/zones/3456/pools/www/A", `{"members":[]}`, http.StatusBadRequest, "{\"code\":\"validation_failed\",\"message\":\"Invalid pool: at least one member is required\"}\n"},
+			{"/zones/3456/pools/www/A", `{"size":3,"members":[{"value":{"ip":"192.0.2.1"}},{"value":{"ip":"192.0.2.2"}}]}`, http.StatusBadRequest, "{\"code\":\"validation_failed\",\"message\":\"Invalid pool: size must be between 1 and the number of members\"}\n"},
+			{"/zones/3456/pools/www/A", `{"members":[{"value":{"ip":"2001:db8::1"}}]}`, http.StatusBadRequest, "{\"code\":\"validation_failed\",\"message\":\"Invalid pool: invalid value for member 0\"}\n"},
+			{"/zones/3456/pools/www/A", `{"members":[{"value":{"ip":"192.0.2.1"}},{"value":{"ip":"192.0.2.1"}}]}`, http.StatusBadRequest, "{\"code\":\"validation_failed\",\"message\":\"Invalid pool: duplicate value for member 1\"}\n"},
+			{"/zones/3456/pools/www/A", `{"members":[{"value":{"ip":"192.0.2.1"},"weight":-1}]}`, http.StatusBadRequest, "{\"code\":\"validation_failed\",\"message\":\"Invalid pool: weight for member 0 must be between 0 and 1000\"}\n"},
+			{"/zones/3456/pools/www/CNAME", `{"size":2,"members":[{"value":{"target":"a.example.net"}},{"value":{"target":"b.example.net"}}]}`, http.StatusBadRequest, "{\"code\":\"validation_failed\",\"message\":\"Invalid pool: CNAME pools must publish a single member\"}\n"},
+			{"/zones/3456/pools/mail/A", `{"members":[{"value":{"ip":"192.0.2.1"}}]}`, http.StatusConflict, "{\"code\":\"conflict\",\"message\":\"Pool conflicts with the existing A record\"}\n"},
+		} {
+			rec := do(http.MethodPut, i.path, i.body)
+			assert.Equal(t, i.status, rec.Code)
+			assert.Equal(t, i.err, rec.Body.String())
+		}
+		assert.Empty(t, publisher.published)
+
+		rec := do(http.MethodPut, "/zones/3456/pools/www/A", `{"ttl":60,"size":2,"members":[{"value":{"ip":"192.0.2.1"},"weight":3},{"value":{"ip":"192.0.2.2"},"weight":1},{"value":{"ip":"192.0.2.3"},"weight":1,"enabled":false}]}`)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "{\"name\":\"www\",\"type\":\"A\",\"ttl\":60,\"size\":2,\"members\":[{\"value\":{\"ip\":\"192.0.2.1\"},\"weight\":3,\"enabled\":true},{\"value\":{\"ip\":\"192.0.2.2\"},\"weight\":1,\"enabled\":true},{\"value\":{\"ip\":\"192.0.2.3\"},\"weight\":1,\"enabled\":false}]}\n", rec.Body.String())
+		assert.Len(t, q.members, 3)
+
+		// replacing the pool replaces the members
+		rec = do(http.MethodPut, "/zones/3456/pools/www/A", `{"members":[{"value":{"ip":"192.0.2.4"},"weight":1}]}`)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Len(t, q.pools, 1)
+		assert.Len(t, q.members, 1)
+		assert.Equal(t, []string{"example.com", "example.com"}, publisher.published)
+
+		rec = do(http.MethodPut, "/zones/3456/pools/www/CNAME", `{"members":[{"value":{"target":"a.example.net"}}]}`)
+		assert.Equal(t, http.StatusConflict, rec.Code)
+		assert.Equal(t, "{\"code\":\"conflict\",\"message\":\"Pool conflicts with the existing A pool\"}\n", rec.Body.String())
+
+		// records cannot be added alongside the pool either
+		rec = do(http.MethodPost, "/zones/3456/records", `{"name":"www","type":"CNAME","value":{"target":"a.example.net"}}`)
+		assert.Equal(t, http.StatusConflict, rec.Code)
+		assert.Equal(t, "{\"code\":\"conflict\",\"message\":\"Record conflicts with the existing A pool\"}\n", rec.Body.String())
+		rec = do(http.MethodPost, "/zones/3456/records", `{"name":"www","type":"A","value":{"ip":"192.0.2.9"}}`)
+		assert.Equal(t, http.StatusConflict, rec.Code)
+		rec = do(http.MethodPost, "/zones/3456/records", `{"name":"www","type":"TXT","value":{"text":"hello"}}`)
+		assert.Equal(t, http.StatusOK, rec.Code)
+	})
+
+	t.Run("GET /zones/3456/pools/www/A", func(t *testing.T) {
+		rec := do(http.MethodGet, "/zones/3456/pools/www/A", "")
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "{\"name\":\"www\",\"type\":\"A\",\"ttl\":null,\"size\":1,\"members\":[{\"value\":{\"ip\":\"192.0.2.4\"},\"weight\":1,\"enabled\":true}]}\n", rec.Body.String())
+
+		rec = do(http.MethodGet, "/zones/3456/pools", "")
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "[{\"name\":\"www\",\"type\":\"A\",\"ttl\":null,\"size\":1,\"members\":[{\"value\":{\"ip\":\"192.0.2.4\"},\"weight\":1,\"enabled\":true}]}]\n", rec.Body.String())
+	})
+
+	t.Run("DELETE /zones/3456/pools/www/A", func(t *testing.T) {
+		rec := do(http.MethodDelete, "/zones/3456/pools/www/A", "")
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Len(t, q.pools, 0)
+		assert.Len(t, q.members, 0)
+		assert.Len(t, publisher.published, 3)
+
+		rec = do(http.MethodDelete, "/zones/3456/pools/www/A", "")
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+	})
+}
@@ -0,0 +1,85 @@
+package routes
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/1f349/mjwt"
+	"github.com/1f349/mjwt/auth"
+	"github.com/1f349/verbena/internal/database"
+	"github.com/1f349/verbena/internal/propagation"
+	"github.com/1f349/verbena/logger"
+	"github.com/go-chi/chi/v5"
+)
+
+// propagationWaitTimeout is kept below the API server write timeout
+const propagationWaitTimeout = 50 * time.Second
+
+type propagationQueries interface {
+	GetZone(ctx context.Context, zoneId int64) (database.Zone, error)
+}
+
+type propagationTracker interface {
+	Commits(zoneName string) []propagation.Commit
+	Commit(zoneName string, serial uint32) (propagation.Commit, bool)
+}
+
+type propagationWaiter interface {
+	WaitPropagated(ctx context.Context, zoneName string, afterSerial uint32) (propagation.Status, error)
+}
+
+func AddPropagationRoutes(r chi.Router, db propagationQueries, keystore *mjwt.KeyStore, tracker propagationTracker) {
+	r.Route("/zones/{zone_id:[0-9]+}/commits", func(r chi.Router) {
+		// List recent commits
+		r.Get("/", validateAuthToken(keystore, func(rw http.ResponseWriter, req *http.Request, b mjwt.BaseTypeClaims[auth.AccessTokenClaims]) {
+			zone, ok := getOwnedZone(rw, req, db, b)
+			if !ok {
+				return
+			}
+
+			json.NewEncoder(rw).Encode(tracker.Commits(zone.Name))
+		}))
+
+		// Show individual commit
+		r.Get("/{serial:[0-9]+}", validateAuthToken(keystore, func(rw http.ResponseWriter, req *http.Request, b mjwt.BaseTypeClaims[auth.AccessTokenClaims]) {
+			serial, err := strconv.ParseUint(chi.URLParam(req, "serial"), 10, 32)
+			if err != nil {
+				writeFieldError(rw, "serial", "Invalid serial")
+				return
+			}
+
+			zone, ok := getOwnedZone(rw, req, db, b)
+			if !ok {
+				return
+			}
+
+			commit, ok := tracker.Commit(zone.Name, uint32(serial))
+			if !ok {
+				writeNotFound(rw)
+				return
+			}
+
+			json.NewEncoder(rw).Encode(commit)
+		}))
+	})
+}
+
+func wantsPropagationWait(req *http.Request) bool {
+	return req.URL.Query().Get("wait") == "propagated"
+}
+
+// waitForPropagation blocks until the change made after the zone was loaded has
+// propagated, the outcome is reported in the Verbena-Propagation header
+func waitForPropagation(rw http.ResponseWriter, req *http.Request, waiter propagationWaiter, zone database.Zone) {
+	ctx, cancel := context.WithTimeout(req.Context(), propagationWaitTimeout)
+	defer cancel()
+
+	status, err := waiter.WaitPropagated(ctx, zone.Name, uint32(zone.Serial))
+	if err != nil {
+		logger.Logger.Debug("Stopped waiting for propagation", "zone", zone.Name, "status", status, "err", err)
+	}
+	rw.Header().Set("Verbena-Propagation", string(status))
+}
@@ -0,0 +1,114 @@
+package routes
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/1f349/mjwt"
+	"github.com/1f349/mjwt/auth"
+	"github.com/1f349/verbena/internal/propagation"
+	"github.com/go-chi/chi/v5"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+type propagationTestTracker struct{}
+
+var testCommit = propagation.Commit{
+	Serial:    2025062802,
+	Committed: time.Date(2025, 6, 28, 12, 0, 0, 0, time.UTC),
+	Status:    propagation.Partial,
+	Nameservers: []propagation.NameserverStatus{
+		{Nameserver: "ns1.example.com", Serial: 2025062802, Updated: true},
+		{Nameserver: "ns2.example.com", Serial: 2025062801, Updated: false},
+	},
+}
+
+func (p *propagationTestTracker) Commits(zoneName string) []propagation.Commit {
+	if zoneName != "example.com" {
+		return nil
+	}
+	return []propagation.Commit{testCommit}
+}
+
+func (p *propagationTestTracker) Commit(zoneName string, serial uint32) (propagation.Commit, bool) {
+	if zoneName != "example.com" || serial != testCommit.Serial {
+		return propagation.Commit{}, false
+	}
+	return testCommit, true
+}
+
+func (p *propagationTestTracker) WaitPropagated(ctx context.Context, zoneName string, afterSerial uint32) (propagation.Status, error) {
+	if zoneName != "example.com" || afterSerial != 2025062801 {
+		return propagation.Pending, nil
+	}
+	return propagation.Complete, nil
+}
+
+func TestAddPropagationRoutes(t *testing.T) {
+	r := chi.NewRouter()
+	issuer, err := mjwt.NewIssuer("hello world", "1", jwt.SigningMethodRS256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	AddPropagationRoutes(r, &zoneFileTestQueries{}, issuer.KeyStore(), &propagationTestTracker{})
+
+	const commitJson = "{\"serial\":2025062802,\"committed\":\"2025-06-28T12:00:00Z\",\"status\":\"partial\",\"nameservers\":[{\"nameserver\":\"ns1.example.com\",\"serial\":2025062802,\"updated\":true},{\"nameserver\":\"ns2.example.com\",\"serial\":2025062801,\"updated\":false}]}"
+
+	t.Run("GET /zones/3456/commits", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/zones/3456/commits", nil)
+		r.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+
+		rec = httptest.NewRecorder()
+		req = httptest.NewRequest(http.MethodGet, "/zones/3456/commits", nil)
+		ps := auth.NewPermStorage()
+		ps.Set("domain:owns=example.org")
+		token, err := issuer.GenerateJwt("1234", "", jwt.ClaimStrings{}, time.Hour, auth.AccessTokenClaims{Perms: ps})
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+		r.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+
+		rec = httptest.NewRecorder()
+		req = httptest.NewRequest(http.MethodGet, "/zones/3456/commits", nil)
+		ps = auth.NewPermStorage()
+		ps.Set("domain:owns=example.com")
+		token, err = issuer.GenerateJwt("1234", "", jwt.ClaimStrings{}, time.Hour, auth.AccessTokenClaims{Perms: ps})
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+		r.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "["+commitJson+"]\n", rec.Body.String())
+	})
+
+	t.Run("GET /zones/3456/commits/{serial}", func(t *testing.T) {
+		ps := auth.NewPermStorage()
+		ps.Set("domain:owns=example.com")
+		token, err := issuer.GenerateJwt("1234", "", jwt.ClaimStrings{}, time.Hour, auth.AccessTokenClaims{Perms: ps})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/zones/3456/commits/2025062801", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		r.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+
+		rec = httptest.NewRecorder()
+		req = httptest.NewRequest(http.MethodGet, "/zones/3456/commits/2025062802", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		r.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, commitJson+"\n", rec.Body.String())
+	})
+}
@@ -2,15 +2,20 @@ package routes
 
 import (
 	"context"
+	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
+	"net/netip"
 	"strconv"
 
 	"github.com/1f349/mjwt"
 	"github.com/1f349/mjwt/auth"
 	"github.com/1f349/verbena/conf"
 	"github.com/1f349/verbena/internal/database"
+	"github.com/1f349/verbena/internal/utils"
+	"github.com/1f349/verbena/internal/webhook"
 	"github.com/1f349/verbena/logger"
 	"github.com/1f349/verbena/rest"
 	"github.com/go-chi/chi/v5"
@@ -21,11 +26,15 @@ type recordQueries interface {
 	GetZoneRecords(ctx context.Context, zoneId int64) ([]database.GetZoneRecordsRow, error)
 	GetZoneRecord(ctx context.Context, row database.GetZoneRecordParams) (database.GetZoneRecordRow, error)
 	GetZone(ctx context.Context, zoneId int64) (database.Zone, error)
-	InsertRecordFromApi(ctx context.Context, row database.InsertRecordFromApiParams) (int64, error)
-	UpdateRecordFromApi(ctx context.Context, row database.UpdateRecordFromApiParams) error
-	DeleteRecordFromApi(ctx context.Context, row database.DeleteRecordFromApiParams) error
+	GetZoneHealthChecks(ctx context.Context, zoneID int64) ([]database.HealthCheck, error)
+	UseRecordTx(ctx context.Context, cb func(tx database.RecordTx) error) error
+	poolLister
 }
 
+// errRecordModified is returned from the transaction when the record was
+// changed by another request since it was read
+var errRecordModified = errors.New("record modified")
+
 func RecordToRestRecord(record database.Record) (rest.Record, error) {
 	v, err := rest.ParseRecordValue(record.Type, record.PreValue)
 	if err != nil {
@@ -33,14 +42,16 @@ func RecordToRestRecord(record database.Record) (rest.Record, error) {
 		return rest.Record{}, err
 	}
 	return rest.Record{
-		ID:     record.ID,
-		Name:   record.Name,
-		ZoneID: record.ZoneID,
-		Ttl:    record.PreTtl,
-		Type:   record.Type,
-		Active: record.PreActive,
-		Value:  v,
-	}, nil
+		ID:      record.ID,
+		Name:    record.Name,
+		ZoneID:  record.ZoneID,
+		Ttl:     record.PreTtl,
+		Type:    record.Type,
+		Active:  record.PreActive,
+		Value:   v,
+		AutoPTR: record.AutoPtr,
+		Views:   utils.SplitViews(record.PreViews),
+	}.WithUnicode(), nil
 }
 
 func appendRecord(slice []rest.Record, record database.Record) []rest.Record {
@@ -51,32 +62,32 @@ func appendRecord(slice []rest.Record, record database.Record) []rest.Record {
 	return append(slice, record2)
 }
 
-func AddRecordRoutes(r chi.Router, db recordQueries, keystore *mjwt.KeyStore, nameservers conf.NameserverConf) {
+func AddRecordRoutes(r chi.Router, db recordQueries, keystore *mjwt.KeyStore, nameservers conf.NameserverConf, views conf.ViewsConf, waiter propagationWaiter, events eventFirer) {
 	r.Route("/zones/{zone_id:[0-9]+}/records", func(r chi.Router) {
 		// List all records
 		r.Get("/", validateAuthToken(keystore, func(rw http.ResponseWriter, req *http.Request, b mjwt.BaseTypeClaims[auth.AccessTokenClaims]) {
 			zoneId, err := getZoneId(req)
 			if err != nil {
-				http.Error(rw, "Invalid zone ID", http.StatusBadRequest)
+				writeFieldError(rw, "zone_id", "Invalid zone ID")
 				return
 			}
 
 			zone, err := db.GetZone(req.Context(), zoneId)
 			if err != nil {
 				logger.Logger.Error("Failed to get zone", "err", err)
-				http.Error(rw, "Database error occurred", http.StatusInternalServerError)
+				writeError(rw, http.StatusInternalServerError, "Database error occurred")
 				return
 			}
 
 			if !b.Claims.Perms.Has("domain:owns=" + zone.Name) {
-				http.NotFound(rw, req)
+				writeNotFound(rw)
 				return
 			}
 
 			rows, err := db.GetZoneRecords(req.Context(), zoneId)
 			if err != nil {
 				logger.Logger.Error("Failed to get zone records", "err", err)
-				http.Error(rw, "Database error occurred", http.StatusInternalServerError)
+				writeError(rw, http.StatusInternalServerError, "Database error occurred")
 				return
 			}
 
@@ -103,6 +114,14 @@ func AddRecordRoutes(r chi.Router, db recordQueries, keystore *mjwt.KeyStore, na
 				records = appendRecord(records, record.Record)
 			}
 
+			checks, err := db.GetZoneHealthChecks(req.Context(), zoneId)
+			if err != nil {
+				logger.Logger.Error("Failed to get zone health checks", "err", err)
+				writeError(rw, http.StatusInternalServerError, "Database error occurred")
+				return
+			}
+			attachHealth(records, checks)
+
 			json.NewEncoder(rw).Encode(records)
 		}))
 
@@ -110,13 +129,13 @@ func AddRecordRoutes(r chi.Router, db recordQueries, keystore *mjwt.KeyStore, na
 		r.Get("/{record_id:[0-9]+}", validateAuthToken(keystore, func(rw http.ResponseWriter, req *http.Request, b mjwt.BaseTypeClaims[auth.AccessTokenClaims]) {
 			zoneId, err := getZoneId(req)
 			if err != nil {
-				http.Error(rw, "Invalid zone ID", http.StatusBadRequest)
+				writeFieldError(rw, "zone_id", "Invalid zone ID")
 				return
 			}
 
 			recordId, err := getRecordId(req)
 			if err != nil {
-				http.Error(rw, "Invalid record ID", http.StatusBadRequest)
+				writeFieldError(rw, "record_id", "Invalid record ID")
 				return
 			}
 
@@ -124,121 +143,218 @@ func AddRecordRoutes(r chi.Router, db recordQueries, keystore *mjwt.KeyStore, na
 				RecordID: recordId,
 				ZoneID:   zoneId,
 			})
-			if err != nil {
-				logger.Logger.Error("Failed to get zone records", "err", err)
-				http.Error(rw, "Database error occurred", http.StatusInternalServerError)
+			switch {
+			case errors.Is(err, sql.ErrNoRows):
+				writeNotFound(rw)
+				return
+			case err != nil:
+				logger.Logger.Error("Failed to get zone record", "err", err)
+				writeError(rw, http.StatusInternalServerError, "Database error occurred")
 				return
 			}
 
 			if !b.Claims.Perms.Has("domain:owns=" + row.Name) {
-				http.NotFound(rw, req)
+				writeNotFound(rw)
 				return
 			}
 
 			record, err := RecordToRestRecord(row.Record)
 			if err != nil {
-				http.Error(rw, "Server error occurred", http.StatusInternalServerError)
+				writeError(rw, http.StatusInternalServerError, "Server error occurred")
 				return
 			}
 
-			json.NewEncoder(rw).Encode(record)
+			checks, err := db.GetZoneHealthChecks(req.Context(), zoneId)
+			if err != nil {
+				logger.Logger.Error("Failed to get zone health checks", "err", err)
+				writeError(rw, http.StatusInternalServerError, "Database error occurred")
+				return
+			}
+			records := []rest.Record{record}
+			attachHealth(records, checks)
+
+			setETag(rw, row.Record.Version)
+			json.NewEncoder(rw).Encode(records[0])
 		}))
 
 		// Create record
 		r.Post("/", validateAuthToken(keystore, func(rw http.ResponseWriter, req *http.Request, b mjwt.BaseTypeClaims[auth.AccessTokenClaims]) {
 			var record struct {
-				Name  string           `json:"name"`
-				Ttl   nulls.Int32      `json:"ttl"`
-				Type  string           `json:"type"`
-				Value rest.RecordValue `json:"value"`
+				Name    string           `json:"name"`
+				Ttl     nulls.Int32      `json:"ttl"`
+				Type    string           `json:"type"`
+				Value   rest.RecordValue `json:"value"`
+				AutoPTR bool             `json:"auto_ptr"`
+				Views   []string         `json:"views"`
 			}
 
 			err := json.NewDecoder(req.Body).Decode(&record)
 			if err != nil {
-				http.Error(rw, "Invalid request body", http.StatusBadRequest)
+				writeError(rw, http.StatusBadRequest, "Invalid request body")
 				return
 			}
 
 			if record.Ttl.Valid && record.Ttl.Int32 > ttlMaxOneWeek {
-				http.Error(rw, fmt.Sprintf("Invalid time to live, expected 'null or ttl <= %d seconds'", ttlMaxOneWeek), http.StatusBadRequest)
+				writeFieldError(rw, "ttl", fmt.Sprintf("Invalid time to live, expected 'null or ttl <= %d seconds'", ttlMaxOneWeek))
+				return
+			}
+
+			record.Views, err = views.Normalize(record.Views)
+			if err != nil {
+				writeFieldError(rw, "views", "Invalid views: "+err.Error())
+				return
+			}
+
+			record.Value, err = record.Value.ToASCII(record.Type)
+			if err != nil {
+				writeFieldError(rw, "value", "Invalid value: "+err.Error())
 				return
 			}
 
 			if !record.Value.IsValidForType(record.Type) {
-				http.Error(rw, "Invalid value for type", http.StatusBadRequest)
+				writeFieldError(rw, "value", "Invalid value for type")
+				return
+			}
+
+			if record.AutoPTR && record.Type != "A" && record.Type != "AAAA" {
+				writeFieldError(rw, "auto_ptr", "Invalid auto PTR: only A and AAAA records can keep a PTR record")
 				return
 			}
 
 			zoneId, err := getZoneId(req)
 			if err != nil {
-				http.Error(rw, "Invalid zone ID", http.StatusBadRequest)
+				writeFieldError(rw, "zone_id", "Invalid zone ID")
 				return
 			}
 
 			zone, err := db.GetZone(req.Context(), zoneId)
 			if err != nil {
-				http.Error(rw, "Invalid zone ID", http.StatusBadRequest)
+				writeFieldError(rw, "zone_id", "Invalid zone ID")
 				return
 			}
 
 			if !b.Claims.Perms.Has("domain:owns=" + zone.Name) {
-				http.NotFound(rw, req)
+				writeNotFound(rw)
 				return
 			}
 
-			genId, err := db.InsertRecordFromApi(req.Context(), database.InsertRecordFromApiParams{
-				Name:      record.Name,
-				ZoneID:    zoneId,
-				Type:      record.Type,
-				PreTtl:    record.Ttl,
-				PreValue:  record.Value.ToValueString(record.Type),
-				PreActive: true,
-			})
+			record.Name, err = utils.NormalizeRecordName(zone.Name, record.Type, record.Name)
 			if err != nil {
-				logger.Logger.Debug("Failed to insert record from API", "err", err)
-				http.Error(rw, "Database error occurred", http.StatusInternalServerError)
+				writeFieldError(rw, "name", "Invalid record name: "+err.Error())
 				return
 			}
 
-			json.NewEncoder(rw).Encode(rest.Record{
-				ID:     genId,
+			if !checkRecordPools(rw, req, db, zoneId, record.Name, record.Type) {
+				return
+			}
+
+			if !lintRecordChange(rw, req, db, zoneId, zone.Name, rest.Record{
 				Name:   record.Name,
 				ZoneID: zoneId,
 				Ttl:    record.Ttl,
 				Type:   record.Type,
-				Active: true,
 				Value:  record.Value,
+				Active: true,
+				Views:  record.Views,
+			}) {
+				return
+			}
+
+			host := recordHost(zone.Name, record.Name)
+			var genId int64
+			var ptrChanges []ptrChange
+			err = db.UseRecordTx(req.Context(), func(tx database.RecordTx) error {
+				ptrChanges, err = planAutoPTR(req.Context(), tx, b, host, netip.Addr{}, autoPTRAddress(record.AutoPTR, true, record.Value))
+				if err != nil {
+					return err
+				}
+				genId, err = tx.InsertRecordFromApi(req.Context(), database.InsertRecordFromApiParams{
+					Name:      record.Name,
+					ZoneID:    zoneId,
+					Type:      record.Type,
+					PreTtl:    record.Ttl,
+					PreValue:  record.Value.ToValueString(record.Type),
+					PreActive: true,
+					PreViews:  utils.JoinViews(record.Views),
+					AutoPtr:   record.AutoPTR,
+				})
+				if err != nil {
+					return err
+				}
+				return applyAutoPTR(req.Context(), tx, host, record.Ttl, ptrChanges)
 			})
+			var ptrErr autoPTRError
+			if errors.As(err, &ptrErr) {
+				writeAutoPTRError(rw, ptrErr)
+				return
+			}
+			if err != nil {
+				logger.Logger.Debug("Failed to insert record from API", "err", err)
+				writeError(rw, http.StatusInternalServerError, "Database error occurred")
+				return
+			}
+
+			created := rest.Record{
+				ID:      genId,
+				Name:    record.Name,
+				ZoneID:  zoneId,
+				Ttl:     record.Ttl,
+				Type:    record.Type,
+				Active:  true,
+				Value:   record.Value,
+				AutoPTR: record.AutoPTR,
+				Views:   record.Views,
+			}.WithUnicode()
+			events.Fire(zoneId, zone.Name, webhook.RecordCreated, created)
+			fireAutoPTR(events, host, record.Ttl, ptrChanges)
+
+			if wantsPropagationWait(req) {
+				waitForPropagation(rw, req, waiter, zone)
+			}
+
+			json.NewEncoder(rw).Encode(created)
 		}))
 
 		// Update record
 		r.Put("/{record_id:[0-9]+}", validateAuthToken(keystore, func(rw http.ResponseWriter, req *http.Request, b mjwt.BaseTypeClaims[auth.AccessTokenClaims]) {
 			var record struct {
-				Ttl    nulls.Int32      `json:"ttl"`
-				Active bool             `json:"active"`
-				Value  rest.RecordValue `json:"value"`
+				Ttl     nulls.Int32      `json:"ttl"`
+				Active  bool             `json:"active"`
+				Value   rest.RecordValue `json:"value"`
+				AutoPTR bool             `json:"auto_ptr"`
+				// Views is a pointer so the views are kept when it is missing
+				Views *[]string `json:"views"`
 			}
 
 			err := json.NewDecoder(req.Body).Decode(&record)
 			if err != nil {
-				http.Error(rw, "Invalid request body", http.StatusBadRequest)
+				writeError(rw, http.StatusBadRequest, "Invalid request body")
 				return
 			}
 
 			if record.Ttl.Valid && record.Ttl.Int32 > ttlMaxOneWeek {
-				http.Error(rw, fmt.Sprintf("Invalid time to live, expected 'null or ttl <= %d seconds'", ttlMaxOneWeek), http.StatusBadRequest)
+				writeFieldError(rw, "ttl", fmt.Sprintf("Invalid time to live, expected 'null or ttl <= %d seconds'", ttlMaxOneWeek))
 				return
 			}
 
+			var recordViews []string
+			if record.Views != nil {
+				recordViews, err = views.Normalize(*record.Views)
+				if err != nil {
+					writeFieldError(rw, "views", "Invalid views: "+err.Error())
+					return
+				}
+			}
+
 			zoneId, err := getZoneId(req)
 			if err != nil {
-				http.Error(rw, "Invalid zone ID", http.StatusBadRequest)
+				writeFieldError(rw, "zone_id", "Invalid zone ID")
 				return
 			}
 
 			recordId, err := getRecordId(req)
 			if err != nil {
-				http.Error(rw, "Invalid record ID", http.StatusBadRequest)
+				writeFieldError(rw, "record_id", "Invalid record ID")
 				return
 			}
 
@@ -246,57 +362,142 @@ func AddRecordRoutes(r chi.Router, db recordQueries, keystore *mjwt.KeyStore, na
 				RecordID: recordId,
 				ZoneID:   zoneId,
 			})
+			switch {
+			case errors.Is(err, sql.ErrNoRows):
+				writeNotFound(rw)
+				return
+			case err != nil:
+				logger.Logger.Error("Failed to get zone record", "err", err)
+				writeError(rw, http.StatusInternalServerError, "Database error occurred")
+				return
+			}
+
+			if record.Views == nil {
+				recordViews = utils.SplitViews(originalRecord.Record.PreViews)
+			}
+
+			record.Value, err = record.Value.ToASCII(originalRecord.Record.Type)
 			if err != nil {
-				logger.Logger.Debug("Failed to get zone record", "err", err)
-				http.Error(rw, "Database error occurred", http.StatusInternalServerError)
+				writeFieldError(rw, "value", "Invalid value: "+err.Error())
 				return
 			}
 
 			if !record.Value.IsValidForType(originalRecord.Record.Type) {
-				http.Error(rw, "Invalid value for type", http.StatusBadRequest)
+				writeFieldError(rw, "value", "Invalid value for type")
 				return
 			}
 
 			if !b.Claims.Perms.Has("domain:owns=" + originalRecord.Name) {
-				http.NotFound(rw, req)
+				writeNotFound(rw)
 				return
 			}
 
-			err = db.UpdateRecordFromApi(req.Context(), database.UpdateRecordFromApiParams{
-				PreTtl:    record.Ttl,
-				PreValue:  record.Value.ToValueString(originalRecord.Record.Type),
-				PreActive: record.Active,
-				ID:        recordId,
-				ZoneID:    zoneId,
-			})
-			if err != nil {
-				logger.Logger.Debug("Failed to update record from API", "err", err)
-				http.Error(rw, "Database error occurred", http.StatusInternalServerError)
+			if !checkIfMatch(rw, req, originalRecord.Record.Version, "record") {
+				return
+			}
+
+			if record.AutoPTR && originalRecord.Record.Type != "A" && originalRecord.Record.Type != "AAAA" {
+				writeFieldError(rw, "auto_ptr", "Invalid auto PTR: only A and AAAA records can keep a PTR record")
 				return
 			}
 
-			json.NewEncoder(rw).Encode(rest.Record{
+			if !lintRecordChange(rw, req, db, zoneId, originalRecord.Name, rest.Record{
 				ID:     recordId,
 				Name:   originalRecord.Record.Name,
 				ZoneID: zoneId,
 				Ttl:    record.Ttl,
 				Type:   originalRecord.Record.Type,
-				Active: record.Active,
 				Value:  record.Value,
+				Active: record.Active,
+				Views:  recordViews,
+			}) {
+				return
+			}
+
+			zone, ok := getZoneForPropagationWait(rw, req, db, zoneId)
+			if !ok {
+				return
+			}
+
+			host := recordHost(originalRecord.Name, originalRecord.Record.Name)
+			var oldPTR netip.Addr
+			if original, err := RecordToRestRecord(originalRecord.Record); err == nil {
+				oldPTR = autoPTRAddress(original.AutoPTR, original.Active, original.Value)
+			}
+			var ptrChanges []ptrChange
+			err = db.UseRecordTx(req.Context(), func(tx database.RecordTx) error {
+				ptrChanges, err = planAutoPTR(req.Context(), tx, b, host, oldPTR, autoPTRAddress(record.AutoPTR, record.Active, record.Value))
+				if err != nil {
+					return err
+				}
+				// the record is only updated when nobody else changed it since
+				// it was read
+				changed, err := tx.UpdateRecordIfVersion(req.Context(), database.UpdateRecordIfVersionParams{
+					PreTtl:    record.Ttl,
+					PreValue:  record.Value.ToValueString(originalRecord.Record.Type),
+					PreActive: record.Active,
+					AutoPtr:   record.AutoPTR,
+					PreViews:  utils.JoinViews(recordViews),
+					ID:        recordId,
+					ZoneID:    zoneId,
+					Version:   originalRecord.Record.Version,
+				})
+				if err != nil {
+					return err
+				}
+				if changed == 0 {
+					return errRecordModified
+				}
+				return applyAutoPTR(req.Context(), tx, host, record.Ttl, ptrChanges)
 			})
+			if errors.Is(err, errRecordModified) {
+				writeModified(rw, req, "record")
+				return
+			}
+			var ptrErr autoPTRError
+			if errors.As(err, &ptrErr) {
+				writeAutoPTRError(rw, ptrErr)
+				return
+			}
+			if err != nil {
+				logger.Logger.Debug("Failed to update record from API", "err", err)
+				writeError(rw, http.StatusInternalServerError, "Database error occurred")
+				return
+			}
+
+			updated := rest.Record{
+				ID:      recordId,
+				Name:    originalRecord.Record.Name,
+				ZoneID:  zoneId,
+				Ttl:     record.Ttl,
+				Type:    originalRecord.Record.Type,
+				Active:  record.Active,
+				Value:   record.Value,
+				AutoPTR: record.AutoPTR,
+				Views:   recordViews,
+			}.WithUnicode()
+			events.Fire(zoneId, originalRecord.Name, webhook.RecordUpdated, updated)
+			fireAutoPTR(events, host, record.Ttl, ptrChanges)
+
+			if wantsPropagationWait(req) {
+				waitForPropagation(rw, req, waiter, zone)
+			}
+
+			setETag(rw, originalRecord.Record.Version+1)
+			json.NewEncoder(rw).Encode(updated)
 		}))
 
 		// Delete record
 		r.Delete("/{record_id:[0-9]+}", validateAuthToken(keystore, func(rw http.ResponseWriter, req *http.Request, b mjwt.BaseTypeClaims[auth.AccessTokenClaims]) {
 			zoneId, err := getZoneId(req)
 			if err != nil {
-				http.Error(rw, "Invalid zone ID", http.StatusBadRequest)
+				writeFieldError(rw, "zone_id", "Invalid zone ID")
 				return
 			}
 
 			recordId, err := getRecordId(req)
 			if err != nil {
-				http.Error(rw, "Invalid record ID", http.StatusBadRequest)
+				writeFieldError(rw, "record_id", "Invalid record ID")
 				return
 			}
 
@@ -304,27 +505,111 @@ func AddRecordRoutes(r chi.Router, db recordQueries, keystore *mjwt.KeyStore, na
 				RecordID: recordId,
 				ZoneID:   zoneId,
 			})
+			switch {
+			case errors.Is(err, sql.ErrNoRows):
+				writeNotFound(rw)
+				return
+			case err != nil:
+				logger.Logger.Error("Failed to get zone record", "err", err)
+				writeError(rw, http.StatusInternalServerError, "Database error occurred")
+				return
+			}
 
 			if !b.Claims.Perms.Has("domain:owns=" + originalRecord.Name) {
-				http.NotFound(rw, req)
+				writeNotFound(rw)
 				return
 			}
 
-			err = db.DeleteRecordFromApi(req.Context(), database.DeleteRecordFromApiParams{
-				RecordID: recordId,
-				ZoneID:   zoneId,
+			if !checkIfMatch(rw, req, originalRecord.Record.Version, "record") {
+				return
+			}
+
+			zone, ok := getZoneForPropagationWait(rw, req, db, zoneId)
+			if !ok {
+				return
+			}
+
+			deleted, err := RecordToRestRecord(originalRecord.Record)
+			if err != nil {
+				writeError(rw, http.StatusInternalServerError, "Server error occurred")
+				return
+			}
+			host := recordHost(originalRecord.Name, originalRecord.Record.Name)
+			var ptrChanges []ptrChange
+			err = db.UseRecordTx(req.Context(), func(tx database.RecordTx) error {
+				ptrChanges, err = planAutoPTR(req.Context(), tx, b, host, autoPTRAddress(deleted.AutoPTR, deleted.Active, deleted.Value), netip.Addr{})
+				if err != nil {
+					return err
+				}
+				changed, err := tx.DeleteRecordIfVersion(req.Context(), database.DeleteRecordIfVersionParams{
+					RecordID: recordId,
+					ZoneID:   zoneId,
+					Version:  originalRecord.Record.Version,
+				})
+				if err != nil {
+					return err
+				}
+				if changed == 0 {
+					return errRecordModified
+				}
+				return applyAutoPTR(req.Context(), tx, host, nulls.Int32{}, ptrChanges)
 			})
+			if errors.Is(err, errRecordModified) {
+				writeModified(rw, req, "record")
+				return
+			}
+			var ptrErr autoPTRError
+			if errors.As(err, &ptrErr) {
+				writeAutoPTRError(rw, ptrErr)
+				return
+			}
 			if err != nil {
 				logger.Logger.Debug("Failed to delete record from API", "err", err)
-				http.Error(rw, "Database error occurred", http.StatusInternalServerError)
+				writeError(rw, http.StatusInternalServerError, "Database error occurred")
 				return
 			}
 
+			events.Fire(zoneId, originalRecord.Name, webhook.RecordDeleted, deleted)
+			fireAutoPTR(events, host, nulls.Int32{}, ptrChanges)
+
+			if wantsPropagationWait(req) {
+				waitForPropagation(rw, req, waiter, zone)
+			}
+
 			rw.WriteHeader(http.StatusOK)
 		}))
 	})
 }
 
+// attachHealth sets the failover status of the records with a health check
+func attachHealth(records []rest.Record, checks []database.HealthCheck) {
+	byRecord := make(map[int64]database.HealthCheck, len(checks))
+	for _, check := range checks {
+		byRecord[check.RecordID] = check
+	}
+	for i := range records {
+		if check, ok := byRecord[records[i].ID]; ok {
+			status := healthCheckStatus(check)
+			records[i].Health = &status
+		}
+	}
+}
+
+// getZoneForPropagationWait loads the zone before a change is written so the
+// serial can be compared once the change has been committed
+func getZoneForPropagationWait(rw http.ResponseWriter, req *http.Request, db recordQueries, zoneId int64) (database.Zone, bool) {
+	if !wantsPropagationWait(req) {
+		return database.Zone{}, true
+	}
+	zone, err := db.GetZone(req.Context(), zoneId)
+	if err != nil {
+		logger.Logger.Error("Failed to get zone", "err", err)
+		writeError(rw, http.StatusInternalServerError, "Database error occurred")
+		return database.Zone{}, false
+	}
+	return zone, true
+}
+
 func getRecordId(req *http.Request) (int64, error) {
 	recordIdRaw := chi.URLParam(req, "record_id")
 	return strconv.ParseInt(recordIdRaw, 10, 64)
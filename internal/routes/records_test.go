@@ -3,6 +3,7 @@ package routes
 import (
 	"context"
 	"database/sql"
+	"maps"
 	"net/http"
 	"net/http/httptest"
 	"strings"
@@ -14,6 +15,7 @@ import (
 	"github.com/1f349/mjwt/auth"
 	"github.com/1f349/verbena/conf"
 	"github.com/1f349/verbena/internal/database"
+	"github.com/1f349/verbena/internal/webhook"
 	"github.com/go-chi/chi/v5"
 	"github.com/gobuffalo/nulls"
 	"github.com/golang-jwt/jwt/v4"
@@ -21,8 +23,13 @@ import (
 )
 
 type recordTestQueries struct {
-	records map[int64]database.Record
-	nextId  atomic.Int64
+	records   map[int64]database.Record
+	nextId    atomic.Int64
+	zonePools []database.Pool
+}
+
+func (r *recordTestQueries) GetZonePools(ctx context.Context, zoneID int64) ([]database.Pool, error) {
+	return r.zonePools, nil
 }
 
 func (r *recordTestQueries) GetZoneRecords(ctx context.Context, zoneId int64) ([]database.GetZoneRecordsRow, error) {
@@ -85,6 +92,9 @@ func (r *recordTestQueries) InsertRecordFromApi(ctx context.Context, row databas
 		PreValue:  row.PreValue,
 		PreActive: row.PreActive,
 		PreDelete: false,
+		PreViews:  row.PreViews,
+		AutoPtr:   row.AutoPtr,
+		Version:   1,
 	}
 	return nextId, nil
 }
@@ -102,6 +112,7 @@ func (r *recordTestQueries) UpdateRecordFromApi(ctx context.Context, row databas
 	record.PreTtl = row.PreTtl
 	record.PreValue = row.PreValue
 	record.PreActive = row.PreActive
+	record.Version++
 
 	r.records[row.ID] = record
 
@@ -125,6 +136,82 @@ func (r *recordTestQueries) DeleteRecordFromApi(ctx context.Context, row databas
 	return nil
 }
 
+func (r *recordTestQueries) GetZoneHealthChecks(ctx context.Context, zoneID int64) ([]database.HealthCheck, error) {
+	return nil, nil
+}
+
+func (r *recordTestQueries) SetRecordViews(ctx context.Context, row database.SetRecordViewsParams) error {
+	if row.ZoneID != 3456 {
+		return sql.ErrNoRows
+	}
+
+	record, ok := r.records[row.ID]
+	if !ok {
+		return sql.ErrNoRows
+	}
+
+	record.PreViews = row.PreViews
+	record.Version++
+
+	r.records[row.ID] = record
+
+	return nil
+}
+
+func (r *recordTestQueries) UpdateRecordIfVersion(ctx context.Context, row database.UpdateRecordIfVersionParams) (int64, error) {
+	record, ok := r.records[row.ID]
+	if !ok || record.ZoneID != row.ZoneID || record.Version != row.Version || record.PreDelete {
+		return 0, nil
+	}
+
+	record.PreTtl = row.PreTtl
+	record.PreValue = row.PreValue
+	record.PreActive = row.PreActive
+	record.AutoPtr = row.AutoPtr
+	record.PreViews = row.PreViews
+	record.Version++
+
+	r.records[row.ID] = record
+
+	return 1, nil
+}
+
+func (r *recordTestQueries) DeleteRecordIfVersion(ctx context.Context, row database.DeleteRecordIfVersionParams) (int64, error) {
+	record, ok := r.records[row.RecordID]
+	if !ok || record.ZoneID != row.ZoneID || record.Version != row.Version || record.PreDelete {
+		return 0, nil
+	}
+
+	record.PreDelete = true
+
+	r.records[row.RecordID] = record
+
+	return 1, nil
+}
+
+func (r *recordTestQueries) GetReverseZones(ctx context.Context) ([]database.Zone, error) {
+	return nil, nil
+}
+
+// UseRecordTx restores the records when the callback fails to act like a
+// rolled back transaction
+func (r *recordTestQueries) UseRecordTx(ctx context.Context, cb func(tx database.RecordTx) error) error {
+	saved := maps.Clone(r.records)
+	err := cb(r)
+	if err != nil {
+		r.records = saved
+	}
+	return err
+}
+
+type recordTestEvents struct {
+	events []webhook.Event
+}
+
+func (r *recordTestEvents) Fire(zoneID int64, zoneName string, event webhook.Event, data any) {
+	r.events = append(r.events, event)
+}
+
 func TestAddRecordRoutes(t *testing.T) {
 	r := chi.NewRouter()
 	issuer, err := mjwt.NewIssuer("hello world", "1", jwt.SigningMethodRS256)
@@ -134,7 +221,8 @@ func TestAddRecordRoutes(t *testing.T) {
 	q := &recordTestQueries{
 		records: make(map[int64]database.Record),
 	}
-	AddRecordRoutes(r, q, issuer.KeyStore(), conf.NameserverConf{})
+	events := &recordTestEvents{}
+	AddRecordRoutes(r, q, issuer.KeyStore(), conf.NameserverConf{}, nil, &propagationTestTracker{}, events)
 	_, err = q.InsertRecordFromApi(t.Context(), database.InsertRecordFromApiParams{
 		Name:      "",
 		ZoneID:    3456,
@@ -259,6 +347,54 @@ func TestAddRecordRoutes(t *testing.T) {
 		assert.Equal(t, "{\"id\":2,\"name\":\"test\",\"zone_id\":3456,\"ttl\":null,\"type\":\"AAAA\",\"value\":{\"ip\":\"2001:db8::6\"},\"active\":true}\n", rec.Body.String())
 	})
 
+	t.Run("POST /zones/3456/records invalid name", func(t *testing.T) {
+		ps := auth.NewPermStorage()
+		ps.Set("domain:owns=example.com")
+		token, err := issuer.GenerateJwt("1234", "", jwt.ClaimStrings{}, time.Hour, auth.AccessTokenClaims{Perms: ps})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, i := range []struct {
+			body string
+			err  string
+		}{
+			{`{"name":"www.example.org.","type":"A","value":{"ip":"192.0.2.1"}}`, "{\"code\":\"validation_failed\",\"message\":\"Invalid record name: www.example.org. is not inside the zone example.com\",\"field\":\"name\"}\n"},
+			{`{"name":"my host","type":"A","value":{"ip":"192.0.2.1"}}`, "{\"code\":\"validation_failed\",\"message\":\"Invalid record name: label my host contains the invalid character ' '\",\"field\":\"name\"}\n"},
+			{`{"name":"sip","type":"SRV","value":{"priority":10,"weight":5,"port":5060,"target":"sip.example.com"}}`, "{\"code\":\"validation_failed\",\"message\":\"Invalid record name: SRV records must start with two underscore labels such as _service._tcp\",\"field\":\"name\"}\n"},
+		} {
+			rec := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodPost, "/zones/3456/records", strings.NewReader(i.body))
+			req.Header.Set("Authorization", "Bearer "+token)
+			r.ServeHTTP(rec, req)
+			assert.Equal(t, http.StatusBadRequest, rec.Code)
+			assert.Equal(t, i.err, rec.Body.String())
+		}
+	})
+
+	t.Run("POST /zones/3456/records?wait=propagated", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/zones/3456/records?wait=propagated", strings.NewReader(`{
+	"name": "wait",
+	"ttl": null,
+	"type": "A",
+	"value": {
+		"ip": "192.0.2.1"
+	}
+}`))
+		ps := auth.NewPermStorage()
+		ps.Set("domain:owns=example.com")
+		token, err := issuer.GenerateJwt("1234", "", jwt.ClaimStrings{}, time.Hour, auth.AccessTokenClaims{Perms: ps})
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+		r.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "complete", rec.Header().Get("Verbena-Propagation"))
+		assert.Equal(t, "{\"id\":3,\"name\":\"wait\",\"zone_id\":3456,\"ttl\":null,\"type\":\"A\",\"value\":{\"ip\":\"192.0.2.1\"},\"active\":true}\n", rec.Body.String())
+	})
+
 	t.Run("PUT /zones/3456/records/2", func(t *testing.T) {
 		rec := httptest.NewRecorder()
 		req := httptest.NewRequest(http.MethodPut, "/zones/3456/records/2", nil)
@@ -301,6 +437,14 @@ func TestAddRecordRoutes(t *testing.T) {
 		r.ServeHTTP(rec, req)
 		assert.Equal(t, http.StatusOK, rec.Code)
 		assert.Equal(t, "{\"id\":2,\"name\":\"test\",\"zone_id\":3456,\"ttl\":null,\"type\":\"AAAA\",\"value\":{\"ip\":\"2001:db8::7\"},\"active\":true}\n", rec.Body.String())
+
+		// missing records are not found rather than a database error
+		rec = httptest.NewRecorder()
+		req = httptest.NewRequest(http.MethodPut, "/zones/3456/records/99", strings.NewReader(`{"ttl":null,"value":{"ip":"2001:db8::7"},"active":true}`))
+		req.Header.Set("Authorization", "Bearer "+token)
+		r.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+		assert.Equal(t, "{\"code\":\"not_found\",\"message\":\"Not found\"}\n", rec.Body.String())
 	})
 
 	t.Run("DELETE /zones/3456/records/2", func(t *testing.T) {
@@ -333,5 +477,157 @@ func TestAddRecordRoutes(t *testing.T) {
 		r.ServeHTTP(rec, req)
 		assert.Equal(t, http.StatusOK, rec.Code)
 		assert.Equal(t, "", rec.Body.String())
+
+		// missing records are not found rather than a database error
+		rec = httptest.NewRecorder()
+		req = httptest.NewRequest(http.MethodDelete, "/zones/3456/records/99", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		r.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+		assert.Equal(t, "{\"code\":\"not_found\",\"message\":\"Not found\"}\n", rec.Body.String())
+
+		// a stored value which cannot be parsed is not deleted without an event
+		q.records[98] = database.Record{ID: 98, ZoneID: 3456, Name: "broken", Type: "A", PreValue: "not an address", PreActive: true}
+		rec = httptest.NewRecorder()
+		req = httptest.NewRequest(http.MethodDelete, "/zones/3456/records/98", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		r.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusInternalServerError, rec.Code)
+		assert.False(t, q.records[98].PreDelete)
+		delete(q.records, 98)
 	})
+
+	assert.Equal(t, []webhook.Event{webhook.RecordCreated, webhook.RecordCreated, webhook.RecordUpdated, webhook.RecordDeleted}, events.events)
+}
+
+func TestAddRecordRoutesViews(t *testing.T) {
+	r := chi.NewRouter()
+	issuer, err := mjwt.NewIssuer("hello world", "1", jwt.SigningMethodRS256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	q := &recordTestQueries{
+		records: make(map[int64]database.Record),
+	}
+	views := conf.ViewsConf{
+		{Name: "internal", MatchClients: []string{"10.0.0.0/8"}},
+		{Name: "external", MatchClients: []string{"any"}},
+	}
+	AddRecordRoutes(r, q, issuer.KeyStore(), conf.NameserverConf{}, views, &propagationTestTracker{}, &recordTestEvents{})
+
+	ps := auth.NewPermStorage()
+	ps.Set("domain:owns=example.com")
+	token, err := issuer.GenerateJwt("1234", "", jwt.ClaimStrings{}, time.Hour, auth.AccessTokenClaims{Perms: ps})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/zones/3456/records", strings.NewReader(`{"name":"www","type":"A","value":{"ip":"192.0.2.1"},"views":["External","internal","external"]}`))
+	req.Header.Set("Authorization", "Bearer "+token)
+	r.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "{\"id\":1,\"name\":\"www\",\"zone_id\":3456,\"ttl\":null,\"type\":\"A\",\"value\":{\"ip\":\"192.0.2.1\"},\"active\":true,\"views\":[\"external\",\"internal\"]}\n", rec.Body.String())
+	assert.Equal(t, "external,internal", q.records[1].PreViews)
+
+	rec = httptest.NewRecorder()
+	req = httptest.NewRequest(http.MethodPost, "/zones/3456/records", strings.NewReader(`{"name":"www","type":"A","value":{"ip":"192.0.2.2"},"views":["guest"]}`))
+	req.Header.Set("Authorization", "Bearer "+token)
+	r.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Equal(t, "{\"code\":\"validation_failed\",\"message\":\"Invalid views: unknown view \\\"guest\\\"\",\"field\":\"views\"}\n", rec.Body.String())
+
+	// the record is moved to the external view so a CNAME can be added in the
+	// internal view
+	rec = httptest.NewRecorder()
+	req = httptest.NewRequest(http.MethodPut, "/zones/3456/records/1", strings.NewReader(`{"value":{"ip":"192.0.2.1"},"active":true,"views":["external"]}`))
+	req.Header.Set("Authorization", "Bearer "+token)
+	r.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "external", q.records[1].PreViews)
+
+	// a PUT without views keeps the existing views
+	rec = httptest.NewRecorder()
+	req = httptest.NewRequest(http.MethodPut, "/zones/3456/records/1", strings.NewReader(`{"ttl":300,"value":{"ip":"192.0.2.1"},"active":true}`))
+	req.Header.Set("Authorization", "Bearer "+token)
+	r.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "external", q.records[1].PreViews)
+	assert.Equal(t, "{\"id\":1,\"name\":\"www\",\"zone_id\":3456,\"ttl\":300,\"type\":\"A\",\"value\":{\"ip\":\"192.0.2.1\"},\"active\":true,\"views\":[\"external\"]}\n", rec.Body.String())
+
+	rec = httptest.NewRecorder()
+	req = httptest.NewRequest(http.MethodPost, "/zones/3456/records", strings.NewReader(`{"name":"www","type":"CNAME","value":{"target":"internal.example.net"},"views":["internal"]}`))
+	req.Header.Set("Authorization", "Bearer "+token)
+	r.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	rec = httptest.NewRecorder()
+	req = httptest.NewRequest(http.MethodPost, "/zones/3456/records", strings.NewReader(`{"name":"www","type":"TXT","value":{"text":"hello"}}`))
+	req.Header.Set("Authorization", "Bearer "+token)
+	r.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusConflict, rec.Code)
+}
+
+func TestAddRecordRoutesETag(t *testing.T) {
+	r := chi.NewRouter()
+	issuer, err := mjwt.NewIssuer("hello world", "1", jwt.SigningMethodRS256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	q := &recordTestQueries{
+		records: make(map[int64]database.Record),
+	}
+	events := &recordTestEvents{}
+	AddRecordRoutes(r, q, issuer.KeyStore(), conf.NameserverConf{}, nil, &propagationTestTracker{}, events)
+	_, err = q.InsertRecordFromApi(t.Context(), database.InsertRecordFromApiParams{Name: "www", ZoneID: 3456, Type: "A", PreValue: "192.0.2.1", PreActive: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ps := auth.NewPermStorage()
+	ps.Set("domain:owns=example.com")
+	token, err := issuer.GenerateJwt("1234", "", jwt.ClaimStrings{}, time.Hour, auth.AccessTokenClaims{Perms: ps})
+	if err != nil {
+		t.Fatal(err)
+	}
+	do := func(method, path, ifMatch, body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+token)
+		if ifMatch != "" {
+			req.Header.Set("If-Match", ifMatch)
+		}
+		r.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := do(http.MethodGet, "/zones/3456/records/1", "", "")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, `"1"`, rec.Header().Get("ETag"))
+
+	// the first editor wins and the second is told the record changed
+	rec = do(http.MethodPut, "/zones/3456/records/1", `"1"`, `{"value":{"ip":"192.0.2.2"},"active":true}`)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, `"2"`, rec.Header().Get("ETag"))
+	rec = do(http.MethodPut, "/zones/3456/records/1", `"1"`, `{"value":{"ip":"192.0.2.3"},"active":true}`)
+	assert.Equal(t, http.StatusPreconditionFailed, rec.Code)
+	assert.Equal(t, "{\"code\":\"precondition_failed\",\"message\":\"Precondition failed: the record has been modified\"}\n", rec.Body.String())
+	assert.Equal(t, "192.0.2.2", q.records[1].PreValue)
+
+	// weak tags never match
+	rec = do(http.MethodPut, "/zones/3456/records/1", `W/"2"`, `{"value":{"ip":"192.0.2.3"},"active":true}`)
+	assert.Equal(t, http.StatusPreconditionFailed, rec.Code)
+
+	rec = do(http.MethodPut, "/zones/3456/records/1", "*", `{"value":{"ip":"192.0.2.3"},"active":true}`)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, `"3"`, rec.Header().Get("ETag"))
+
+	rec = do(http.MethodDelete, "/zones/3456/records/1", `"2"`, "")
+	assert.Equal(t, http.StatusPreconditionFailed, rec.Code)
+	assert.False(t, q.records[1].PreDelete)
+
+	rec = do(http.MethodDelete, "/zones/3456/records/1", `"3"`, "")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.True(t, q.records[1].PreDelete)
+	assert.Equal(t, []webhook.Event{webhook.RecordUpdated, webhook.RecordUpdated, webhook.RecordDeleted}, events.events)
 }
@@ -0,0 +1,688 @@
+package routes
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/netip"
+	"strings"
+	"time"
+
+	"github.com/1f349/mjwt"
+	"github.com/1f349/mjwt/auth"
+	"github.com/1f349/verbena/conf"
+	"github.com/1f349/verbena/internal/database"
+	"github.com/1f349/verbena/internal/delegation"
+	"github.com/1f349/verbena/internal/reverse"
+	"github.com/1f349/verbena/internal/utils"
+	"github.com/1f349/verbena/internal/webhook"
+	"github.com/1f349/verbena/logger"
+	"github.com/1f349/verbena/rest"
+	"github.com/go-chi/chi/v5"
+	"github.com/gobuffalo/nulls"
+)
+
+// SOA timers of new reverse zones, these can be changed with the zone update
+// route afterwards
+const (
+	reverseZoneRefresh = 2 * 60 * 60
+	reverseZoneRetry   = 60 * 60
+	reverseZoneExpire  = oneDaySeconds * 14
+	reverseZoneTtl     = 60 * 60
+)
+
+type reverseQueries interface {
+	GetZone(ctx context.Context, zoneId int64) (database.Zone, error)
+	LookupZone(ctx context.Context, name string) (int64, error)
+	GetZoneRecords(ctx context.Context, zoneId int64) ([]database.GetZoneRecordsRow, error)
+	UseZoneTx(ctx context.Context, cb func(tx database.ZoneTx) error) error
+	UseRecordTx(ctx context.Context, cb func(tx database.RecordTx) error) error
+}
+
+// zoneExistsError is returned from the transaction when a reverse zone has
+// already been created
+type zoneExistsError struct {
+	name string
+}
+
+func (e zoneExistsError) Error() string {
+	return "Zone " + e.name + " already exists"
+}
+
+// classlessConflictError is returned from the transaction when a record is in
+// the way of the classless delegation
+type classlessConflictError struct {
+	name string
+}
+
+func (e classlessConflictError) Error() string {
+	return "Delegation conflicts with the existing record " + e.name
+}
+
+func AddReverseRoutes(r chi.Router, db reverseQueries, keystore *mjwt.KeyStore, nameservers conf.NameserverConf, events eventFirer) {
+	// Create the reverse zones covering a prefix
+	r.Post("/zones/reverse", validateAuthToken(keystore, func(rw http.ResponseWriter, req *http.Request, b mjwt.BaseTypeClaims[auth.AccessTokenClaims]) {
+		var createZone rest.CreateReverseZone
+		err := json.NewDecoder(req.Body).Decode(&createZone)
+		if err != nil {
+			writeError(rw, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+
+		names, err := reverse.ZoneNames(createZone.Prefix)
+		if err != nil {
+			writeFieldError(rw, "prefix", "Invalid prefix: "+err.Error())
+			return
+		}
+
+		admin := strings.TrimSuffix(strings.ToLower(createZone.Admin), ".")
+		if admin != "" && !utils.ValidateDomainName(admin) {
+			writeFieldError(rw, "admin", "Invalid admin mailbox")
+			return
+		}
+
+		for _, name := range names {
+			if !b.Claims.Perms.Has("domain:owns=" + name) {
+				writeError(rw, http.StatusForbidden, "Missing permission for zone "+name)
+				return
+			}
+		}
+
+		zones := make([]rest.Zone, 0, len(names))
+		err = db.UseZoneTx(req.Context(), func(tx database.ZoneTx) error {
+			for _, name := range names {
+				_, err := tx.LookupZone(req.Context(), name)
+				if err == nil {
+					return zoneExistsError{name}
+				}
+				if !errors.Is(err, sql.ErrNoRows) {
+					return err
+				}
+
+				zone := database.Zone{
+					Name:    name,
+					Serial:  initialSerial(time.Now()),
+					Admin:   admin,
+					Refresh: reverseZoneRefresh,
+					Retry:   reverseZoneRetry,
+					Expire:  reverseZoneExpire,
+					Ttl:     reverseZoneTtl,
+					Active:  true,
+				}
+				if zone.Admin == "" {
+					zone.Admin = "hostmaster." + name
+				}
+				zone.ID, err = tx.InsertZone(req.Context(), database.InsertZoneParams{
+					Name:    zone.Name,
+					Serial:  zone.Serial,
+					Admin:   zone.Admin,
+					Refresh: zone.Refresh,
+					Retry:   zone.Retry,
+					Expire:  zone.Expire,
+					Ttl:     zone.Ttl,
+				})
+				if err != nil {
+					return err
+				}
+				err = tx.InsertOwner(req.Context(), database.InsertOwnerParams{
+					ZoneID: zone.ID,
+					UserID: b.Subject,
+				})
+				if err != nil {
+					return err
+				}
+				zones = append(zones, ZoneToRestZone(zone, nameservers.GetNameserversForZone(zone)))
+			}
+			return nil
+		})
+		var existsErr zoneExistsError
+		if errors.As(err, &existsErr) {
+			writeError(rw, http.StatusConflict, existsErr.Error())
+			return
+		}
+		if err != nil {
+			logger.Logger.Debug("Failed to create reverse zones", "err", err)
+			writeError(rw, http.StatusInternalServerError, "Database error occurred")
+			return
+		}
+
+		json.NewEncoder(rw).Encode(zones)
+	}))
+
+	r.Route("/zones/{zone_id:[0-9]+}/classless", func(r chi.Router) {
+		// List the classless delegations of a /24 reverse zone
+		r.Get("/", validateAuthToken(keystore, func(rw http.ResponseWriter, req *http.Request, b mjwt.BaseTypeClaims[auth.AccessTokenClaims]) {
+			zone, ok := getOwnedZone(rw, req, db, b)
+			if !ok {
+				return
+			}
+
+			parent, ok := getClasslessParent(rw, zone)
+			if !ok {
+				return
+			}
+
+			records, ok := getRestZoneRecords(rw, req, db, zone.ID)
+			if !ok {
+				return
+			}
+
+			out := []rest.ClasslessDelegation{}
+			for _, d := range delegation.FromRecords(zone.Name, records) {
+				c, err := reverse.ParseClassless(parent, d.Name)
+				if err != nil {
+					continue
+				}
+				_, err = db.LookupZone(req.Context(), c.ZoneName())
+				out = append(out, rest.ClasslessDelegation{
+					Name:        c.Label(),
+					Prefix:      c.Prefix,
+					Zone:        c.ZoneName(),
+					Nameservers: d.Nameservers,
+					Automatic:   err == nil,
+				})
+			}
+			json.NewEncoder(rw).Encode(out)
+		}))
+
+		// Create or replace a classless delegation
+		r.Put("/{name}", validateAuthToken(keystore, func(rw http.ResponseWriter, req *http.Request, b mjwt.BaseTypeClaims[auth.AccessTokenClaims]) {
+			var putDelegation rest.PutClasslessDelegation
+			err := json.NewDecoder(req.Body).Decode(&putDelegation)
+			if err != nil {
+				writeError(rw, http.StatusBadRequest, "Invalid request body")
+				return
+			}
+
+			if putDelegation.Ttl.Valid && putDelegation.Ttl.Int32 > ttlMaxOneWeek {
+				writeFieldError(rw, "ttl", fmt.Sprintf("Invalid time to live, expected 'null or ttl <= %d seconds'", ttlMaxOneWeek))
+				return
+			}
+
+			zone, ok := getOwnedZone(rw, req, db, b)
+			if !ok {
+				return
+			}
+
+			c, ok := getClassless(rw, req, zone)
+			if !ok {
+				return
+			}
+
+			// the nameservers of a Verbena child zone are known
+			out := rest.ClasslessDelegation{
+				Name:        c.Label(),
+				Prefix:      c.Prefix,
+				Zone:        c.ZoneName(),
+				Nameservers: []string{},
+			}
+			childId, err := db.LookupZone(req.Context(), c.ZoneName())
+			switch {
+			case err == nil:
+				out.Automatic = true
+				if len(putDelegation.Nameservers) == 0 {
+					child, err := db.GetZone(req.Context(), childId)
+					if err != nil {
+						logger.Logger.Error("Failed to get child zone", "err", err)
+						writeError(rw, http.StatusInternalServerError, "Database error occurred")
+						return
+					}
+					putDelegation.Nameservers = nameservers.GetNameserversForZone(child)
+				}
+			case !errors.Is(err, sql.ErrNoRows):
+				logger.Logger.Error("Failed to lookup child zone", "err", err)
+				writeError(rw, http.StatusInternalServerError, "Database error occurred")
+				return
+			}
+
+			nsDelegation, err := rest.PutDelegation{Nameservers: putDelegation.Nameservers}.ToASCII()
+			if err != nil {
+				writeError(rw, http.StatusBadRequest, "Invalid classless delegation: "+err.Error())
+				return
+			}
+			err = delegation.Validate(zone.Name, c.Label(), nsDelegation)
+			if err != nil {
+				writeError(rw, http.StatusBadRequest, "Invalid classless delegation: "+err.Error())
+				return
+			}
+
+			var changes []recordChange
+			err = db.UseRecordTx(req.Context(), func(tx database.RecordTx) error {
+				before, err := zoneRecordsInTx(req.Context(), tx, zone.ID)
+				if err != nil {
+					return err
+				}
+				existing := classlessRecords(c, before)
+				if conflict := classlessConflict(zone.Name, c, before, existing); conflict != "" {
+					return classlessConflictError{conflict}
+				}
+
+				// records with a different ttl are replaced
+				var kept, remove []rest.Record
+				for _, record := range existing {
+					if record.Ttl != putDelegation.Ttl {
+						remove = append(remove, record)
+						continue
+					}
+					kept = append(kept, record)
+				}
+				create, stale := delegation.Diff(kept, classlessWantedRecords(zone.Name, c, nsDelegation, putDelegation))
+				remove = append(remove, stale...)
+
+				for _, record := range remove {
+					err = tx.DeleteRecordFromApi(req.Context(), database.DeleteRecordFromApiParams{
+						RecordID: record.ID,
+						ZoneID:   zone.ID,
+					})
+					if err != nil {
+						return err
+					}
+					changes = append(changes, recordChange{webhook.RecordDeleted, record})
+				}
+				for _, record := range create {
+					id, err := tx.InsertRecordFromApi(req.Context(), database.InsertRecordFromApiParams{
+						Name:      record.Name,
+						ZoneID:    zone.ID,
+						Type:      record.Type,
+						PreTtl:    record.Ttl,
+						PreValue:  record.Value.ToValueString(record.Type),
+						PreActive: true,
+					})
+					if err != nil {
+						return err
+					}
+					changes = append(changes, recordChange{webhook.RecordCreated, rest.Record{
+						ID:     id,
+						Name:   record.Name,
+						ZoneID: zone.ID,
+						Ttl:    record.Ttl,
+						Type:   record.Type,
+						Value:  record.Value,
+						Active: true,
+					}})
+				}
+				return lintTx(req.Context(), tx, zone, before)
+			})
+			var conflictErr classlessConflictError
+			if errors.As(err, &conflictErr) {
+				writeError(rw, http.StatusConflict, conflictErr.Error())
+				return
+			}
+			var lintErr lintFailure
+			if errors.As(err, &lintErr) {
+				writeLintFailure(rw, lintErr)
+				return
+			}
+			if err != nil {
+				logger.Logger.Debug("Failed to update classless delegation", "err", err)
+				writeError(rw, http.StatusInternalServerError, "Database error occurred")
+				return
+			}
+
+			for _, change := range changes {
+				events.Fire(zone.ID, zone.Name, change.event, change.record)
+			}
+
+			for _, ns := range nsDelegation.Nameservers {
+				out.Nameservers = append(out.Nameservers, strings.TrimSuffix(strings.ToLower(ns), "."))
+			}
+			json.NewEncoder(rw).Encode(out)
+		}))
+
+		// Delete a classless delegation
+		r.Delete("/{name}", validateAuthToken(keystore, func(rw http.ResponseWriter, req *http.Request, b mjwt.BaseTypeClaims[auth.AccessTokenClaims]) {
+			zone, ok := getOwnedZone(rw, req, db, b)
+			if !ok {
+				return
+			}
+
+			c, ok := getClassless(rw, req, zone)
+			if !ok {
+				return
+			}
+
+			var existing []rest.Record
+			err := db.UseRecordTx(req.Context(), func(tx database.RecordTx) error {
+				records, err := zoneRecordsInTx(req.Context(), tx, zone.ID)
+				if err != nil {
+					return err
+				}
+				existing = classlessRecords(c, records)
+				for _, record := range existing {
+					err = tx.DeleteRecordFromApi(req.Context(), database.DeleteRecordFromApiParams{
+						RecordID: record.ID,
+						ZoneID:   zone.ID,
+					})
+					if err != nil {
+						return err
+					}
+				}
+				return nil
+			})
+			if err != nil {
+				logger.Logger.Debug("Failed to delete classless delegation", "err", err)
+				writeError(rw, http.StatusInternalServerError, "Database error occurred")
+				return
+			}
+
+			if len(existing) == 0 {
+				writeNotFound(rw)
+				return
+			}
+
+			for _, record := range existing {
+				events.Fire(zone.ID, zone.Name, webhook.RecordDeleted, record)
+			}
+
+			rw.WriteHeader(http.StatusOK)
+		}))
+	})
+}
+
+// initialSerial returns the first serial of the day in the same format used
+// when the serial is updated
+func initialSerial(now time.Time) int64 {
+	y, m, d := now.UTC().Date()
+	return int64(y*10000+int(m)*100+d)*100 + 1
+}
+
+// getClasslessParent returns the prefix of a /24 reverse zone, an error
+// response is written when false is returned
+func getClasslessParent(rw http.ResponseWriter, zone database.Zone) (netip.Prefix, bool) {
+	parent, err := reverse.ParseZoneName(zone.Name)
+	if err != nil || !parent.Addr().Is4() || parent.Bits() != 24 {
+		writeError(rw, http.StatusBadRequest, "Classless delegation is only possible from a /24 reverse zone")
+		return netip.Prefix{}, false
+	}
+	return parent, true
+}
+
+// getClassless reads the delegated block from the URL, an error response is
+// written when false is returned
+func getClassless(rw http.ResponseWriter, req *http.Request, zone database.Zone) (reverse.Classless, bool) {
+	parent, ok := getClasslessParent(rw, zone)
+	if !ok {
+		return reverse.Classless{}, false
+	}
+	c, err := reverse.ParseClassless(parent, chi.URLParam(req, "name"))
+	if err != nil {
+		writeError(rw, http.StatusBadRequest, "Invalid classless delegation: "+err.Error())
+		return reverse.Classless{}, false
+	}
+	return c, true
+}
+
+// classlessRecords returns the NS records of the delegation along with the
+// CNAME records pointing into the child zone
+func classlessRecords(c reverse.Classless, records []rest.Record) []rest.Record {
+	label := c.Label()
+	targets := make(map[string]string)
+	for _, alias := range c.Aliases() {
+		targets[alias.Name] = alias.Target
+	}
+
+	var out []rest.Record
+	for _, r := range records {
+		name := strings.ToLower(r.Name)
+		switch {
+		case r.Type == "NS" && name == label:
+			out = append(out, r)
+		case r.Type == "CNAME" && targets[name] != "" && strings.TrimSuffix(strings.ToLower(r.Value.Target), ".") == targets[name]:
+			out = append(out, r)
+		}
+	}
+	return out
+}
+
+// classlessConflict returns the name of the first record using one of the
+// addresses of the block or hidden below the delegation
+func classlessConflict(zoneName string, c reverse.Classless, records, existing []rest.Record) string {
+	if occluded := occludedRecords(zoneName, c.Label(), records, existing, rest.PutDelegation{}); occluded != "" {
+		return occluded
+	}
+
+	part := make(map[int64]bool, len(existing))
+	for _, r := range existing {
+		part[r.ID] = true
+	}
+	aliases := make(map[string]bool)
+	for _, alias := range c.Aliases() {
+		aliases[alias.Name] = true
+	}
+	for _, r := range records {
+		if !part[r.ID] && aliases[strings.ToLower(r.Name)] {
+			return delegation.ChildName(zoneName, strings.ToLower(r.Name))
+		}
+	}
+	return ""
+}
+
+func classlessWantedRecords(zoneName string, c reverse.Classless, nsDelegation rest.PutDelegation, putDelegation rest.PutClasslessDelegation) []rest.CreateRecord {
+	records := delegation.ToRecords(zoneName, c.Label(), nsDelegation)
+	for i := range records {
+		records[i].Ttl = putDelegation.Ttl
+	}
+	for _, alias := range c.Aliases() {
+		records = append(records, rest.CreateRecord{
+			Name:   alias.Name,
+			Ttl:    putDelegation.Ttl,
+			Type:   "CNAME",
+			Value:  rest.RecordValue{Target: alias.Target},
+			Active: true,
+		})
+	}
+	return records
+}
+
+type autoPTRQueries interface {
+	GetReverseZones(ctx context.Context) ([]database.Zone, error)
+	GetZoneRecords(ctx context.Context, zoneId int64) ([]database.GetZoneRecordsRow, error)
+	InsertRecordFromApi(ctx context.Context, row database.InsertRecordFromApiParams) (int64, error)
+	DeleteRecordFromApi(ctx context.Context, row database.DeleteRecordFromApiParams) error
+}
+
+// autoPTRError is a problem with the PTR record kept for an address record
+type autoPTRError struct {
+	status int
+	msg    string
+}
+
+func (e autoPTRError) Error() string {
+	return e.msg
+}
+
+// autoPTRKept is returned when a record which keeps a PTR record would be
+// changed by a route which does not maintain the PTR record
+func autoPTRKept(id int64) autoPTRError {
+	return autoPTRError{http.StatusConflict, fmt.Sprintf("the record %d keeps a PTR record, change it with the record routes", id)}
+}
+
+// ptrChange is the PTR record to add or remove for one address in a reverse
+// zone managed by Verbena
+type ptrChange struct {
+	zone   database.Zone
+	name   string
+	remove []rest.Record
+	create bool
+	// id is the created PTR record once the change has been applied
+	id int64
+}
+
+// planAutoPTR finds the PTR changes needed when the address of host changes
+// from old to new, an invalid address means there is no PTR record for that
+// side of the change. Addresses without a Verbena reverse zone are skipped
+// and stale PTR records are only removed from zones owned by the token.
+func planAutoPTR(ctx context.Context, db autoPTRQueries, b mjwt.BaseTypeClaims[auth.AccessTokenClaims], host string, old, new netip.Addr) ([]ptrChange, error) {
+	if !old.IsValid() && !new.IsValid() {
+		return nil, nil
+	}
+	zones, err := db.GetReverseZones(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var changes []ptrChange
+	if old.IsValid() && old != new {
+		zone, ok := findReverseZone(zones, old)
+		if ok && b.Claims.Perms.Has("domain:owns="+zone.Name) {
+			name, _ := reverse.RecordName(zone.Name, old)
+			records, err := zoneRecordsInTx(ctx, db, zone.ID)
+			if err != nil {
+				return nil, err
+			}
+			change := ptrChange{zone: zone, name: name}
+			for _, r := range ptrRecords(records, name) {
+				if sameHost(r.Value.Target, host) {
+					change.remove = append(change.remove, r)
+				}
+			}
+			if len(change.remove) > 0 {
+				changes = append(changes, change)
+			}
+		}
+	}
+
+	if new.IsValid() {
+		zone, ok := findReverseZone(zones, new)
+		if !ok {
+			return changes, nil
+		}
+		if !b.Claims.Perms.Has("domain:owns=" + zone.Name) {
+			return nil, autoPTRError{http.StatusBadRequest, "missing permission for the reverse zone " + zone.Name}
+		}
+		name, _ := reverse.RecordName(zone.Name, new)
+		records, err := zoneRecordsInTx(ctx, db, zone.ID)
+		if err != nil {
+			return nil, err
+		}
+		create := true
+		for _, r := range ptrRecords(records, name) {
+			if !sameHost(r.Value.Target, host) {
+				return nil, autoPTRError{http.StatusConflict, fmt.Sprintf("the PTR record for %s already points at %s", new, r.Value.Target)}
+			}
+			create = false
+		}
+		if create {
+			changes = append(changes, ptrChange{zone: zone, name: name, create: true})
+		}
+	}
+	return changes, nil
+}
+
+// applyAutoPTR stages the PTR changes in the reverse zones, the ID of each
+// created PTR record is stored in its change
+func applyAutoPTR(ctx context.Context, tx autoPTRQueries, host string, ttl nulls.Int32, changes []ptrChange) error {
+	for i, change := range changes {
+		for _, record := range change.remove {
+			err := tx.DeleteRecordFromApi(ctx, database.DeleteRecordFromApiParams{
+				RecordID: record.ID,
+				ZoneID:   change.zone.ID,
+			})
+			if err != nil {
+				return err
+			}
+		}
+		if !change.create {
+			continue
+		}
+		id, err := tx.InsertRecordFromApi(ctx, database.InsertRecordFromApiParams{
+			Name:      change.name,
+			ZoneID:    change.zone.ID,
+			Type:      "PTR",
+			PreTtl:    ttl,
+			PreValue:  host,
+			PreActive: true,
+		})
+		if err != nil {
+			return err
+		}
+		changes[i].id = id
+	}
+	return nil
+}
+
+// fireAutoPTR fires the webhook events of the PTR changes once the
+// transaction applying them has been committed
+func fireAutoPTR(events eventFirer, host string, ttl nulls.Int32, changes []ptrChange) {
+	for _, change := range changes {
+		for _, record := range change.remove {
+			events.Fire(change.zone.ID, change.zone.Name, webhook.RecordDeleted, record)
+		}
+		if !change.create {
+			continue
+		}
+		events.Fire(change.zone.ID, change.zone.Name, webhook.RecordCreated, rest.Record{
+			ID:     change.id,
+			Name:   change.name,
+			ZoneID: change.zone.ID,
+			Ttl:    ttl,
+			Type:   "PTR",
+			Value:  rest.RecordValue{Target: host},
+			Active: true,
+		}.WithUnicode())
+	}
+}
+
+// writeAutoPTRError writes the response for an error from planAutoPTR or
+// applyAutoPTR
+func writeAutoPTRError(rw http.ResponseWriter, err error) {
+	var ptrErr autoPTRError
+	if errors.As(err, &ptrErr) {
+		if ptrErr.status == http.StatusBadRequest {
+			writeFieldError(rw, "auto_ptr", "Invalid auto PTR: "+ptrErr.msg)
+			return
+		}
+		writeError(rw, ptrErr.status, "Invalid auto PTR: "+ptrErr.msg)
+		return
+	}
+	logger.Logger.Debug("Failed to update PTR record", "err", err)
+	writeError(rw, http.StatusInternalServerError, "Database error occurred")
+}
+
+// autoPTRAddress returns the address of an A or AAAA record which keeps a PTR
+// record, the address is invalid when there should be no PTR record
+func autoPTRAddress(autoPTR, active bool, value rest.RecordValue) netip.Addr {
+	if !autoPTR || !active || value.IP == nil {
+		return netip.Addr{}
+	}
+	return *value.IP
+}
+
+// recordHost returns the hostname of a record name relative to the zone
+func recordHost(zoneName, name string) string {
+	return delegation.ChildName(zoneName, name)
+}
+
+func findReverseZone(zones []database.Zone, addr netip.Addr) (database.Zone, bool) {
+	names := make([]string, len(zones))
+	for i, zone := range zones {
+		names[i] = zone.Name
+	}
+	name, ok := reverse.FindZone(addr, names)
+	if !ok {
+		return database.Zone{}, false
+	}
+	for _, zone := range zones {
+		if zone.Name == name {
+			return zone, true
+		}
+	}
+	return database.Zone{}, false
+}
+
+func ptrRecords(records []rest.Record, name string) []rest.Record {
+	var out []rest.Record
+	for _, r := range records {
+		if r.Type == "PTR" && strings.EqualFold(r.Name, name) {
+			out = append(out, r)
+		}
+	}
+	return out
+}
+
+func sameHost(a, b string) bool {
+	return strings.EqualFold(strings.TrimSuffix(a, "."), strings.TrimSuffix(b, "."))
+}
@@ -0,0 +1,421 @@
+package routes
+
+import (
+	"cmp"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/1f349/mjwt"
+	"github.com/1f349/mjwt/auth"
+	"github.com/1f349/verbena/conf"
+	"github.com/1f349/verbena/internal/database"
+	"github.com/1f349/verbena/internal/webhook"
+	"github.com/1f349/verbena/rest"
+	"github.com/go-chi/chi/v5"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+// reverseTestQueries stores records for several zones so PTR records can be
+// kept in a reverse zone while the forward zone is changed
+type reverseTestQueries struct {
+	zones   map[int64]database.Zone
+	records map[int64]database.Record
+	nextId  int64
+	failPTR bool
+}
+
+func (r *reverseTestQueries) GetZonePools(ctx context.Context, zoneID int64) ([]database.Pool, error) {
+	return nil, nil
+}
+
+func (r *reverseTestQueries) GetZone(ctx context.Context, zoneId int64) (database.Zone, error) {
+	zone, ok := r.zones[zoneId]
+	if !ok {
+		return database.Zone{}, sql.ErrNoRows
+	}
+	return zone, nil
+}
+
+func (r *reverseTestQueries) LookupZone(ctx context.Context, name string) (int64, error) {
+	for _, zone := range r.zones {
+		if zone.Name == name {
+			return zone.ID, nil
+		}
+	}
+	return 0, sql.ErrNoRows
+}
+
+func (r *reverseTestQueries) GetReverseZones(ctx context.Context) ([]database.Zone, error) {
+	var zones []database.Zone
+	for _, zone := range r.zones {
+		if strings.HasSuffix(zone.Name, ".arpa") {
+			zones = append(zones, zone)
+		}
+	}
+	return zones, nil
+}
+
+func (r *reverseTestQueries) InsertZone(ctx context.Context, arg database.InsertZoneParams) (int64, error) {
+	r.nextId++
+	r.zones[r.nextId] = database.Zone{
+		ID:      r.nextId,
+		Name:    arg.Name,
+		Serial:  arg.Serial,
+		Admin:   arg.Admin,
+		Refresh: arg.Refresh,
+		Retry:   arg.Retry,
+		Expire:  arg.Expire,
+		Ttl:     arg.Ttl,
+		Active:  true,
+	}
+	return r.nextId, nil
+}
+
+func (r *reverseTestQueries) InsertOwner(ctx context.Context, arg database.InsertOwnerParams) error {
+	return nil
+}
+
+func (r *reverseTestQueries) GetZoneRecords(ctx context.Context, zoneId int64) ([]database.GetZoneRecordsRow, error) {
+	var rows []database.GetZoneRecordsRow
+	for _, record := range r.records {
+		if record.ZoneID == zoneId {
+			rows = append(rows, database.GetZoneRecordsRow{Record: record, Name: r.zones[zoneId].Name})
+		}
+	}
+	// the database returns records in insert order
+	slices.SortFunc(rows, func(a, b database.GetZoneRecordsRow) int {
+		return cmp.Compare(a.Record.ID, b.Record.ID)
+	})
+	return rows, nil
+}
+
+func (r *reverseTestQueries) GetZoneRecord(ctx context.Context, row database.GetZoneRecordParams) (database.GetZoneRecordRow, error) {
+	record, ok := r.records[row.RecordID]
+	if !ok || record.ZoneID != row.ZoneID {
+		return database.GetZoneRecordRow{}, sql.ErrNoRows
+	}
+	return database.GetZoneRecordRow{Record: record, Name: r.zones[row.ZoneID].Name}, nil
+}
+
+func (r *reverseTestQueries) InsertRecordFromApi(ctx context.Context, row database.InsertRecordFromApiParams) (int64, error) {
+	if r.failPTR && row.Type == "PTR" {
+		return 0, errors.New("insert failed")
+	}
+	r.nextId++
+	r.records[r.nextId] = database.Record{
+		ID:        r.nextId,
+		Name:      row.Name,
+		ZoneID:    row.ZoneID,
+		Type:      row.Type,
+		PreTtl:    row.PreTtl,
+		PreValue:  row.PreValue,
+		PreActive: row.PreActive,
+		PreViews:  row.PreViews,
+		AutoPtr:   row.AutoPtr,
+	}
+	return r.nextId, nil
+}
+
+func (r *reverseTestQueries) UpdateRecordFromApi(ctx context.Context, row database.UpdateRecordFromApiParams) error {
+	record, ok := r.records[row.ID]
+	if !ok || record.ZoneID != row.ZoneID {
+		return sql.ErrNoRows
+	}
+	record.PreTtl = row.PreTtl
+	record.PreValue = row.PreValue
+	record.PreActive = row.PreActive
+	r.records[row.ID] = record
+	return nil
+}
+
+func (r *reverseTestQueries) DeleteRecordFromApi(ctx context.Context, row database.DeleteRecordFromApiParams) error {
+	record, ok := r.records[row.RecordID]
+	if !ok || record.ZoneID != row.ZoneID {
+		return sql.ErrNoRows
+	}
+	record.PreDelete = true
+	r.records[row.RecordID] = record
+	return nil
+}
+
+func (r *reverseTestQueries) UpdateRecordIfVersion(ctx context.Context, row database.UpdateRecordIfVersionParams) (int64, error) {
+	record, ok := r.records[row.ID]
+	if !ok || record.ZoneID != row.ZoneID || record.Version != row.Version {
+		return 0, nil
+	}
+	record.PreTtl = row.PreTtl
+	record.PreValue = row.PreValue
+	record.PreActive = row.PreActive
+	record.AutoPtr = row.AutoPtr
+	record.PreViews = row.PreViews
+	record.Version++
+	r.records[row.ID] = record
+	return 1, nil
+}
+
+func (r *reverseTestQueries) DeleteRecordIfVersion(ctx context.Context, row database.DeleteRecordIfVersionParams) (int64, error) {
+	record, ok := r.records[row.RecordID]
+	if !ok || record.ZoneID != row.ZoneID || record.Version != row.Version {
+		return 0, nil
+	}
+	record.PreDelete = true
+	r.records[row.RecordID] = record
+	return 1, nil
+}
+
+func (r *reverseTestQueries) GetZoneHealthChecks(ctx context.Context, zoneID int64) ([]database.HealthCheck, error) {
+	return nil, nil
+}
+
+func (r *reverseTestQueries) SetRecordViews(ctx context.Context, row database.SetRecordViewsParams) error {
+	record, ok := r.records[row.ID]
+	if !ok || record.ZoneID != row.ZoneID {
+		return sql.ErrNoRows
+	}
+	record.PreViews = row.PreViews
+	r.records[row.ID] = record
+	return nil
+}
+
+func (r *reverseTestQueries) UseZoneTx(ctx context.Context, cb func(tx database.ZoneTx) error) error {
+	return cb(r)
+}
+
+// UseRecordTx restores the records when the callback fails to act like a
+// rolled back transaction
+func (r *reverseTestQueries) UseRecordTx(ctx context.Context, cb func(tx database.RecordTx) error) error {
+	saved := maps.Clone(r.records)
+	err := cb(r)
+	if err != nil {
+		r.records = saved
+	}
+	return err
+}
+
+// liveRecords returns the records of a zone which are not staged for deletion
+func (r *reverseTestQueries) liveRecords(zoneId int64, ty string) []database.Record {
+	var out []database.Record
+	for id := int64(1); id <= r.nextId; id++ {
+		record, ok := r.records[id]
+		if ok && record.ZoneID == zoneId && record.Type == ty && !record.PreDelete {
+			out = append(out, record)
+		}
+	}
+	return out
+}
+
+func TestAddReverseRoutes(t *testing.T) {
+	r := chi.NewRouter()
+	issuer, err := mjwt.NewIssuer("hello world", "1", jwt.SigningMethodRS256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	q := &reverseTestQueries{
+		zones: map[int64]database.Zone{
+			1: {ID: 1, Name: "example.com", Active: true},
+		},
+		records: make(map[int64]database.Record),
+		nextId:  1,
+	}
+	events := &recordTestEvents{}
+	nameservers := conf.MustNameserverConf([][]string{{"ns1.example.com", "ns2.example.com"}})
+	AddReverseRoutes(r, q, issuer.KeyStore(), nameservers, events)
+	AddRecordRoutes(r, q, issuer.KeyStore(), nameservers, nil, &propagationTestTracker{}, events)
+
+	ps := auth.NewPermStorage()
+	ps.Set("domain:owns=example.com")
+	forwardToken, err := issuer.GenerateJwt("1234", "", jwt.ClaimStrings{}, time.Hour, auth.AccessTokenClaims{Perms: ps})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ps = auth.NewPermStorage()
+	ps.Set("domain:owns=example.com")
+	ps.Set("domain:owns=2.0.192.in-addr.arpa")
+	ps.Set("domain:owns=64-26.2.0.192.in-addr.arpa")
+	token, err := issuer.GenerateJwt("1234", "", jwt.ClaimStrings{}, time.Hour, auth.AccessTokenClaims{Perms: ps})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	do := func(method, target, body, token string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		r.ServeHTTP(rec, req)
+		return rec
+	}
+
+	t.Run("POST /zones/reverse", func(t *testing.T) {
+		rec := do(http.MethodPost, "/zones/reverse", `{"prefix":"192.0.2.0/24"}`, "")
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+
+		rec = do(http.MethodPost, "/zones/reverse", `{"prefix":"192.0.2.0/24"}`, forwardToken)
+		assert.Equal(t, http.StatusForbidden, rec.Code)
+		assert.Equal(t, "{\"code\":\"forbidden\",\"message\":\"Missing permission for zone 2.0.192.in-addr.arpa\"}\n", rec.Body.String())
+
+		rec = do(http.MethodPost, "/zones/reverse", `{"prefix":"192.0.0.0/4"}`, token)
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.Equal(t, "{\"code\":\"validation_failed\",\"message\":\"Invalid prefix: IPv4 prefixes must be /8 or longer\",\"field\":\"prefix\"}\n", rec.Body.String())
+
+		rec = do(http.MethodPost, "/zones/reverse", `{"prefix":"192.0.2.0/24"}`, token)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		var zones []rest.Zone
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &zones))
+		assert.Len(t, zones, 1)
+		assert.Equal(t, "2.0.192.in-addr.arpa", zones[0].Name)
+		assert.Equal(t, "hostmaster.2.0.192.in-addr.arpa", zones[0].Admin)
+		assert.Equal(t, []string{"ns1.example.com", "ns2.example.com"}, zones[0].Nameservers)
+		assert.Equal(t, int32(reverseZoneRefresh), zones[0].Refresh)
+		assert.Equal(t, int64(2), zones[0].ID)
+
+		rec = do(http.MethodPost, "/zones/reverse", `{"prefix":"192.0.2.128/24"}`, token)
+		assert.Equal(t, http.StatusConflict, rec.Code)
+		assert.Equal(t, "{\"code\":\"conflict\",\"message\":\"Zone 2.0.192.in-addr.arpa already exists\"}\n", rec.Body.String())
+	})
+
+	t.Run("auto PTR", func(t *testing.T) {
+		rec := do(http.MethodPost, "/zones/1/records", `{"name":"www","type":"TXT","value":{"text":"hello"},"auto_ptr":true}`, token)
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.Equal(t, "{\"code\":\"validation_failed\",\"message\":\"Invalid auto PTR: only A and AAAA records can keep a PTR record\",\"field\":\"auto_ptr\"}\n", rec.Body.String())
+
+		rec = do(http.MethodPost, "/zones/1/records", `{"name":"www","type":"A","value":{"ip":"192.0.2.10"},"auto_ptr":true}`, forwardToken)
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.Equal(t, "{\"code\":\"validation_failed\",\"message\":\"Invalid auto PTR: missing permission for the reverse zone 2.0.192.in-addr.arpa\",\"field\":\"auto_ptr\"}\n", rec.Body.String())
+
+		// the record is not created when its PTR record cannot be written
+		q.failPTR = true
+		fired := len(events.events)
+		rec = do(http.MethodPost, "/zones/1/records", `{"name":"www","type":"A","value":{"ip":"192.0.2.10"},"auto_ptr":true}`, token)
+		q.failPTR = false
+		assert.Equal(t, http.StatusInternalServerError, rec.Code)
+		assert.Empty(t, q.liveRecords(1, "A"))
+		assert.Len(t, events.events, fired)
+
+		rec = do(http.MethodPost, "/zones/1/records", `{"name":"www","type":"A","value":{"ip":"192.0.2.10"},"auto_ptr":true}`, token)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, []webhook.Event{webhook.RecordCreated, webhook.RecordCreated}, events.events[fired:])
+		var created rest.Record
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &created))
+		assert.True(t, created.AutoPTR)
+		ptrs := q.liveRecords(2, "PTR")
+		assert.Len(t, ptrs, 1)
+		assert.Equal(t, "10", ptrs[0].Name)
+		assert.Equal(t, "www.example.com", ptrs[0].PreValue)
+
+		// addresses outside the Verbena reverse zones are skipped
+		rec = do(http.MethodPost, "/zones/1/records", `{"name":"mail","type":"A","value":{"ip":"198.51.100.1"},"auto_ptr":true}`, forwardToken)
+		assert.Equal(t, http.StatusOK, rec.Code)
+
+		rec = do(http.MethodPut, "/zones/1/records/"+strconv.FormatInt(created.ID, 10), `{"ttl":300,"active":true,"value":{"ip":"192.0.2.11"},"auto_ptr":true}`, token)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		ptrs = q.liveRecords(2, "PTR")
+		assert.Len(t, ptrs, 1)
+		assert.Equal(t, "11", ptrs[0].Name)
+		assert.Equal(t, "www.example.com", ptrs[0].PreValue)
+
+		_, err := q.InsertRecordFromApi(t.Context(), database.InsertRecordFromApiParams{
+			Name:      "20",
+			ZoneID:    2,
+			Type:      "PTR",
+			PreValue:  "other.example.net",
+			PreActive: true,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		rec = do(http.MethodPut, "/zones/1/records/"+strconv.FormatInt(created.ID, 10), `{"ttl":300,"active":true,"value":{"ip":"192.0.2.20"},"auto_ptr":true}`, token)
+		assert.Equal(t, http.StatusConflict, rec.Code)
+		assert.Equal(t, "{\"code\":\"conflict\",\"message\":\"Invalid auto PTR: the PTR record for 192.0.2.20 already points at other.example.net\"}\n", rec.Body.String())
+
+		rec = do(http.MethodDelete, "/zones/1/records/"+strconv.FormatInt(created.ID, 10), "", token)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		ptrs = q.liveRecords(2, "PTR")
+		assert.Len(t, ptrs, 1)
+		assert.Equal(t, "20", ptrs[0].Name)
+	})
+
+	t.Run("PUT /zones/2/classless/64-26", func(t *testing.T) {
+		rec := do(http.MethodPut, "/zones/2/classless/64-26", `{}`, forwardToken)
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+
+		rec = do(http.MethodPut, "/zones/1/classless/64-26", `{}`, token)
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.Equal(t, "{\"code\":\"validation_failed\",\"message\":\"Classless delegation is only possible from a /24 reverse zone\"}\n", rec.Body.String())
+
+		rec = do(http.MethodPut, "/zones/2/classless/65-26", `{}`, token)
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.Equal(t, "{\"code\":\"validation_failed\",\"message\":\"Invalid classless delegation: 192.0.2.65 is not the start of a /26 block\"}\n", rec.Body.String())
+
+		rec = do(http.MethodPut, "/zones/2/classless/64-26", `{}`, token)
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.Equal(t, "{\"code\":\"validation_failed\",\"message\":\"Invalid classless delegation: at least one nameserver is required\"}\n", rec.Body.String())
+
+		rec = do(http.MethodPost, "/zones/reverse", `{"prefix":"192.0.2.64/26","admin":"hostmaster.example.com"}`, token)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		var zones []rest.Zone
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &zones))
+		assert.Len(t, zones, 1)
+		assert.Equal(t, "64-26.2.0.192.in-addr.arpa", zones[0].Name)
+		assert.Equal(t, "hostmaster.example.com", zones[0].Admin)
+
+		rec = do(http.MethodPut, "/zones/2/classless/64-26", `{"ttl":3600}`, token)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "{\"name\":\"64-26\",\"prefix\":\"192.0.2.64/26\",\"zone\":\"64-26.2.0.192.in-addr.arpa\",\"nameservers\":[\"ns1.example.com\",\"ns2.example.com\"],\"automatic\":true}\n", rec.Body.String())
+		cnames := q.liveRecords(2, "CNAME")
+		assert.Len(t, cnames, 64)
+		assert.Equal(t, "64", cnames[0].Name)
+		assert.Equal(t, "64.64-26.2.0.192.in-addr.arpa", cnames[0].PreValue)
+		assert.Len(t, q.liveRecords(2, "NS"), 2)
+
+		// nothing changes when the delegation is unchanged
+		count := len(events.events)
+		rec = do(http.MethodPut, "/zones/2/classless/64-26", `{"ttl":3600}`, token)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, count, len(events.events))
+
+		rec = do(http.MethodGet, "/zones/2/classless", "", token)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "[{\"name\":\"64-26\",\"prefix\":\"192.0.2.64/26\",\"zone\":\"64-26.2.0.192.in-addr.arpa\",\"nameservers\":[\"ns1.example.com\",\"ns2.example.com\"],\"automatic\":true}]\n", rec.Body.String())
+
+		// a PTR record at one of the addresses is in the way
+		rec = do(http.MethodPut, "/zones/2/classless/0-27", `{"nameservers":["ns.example.net"]}`, token)
+		assert.Equal(t, http.StatusConflict, rec.Code)
+		assert.Equal(t, "{\"code\":\"conflict\",\"message\":\"Delegation conflicts with the existing record 20.2.0.192.in-addr.arpa\"}\n", rec.Body.String())
+	})
+
+	t.Run("auto PTR in classless zone", func(t *testing.T) {
+		rec := do(http.MethodPost, "/zones/1/records", `{"name":"host","type":"A","value":{"ip":"192.0.2.65"},"auto_ptr":true}`, token)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		childId, err := q.LookupZone(t.Context(), "64-26.2.0.192.in-addr.arpa")
+		if err != nil {
+			t.Fatal(err)
+		}
+		ptrs := q.liveRecords(childId, "PTR")
+		assert.Len(t, ptrs, 1)
+		assert.Equal(t, "65", ptrs[0].Name)
+		assert.Equal(t, "host.example.com", ptrs[0].PreValue)
+	})
+
+	t.Run("DELETE /zones/2/classless/64-26", func(t *testing.T) {
+		rec := do(http.MethodDelete, "/zones/2/classless/64-26", "", token)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Empty(t, q.liveRecords(2, "CNAME"))
+		assert.Empty(t, q.liveRecords(2, "NS"))
+
+		rec = do(http.MethodDelete, "/zones/2/classless/64-26", "", token)
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+	})
+}
@@ -0,0 +1,394 @@
+package routes
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/1f349/mjwt"
+	"github.com/1f349/mjwt/auth"
+	"github.com/1f349/verbena/internal/database"
+	"github.com/1f349/verbena/internal/utils"
+	"github.com/1f349/verbena/internal/webhook"
+	"github.com/1f349/verbena/internal/zone"
+	"github.com/1f349/verbena/logger"
+	"github.com/1f349/verbena/rest"
+	"github.com/go-chi/chi/v5"
+)
+
+type rrsetQueries interface {
+	GetZone(ctx context.Context, zoneId int64) (database.Zone, error)
+	GetZoneRecords(ctx context.Context, zoneId int64) ([]database.GetZoneRecordsRow, error)
+	UseRecordTx(ctx context.Context, cb func(tx database.RecordTx) error) error
+	poolLister
+}
+
+// recordChange is a staged change which fires a webhook event once the
+// transaction has been committed
+type recordChange struct {
+	event  webhook.Event
+	record rest.Record
+}
+
+// rrsetViewsError is returned from the transaction when a record of the set
+// is limited to views, an RRset is only replaced or deleted in every view
+type rrsetViewsError int64
+
+func (e rrsetViewsError) Error() string {
+	return fmt.Sprintf("The record %d is limited to views, change it with the record routes", int64(e))
+}
+
+func AddRRsetRoutes(r chi.Router, db rrsetQueries, keystore *mjwt.KeyStore, events eventFirer) {
+	r.Route("/zones/{zone_id:[0-9]+}/rrsets/{name}/{type}", func(r chi.Router) {
+		// Get all records of a name and type
+		r.Get("/", validateAuthToken(keystore, func(rw http.ResponseWriter, req *http.Request, b mjwt.BaseTypeClaims[auth.AccessTokenClaims]) {
+			zoneInfo, ok := getOwnedZone(rw, req, db, b)
+			if !ok {
+				return
+			}
+
+			name, recordType, ok := getRRsetKey(rw, req, zoneInfo.Name)
+			if !ok {
+				return
+			}
+
+			records, ok := getRestZoneRecords(rw, req, db, zoneInfo.ID)
+			if !ok {
+				return
+			}
+
+			set := rrsetRecords(records, name, recordType)
+			if len(set) == 0 {
+				writeNotFound(rw)
+				return
+			}
+
+			json.NewEncoder(rw).Encode(toRestRRset(name, recordType, set))
+		}))
+
+		// Replace all records of a name and type
+		r.Put("/", validateAuthToken(keystore, func(rw http.ResponseWriter, req *http.Request, b mjwt.BaseTypeClaims[auth.AccessTokenClaims]) {
+			var putRRset rest.PutRRset
+			err := json.NewDecoder(req.Body).Decode(&putRRset)
+			if err != nil {
+				writeError(rw, http.StatusBadRequest, "Invalid request body")
+				return
+			}
+
+			if putRRset.Ttl.Valid && putRRset.Ttl.Int32 > ttlMaxOneWeek {
+				writeFieldError(rw, "ttl", fmt.Sprintf("Invalid time to live, expected 'null or ttl <= %d seconds'", ttlMaxOneWeek))
+				return
+			}
+
+			zoneInfo, ok := getOwnedZone(rw, req, db, b)
+			if !ok {
+				return
+			}
+
+			name, recordType, ok := getRRsetKey(rw, req, zoneInfo.Name)
+			if !ok {
+				return
+			}
+
+			for i := range putRRset.Records {
+				putRRset.Records[i].Value, err = putRRset.Records[i].Value.ToASCII(recordType)
+				if err != nil {
+					writeError(rw, http.StatusBadRequest, fmt.Sprintf("Invalid RRset: invalid value for record %d: %s", i, err))
+					return
+				}
+			}
+
+			err = validateRRset(recordType, putRRset)
+			if err != nil {
+				writeError(rw, http.StatusBadRequest, "Invalid RRset: "+err.Error())
+				return
+			}
+
+			if len(putRRset.Records) > 0 && !checkRecordPools(rw, req, db, zoneInfo.ID, name, recordType) {
+				return
+			}
+
+			var out []rest.Record
+			var changes []recordChange
+			err = db.UseRecordTx(req.Context(), func(tx database.RecordTx) error {
+				before, err := zoneRecordsInTx(req.Context(), tx, zoneInfo.ID)
+				if err != nil {
+					return err
+				}
+				existing := rrsetRecords(before, name, recordType)
+				for _, record := range existing {
+					if record.AutoPTR {
+						return autoPTRKept(record.ID)
+					}
+					if len(record.Views) > 0 {
+						return rrsetViewsError(record.ID)
+					}
+				}
+				out, changes, err = replaceRRset(req.Context(), tx, zoneInfo, name, recordType, putRRset, existing)
+				if err != nil {
+					return err
+				}
+				return lintTx(req.Context(), tx, zoneInfo, before)
+			})
+			var ptrErr autoPTRError
+			if errors.As(err, &ptrErr) {
+				writeAutoPTRError(rw, ptrErr)
+				return
+			}
+			var viewsErr rrsetViewsError
+			if errors.As(err, &viewsErr) {
+				writeError(rw, http.StatusConflict, viewsErr.Error())
+				return
+			}
+			var lintErr lintFailure
+			if errors.As(err, &lintErr) {
+				writeLintFailure(rw, lintErr)
+				return
+			}
+			if err != nil {
+				logger.Logger.Debug("Failed to replace RRset", "err", err)
+				writeError(rw, http.StatusInternalServerError, "Database error occurred")
+				return
+			}
+
+			for _, change := range changes {
+				events.Fire(zoneInfo.ID, zoneInfo.Name, change.event, change.record)
+			}
+
+			json.NewEncoder(rw).Encode(toRestRRset(name, recordType, out))
+		}))
+
+		// Delete all records of a name and type
+		r.Delete("/", validateAuthToken(keystore, func(rw http.ResponseWriter, req *http.Request, b mjwt.BaseTypeClaims[auth.AccessTokenClaims]) {
+			zoneInfo, ok := getOwnedZone(rw, req, db, b)
+			if !ok {
+				return
+			}
+
+			name, recordType, ok := getRRsetKey(rw, req, zoneInfo.Name)
+			if !ok {
+				return
+			}
+
+			var existing []rest.Record
+			err := db.UseRecordTx(req.Context(), func(tx database.RecordTx) error {
+				records, err := zoneRecordsInTx(req.Context(), tx, zoneInfo.ID)
+				if err != nil {
+					return err
+				}
+				existing = rrsetRecords(records, name, recordType)
+				for _, record := range existing {
+					if record.AutoPTR {
+						return autoPTRKept(record.ID)
+					}
+					if len(record.Views) > 0 {
+						return rrsetViewsError(record.ID)
+					}
+				}
+				for _, record := range existing {
+					err = tx.DeleteRecordFromApi(req.Context(), database.DeleteRecordFromApiParams{
+						RecordID: record.ID,
+						ZoneID:   zoneInfo.ID,
+					})
+					if err != nil {
+						return err
+					}
+				}
+				return nil
+			})
+			var ptrErr autoPTRError
+			if errors.As(err, &ptrErr) {
+				writeAutoPTRError(rw, ptrErr)
+				return
+			}
+			var viewsErr rrsetViewsError
+			if errors.As(err, &viewsErr) {
+				writeError(rw, http.StatusConflict, viewsErr.Error())
+				return
+			}
+			if err != nil {
+				logger.Logger.Debug("Failed to delete RRset", "err", err)
+				writeError(rw, http.StatusInternalServerError, "Database error occurred")
+				return
+			}
+
+			if len(existing) == 0 {
+				writeNotFound(rw)
+				return
+			}
+
+			for _, record := range existing {
+				events.Fire(zoneInfo.ID, zoneInfo.Name, webhook.RecordDeleted, record)
+			}
+
+			rw.WriteHeader(http.StatusOK)
+		}))
+	})
+}
+
+// getRRsetKey reads the name and type from the URL, an error response is
+// written when false is returned
+func getRRsetKey(rw http.ResponseWriter, req *http.Request, zoneName string) (string, string, bool) {
+	recordType := strings.ToUpper(chi.URLParam(req, "type"))
+	if !zone.RecordTypeFromString(recordType).IsValid() {
+		writeFieldError(rw, "type", "Invalid record type")
+		return "", "", false
+	}
+	name, err := utils.NormalizeRecordName(zoneName, recordType, chi.URLParam(req, "name"))
+	if err != nil {
+		writeFieldError(rw, "name", "Invalid record name: "+err.Error())
+		return "", "", false
+	}
+	return name, recordType, true
+}
+
+// validateRRset checks the records are valid when taken together
+func validateRRset(recordType string, putRRset rest.PutRRset) error {
+	if len(putRRset.Records) == 0 {
+		return errors.New("at least one record is required")
+	}
+	if len(putRRset.Records) > 1 && (recordType == "CNAME" || recordType == "ALIAS") {
+		return fmt.Errorf("%s RRsets must contain a single record", recordType)
+	}
+
+	values := make(map[string]bool, len(putRRset.Records))
+	for i, record := range putRRset.Records {
+		if !record.Value.IsValidForType(recordType) {
+			return fmt.Errorf("invalid value for record %d", i)
+		}
+		value := record.Value.ToValueString(recordType)
+		if values[value] {
+			return fmt.Errorf("duplicate value for record %d", i)
+		}
+		values[value] = true
+	}
+	return nil
+}
+
+// replaceRRset stages the changes needed to turn the existing records into the
+// wanted records, records with an unchanged value keep their ID
+func replaceRRset(ctx context.Context, tx database.RecordTx, zoneInfo database.Zone, name, recordType string, putRRset rest.PutRRset, existing []rest.Record) ([]rest.Record, []recordChange, error) {
+	out := make([]rest.Record, 0, len(putRRset.Records))
+	var changes []recordChange
+	used := make(map[int]bool)
+
+outer:
+	for _, want := range putRRset.Records {
+		value := want.Value.ToValueString(recordType)
+		record := rest.Record{
+			Name:   name,
+			ZoneID: zoneInfo.ID,
+			Ttl:    putRRset.Ttl,
+			Type:   recordType,
+			Value:  want.Value,
+			Active: want.Active,
+		}.WithUnicode()
+
+		for i, e := range existing {
+			if used[i] || e.Value.ToValueString(recordType) != value {
+				continue
+			}
+			used[i] = true
+			record.ID = e.ID
+			record.Name = e.Name
+			out = append(out, record)
+			if e.Ttl == putRRset.Ttl && e.Active == want.Active {
+				continue outer
+			}
+			err := tx.UpdateRecordFromApi(ctx, database.UpdateRecordFromApiParams{
+				PreTtl:    putRRset.Ttl,
+				PreValue:  value,
+				PreActive: want.Active,
+				ID:        e.ID,
+				ZoneID:    zoneInfo.ID,
+			})
+			if err != nil {
+				return nil, nil, err
+			}
+			changes = append(changes, recordChange{webhook.RecordUpdated, record})
+			continue outer
+		}
+
+		id, err := tx.InsertRecordFromApi(ctx, database.InsertRecordFromApiParams{
+			Name:      name,
+			ZoneID:    zoneInfo.ID,
+			Type:      recordType,
+			PreTtl:    putRRset.Ttl,
+			PreValue:  value,
+			PreActive: want.Active,
+		})
+		if err != nil {
+			return nil, nil, err
+		}
+		record.ID = id
+		out = append(out, record)
+		changes = append(changes, recordChange{webhook.RecordCreated, record})
+	}
+
+	for i, e := range existing {
+		if used[i] {
+			continue
+		}
+		err := tx.DeleteRecordFromApi(ctx, database.DeleteRecordFromApiParams{
+			RecordID: e.ID,
+			ZoneID:   zoneInfo.ID,
+		})
+		if err != nil {
+			return nil, nil, err
+		}
+		changes = append(changes, recordChange{webhook.RecordDeleted, e})
+	}
+	return out, changes, nil
+}
+
+// rrsetRecords filters the records matching the name and type sorted by ID,
+// the apex can be named either "@" or left empty
+func rrsetRecords(records []rest.Record, name, recordType string) []rest.Record {
+	var out []rest.Record
+	for _, r := range records {
+		if r.Type == recordType && sameRecordName(r.Name, name) {
+			out = append(out, r)
+		}
+	}
+	slices.SortFunc(out, func(a, b rest.Record) int {
+		return cmp.Compare(a.ID, b.ID)
+	})
+	return out
+}
+
+func sameRecordName(a, b string) bool {
+	a = strings.ToLower(strings.TrimSpace(a))
+	b = strings.ToLower(strings.TrimSpace(b))
+	if a == "@" {
+		a = ""
+	}
+	if b == "@" {
+		b = ""
+	}
+	return a == b
+}
+
+func toRestRRset(name, recordType string, records []rest.Record) rest.RRset {
+	rrset := rest.RRset{
+		Name:        name,
+		NameUnicode: utils.UnicodeNameIfDifferent(name),
+		Type:        recordType,
+		Records:     make([]rest.RRsetRecord, 0, len(records)),
+	}
+	if len(records) > 0 {
+		rrset.Ttl = records[0].Ttl
+	}
+	for _, r := range records {
+		rrset.Records = append(rrset.Records, rest.RRsetRecord{
+			ID:     r.ID,
+			Value:  r.Value,
+			Active: r.Active,
+		})
+	}
+	return rrset
+}
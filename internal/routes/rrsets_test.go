@@ -0,0 +1,226 @@
+package routes
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/1f349/mjwt"
+	"github.com/1f349/mjwt/auth"
+	"github.com/1f349/verbena/internal/database"
+	"github.com/1f349/verbena/internal/webhook"
+	"github.com/go-chi/chi/v5"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+type rrsetTestQueries struct {
+	recordTestQueries
+	failInsert bool
+}
+
+func (r *rrsetTestQueries) InsertRecordFromApi(ctx context.Context, row database.InsertRecordFromApiParams) (int64, error) {
+	if r.failInsert {
+		return 0, errors.New("insert failed")
+	}
+	return r.recordTestQueries.InsertRecordFromApi(ctx, row)
+}
+
+// UseRecordTx restores the records when the callback fails to act like a
+// rolled back transaction
+func (r *rrsetTestQueries) UseRecordTx(ctx context.Context, cb func(tx database.RecordTx) error) error {
+	saved := maps.Clone(r.records)
+	err := cb(r)
+	if err != nil {
+		r.records = saved
+	}
+	return err
+}
+
+func TestAddRRsetRoutes(t *testing.T) {
+	r := chi.NewRouter()
+	issuer, err := mjwt.NewIssuer("hello world", "1", jwt.SigningMethodRS256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	q := &rrsetTestQueries{recordTestQueries: recordTestQueries{
+		records: make(map[int64]database.Record),
+	}}
+	events := &recordTestEvents{}
+	AddRRsetRoutes(r, q, issuer.KeyStore(), events)
+	for _, i := range []database.InsertRecordFromApiParams{
+		{Name: "www", ZoneID: 3456, Type: "A", PreValue: "192.0.2.1", PreActive: true},
+		{Name: "www", ZoneID: 3456, Type: "A", PreValue: "192.0.2.2", PreActive: true},
+		{Name: "www", ZoneID: 3456, Type: "AAAA", PreValue: "2001:db8::1", PreActive: true},
+		{Name: "api", ZoneID: 3456, Type: "A", PreValue: "192.0.2.9", PreActive: true},
+	} {
+		_, err = q.InsertRecordFromApi(t.Context(), i)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ps := auth.NewPermStorage()
+	ps.Set("domain:owns=example.org")
+	wrongToken, err := issuer.GenerateJwt("1234", "", jwt.ClaimStrings{}, time.Hour, auth.AccessTokenClaims{Perms: ps})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ps = auth.NewPermStorage()
+	ps.Set("domain:owns=example.com")
+	token, err := issuer.GenerateJwt("1234", "", jwt.ClaimStrings{}, time.Hour, auth.AccessTokenClaims{Perms: ps})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("GET /zones/3456/rrsets/www/A", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/zones/3456/rrsets/www/A", nil)
+		r.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+
+		rec = httptest.NewRecorder()
+		req = httptest.NewRequest(http.MethodGet, "/zones/3456/rrsets/www/A", nil)
+		req.Header.Set("Authorization", "Bearer "+wrongToken)
+		r.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+
+		rec = httptest.NewRecorder()
+		req = httptest.NewRequest(http.MethodGet, "/zones/3456/rrsets/www/BOGUS", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		r.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+		rec = httptest.NewRecorder()
+		req = httptest.NewRequest(http.MethodGet, "/zones/3456/rrsets/www/TXT", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		r.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+
+		rec = httptest.NewRecorder()
+		req = httptest.NewRequest(http.MethodGet, "/zones/3456/rrsets/www/A", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		r.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "{\"name\":\"www\",\"type\":\"A\",\"ttl\":null,\"records\":[{\"id\":1,\"value\":{\"ip\":\"192.0.2.1\"},\"active\":true},{\"id\":2,\"value\":{\"ip\":\"192.0.2.2\"},\"active\":true}]}\n", rec.Body.String())
+	})
+
+	t.Run("PUT /zones/3456/rrsets/www/A", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPut, "/zones/3456/rrsets/www/A", strings.NewReader(`{"ttl":300,"records":[{"value":{"ip":"192.0.2.3"},"active":true},{"value":{"ip":"192.0.2.3"},"active":true}]}`))
+		req.Header.Set("Authorization", "Bearer "+token)
+		r.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.Equal(t, "{\"code\":\"validation_failed\",\"message\":\"Invalid RRset: duplicate value for record 1\"}\n", rec.Body.String())
+
+		rec = httptest.NewRecorder()
+		req = httptest.NewRequest(http.MethodPut, "/zones/3456/rrsets/www/CNAME", strings.NewReader(`{"ttl":300,"records":[{"value":{"target":"a.example.net"},"active":true},{"value":{"target":"b.example.net"},"active":true}]}`))
+		req.Header.Set("Authorization", "Bearer "+token)
+		r.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.Equal(t, "{\"code\":\"validation_failed\",\"message\":\"Invalid RRset: CNAME RRsets must contain a single record\"}\n", rec.Body.String())
+
+		rec = httptest.NewRecorder()
+		req = httptest.NewRequest(http.MethodPut, "/zones/3456/rrsets/www/A", strings.NewReader(`{"ttl":300,"records":[{"value":{"ip":"2001:db8::1"},"active":true}]}`))
+		req.Header.Set("Authorization", "Bearer "+token)
+		r.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.Equal(t, "{\"code\":\"validation_failed\",\"message\":\"Invalid RRset: invalid value for record 0\"}\n", rec.Body.String())
+
+		q.zonePools = []database.Pool{{ID: 1, ZoneID: 3456, Name: "www", Type: "A", Size: 1}}
+		rec = httptest.NewRecorder()
+		req = httptest.NewRequest(http.MethodPut, "/zones/3456/rrsets/www/A", strings.NewReader(`{"ttl":300,"records":[{"value":{"ip":"192.0.2.3"},"active":true}]}`))
+		req.Header.Set("Authorization", "Bearer "+token)
+		r.ServeHTTP(rec, req)
+		q.zonePools = nil
+		assert.Equal(t, http.StatusConflict, rec.Code)
+		assert.Equal(t, "{\"code\":\"conflict\",\"message\":\"Record conflicts with the existing A pool\"}\n", rec.Body.String())
+
+		// a failure part way through leaves the records untouched
+		q.failInsert = true
+		rec = httptest.NewRecorder()
+		req = httptest.NewRequest(http.MethodPut, "/zones/3456/rrsets/www/A", strings.NewReader(`{"ttl":300,"records":[{"value":{"ip":"192.0.2.2"},"active":true},{"value":{"ip":"192.0.2.3"},"active":true}]}`))
+		req.Header.Set("Authorization", "Bearer "+token)
+		r.ServeHTTP(rec, req)
+		q.failInsert = false
+		assert.Equal(t, http.StatusInternalServerError, rec.Code)
+		assert.Len(t, q.records, 4)
+		assert.False(t, q.records[2].PreTtl.Valid)
+		assert.Empty(t, events.events)
+
+		rec = httptest.NewRecorder()
+		req = httptest.NewRequest(http.MethodPut, "/zones/3456/rrsets/www/a", strings.NewReader(`{"ttl":300,"records":[{"value":{"ip":"192.0.2.2"},"active":true},{"value":{"ip":"192.0.2.3"},"active":true}]}`))
+		req.Header.Set("Authorization", "Bearer "+token)
+		r.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "{\"name\":\"www\",\"type\":\"A\",\"ttl\":300,\"records\":[{\"id\":2,\"value\":{\"ip\":\"192.0.2.2\"},\"active\":true},{\"id\":5,\"value\":{\"ip\":\"192.0.2.3\"},\"active\":true}]}\n", rec.Body.String())
+		assert.Equal(t, []webhook.Event{webhook.RecordUpdated, webhook.RecordCreated, webhook.RecordDeleted}, events.events)
+		assert.True(t, q.records[1].PreDelete)
+		assert.Equal(t, int32(300), q.records[2].PreTtl.Int32)
+	})
+
+	t.Run("DELETE /zones/3456/rrsets/www/A", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodDelete, "/zones/3456/rrsets/www/A", nil)
+		req.Header.Set("Authorization", "Bearer "+wrongToken)
+		r.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+
+		// the PTR record of an auto PTR record is only kept by the record routes
+		api := q.records[4]
+		api.AutoPtr = true
+		q.records[4] = api
+		rec = httptest.NewRecorder()
+		req = httptest.NewRequest(http.MethodPut, "/zones/3456/rrsets/api/A", strings.NewReader(`{"ttl":300,"records":[{"value":{"ip":"192.0.2.10"},"active":true}]}`))
+		req.Header.Set("Authorization", "Bearer "+token)
+		r.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusConflict, rec.Code)
+		assert.Equal(t, "{\"code\":\"conflict\",\"message\":\"Invalid auto PTR: the record 4 keeps a PTR record, change it with the record routes\"}\n", rec.Body.String())
+		rec = httptest.NewRecorder()
+		req = httptest.NewRequest(http.MethodDelete, "/zones/3456/rrsets/api/A", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		r.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusConflict, rec.Code)
+		assert.Equal(t, "192.0.2.9", q.records[4].PreValue)
+		assert.False(t, q.records[4].PreDelete)
+
+		// records limited to views are not replaced in every view
+		api.AutoPtr = false
+		api.PreViews = "internal"
+		q.records[4] = api
+		rec = httptest.NewRecorder()
+		req = httptest.NewRequest(http.MethodPut, "/zones/3456/rrsets/api/A", strings.NewReader(`{"ttl":300,"records":[{"value":{"ip":"192.0.2.10"},"active":true}]}`))
+		req.Header.Set("Authorization", "Bearer "+token)
+		r.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusConflict, rec.Code)
+		assert.Equal(t, "{\"code\":\"conflict\",\"message\":\"The record 4 is limited to views, change it with the record routes\"}\n", rec.Body.String())
+		rec = httptest.NewRecorder()
+		req = httptest.NewRequest(http.MethodDelete, "/zones/3456/rrsets/api/A", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		r.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusConflict, rec.Code)
+		assert.Equal(t, "192.0.2.9", q.records[4].PreValue)
+		assert.False(t, q.records[4].PreDelete)
+
+		rec = httptest.NewRecorder()
+		req = httptest.NewRequest(http.MethodDelete, "/zones/3456/rrsets/www/A", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		r.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.True(t, q.records[2].PreDelete)
+		assert.True(t, q.records[5].PreDelete)
+		assert.False(t, q.records[3].PreDelete)
+		assert.False(t, q.records[4].PreDelete)
+
+		rec = httptest.NewRecorder()
+		req = httptest.NewRequest(http.MethodDelete, "/zones/3456/rrsets/www/A", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		r.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+	})
+}
@@ -0,0 +1,199 @@
+package routes
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"net/http"
+	"slices"
+
+	"github.com/1f349/mjwt"
+	"github.com/1f349/mjwt/auth"
+	"github.com/1f349/verbena/internal/database"
+	"github.com/1f349/verbena/internal/utils"
+	"github.com/1f349/verbena/internal/webhook"
+	"github.com/1f349/verbena/internal/zonetemplate"
+	"github.com/1f349/verbena/logger"
+	"github.com/1f349/verbena/rest"
+	"github.com/go-chi/chi/v5"
+)
+
+type templateQueries interface {
+	GetZone(ctx context.Context, zoneId int64) (database.Zone, error)
+	UseRecordTx(ctx context.Context, cb func(tx database.RecordTx) error) error
+	poolLister
+}
+
+// templateModified is returned from the transaction when a record which the
+// template updates was changed by another request
+type templateModified int64
+
+func (e templateModified) Error() string {
+	return fmt.Sprintf("The record %d was modified by another request", int64(e))
+}
+
+func AddTemplateRoutes(r chi.Router, db templateQueries, keystore *mjwt.KeyStore, templates map[string]zonetemplate.Template, events eventFirer) {
+	// List all templates
+	r.Get("/templates", validateAuthToken(keystore, func(rw http.ResponseWriter, req *http.Request, b mjwt.BaseTypeClaims[auth.AccessTokenClaims]) {
+		out := make([]rest.Template, 0, len(templates))
+		for _, name := range slices.Sorted(maps.Keys(templates)) {
+			out = append(out, rest.Template{
+				Name:      name,
+				Variables: templates[name].Variables,
+			})
+		}
+		json.NewEncoder(rw).Encode(out)
+	}))
+
+	// Apply template to a zone
+	r.Post("/zones/{zone_id:[0-9]+}/templates/{template_name}/apply", validateAuthToken(keystore, func(rw http.ResponseWriter, req *http.Request, b mjwt.BaseTypeClaims[auth.AccessTokenClaims]) {
+		var apply rest.ApplyTemplate
+		err := json.NewDecoder(req.Body).Decode(&apply)
+		if err != nil {
+			writeError(rw, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+
+		zone, ok := getOwnedZone(rw, req, db, b)
+		if !ok {
+			return
+		}
+
+		template, ok := templates[chi.URLParam(req, "template_name")]
+		if !ok {
+			writeNotFound(rw)
+			return
+		}
+
+		records, err := template.Render(zone.Name, apply.Variables)
+		if err != nil {
+			writeFieldError(rw, "variables", "Invalid template: "+err.Error())
+			return
+		}
+		for _, record := range records {
+			if record.Ttl.Valid && record.Ttl.Int32 > ttlMaxOneWeek {
+				writeFieldError(rw, "ttl", fmt.Sprintf("Invalid time to live, expected 'null or ttl <= %d seconds'", ttlMaxOneWeek))
+				return
+			}
+		}
+
+		pools, err := db.GetZonePools(req.Context(), zone.ID)
+		if err != nil {
+			logger.Logger.Error("Failed to get zone pools", "err", err)
+			writeError(rw, http.StatusInternalServerError, "Database error occurred")
+			return
+		}
+
+		dryRun := req.URL.Query().Get("dry_run") == "true"
+		var changes []rest.TemplateChange
+		var fired []recordChange
+		err = db.UseRecordTx(req.Context(), func(tx database.RecordTx) error {
+			before, err := zoneRecordsInTx(req.Context(), tx, zone.ID)
+			if err != nil {
+				return err
+			}
+
+			plan := zonetemplate.Plan(before, records)
+			changes = make([]rest.TemplateChange, 0, len(plan))
+			fired = make([]recordChange, 0, len(plan))
+			for _, change := range plan {
+				record := change.Record
+				record.ZoneID = zone.ID
+
+				if !dryRun {
+					switch change.Action {
+					case zonetemplate.Create:
+						err = findRecordPoolConflict(record.Name, record.Type, pools)
+						if err != nil {
+							return err
+						}
+						record.ID, err = tx.InsertRecordFromApi(req.Context(), database.InsertRecordFromApiParams{
+							Name:      record.Name,
+							ZoneID:    zone.ID,
+							Type:      record.Type,
+							PreTtl:    record.Ttl,
+							PreValue:  record.Value.ToValueString(record.Type),
+							PreActive: true,
+						})
+						if err != nil {
+							return err
+						}
+						fired = append(fired, recordChange{webhook.RecordCreated, record})
+					case zonetemplate.Update:
+						row, err := tx.GetZoneRecord(req.Context(), database.GetZoneRecordParams{
+							RecordID: record.ID,
+							ZoneID:   zone.ID,
+						})
+						if err != nil {
+							return err
+						}
+						// the PTR record would be left behind in the reverse zone
+						if row.Record.AutoPtr {
+							return autoPTRKept(record.ID)
+						}
+						record.Views = utils.SplitViews(row.Record.PreViews)
+						changed, err := tx.UpdateRecordIfVersion(req.Context(), database.UpdateRecordIfVersionParams{
+							PreTtl:    record.Ttl,
+							PreValue:  record.Value.ToValueString(record.Type),
+							PreActive: true,
+							AutoPtr:   row.Record.AutoPtr,
+							PreViews:  row.Record.PreViews,
+							ID:        record.ID,
+							ZoneID:    zone.ID,
+							Version:   row.Record.Version,
+						})
+						if err != nil {
+							return err
+						}
+						if changed == 0 {
+							return templateModified(record.ID)
+						}
+						fired = append(fired, recordChange{webhook.RecordUpdated, record})
+					}
+				}
+
+				changes = append(changes, rest.TemplateChange{
+					Action: string(change.Action),
+					Record: record,
+				})
+			}
+			if dryRun {
+				return nil
+			}
+			return lintTx(req.Context(), tx, zone, before)
+		})
+		var ptrErr autoPTRError
+		if errors.As(err, &ptrErr) {
+			writeAutoPTRError(rw, ptrErr)
+			return
+		}
+		var modErr templateModified
+		if errors.As(err, &modErr) {
+			writeError(rw, http.StatusConflict, modErr.Error())
+			return
+		}
+		var poolErr recordPoolConflict
+		if errors.As(err, &poolErr) {
+			writeError(rw, http.StatusConflict, poolErr.Error())
+			return
+		}
+		var lintErr lintFailure
+		if errors.As(err, &lintErr) {
+			writeLintFailure(rw, lintErr)
+			return
+		}
+		if err != nil {
+			logger.Logger.Debug("Failed to apply template", "err", err)
+			writeError(rw, http.StatusInternalServerError, "Database error occurred")
+			return
+		}
+
+		for _, change := range fired {
+			events.Fire(zone.ID, zone.Name, change.event, change.record)
+		}
+
+		json.NewEncoder(rw).Encode(changes)
+	}))
+}
@@ -0,0 +1,199 @@
+package routes
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/1f349/mjwt"
+	"github.com/1f349/mjwt/auth"
+	"github.com/1f349/verbena/internal/database"
+	"github.com/1f349/verbena/internal/webhook"
+	"github.com/1f349/verbena/internal/zonetemplate"
+	"github.com/go-chi/chi/v5"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAddTemplateRoutes(t *testing.T) {
+	r := chi.NewRouter()
+	issuer, err := mjwt.NewIssuer("hello world", "1", jwt.SigningMethodRS256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	q := &rrsetTestQueries{recordTestQueries: recordTestQueries{
+		records: make(map[int64]database.Record),
+	}}
+	events := &recordTestEvents{}
+	templates, err := zonetemplate.Load(map[string][]map[string]any{
+		"mail": {
+			{"name": "@", "type": "MX", "value": map[string]any{"preference": 10, "target": "mx.{{provider}}"}},
+			{"name": "www", "type": "CNAME", "value": map[string]any{"target": "{{zone}}"}},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	AddTemplateRoutes(r, q, issuer.KeyStore(), templates, events)
+	_, err = q.InsertRecordFromApi(t.Context(), database.InsertRecordFromApiParams{
+		Name:      "@",
+		ZoneID:    3456,
+		Type:      "MX",
+		PreValue:  "20\tmx.example.net",
+		PreActive: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("GET /templates", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/templates", nil)
+		r.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+
+		rec = httptest.NewRecorder()
+		req = httptest.NewRequest(http.MethodGet, "/templates", nil)
+		ps := auth.NewPermStorage()
+		token, err := issuer.GenerateJwt("1234", "", jwt.ClaimStrings{}, time.Hour, auth.AccessTokenClaims{Perms: ps})
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+		r.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "[{\"name\":\"mail\",\"variables\":[\"provider\"]}]\n", rec.Body.String())
+	})
+
+	t.Run("POST /zones/3456/templates/mail/apply", func(t *testing.T) {
+		body := `{"variables":{"provider":"example.net"}}`
+
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/zones/3456/templates/mail/apply", strings.NewReader(body))
+		r.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+
+		rec = httptest.NewRecorder()
+		req = httptest.NewRequest(http.MethodPost, "/zones/3456/templates/mail/apply", strings.NewReader(body))
+		ps := auth.NewPermStorage()
+		ps.Set("domain:owns=example.org")
+		token, err := issuer.GenerateJwt("1234", "", jwt.ClaimStrings{}, time.Hour, auth.AccessTokenClaims{Perms: ps})
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+		r.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+
+		ps = auth.NewPermStorage()
+		ps.Set("domain:owns=example.com")
+		token, err = issuer.GenerateJwt("1234", "", jwt.ClaimStrings{}, time.Hour, auth.AccessTokenClaims{Perms: ps})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		rec = httptest.NewRecorder()
+		req = httptest.NewRequest(http.MethodPost, "/zones/3456/templates/missing/apply", strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+token)
+		r.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+
+		rec = httptest.NewRecorder()
+		req = httptest.NewRequest(http.MethodPost, "/zones/3456/templates/mail/apply", strings.NewReader(`{"variables":{}}`))
+		req.Header.Set("Authorization", "Bearer "+token)
+		r.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.Equal(t, "{\"code\":\"validation_failed\",\"message\":\"Invalid template: missing template variables: provider\",\"field\":\"variables\"}\n", rec.Body.String())
+
+		rec = httptest.NewRecorder()
+		req = httptest.NewRequest(http.MethodPost, "/zones/3456/templates/mail/apply?dry_run=true", strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+token)
+		r.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "[{\"action\":\"update\",\"record\":{\"id\":1,\"name\":\"@\",\"zone_id\":3456,\"ttl\":null,\"type\":\"MX\",\"value\":{\"target\":\"mx.example.net\",\"preference\":10},\"active\":true}},{\"action\":\"create\",\"record\":{\"id\":0,\"name\":\"www\",\"zone_id\":3456,\"ttl\":null,\"type\":\"CNAME\",\"value\":{\"target\":\"example.com\"},\"active\":true}}]\n", rec.Body.String())
+		assert.Equal(t, "20\tmx.example.net", q.records[1].PreValue)
+		assert.Empty(t, events.events)
+
+		// the MX update is rolled back when the CNAME conflicts with a pool
+		q.zonePools = []database.Pool{{ID: 1, ZoneID: 3456, Name: "www", Type: "A", Size: 1}}
+		rec = httptest.NewRecorder()
+		req = httptest.NewRequest(http.MethodPost, "/zones/3456/templates/mail/apply", strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+token)
+		r.ServeHTTP(rec, req)
+		q.zonePools = nil
+		assert.Equal(t, http.StatusConflict, rec.Code)
+		assert.Equal(t, "{\"code\":\"conflict\",\"message\":\"Record conflicts with the existing A pool\"}\n", rec.Body.String())
+		assert.Equal(t, "20\tmx.example.net", q.records[1].PreValue)
+		assert.Empty(t, events.events)
+
+		rec = httptest.NewRecorder()
+		req = httptest.NewRequest(http.MethodPost, "/zones/3456/templates/mail/apply", strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+token)
+		r.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "[{\"action\":\"update\",\"record\":{\"id\":1,\"name\":\"@\",\"zone_id\":3456,\"ttl\":null,\"type\":\"MX\",\"value\":{\"target\":\"mx.example.net\",\"preference\":10},\"active\":true}},{\"action\":\"create\",\"record\":{\"id\":2,\"name\":\"www\",\"zone_id\":3456,\"ttl\":null,\"type\":\"CNAME\",\"value\":{\"target\":\"example.com\"},\"active\":true}}]\n", rec.Body.String())
+		assert.Equal(t, "10\tmx.example.net", q.records[1].PreValue)
+		assert.Equal(t, "example.com", q.records[2].PreValue)
+		assert.Equal(t, []webhook.Event{webhook.RecordUpdated, webhook.RecordCreated}, events.events)
+
+		// Applying again makes no changes
+		rec = httptest.NewRecorder()
+		req = httptest.NewRequest(http.MethodPost, "/zones/3456/templates/mail/apply", strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+token)
+		r.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "[{\"action\":\"unchanged\",\"record\":{\"id\":1,\"name\":\"@\",\"zone_id\":3456,\"ttl\":null,\"type\":\"MX\",\"value\":{\"target\":\"mx.example.net\",\"preference\":10},\"active\":true}},{\"action\":\"unchanged\",\"record\":{\"id\":2,\"name\":\"www\",\"zone_id\":3456,\"ttl\":null,\"type\":\"CNAME\",\"value\":{\"target\":\"example.com\"},\"active\":true}}]\n", rec.Body.String())
+		assert.Len(t, events.events, 2)
+	})
+}
+
+func TestAddTemplateRoutesLint(t *testing.T) {
+	r := chi.NewRouter()
+	issuer, err := mjwt.NewIssuer("hello world", "1", jwt.SigningMethodRS256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	q := &rrsetTestQueries{recordTestQueries: recordTestQueries{
+		records: make(map[int64]database.Record),
+	}}
+	events := &recordTestEvents{}
+	templates, err := zonetemplate.Load(map[string][]map[string]any{
+		"mail": {
+			{"name": "@", "type": "MX", "value": map[string]any{"preference": 10, "target": "mx.{{provider}}"}},
+			{"name": "www", "type": "CNAME", "value": map[string]any{"target": "{{zone}}"}},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	AddTemplateRoutes(r, q, issuer.KeyStore(), templates, events)
+	_, err = q.InsertRecordFromApi(t.Context(), database.InsertRecordFromApiParams{
+		Name:      "www",
+		ZoneID:    3456,
+		Type:      "A",
+		PreValue:  "192.0.2.1",
+		PreActive: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ps := auth.NewPermStorage()
+	ps.Set("domain:owns=example.com")
+	token, err := issuer.GenerateJwt("1234", "", jwt.ClaimStrings{}, time.Hour, auth.AccessTokenClaims{Perms: ps})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the CNAME would sit beside the existing A record so nothing is applied
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/zones/3456/templates/mail/apply", strings.NewReader(`{"variables":{"provider":"example.net"}}`))
+	req.Header.Set("Authorization", "Bearer "+token)
+	r.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusConflict, rec.Code)
+	assert.Equal(t, "{\"code\":\"lint_failed\",\"message\":\"Zone lint failed: www.example.com CNAME: CNAME conflicts with the A record\",\"lint\":[{\"severity\":\"error\",\"name\":\"www.example.com\",\"type\":\"CNAME\",\"message\":\"CNAME conflicts with the A record\"}]}\n", rec.Body.String())
+	assert.Len(t, q.records, 1)
+	assert.Empty(t, events.events)
+}
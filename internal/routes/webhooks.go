@@ -0,0 +1,280 @@
+package routes
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/1f349/mjwt"
+	"github.com/1f349/mjwt/auth"
+	"github.com/1f349/verbena/internal/database"
+	"github.com/1f349/verbena/internal/utils"
+	"github.com/1f349/verbena/internal/webhook"
+	"github.com/1f349/verbena/logger"
+	"github.com/1f349/verbena/rest"
+	"github.com/go-chi/chi/v5"
+)
+
+type eventFirer interface {
+	Fire(zoneID int64, zoneName string, event webhook.Event, data any)
+}
+
+type webhookQueries interface {
+	GetZone(ctx context.Context, zoneId int64) (database.Zone, error)
+	GetZoneWebhooks(ctx context.Context, zoneID int64) ([]database.Webhook, error)
+	GetZoneWebhook(ctx context.Context, arg database.GetZoneWebhookParams) (database.Webhook, error)
+	InsertWebhook(ctx context.Context, arg database.InsertWebhookParams) (int64, error)
+	UpdateWebhook(ctx context.Context, arg database.UpdateWebhookParams) error
+	DeleteWebhook(ctx context.Context, arg database.DeleteWebhookParams) error
+	GetWebhookDeliveries(ctx context.Context, webhookID int64) ([]database.WebhookDelivery, error)
+}
+
+func WebhookToRestWebhook(w database.Webhook) rest.Webhook {
+	events := []string{}
+	if w.Events != "" {
+		events = strings.Split(w.Events, ",")
+	}
+	return rest.Webhook{
+		ID:     w.ID,
+		ZoneID: w.ZoneID,
+		Url:    w.Url,
+		Events: events,
+		Active: w.Active,
+	}
+}
+
+// AddWebhookRoutes adds the webhook routes, URLs of hosts which are not public
+// are refused unless allowPrivateTargets is set
+func AddWebhookRoutes(r chi.Router, db webhookQueries, keystore *mjwt.KeyStore, allowPrivateTargets bool) {
+	r.Route("/zones/{zone_id:[0-9]+}/webhooks", func(r chi.Router) {
+		// List all webhooks
+		r.Get("/", validateAuthToken(keystore, func(rw http.ResponseWriter, req *http.Request, b mjwt.BaseTypeClaims[auth.AccessTokenClaims]) {
+			zone, ok := getOwnedZone(rw, req, db, b)
+			if !ok {
+				return
+			}
+
+			rows, err := db.GetZoneWebhooks(req.Context(), zone.ID)
+			if err != nil {
+				logger.Logger.Error("Failed to get zone webhooks", "err", err)
+				writeError(rw, http.StatusInternalServerError, "Database error occurred")
+				return
+			}
+
+			webhooks := make([]rest.Webhook, 0, len(rows))
+			for _, row := range rows {
+				webhooks = append(webhooks, WebhookToRestWebhook(row))
+			}
+			json.NewEncoder(rw).Encode(webhooks)
+		}))
+
+		// Create webhook
+		r.Post("/", validateAuthToken(keystore, func(rw http.ResponseWriter, req *http.Request, b mjwt.BaseTypeClaims[auth.AccessTokenClaims]) {
+			var createWebhook rest.CreateWebhook
+			err := json.NewDecoder(req.Body).Decode(&createWebhook)
+			if err != nil {
+				writeError(rw, http.StatusBadRequest, "Invalid request body")
+				return
+			}
+
+			if !validateWebhookUrl(createWebhook.Url, allowPrivateTargets) {
+				writeFieldError(rw, "url", "Invalid webhook URL")
+				return
+			}
+			if createWebhook.Secret == "" {
+				writeFieldError(rw, "secret", "Missing webhook secret")
+				return
+			}
+			events, ok := joinWebhookEvents(createWebhook.Events)
+			if !ok {
+				writeFieldError(rw, "events", "Invalid webhook event")
+				return
+			}
+
+			zone, ok := getOwnedZone(rw, req, db, b)
+			if !ok {
+				return
+			}
+
+			genId, err := db.InsertWebhook(req.Context(), database.InsertWebhookParams{
+				ZoneID: zone.ID,
+				Url:    createWebhook.Url,
+				Secret: createWebhook.Secret,
+				Events: events,
+				Active: createWebhook.Active,
+			})
+			if err != nil {
+				logger.Logger.Debug("Failed to insert webhook", "err", err)
+				writeError(rw, http.StatusInternalServerError, "Database error occurred")
+				return
+			}
+
+			json.NewEncoder(rw).Encode(WebhookToRestWebhook(database.Webhook{
+				ID:     genId,
+				ZoneID: zone.ID,
+				Url:    createWebhook.Url,
+				Events: events,
+				Active: createWebhook.Active,
+			}))
+		}))
+
+		// Show individual webhook
+		r.Get("/{webhook_id:[0-9]+}", validateAuthToken(keystore, func(rw http.ResponseWriter, req *http.Request, b mjwt.BaseTypeClaims[auth.AccessTokenClaims]) {
+			w, ok := getOwnedWebhook(rw, req, db, b)
+			if !ok {
+				return
+			}
+
+			json.NewEncoder(rw).Encode(WebhookToRestWebhook(w))
+		}))
+
+		// Update webhook
+		r.Put("/{webhook_id:[0-9]+}", validateAuthToken(keystore, func(rw http.ResponseWriter, req *http.Request, b mjwt.BaseTypeClaims[auth.AccessTokenClaims]) {
+			var putWebhook rest.PutWebhook
+			err := json.NewDecoder(req.Body).Decode(&putWebhook)
+			if err != nil {
+				writeError(rw, http.StatusBadRequest, "Invalid request body")
+				return
+			}
+
+			if !validateWebhookUrl(putWebhook.Url, allowPrivateTargets) {
+				writeFieldError(rw, "url", "Invalid webhook URL")
+				return
+			}
+			events, ok := joinWebhookEvents(putWebhook.Events)
+			if !ok {
+				writeFieldError(rw, "events", "Invalid webhook event")
+				return
+			}
+
+			w, ok := getOwnedWebhook(rw, req, db, b)
+			if !ok {
+				return
+			}
+
+			err = db.UpdateWebhook(req.Context(), database.UpdateWebhookParams{
+				Url:    putWebhook.Url,
+				Events: events,
+				Active: putWebhook.Active,
+				ID:     w.ID,
+				ZoneID: w.ZoneID,
+			})
+			if err != nil {
+				logger.Logger.Debug("Failed to update webhook", "err", err)
+				writeError(rw, http.StatusInternalServerError, "Database error occurred")
+				return
+			}
+
+			w.Url = putWebhook.Url
+			w.Events = events
+			w.Active = putWebhook.Active
+			json.NewEncoder(rw).Encode(WebhookToRestWebhook(w))
+		}))
+
+		// Delete webhook
+		r.Delete("/{webhook_id:[0-9]+}", validateAuthToken(keystore, func(rw http.ResponseWriter, req *http.Request, b mjwt.BaseTypeClaims[auth.AccessTokenClaims]) {
+			w, ok := getOwnedWebhook(rw, req, db, b)
+			if !ok {
+				return
+			}
+
+			err := db.DeleteWebhook(req.Context(), database.DeleteWebhookParams{
+				WebhookID: w.ID,
+				ZoneID:    w.ZoneID,
+			})
+			if err != nil {
+				logger.Logger.Debug("Failed to delete webhook", "err", err)
+				writeError(rw, http.StatusInternalServerError, "Database error occurred")
+				return
+			}
+
+			rw.WriteHeader(http.StatusOK)
+		}))
+
+		// List recent deliveries
+		r.Get("/{webhook_id:[0-9]+}/deliveries", validateAuthToken(keystore, func(rw http.ResponseWriter, req *http.Request, b mjwt.BaseTypeClaims[auth.AccessTokenClaims]) {
+			w, ok := getOwnedWebhook(rw, req, db, b)
+			if !ok {
+				return
+			}
+
+			rows, err := db.GetWebhookDeliveries(req.Context(), w.ID)
+			if err != nil {
+				logger.Logger.Error("Failed to get webhook deliveries", "err", err)
+				writeError(rw, http.StatusInternalServerError, "Database error occurred")
+				return
+			}
+
+			deliveries := make([]rest.WebhookDelivery, 0, len(rows))
+			for _, row := range rows {
+				deliveries = append(deliveries, rest.WebhookDelivery{
+					ID:         row.ID,
+					Event:      row.Event,
+					Payload:    json.RawMessage(row.Payload),
+					Attempt:    row.Attempt,
+					StatusCode: row.StatusCode,
+					Error:      row.Error,
+					Success:    row.Success,
+					Time:       time.Unix(row.CreatedAt, 0).UTC(),
+				})
+			}
+			json.NewEncoder(rw).Encode(deliveries)
+		}))
+	})
+}
+
+func getOwnedWebhook(rw http.ResponseWriter, req *http.Request, db webhookQueries, b mjwt.BaseTypeClaims[auth.AccessTokenClaims]) (database.Webhook, bool) {
+	webhookId, err := strconv.ParseInt(chi.URLParam(req, "webhook_id"), 10, 64)
+	if err != nil {
+		writeFieldError(rw, "webhook_id", "Invalid webhook ID")
+		return database.Webhook{}, false
+	}
+
+	zone, ok := getOwnedZone(rw, req, db, b)
+	if !ok {
+		return database.Webhook{}, false
+	}
+
+	w, err := db.GetZoneWebhook(req.Context(), database.GetZoneWebhookParams{
+		WebhookID: webhookId,
+		ZoneID:    zone.ID,
+	})
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		writeNotFound(rw)
+		return database.Webhook{}, false
+	case err != nil:
+		logger.Logger.Error("Failed to get webhook", "err", err)
+		writeError(rw, http.StatusInternalServerError, "Database error occurred")
+		return database.Webhook{}, false
+	}
+	return w, true
+}
+
+// validateWebhookUrl checks the URL is http or https, names are also checked
+// by the dispatcher once resolved
+func validateWebhookUrl(rawUrl string, allowPrivate bool) bool {
+	u, err := url.Parse(rawUrl)
+	if err != nil {
+		return false
+	}
+	if u.Scheme != "http" && u.Scheme != "https" || u.Host == "" {
+		return false
+	}
+	return allowPrivate || utils.IsPublicHost(u.Hostname())
+}
+
+func joinWebhookEvents(events []string) (string, bool) {
+	for _, e := range events {
+		if !webhook.Event(e).IsValid() {
+			return "", false
+		}
+	}
+	return strings.Join(events, ","), true
+}
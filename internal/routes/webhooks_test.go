@@ -0,0 +1,195 @@
+package routes
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/1f349/mjwt"
+	"github.com/1f349/mjwt/auth"
+	"github.com/1f349/verbena/internal/database"
+	"github.com/go-chi/chi/v5"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+type webhookTestQueries struct {
+	zoneFileTestQueries
+	webhooks map[int64]database.Webhook
+	nextId   int64
+}
+
+func (w *webhookTestQueries) GetZoneWebhooks(ctx context.Context, zoneID int64) ([]database.Webhook, error) {
+	var rows []database.Webhook
+	for _, row := range w.webhooks {
+		if row.ZoneID == zoneID {
+			rows = append(rows, row)
+		}
+	}
+	return rows, nil
+}
+
+func (w *webhookTestQueries) GetZoneWebhook(ctx context.Context, arg database.GetZoneWebhookParams) (database.Webhook, error) {
+	row, ok := w.webhooks[arg.WebhookID]
+	if !ok || row.ZoneID != arg.ZoneID {
+		return database.Webhook{}, sql.ErrNoRows
+	}
+	return row, nil
+}
+
+func (w *webhookTestQueries) InsertWebhook(ctx context.Context, arg database.InsertWebhookParams) (int64, error) {
+	w.nextId++
+	w.webhooks[w.nextId] = database.Webhook{
+		ID:     w.nextId,
+		ZoneID: arg.ZoneID,
+		Url:    arg.Url,
+		Secret: arg.Secret,
+		Events: arg.Events,
+		Active: arg.Active,
+	}
+	return w.nextId, nil
+}
+
+func (w *webhookTestQueries) UpdateWebhook(ctx context.Context, arg database.UpdateWebhookParams) error {
+	row, ok := w.webhooks[arg.ID]
+	if !ok || row.ZoneID != arg.ZoneID {
+		return sql.ErrNoRows
+	}
+	row.Url = arg.Url
+	row.Events = arg.Events
+	row.Active = arg.Active
+	w.webhooks[arg.ID] = row
+	return nil
+}
+
+func (w *webhookTestQueries) DeleteWebhook(ctx context.Context, arg database.DeleteWebhookParams) error {
+	delete(w.webhooks, arg.WebhookID)
+	return nil
+}
+
+func (w *webhookTestQueries) GetWebhookDeliveries(ctx context.Context, webhookID int64) ([]database.WebhookDelivery, error) {
+	return []database.WebhookDelivery{
+		{
+			ID:         1,
+			WebhookID:  webhookID,
+			Event:      "record.created",
+			Payload:    `{"event":"record.created"}`,
+			Attempt:    1,
+			StatusCode: 200,
+			Success:    true,
+			CreatedAt:  1751112000,
+		},
+	}, nil
+}
+
+func TestAddWebhookRoutes(t *testing.T) {
+	r := chi.NewRouter()
+	issuer, err := mjwt.NewIssuer("hello world", "1", jwt.SigningMethodRS256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	q := &webhookTestQueries{webhooks: make(map[int64]database.Webhook)}
+	AddWebhookRoutes(r, q, issuer.KeyStore(), false)
+
+	ps := auth.NewPermStorage()
+	ps.Set("domain:owns=example.org")
+	wrongToken, err := issuer.GenerateJwt("1234", "", jwt.ClaimStrings{}, time.Hour, auth.AccessTokenClaims{Perms: ps})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ps = auth.NewPermStorage()
+	ps.Set("domain:owns=example.com")
+	token, err := issuer.GenerateJwt("1234", "", jwt.ClaimStrings{}, time.Hour, auth.AccessTokenClaims{Perms: ps})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("POST /zones/3456/webhooks", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/zones/3456/webhooks", nil)
+		r.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+
+		body := `{"url":"https://hooks.example.com/dns","secret":"abc","events":["record.created"],"active":true}`
+
+		rec = httptest.NewRecorder()
+		req = httptest.NewRequest(http.MethodPost, "/zones/3456/webhooks", strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+wrongToken)
+		r.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+
+		rec = httptest.NewRecorder()
+		req = httptest.NewRequest(http.MethodPost, "/zones/3456/webhooks", strings.NewReader(`{"url":"ftp://hooks.example.com","secret":"abc"}`))
+		req.Header.Set("Authorization", "Bearer "+token)
+		r.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+		for _, u := range []string{"http://127.0.0.1:8080/hook", "http://169.254.169.254/latest", "https://[fd00::1]/hook", "http://localhost/hook"} {
+			rec = httptest.NewRecorder()
+			req = httptest.NewRequest(http.MethodPost, "/zones/3456/webhooks", strings.NewReader(`{"url":"`+u+`","secret":"abc"}`))
+			req.Header.Set("Authorization", "Bearer "+token)
+			r.ServeHTTP(rec, req)
+			assert.Equal(t, http.StatusBadRequest, rec.Code, u)
+			assert.Equal(t, "{\"code\":\"validation_failed\",\"message\":\"Invalid webhook URL\",\"field\":\"url\"}\n", rec.Body.String(), u)
+		}
+
+		rec = httptest.NewRecorder()
+		req = httptest.NewRequest(http.MethodPost, "/zones/3456/webhooks", strings.NewReader(`{"url":"https://hooks.example.com/dns","secret":"abc","events":["record.exploded"]}`))
+		req.Header.Set("Authorization", "Bearer "+token)
+		r.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+		rec = httptest.NewRecorder()
+		req = httptest.NewRequest(http.MethodPost, "/zones/3456/webhooks", strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+token)
+		r.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "{\"id\":1,\"zone_id\":3456,\"url\":\"https://hooks.example.com/dns\",\"events\":[\"record.created\"],\"active\":true}\n", rec.Body.String())
+	})
+
+	t.Run("GET /zones/3456/webhooks", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/zones/3456/webhooks", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		r.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "[{\"id\":1,\"zone_id\":3456,\"url\":\"https://hooks.example.com/dns\",\"events\":[\"record.created\"],\"active\":true}]\n", rec.Body.String())
+	})
+
+	t.Run("PUT /zones/3456/webhooks/1", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPut, "/zones/3456/webhooks/1", strings.NewReader(`{"url":"https://hooks.example.com/v2","events":[],"active":false}`))
+		req.Header.Set("Authorization", "Bearer "+token)
+		r.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "{\"id\":1,\"zone_id\":3456,\"url\":\"https://hooks.example.com/v2\",\"events\":[],\"active\":false}\n", rec.Body.String())
+
+		rec = httptest.NewRecorder()
+		req = httptest.NewRequest(http.MethodPut, "/zones/3456/webhooks/2", strings.NewReader(`{"url":"https://hooks.example.com/v2"}`))
+		req.Header.Set("Authorization", "Bearer "+token)
+		r.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+	})
+
+	t.Run("GET /zones/3456/webhooks/1/deliveries", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/zones/3456/webhooks/1/deliveries", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		r.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "[{\"id\":1,\"event\":\"record.created\",\"payload\":{\"event\":\"record.created\"},\"attempt\":1,\"status_code\":200,\"success\":true,\"time\":\"2025-06-28T12:00:00Z\"}]\n", rec.Body.String())
+	})
+
+	t.Run("DELETE /zones/3456/webhooks/1", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodDelete, "/zones/3456/webhooks/1", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		r.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Empty(t, q.webhooks)
+	})
+}
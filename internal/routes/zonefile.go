@@ -4,9 +4,12 @@ import (
 	"context"
 	"io"
 	"net/http"
+	"slices"
+	"strings"
 
 	"github.com/1f349/mjwt"
 	"github.com/1f349/mjwt/auth"
+	"github.com/1f349/verbena/conf"
 	"github.com/1f349/verbena/internal/database"
 	"github.com/1f349/verbena/logger"
 	"github.com/go-chi/chi/v5"
@@ -16,28 +19,40 @@ type zoneFileQueries interface {
 	GetZone(ctx context.Context, zoneId int64) (database.Zone, error)
 }
 
-type previewFunc func(ctx context.Context, w io.Writer, zoneInfo database.Zone) error
+type previewFunc func(ctx context.Context, w io.Writer, zoneInfo database.Zone, view string) error
 
-func AddZoneFileRoutes(r chi.Router, db zoneFileQueries, keystore *mjwt.KeyStore, preview previewFunc) {
+// AddZoneFileRoutes adds the zone file preview, the view query parameter
+// selects the view to preview when split-horizon views are configured
+func AddZoneFileRoutes(r chi.Router, db zoneFileQueries, keystore *mjwt.KeyStore, views conf.ViewsConf, preview previewFunc) {
 	r.Post("/zones/{zone_id:[0-9]+}/zone-file", validateAuthToken(keystore, func(rw http.ResponseWriter, req *http.Request, b mjwt.BaseTypeClaims[auth.AccessTokenClaims]) {
 		zoneId, err := getZoneId(req)
 		if err != nil {
-			http.Error(rw, "Invalid zone ID", http.StatusBadRequest)
+			writeFieldError(rw, "zone_id", "Invalid zone ID")
+			return
+		}
+
+		view := req.URL.Query().Get("view")
+		if len(views) > 0 && !slices.Contains(views.Names(), view) {
+			writeFieldError(rw, "view", "Invalid view, expected one of: "+strings.Join(views.Names(), ", "))
+			return
+		}
+		if len(views) == 0 && view != "" {
+			writeFieldError(rw, "view", "Invalid view, no views are configured")
 			return
 		}
 
 		zone, err := db.GetZone(req.Context(), zoneId)
 		if err != nil {
 			logger.Logger.Error("Failed to get zone", "err", err)
-			http.Error(rw, "Database error occurred", http.StatusInternalServerError)
+			writeError(rw, http.StatusInternalServerError, "Database error occurred")
 			return
 		}
 
 		if !b.Claims.Perms.Has("domain:owns=" + zone.Name) {
-			http.NotFound(rw, req)
+			writeNotFound(rw)
 			return
 		}
 
-		_ = preview(req.Context(), rw, zone)
+		_ = preview(req.Context(), rw, zone, view)
 	}))
 }
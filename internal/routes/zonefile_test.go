@@ -12,6 +12,7 @@ import (
 
 	"github.com/1f349/mjwt"
 	"github.com/1f349/mjwt/auth"
+	"github.com/1f349/verbena/conf"
 	"github.com/1f349/verbena/internal/database"
 	"github.com/go-chi/chi/v5"
 	"github.com/golang-jwt/jwt/v4"
@@ -40,7 +41,7 @@ func TestAddZoneFileRoutes(t *testing.T) {
 		t.Fatal(err)
 	}
 	q := &zoneFileTestQueries{}
-	AddZoneFileRoutes(r, q, issuer.KeyStore(), func(ctx context.Context, w io.Writer, zoneInfo database.Zone) error {
+	AddZoneFileRoutes(r, q, issuer.KeyStore(), nil, func(ctx context.Context, w io.Writer, zoneInfo database.Zone, view string) error {
 		_, err := fmt.Fprintln(w, "example zone file")
 		return err
 	})
@@ -84,3 +85,42 @@ func TestAddZoneFileRoutes(t *testing.T) {
 		assert.Equal(t, "example zone file\n", rec.Body.String())
 	})
 }
+
+func TestAddZoneFileRoutesViews(t *testing.T) {
+	r := chi.NewRouter()
+	issuer, err := mjwt.NewIssuer("hello world", "1", jwt.SigningMethodRS256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	q := &zoneFileTestQueries{}
+	views := conf.ViewsConf{
+		{Name: "internal", MatchClients: []string{"10.0.0.0/8"}},
+		{Name: "external", MatchClients: []string{"any"}},
+	}
+	AddZoneFileRoutes(r, q, issuer.KeyStore(), views, func(ctx context.Context, w io.Writer, zoneInfo database.Zone, view string) error {
+		_, err := fmt.Fprintln(w, "example zone file for", view)
+		return err
+	})
+
+	ps := auth.NewPermStorage()
+	ps.Set("domain:owns=example.com")
+	token, err := issuer.GenerateJwt("1234", "", jwt.ClaimStrings{}, time.Hour, auth.AccessTokenClaims{Perms: ps})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, view := range []string{"", "unknown"} {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/zones/3456/zone-file?view="+view, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		r.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusBadRequest, rec.Code, view)
+	}
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/zones/3456/zone-file?view=internal", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	r.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "example zone file for internal\n", rec.Body.String())
+}
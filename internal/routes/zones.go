@@ -7,15 +7,16 @@ import (
 	"errors"
 	"net/http"
 	"strconv"
+	"strings"
 
 	"github.com/1f349/mjwt"
 	"github.com/1f349/mjwt/auth"
 	"github.com/1f349/verbena/conf"
 	"github.com/1f349/verbena/internal/database"
+	"github.com/1f349/verbena/internal/utils"
 	"github.com/1f349/verbena/logger"
 	"github.com/1f349/verbena/rest"
 	"github.com/go-chi/chi/v5"
-	"github.com/miekg/dns"
 )
 
 const oneDaySeconds = 60 * 60 * 24
@@ -38,20 +39,21 @@ type zoneQueries interface {
 	GetOwnedZones(ctx context.Context, userID string) ([]database.GetOwnedZonesRow, error)
 	GetZone(ctx context.Context, id int64) (database.Zone, error)
 	LookupZone(ctx context.Context, name string) (int64, error)
-	UpdateZoneConfig(ctx context.Context, updateZoneConfigParams database.UpdateZoneConfigParams) error
+	UpdateZoneConfig(ctx context.Context, updateZoneConfigParams database.UpdateZoneConfigParams) (int64, error)
 }
 
 func ZoneToRestZone(zone database.Zone, nameservers []string) rest.Zone {
 	return rest.Zone{
-		ID:      zone.ID,
-		Name:    zone.Name,
-		Serial:  uint32(zone.Serial),
-		Admin:   zone.Admin,
-		Refresh: zone.Refresh,
-		Retry:   zone.Retry,
-		Expire:  zone.Expire,
-		Ttl:     zone.Ttl,
-		Active:  zone.Active,
+		ID:          zone.ID,
+		Name:        zone.Name,
+		NameUnicode: utils.UnicodeNameIfDifferent(zone.Name),
+		Serial:      uint32(zone.Serial),
+		Admin:       zone.Admin,
+		Refresh:     zone.Refresh,
+		Retry:       zone.Retry,
+		Expire:      zone.Expire,
+		Ttl:         zone.Ttl,
+		Active:      zone.Active,
 
 		Nameservers: nameservers,
 	}
@@ -63,7 +65,7 @@ func AddZoneRoutes(r chi.Router, db zoneQueries, keystore *mjwt.KeyStore, namese
 		zones, err := db.GetOwnedZones(req.Context(), b.Subject)
 		if err != nil {
 			logger.Logger.Error("Failed to get owned zones", "err", err)
-			http.Error(rw, "Database error occurred", http.StatusInternalServerError)
+			writeError(rw, http.StatusInternalServerError, "Database error occurred")
 			return
 		}
 
@@ -82,25 +84,26 @@ func AddZoneRoutes(r chi.Router, db zoneQueries, keystore *mjwt.KeyStore, namese
 	r.Get("/zones/{zone_id:[0-9]+}", validateAuthToken(keystore, func(rw http.ResponseWriter, req *http.Request, b mjwt.BaseTypeClaims[auth.AccessTokenClaims]) {
 		zoneId, err := getZoneId(req)
 		if err != nil {
-			http.Error(rw, "Invalid zone ID", http.StatusBadRequest)
+			writeFieldError(rw, "zone_id", "Invalid zone ID")
 			return
 		}
 
 		zone, err := db.GetZone(req.Context(), zoneId)
 		switch {
 		case errors.Is(err, sql.ErrNoRows):
-			http.NotFound(rw, req)
+			writeNotFound(rw)
 			return
 		case err != nil:
 			logger.Logger.Error("Failed to get zone", "err", err)
-			http.Error(rw, "Database error occurred", http.StatusInternalServerError)
+			writeError(rw, http.StatusInternalServerError, "Database error occurred")
 			return
 		}
 
 		if !b.Claims.Perms.Has("domain:owns=" + zone.Name) {
-			http.NotFound(rw, req)
+			writeNotFound(rw)
 			return
 		}
+		setETag(rw, zone.Version)
 		json.NewEncoder(rw).Encode(ZoneToRestZone(zone, nameservers.GetNameserversForZone(zone)))
 	}))
 
@@ -108,7 +111,7 @@ func AddZoneRoutes(r chi.Router, db zoneQueries, keystore *mjwt.KeyStore, namese
 	r.Put("/zones/{zone_id:[0-9]+}", validateAuthToken(keystore, func(rw http.ResponseWriter, req *http.Request, b mjwt.BaseTypeClaims[auth.AccessTokenClaims]) {
 		zoneId, err := getZoneId(req)
 		if err != nil {
-			http.Error(rw, "Invalid zone ID", http.StatusBadRequest)
+			writeFieldError(rw, "zone_id", "Invalid zone ID")
 			return
 		}
 
@@ -118,71 +121,85 @@ func AddZoneRoutes(r chi.Router, db zoneQueries, keystore *mjwt.KeyStore, namese
 		err = dec.Decode(&updates)
 		if err != nil {
 			logger.Logger.Error("Failed to decode zone updates", "err", err)
-			http.Error(rw, "Invalid zone update configuration", http.StatusBadRequest)
+			writeError(rw, http.StatusBadRequest, "Invalid zone update configuration")
 			return
 		}
 
 		if updates.Refresh > refreshMaxOneWeek {
-			http.Error(rw, "Invalid refresh value, expected less than one week", http.StatusBadRequest)
+			writeFieldError(rw, "refresh", "Invalid refresh value, expected less than one week")
 			return
 		}
 		if updates.Retry > retryMaxOneWeek {
-			http.Error(rw, "Invalid retry value, expected less than one week", http.StatusBadRequest)
+			writeFieldError(rw, "retry", "Invalid retry value, expected less than one week")
 			return
 		}
 		if updates.Expire > expireMax90Days {
-			http.Error(rw, "Invalid expire value, expected less than 90 days", http.StatusBadRequest)
+			writeFieldError(rw, "expire", "Invalid expire value, expected less than 90 days")
 			return
 		}
 		if updates.Ttl > ttlMaxOneWeek {
-			http.Error(rw, "Invalid time-to-live value, expected less than one week", http.StatusBadRequest)
+			writeFieldError(rw, "ttl", "Invalid time-to-live value, expected less than one week")
 			return
 		}
 
 		zone, err := db.GetZone(req.Context(), zoneId)
 		switch {
 		case errors.Is(err, sql.ErrNoRows):
-			http.NotFound(rw, req)
+			writeNotFound(rw)
 			return
 		case err != nil:
 			logger.Logger.Error("Failed to get zone", "err", err)
-			http.Error(rw, "Database error occurred", http.StatusInternalServerError)
+			writeError(rw, http.StatusInternalServerError, "Database error occurred")
 			return
 		}
 
 		if !b.Claims.Perms.Has("domain:owns=" + zone.Name) {
-			http.NotFound(rw, req)
+			writeNotFound(rw)
 			return
 		}
 
-		err = db.UpdateZoneConfig(req.Context(), database.UpdateZoneConfigParams{
+		if !checkIfMatch(rw, req, zone.Version, "zone") {
+			return
+		}
+
+		updated, err := db.UpdateZoneConfig(req.Context(), database.UpdateZoneConfigParams{
 			Refresh: updates.Refresh,
 			Retry:   updates.Retry,
 			Expire:  updates.Expire,
 			Ttl:     updates.Ttl,
 			ID:      zoneId,
+			Version: zone.Version,
 		})
 		if err != nil {
 			logger.Logger.Error("Failed to update zone config", "err", err)
-			http.Error(rw, "Database error occurred", http.StatusInternalServerError)
+			writeError(rw, http.StatusInternalServerError, "Database error occurred")
+			return
+		}
+		if updated == 0 {
+			writeModified(rw, req, "zone")
 			return
 		}
+		setETag(rw, zone.Version+1)
 		http.Error(rw, "OK", http.StatusOK)
 	}))
 
-	r.Get("/zones/lookup/{zone_name:[a-z0-9-.]+}", validateAuthToken(keystore, func(rw http.ResponseWriter, req *http.Request, b mjwt.BaseTypeClaims[auth.AccessTokenClaims]) {
-		zoneName := chi.URLParam(req, "zone_name")
+	r.Get("/zones/lookup/{zone_name}", validateAuthToken(keystore, func(rw http.ResponseWriter, req *http.Request, b mjwt.BaseTypeClaims[auth.AccessTokenClaims]) {
+		// Unicode zone names are stored as punycode
+		zoneName, err := utils.ToASCIIName(strings.ToLower(chi.URLParam(req, "zone_name")))
+		if err != nil {
+			writeFieldError(rw, "zone_name", "Invalid zone name")
+			return
+		}
 
 		// Check if zone looks real
-		_, validDomain := dns.IsDomainName(zoneName)
-		if !validDomain {
-			http.Error(rw, "Invalid zone name", http.StatusBadRequest)
+		if !utils.ValidateDomainName(zoneName) {
+			writeFieldError(rw, "zone_name", "Invalid zone name")
 			return
 		}
 
 		// Check ownership
 		if !b.Claims.Perms.Has("domain:owns=" + zoneName) {
-			http.NotFound(rw, req)
+			writeNotFound(rw)
 			return
 		}
 
@@ -199,6 +216,33 @@ func AddZoneRoutes(r chi.Router, db zoneQueries, keystore *mjwt.KeyStore, namese
 	}))
 }
 
+type ownedZoneQueries interface {
+	GetZone(ctx context.Context, zoneId int64) (database.Zone, error)
+}
+
+// getOwnedZone loads the zone from the URL and checks the token owns it, an
+// error response is written when false is returned
+func getOwnedZone(rw http.ResponseWriter, req *http.Request, db ownedZoneQueries, b mjwt.BaseTypeClaims[auth.AccessTokenClaims]) (database.Zone, bool) {
+	zoneId, err := getZoneId(req)
+	if err != nil {
+		writeFieldError(rw, "zone_id", "Invalid zone ID")
+		return database.Zone{}, false
+	}
+
+	zone, err := db.GetZone(req.Context(), zoneId)
+	if err != nil {
+		logger.Logger.Error("Failed to get zone", "err", err)
+		writeError(rw, http.StatusInternalServerError, "Database error occurred")
+		return database.Zone{}, false
+	}
+
+	if !b.Claims.Perms.Has("domain:owns=" + zone.Name) {
+		writeNotFound(rw)
+		return database.Zone{}, false
+	}
+	return zone, true
+}
+
 func getZoneId(req *http.Request) (int64, error) {
 	zoneIdRaw := chi.URLParam(req, "zone_id")
 	return strconv.ParseInt(zoneIdRaw, 10, 64)
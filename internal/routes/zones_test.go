@@ -22,13 +22,16 @@ import (
 type zoneTestQueries struct {
 }
 
-func (z *zoneTestQueries) UpdateZoneConfig(ctx context.Context, updateZoneConfigParams database.UpdateZoneConfigParams) error {
+func (z *zoneTestQueries) UpdateZoneConfig(ctx context.Context, updateZoneConfigParams database.UpdateZoneConfigParams) (int64, error) {
 	if updateZoneConfigParams.ID != 3456 {
-		return sql.ErrNoRows
+		return 0, sql.ErrNoRows
+	}
+	if updateZoneConfigParams.Version != 5 {
+		return 0, nil
 	}
 
 	// Fake update zone config
-	return nil
+	return 1, nil
 }
 
 func (z *zoneTestQueries) GetOwnedZones(ctx context.Context, userID string) ([]database.GetOwnedZonesRow, error) {
@@ -69,15 +72,18 @@ func (z *zoneTestQueries) GetZone(ctx context.Context, zoneId int64) (database.Z
 		Expire:  12,
 		Ttl:     13,
 		Active:  true,
+		Version: 5,
 	}, nil
 }
 
 func (z *zoneTestQueries) LookupZone(ctx context.Context, name string) (int64, error) {
-	if name != "example.com" {
-		return 0, sql.ErrNoRows
+	switch name {
+	case "example.com":
+		return 3456, nil
+	case "xn--bcher-kva.example":
+		return 3457, nil
 	}
-
-	return 3456, nil
+	return 0, sql.ErrNoRows
 }
 
 func TestAddZoneRoutes(t *testing.T) {
@@ -151,6 +157,7 @@ func TestAddZoneRoutes(t *testing.T) {
 		r.ServeHTTP(rec, req)
 		assert.Equal(t, http.StatusOK, rec.Code)
 		assert.Equal(t, "{\"id\":3456,\"name\":\"example.com\",\"serial\":2025062801,\"admin\":\"admin.example.com\",\"refresh\":10,\"retry\":11,\"expire\":12,\"ttl\":13,\"active\":true,\"nameservers\":[\"ns1.example.com\",\"ns2.example.com\"]}\n", rec.Body.String())
+		assert.Equal(t, `"5"`, rec.Header().Get("ETag"))
 	})
 
 	t.Run("PUT /zones/{id}", func(t *testing.T) {
@@ -237,6 +244,23 @@ func TestAddZoneRoutes(t *testing.T) {
 		r.ServeHTTP(rec, req)
 		assert.Equal(t, http.StatusOK, rec.Code)
 		assert.Equal(t, "OK\n", rec.Body.String())
+		assert.Equal(t, `"6"`, rec.Header().Get("ETag"))
+
+		rec = httptest.NewRecorder()
+		req = httptest.NewRequest(http.MethodPut, "/zones/3456", bytes.NewReader(zoneUpdatesValidJson))
+		req.Header.Set("Authorization", "Bearer "+token)
+		req.Header.Set("If-Match", `"4"`)
+		r.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusPreconditionFailed, rec.Code)
+		assert.Equal(t, "{\"code\":\"precondition_failed\",\"message\":\"Precondition failed: the zone has been modified\"}\n", rec.Body.String())
+
+		rec = httptest.NewRecorder()
+		req = httptest.NewRequest(http.MethodPut, "/zones/3456", bytes.NewReader(zoneUpdatesValidJson))
+		req.Header.Set("Authorization", "Bearer "+token)
+		req.Header.Set("If-Match", `"4", "5"`)
+		r.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, `"6"`, rec.Header().Get("ETag"))
 	})
 
 	t.Run("/zones/lookup/{name}", func(t *testing.T) {
@@ -269,5 +293,19 @@ func TestAddZoneRoutes(t *testing.T) {
 		r.ServeHTTP(rec, req)
 		assert.Equal(t, http.StatusOK, rec.Code)
 		assert.Equal(t, "{\"id\":3456}\n", rec.Body.String())
+
+		// Unicode names are looked up as punycode
+		rec = httptest.NewRecorder()
+		req = httptest.NewRequest(http.MethodGet, "/zones/lookup/B%C3%BCcher.example", nil)
+		ps = auth.NewPermStorage()
+		ps.Set("domain:owns=xn--bcher-kva.example")
+		token, err = issuer.GenerateJwt("1234", "", jwt.ClaimStrings{}, time.Hour, auth.AccessTokenClaims{Perms: ps})
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+		r.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "{\"id\":3457}\n", rec.Body.String())
 	})
 }
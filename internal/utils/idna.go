@@ -0,0 +1,62 @@
+package utils
+
+import (
+	"fmt"
+	"strings"
+
+	"golang.org/x/net/idna"
+)
+
+// ToASCIIName converts the Unicode labels of a domain or record name to
+// punycode using IDNA2008. ASCII labels are left alone so "@", wildcard and
+// underscore labels which are not valid hostnames can be used in record names.
+func ToASCIIName(name string) (string, error) {
+	labels := strings.Split(name, ".")
+	for i, label := range labels {
+		if isASCII(label) {
+			continue
+		}
+		a, err := idna.Lookup.ToASCII(label)
+		if err != nil {
+			return "", fmt.Errorf("invalid internationalized label %s: %w", label, err)
+		}
+		labels[i] = a
+	}
+	return strings.Join(labels, "."), nil
+}
+
+// ToUnicodeName converts the punycode labels of a name to Unicode for display,
+// labels which are not valid punycode are left unchanged
+func ToUnicodeName(name string) string {
+	labels := strings.Split(name, ".")
+	for i, label := range labels {
+		if !strings.HasPrefix(strings.ToLower(label), "xn--") {
+			continue
+		}
+		u, err := idna.Display.ToUnicode(label)
+		if err != nil {
+			continue
+		}
+		labels[i] = u
+	}
+	return strings.Join(labels, ".")
+}
+
+// UnicodeNameIfDifferent returns the Unicode form of the name or an empty
+// string when it is the same as the name
+func UnicodeNameIfDifferent(name string) string {
+	u := ToUnicodeName(name)
+	if u == name {
+		return ""
+	}
+	return u
+}
+
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= 0x80 {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,36 @@
+package utils
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestToASCIIName(t *testing.T) {
+	for _, i := range []struct {
+		name  string
+		ascii string
+	}{
+		{"example.com", "example.com"},
+		{"bücher.example", "xn--bcher-kva.example"},
+		{"Bücher.example.", "xn--bcher-kva.example."},
+		{"_dmarc.bücher", "_dmarc.xn--bcher-kva"},
+		{"*.例え", "*.xn--r8jz45g"},
+		{"@", "@"},
+	} {
+		ascii, err := ToASCIIName(i.name)
+		assert.NoError(t, err, i.name)
+		assert.Equal(t, i.ascii, ascii, i.name)
+	}
+
+	_, err := ToASCIIName("bü cher.example")
+	assert.Error(t, err)
+}
+
+func TestToUnicodeName(t *testing.T) {
+	assert.Equal(t, "bücher.example", ToUnicodeName("xn--bcher-kva.example"))
+	assert.Equal(t, "*.例え", ToUnicodeName("*.xn--r8jz45g"))
+	assert.Equal(t, "xn--invalid-.example", ToUnicodeName("xn--invalid-.example"))
+	assert.Equal(t, "", UnicodeNameIfDifferent("example.com"))
+	assert.Equal(t, "bücher.example", UnicodeNameIfDifferent("xn--bcher-kva.example"))
+}
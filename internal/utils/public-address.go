@@ -0,0 +1,55 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"net/netip"
+	"strings"
+	"syscall"
+)
+
+// ErrNotPublicAddr is returned when connecting to an address which is not
+// public without internal targets being allowed
+var ErrNotPublicAddr = errors.New("address is not public")
+
+// sharedAddressSpace is the carrier-grade NAT range from RFC 6598, it is not
+// covered by netip.Addr.IsPrivate
+var sharedAddressSpace = netip.MustParsePrefix("100.64.0.0/10")
+
+// IsPublicAddr reports if the address can be reached over the internet,
+// loopback, private, shared, link-local, multicast and unspecified addresses
+// are not
+func IsPublicAddr(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	return addr.IsValid() &&
+		!sharedAddressSpace.Contains(addr) &&
+		!addr.IsLoopback() &&
+		!addr.IsPrivate() &&
+		!addr.IsLinkLocalUnicast() &&
+		!addr.IsMulticast() &&
+		!addr.IsUnspecified()
+}
+
+// IsPublicHost rejects literal addresses which are not public and localhost,
+// other names need checking with PublicDialControl once resolved
+func IsPublicHost(host string) bool {
+	if addr, err := netip.ParseAddr(host); err == nil {
+		return IsPublicAddr(addr)
+	}
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	return host != "localhost" && !strings.HasSuffix(host, ".localhost")
+}
+
+// PublicDialControl is the Control function of a net.Dialer which refuses
+// connections to addresses which are not public. It runs after the host is
+// resolved so names pointing at internal addresses are refused too.
+func PublicDialControl(network, address string, _ syscall.RawConn) error {
+	addrPort, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return err
+	}
+	if !IsPublicAddr(addrPort.Addr()) {
+		return fmt.Errorf("%w: %s", ErrNotPublicAddr, addrPort.Addr())
+	}
+	return nil
+}
@@ -0,0 +1,42 @@
+package utils
+
+import (
+	"net/netip"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsPublicAddr(t *testing.T) {
+	for _, i := range []struct {
+		addr   string
+		public bool
+	}{
+		{"192.0.2.1", true},
+		{"2001:db8::1", true},
+		{"127.0.0.1", false},
+		{"::1", false},
+		{"10.1.2.3", false},
+		{"172.16.0.1", false},
+		{"192.168.1.1", false},
+		{"100.64.0.1", false},
+		{"100.127.255.254", false},
+		{"::ffff:100.64.0.1", false},
+		{"100.128.0.1", true},
+		{"fd00::1", false},
+		{"169.254.169.254", false},
+		{"fe80::1", false},
+		{"0.0.0.0", false},
+		{"::", false},
+		{"224.0.0.1", false},
+		{"::ffff:127.0.0.1", false},
+	} {
+		assert.Equal(t, i.public, IsPublicAddr(netip.MustParseAddr(i.addr)), i.addr)
+	}
+}
+
+func TestPublicDialControl(t *testing.T) {
+	assert.NoError(t, PublicDialControl("tcp", "192.0.2.1:443", nil))
+	assert.ErrorIs(t, PublicDialControl("tcp", "127.0.0.1:443", nil), ErrNotPublicAddr)
+	assert.ErrorIs(t, PublicDialControl("udp", "[fe80::1%eth0]:53", nil), ErrNotPublicAddr)
+}
@@ -0,0 +1,68 @@
+package utils
+
+import (
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// caaCriticalFlag is the issuer critical flag, all other bits are reserved
+const caaCriticalFlag = 128
+
+var (
+	caaIssuerDomainName = regexp.MustCompile(`^[a-zA-Z0-9]+(-*[a-zA-Z0-9]+)*(\.[a-zA-Z0-9]+(-*[a-zA-Z0-9]+)*)*$`)
+	caaParameterTag     = regexp.MustCompile(`^[a-zA-Z0-9]+$`)
+	caaParameterValue   = regexp.MustCompile(`^[\x21-\x3a\x3c-\x7e]*$`)
+)
+
+// ValidateCaa checks a CAA record against RFC 8659 and RFC 9495 for the
+// issue, issuewild, issuemail and iodef tags
+func ValidateCaa(flags uint8, tag, value string) bool {
+	if flags != 0 && flags != caaCriticalFlag {
+		return false
+	}
+	if !ValidateCharacterString(value) {
+		return false
+	}
+	switch tag {
+	case "issue", "issuewild", "issuemail":
+		return validateCaaIssuerValue(value)
+	case "iodef":
+		u, err := url.Parse(value)
+		if err != nil {
+			return false
+		}
+		switch u.Scheme {
+		case "mailto":
+			return u.Opaque != ""
+		case "http", "https":
+			return u.Host != ""
+		default:
+			return false
+		}
+	default:
+		return false
+	}
+}
+
+// validateCaaIssuerValue checks the value is an optional issuer domain name
+// followed by any number of "; key=value" parameters
+func validateCaaIssuerValue(value string) bool {
+	parts := strings.Split(value, ";")
+	issuer := strings.TrimSpace(parts[0])
+	if issuer != "" && !caaIssuerDomainName.MatchString(issuer) {
+		return false
+	}
+	params := parts[1:]
+	if len(params) == 1 && strings.TrimSpace(params[0]) == "" {
+		// a semicolon without parameters, e.g. ";" to forbid issuance
+		return true
+	}
+	for _, param := range params {
+		key, val, ok := strings.Cut(strings.TrimSpace(param), "=")
+		if !ok || !caaParameterTag.MatchString(key) || !caaParameterValue.MatchString(val) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,40 @@
+package utils
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateCaa(t *testing.T) {
+	for _, i := range []struct {
+		flags uint8
+		tag   string
+		value string
+		valid bool
+	}{
+		{0, "issue", "letsencrypt.org", true},
+		{0, "issue", ";", true},
+		{0, "issue", "letsencrypt.org;", true},
+		{0, "issue", "letsencrypt.org; accounturi=https://acme-v02.api.letsencrypt.org/acme/acct/1234; validationmethods=dns-01,http-01", true},
+		{0, "issuewild", "  pki.example.net ;policy=ev", true},
+		{0, "issuemail", "pki.example.net", true},
+		{128, "issue", "letsencrypt.org", true},
+		{0, "iodef", "mailto:security@example.com", true},
+		{0, "iodef", "https://example.com/caa-report", true},
+		{0, "issue", "letsencrypt.org.", false},
+		{0, "issue", "lets_encrypt.org", false},
+		{0, "issue", "-letsencrypt.org", false},
+		{0, "issue", "letsencrypt.org;;", false},
+		{0, "issue", "letsencrypt.org; accounturi", false},
+		{0, "issue", "letsencrypt.org; account uri=1", false},
+		{0, "issue", "letsencrypt.org\tfoo", false},
+		{1, "issue", "letsencrypt.org", false},
+		{0, "iodef", "ftp://example.com", false},
+		{0, "iodef", "mailto:", false},
+		{0, "iodef", "https://", false},
+		{0, "unknown", "value", false},
+	} {
+		assert.Equal(t, i.valid, ValidateCaa(i.flags, i.tag, i.value), "%d %s %q", i.flags, i.tag, i.value)
+	}
+}
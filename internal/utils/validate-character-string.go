@@ -0,0 +1,15 @@
+package utils
+
+// ValidateCharacterString checks the string fits in a single DNS
+// <character-string> and only contains printable ASCII characters
+func ValidateCharacterString(s string) bool {
+	if len(s) > 255 {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if s[i] < 0x20 || s[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
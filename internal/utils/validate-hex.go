@@ -0,0 +1,56 @@
+package utils
+
+import "encoding/hex"
+
+// ValidateHex checks the string is non-empty hex encoded data, when size is
+// above zero the decoded data must be exactly size bytes
+func ValidateHex(data string, size int) bool {
+	if data == "" || len(data)%2 != 0 {
+		return false
+	}
+	_, err := hex.DecodeString(data)
+	if err != nil {
+		return false
+	}
+	return size <= 0 || len(data) == size*2
+}
+
+// TlsaDigestSize returns the digest size for a TLSA or SMIMEA matching type,
+// zero means the full certificate or public key is present
+func TlsaDigestSize(matchingType uint8) int {
+	switch matchingType {
+	case 1:
+		return 32 // SHA-256
+	case 2:
+		return 64 // SHA-512
+	default:
+		return 0
+	}
+}
+
+// SshfpDigestSize returns the digest size for an SSHFP fingerprint type
+func SshfpDigestSize(fingerprintType uint8) int {
+	switch fingerprintType {
+	case 1:
+		return 20 // SHA-1
+	case 2:
+		return 32 // SHA-256
+	default:
+		return 0
+	}
+}
+
+// DsDigestSize returns the digest size for a DS digest type, zero means the
+// digest type is unknown
+func DsDigestSize(digestType uint8) int {
+	switch digestType {
+	case 1:
+		return 20 // SHA-1
+	case 2:
+		return 32 // SHA-256
+	case 4:
+		return 48 // SHA-384
+	default:
+		return 0
+	}
+}
@@ -0,0 +1,99 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// maxNameLength is the longest fully qualified name in presentation format
+// including the trailing dot, the wire format adds one more byte
+const maxNameLength = 254
+
+// NormalizeRecordName validates a record name and returns it lowercase and
+// relative to the zone with "@" for the zone apex. Names ending in a dot are
+// fully qualified and must be inside the zone, all other names are relative
+// to the zone like in a zone file. Unicode labels are converted to punycode.
+func NormalizeRecordName(zoneName, recordType, name string) (string, error) {
+	zoneName = strings.TrimSuffix(strings.ToLower(strings.TrimSpace(zoneName)), ".")
+	name, err := ToASCIIName(strings.ToLower(strings.TrimSpace(name)))
+	if err != nil {
+		return "", err
+	}
+
+	if strings.HasSuffix(name, ".") {
+		fqdn := strings.TrimSuffix(name, ".")
+		switch {
+		case fqdn == zoneName:
+			name = "@"
+		case strings.HasSuffix(fqdn, "."+zoneName):
+			name = strings.TrimSuffix(fqdn, "."+zoneName)
+		default:
+			return "", fmt.Errorf("%s is not inside the zone %s", name, zoneName)
+		}
+	}
+	if name == "" || name == "@" {
+		return "@", nil
+	}
+
+	if len(name)+len(zoneName)+2 > maxNameLength {
+		return "", fmt.Errorf("%s.%s is longer than %d characters", name, zoneName, maxNameLength-1)
+	}
+
+	labels := strings.Split(name, ".")
+	for i, label := range labels {
+		if label == "*" {
+			if i != 0 {
+				return "", errors.New("wildcard must be the leftmost label")
+			}
+			continue
+		}
+		err = validateLabel(label)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	switch recordType {
+	case "NS", "DS":
+		if labels[0] == "*" {
+			return "", fmt.Errorf("wildcard names are not allowed for %s records", recordType)
+		}
+	case "SRV", "URI", "TLSA":
+		// _service._proto for SRV and URI and _port._proto for TLSA
+		if len(labels) < 2 || !strings.HasPrefix(labels[0], "_") || !strings.HasPrefix(labels[1], "_") {
+			return "", fmt.Errorf("%s records must start with two underscore labels such as _service._tcp", recordType)
+		}
+	}
+	return name, nil
+}
+
+// validateLabel checks a single label only contains letters, digits and
+// hyphens, an underscore is allowed as the first character for service labels
+func validateLabel(label string) error {
+	if label == "" {
+		return errors.New("name contains an empty label")
+	}
+	if len(label) > 63 {
+		return fmt.Errorf("label %s is longer than 63 characters", label)
+	}
+	if strings.Contains(label, "*") {
+		return fmt.Errorf("label %s contains a wildcard which is not a whole label", label)
+	}
+	for i := 0; i < len(label); i++ {
+		c := label[i]
+		switch {
+		case c >= 'a' && c <= 'z', c >= '0' && c <= '9', c == '-':
+		case c == '_' && i == 0:
+		case c == '_':
+			return fmt.Errorf("label %s contains an underscore which is only allowed at the start", label)
+		default:
+			return fmt.Errorf("label %s contains the invalid character %q", label, c)
+		}
+	}
+	rest := strings.TrimPrefix(label, "_")
+	if strings.HasPrefix(rest, "-") || strings.HasSuffix(rest, "-") {
+		return fmt.Errorf("label %s cannot start or end with a hyphen", label)
+	}
+	return nil
+}
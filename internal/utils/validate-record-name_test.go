@@ -0,0 +1,64 @@
+package utils
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalizeRecordName(t *testing.T) {
+	for _, i := range []struct {
+		recordType string
+		name       string
+		normal     string
+	}{
+		{"A", "", "@"},
+		{"A", "@", "@"},
+		{"A", " WWW ", "www"},
+		{"A", "example.com.", "@"},
+		{"A", "www.Example.com.", "www"},
+		{"A", "www.example.com", "www.example.com"},
+		{"A", "*", "*"},
+		{"A", "*.cdn", "*.cdn"},
+		{"A", "xn--bcher-kva", "xn--bcher-kva"},
+		{"TXT", "_dmarc", "_dmarc"},
+		{"TXT", "selector1._domainkey", "selector1._domainkey"},
+		{"SRV", "_sip._tcp", "_sip._tcp"},
+		{"TLSA", "_443._tcp.www", "_443._tcp.www"},
+	} {
+		normal, err := NormalizeRecordName("example.com", i.recordType, i.name)
+		assert.NoError(t, err, i.name)
+		assert.Equal(t, i.normal, normal, i.name)
+	}
+
+	for _, i := range []struct {
+		recordType string
+		name       string
+	}{
+		{"A", "www.example.org."},
+		{"A", "www..test"},
+		{"A", "."},
+		{"A", "my host"},
+		{"A", "www\ttest"},
+		{"A", "www.*"},
+		{"A", "w*w"},
+		{"A", "-www"},
+		{"A", "www-"},
+		{"A", "my_host"},
+		{"A", "a123456789012345678901234567890123456789012345678901234567890123"},
+		{"NS", "*.child"},
+		{"SRV", "sip"},
+		{"SRV", "_sip.tcp"},
+		{"URI", "_ftp"},
+	} {
+		_, err := NormalizeRecordName("example.com", i.recordType, i.name)
+		assert.Error(t, err, i.name)
+	}
+
+	long := "a"
+	for len(long) < 250 {
+		long += ".a"
+	}
+	_, err := NormalizeRecordName("example.com", "A", long)
+	assert.Error(t, err)
+}
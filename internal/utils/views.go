@@ -0,0 +1,27 @@
+package utils
+
+import (
+	"slices"
+	"strings"
+)
+
+// SplitViews reads the comma separated views column of a record, an empty
+// column means the record is in every view
+func SplitViews(views string) []string {
+	if views == "" {
+		return nil
+	}
+	return strings.Split(views, ",")
+}
+
+// JoinViews is the reverse of SplitViews, the views should be normalized
+// first so equal lists are stored the same way
+func JoinViews(views []string) string {
+	return strings.Join(views, ",")
+}
+
+// InView reports if a record tagged with views belongs in view, records
+// without views are in every view
+func InView(views []string, view string) bool {
+	return len(views) == 0 || slices.Contains(views, view)
+}
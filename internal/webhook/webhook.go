@@ -0,0 +1,208 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/1f349/verbena/internal/database"
+	"github.com/1f349/verbena/internal/utils"
+	"github.com/1f349/verbena/logger"
+)
+
+type Event string
+
+const (
+	RecordCreated   Event = "record.created"
+	RecordUpdated   Event = "record.updated"
+	RecordDeleted   Event = "record.deleted"
+	CommitSucceeded Event = "commit.succeeded"
+	CommitFailed    Event = "commit.failed"
+	GenerateFailed  Event = "generate.failed"
+	FailoverChanged Event = "failover.changed"
+)
+
+var Events = []Event{
+	RecordCreated,
+	RecordUpdated,
+	RecordDeleted,
+	CommitSucceeded,
+	CommitFailed,
+	GenerateFailed,
+	FailoverChanged,
+}
+
+func (e Event) IsValid() bool {
+	return slices.Contains(Events, e)
+}
+
+const (
+	SignatureHeader = "Verbena-Signature"
+	EventHeader     = "Verbena-Event"
+
+	defaultMaxAttempts    = 5
+	defaultInitialBackoff = 5 * time.Second
+)
+
+// Payload is the JSON body sent to webhook receivers.
+type Payload struct {
+	Event    Event     `json:"event"`
+	ZoneID   int64     `json:"zone_id"`
+	ZoneName string    `json:"zone_name"`
+	Time     time.Time `json:"time"`
+	Data     any       `json:"data,omitempty"`
+}
+
+type webhookQueries interface {
+	GetActiveZoneWebhooks(ctx context.Context, zoneID int64) ([]database.Webhook, error)
+	InsertWebhookDelivery(ctx context.Context, arg database.InsertWebhookDeliveryParams) (int64, error)
+}
+
+// Dispatcher delivers zone events to the webhooks registered for the zone.
+type Dispatcher struct {
+	db             webhookQueries
+	client         *http.Client
+	maxAttempts    int
+	initialBackoff time.Duration
+}
+
+// New creates the dispatcher, deliveries to addresses which are not public are
+// refused unless allowPrivateTargets is set
+func New(db webhookQueries, allowPrivateTargets bool) *Dispatcher {
+	dialer := &net.Dialer{}
+	if !allowPrivateTargets {
+		// checked after resolving so redirects and names which resolve to
+		// internal addresses are refused too
+		dialer.Control = utils.PublicDialControl
+	}
+	return &Dispatcher{
+		db: db,
+		client: &http.Client{
+			Transport: &http.Transport{DialContext: dialer.DialContext},
+			Timeout:   30 * time.Second,
+		},
+		maxAttempts:    defaultMaxAttempts,
+		initialBackoff: defaultInitialBackoff,
+	}
+}
+
+// Fire delivers the event in the background to every active webhook of the zone
+// subscribed to the event.
+func (d *Dispatcher) Fire(zoneID int64, zoneName string, event Event, data any) {
+	payload, err := json.Marshal(Payload{
+		Event:    event,
+		ZoneID:   zoneID,
+		ZoneName: zoneName,
+		Time:     time.Now().UTC(),
+		Data:     data,
+	})
+	if err != nil {
+		logger.Logger.Error("Failed to encode webhook payload", "zone id", zoneID, "event", event, "err", err)
+		return
+	}
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+		webhooks, err := d.db.GetActiveZoneWebhooks(ctx, zoneID)
+		cancel()
+		if err != nil {
+			logger.Logger.Error("Failed to get zone webhooks", "zone id", zoneID, "err", err)
+			return
+		}
+		for _, w := range webhooks {
+			if !Subscribed(w.Events, event) {
+				continue
+			}
+			go d.deliver(w, event, payload)
+		}
+	}()
+}
+
+func (d *Dispatcher) deliver(w database.Webhook, event Event, payload []byte) {
+	backoff := d.initialBackoff
+	for attempt := 1; attempt <= d.maxAttempts; attempt++ {
+		statusCode, err := d.send(w, event, payload)
+		success := err == nil
+
+		errMsg := ""
+		if err != nil {
+			errMsg = err.Error()
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		_, dbErr := d.db.InsertWebhookDelivery(ctx, database.InsertWebhookDeliveryParams{
+			WebhookID:  w.ID,
+			Event:      string(event),
+			Payload:    string(payload),
+			Attempt:    int32(attempt),
+			StatusCode: int32(statusCode),
+			Error:      errMsg,
+			Success:    success,
+			CreatedAt:  time.Now().Unix(),
+		})
+		cancel()
+		if dbErr != nil {
+			logger.Logger.Error("Failed to log webhook delivery", "webhook id", w.ID, "err", dbErr)
+		}
+
+		if success {
+			return
+		}
+		logger.Logger.Debug("Failed to deliver webhook", "webhook id", w.ID, "attempt", attempt, "err", err)
+		if attempt < d.maxAttempts {
+			time.Sleep(backoff)
+			backoff *= 2
+		}
+	}
+	logger.Logger.Warn("Giving up on webhook delivery", "webhook id", w.ID, "event", event)
+}
+
+func (d *Dispatcher) send(w database.Webhook, event Event, payload []byte) (int, error) {
+	req, err := http.NewRequest(http.MethodPost, w.Url, bytes.NewReader(payload))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(EventHeader, string(event))
+	req.Header.Set(SignatureHeader, Sign(w.Secret, payload))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+// Sign returns the signature header value for the payload, receivers should
+// compute the same HMAC-SHA256 with the shared secret.
+func Sign(secret string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Subscribed reports whether a comma separated event list includes the event,
+// an empty list subscribes to every event.
+func Subscribed(events string, event Event) bool {
+	if events == "" {
+		return true
+	}
+	for e := range strings.SplitSeq(events, ",") {
+		if Event(e) == event {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,153 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/1f349/verbena/internal/database"
+	"github.com/1f349/verbena/internal/utils"
+)
+
+type webhookTestQueries struct {
+	url string
+
+	mu         sync.Mutex
+	deliveries []database.InsertWebhookDeliveryParams
+}
+
+func (w *webhookTestQueries) GetActiveZoneWebhooks(ctx context.Context, zoneID int64) ([]database.Webhook, error) {
+	if zoneID != 3456 {
+		return nil, nil
+	}
+	return []database.Webhook{
+		{ID: 1, ZoneID: 3456, Url: w.url, Secret: "secret", Events: "record.created,commit.failed", Active: true},
+		{ID: 2, ZoneID: 3456, Url: w.url, Secret: "secret", Events: "commit.succeeded", Active: true},
+	}, nil
+}
+
+func (w *webhookTestQueries) InsertWebhookDelivery(ctx context.Context, arg database.InsertWebhookDeliveryParams) (int64, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.deliveries = append(w.deliveries, arg)
+	return int64(len(w.deliveries)), nil
+}
+
+func (w *webhookTestQueries) getDeliveries() []database.InsertWebhookDeliveryParams {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return append([]database.InsertWebhookDeliveryParams{}, w.deliveries...)
+}
+
+func TestDispatcher_Fire(t *testing.T) {
+	received := make(chan Payload, 10)
+	calls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		body, err := io.ReadAll(req.Body)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		if req.Header.Get(SignatureHeader) != Sign("secret", body) {
+			t.Error("invalid signature")
+		}
+		if req.Header.Get(EventHeader) != string(RecordCreated) {
+			t.Error("unexpected event header", req.Header.Get(EventHeader))
+		}
+
+		// Fail the first delivery to check the retry
+		calls++
+		if calls == 1 {
+			rw.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+
+		var p Payload
+		err = json.Unmarshal(body, &p)
+		if err != nil {
+			t.Error(err)
+		}
+		received <- p
+	}))
+	defer srv.Close()
+
+	q := &webhookTestQueries{url: srv.URL}
+	// the test server is on loopback
+	d := New(q, true)
+	d.initialBackoff = 10 * time.Millisecond
+
+	d.Fire(3456, "example.com", RecordCreated, map[string]string{"name": "www"})
+
+	select {
+	case p := <-received:
+		if p.Event != RecordCreated || p.ZoneID != 3456 || p.ZoneName != "example.com" {
+			t.Fatal("unexpected payload", p)
+		}
+		if p.Data.(map[string]any)["name"] != "www" {
+			t.Fatal("unexpected payload data", p.Data)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("webhook was not delivered")
+	}
+
+	// Wait for the successful delivery to be logged
+	deadline := time.Now().Add(5 * time.Second)
+	for len(q.getDeliveries()) < 2 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	deliveries := q.getDeliveries()
+	if len(deliveries) != 2 {
+		t.Fatal("expected 2 delivery attempts, got", len(deliveries))
+	}
+	if deliveries[0].Success || deliveries[0].StatusCode != http.StatusServiceUnavailable || deliveries[0].Attempt != 1 {
+		t.Fatal("unexpected first delivery", deliveries[0])
+	}
+	if !deliveries[1].Success || deliveries[1].StatusCode != http.StatusOK || deliveries[1].Attempt != 2 {
+		t.Fatal("unexpected second delivery", deliveries[1])
+	}
+	if deliveries[1].WebhookID != 1 {
+		t.Fatal("delivered to the wrong webhook", deliveries[1].WebhookID)
+	}
+}
+
+func TestDispatcher_sendPrivate(t *testing.T) {
+	var called atomic.Bool
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		called.Store(true)
+	}))
+	defer srv.Close()
+
+	d := New(&webhookTestQueries{}, false)
+	_, err := d.send(database.Webhook{ID: 1, Url: srv.URL, Secret: "secret"}, RecordCreated, []byte("{}"))
+	if !errors.Is(err, utils.ErrNotPublicAddr) {
+		t.Fatal("expected the loopback address to be refused", err)
+	}
+	if called.Load() {
+		t.Fatal("webhook was delivered to a loopback address")
+	}
+}
+
+func TestSubscribed(t *testing.T) {
+	if !Subscribed("", CommitFailed) {
+		t.Fatal("empty event list should subscribe to every event")
+	}
+	if !Subscribed("record.created,commit.failed", CommitFailed) {
+		t.Fatal("expected commit.failed to be subscribed")
+	}
+	if Subscribed("record.created,commit.failed", CommitSucceeded) {
+		t.Fatal("expected commit.succeeded not to be subscribed")
+	}
+}
+
+func TestSign(t *testing.T) {
+	if Sign("secret", []byte("hello")) != "sha256=88aab3ede8d3adf94d26ab90d3bafd4a2083070c3bcce9c014ee04a443847c0b" {
+		t.Fatal("unexpected signature", Sign("secret", []byte("hello")))
+	}
+}
@@ -0,0 +1,72 @@
+package zone
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/miekg/dns"
+)
+
+// recordFqdn returns the lowercase fully qualified name of a record
+func recordFqdn(origin, name string) string {
+	name = zoneRecordName(name)
+	if name == "@" {
+		return strings.ToLower(dns.Fqdn(origin))
+	}
+	return strings.ToLower(dns.Fqdn(name + "." + dns.Fqdn(origin)))
+}
+
+// validateDelegations checks NS records below the apex form valid delegations.
+// DS records must be at a delegation point and the only records allowed at or
+// below a delegation point are the NS and DS records and A/AAAA glue records
+// for the nameservers of the delegation.
+func validateDelegations(origin string, records []Record) error {
+	apex := recordFqdn(origin, "@")
+
+	// find the delegation points and the in-bailiwick nameservers needing glue
+	delegations := make(map[string]bool)
+	glue := make(map[string]bool)
+	for _, record := range records {
+		if record.Type != NS {
+			continue
+		}
+		name := recordFqdn(origin, record.Name)
+		if name == apex {
+			continue
+		}
+		delegations[name] = true
+		glue[strings.ToLower(dns.Fqdn(record.Value))] = true
+	}
+
+	for _, record := range records {
+		name := recordFqdn(origin, record.Name)
+		if record.Type == DS && !delegations[name] {
+			return fmt.Errorf("invalid DS record: %s is not a delegation point", name)
+		}
+
+		cut := delegationCut(name, apex, delegations)
+		if cut == "" {
+			continue
+		}
+		switch {
+		case record.Type == NS && name == cut, record.Type == DS && name == cut:
+		case (record.Type == A || record.Type == AAAA) && glue[name]:
+		default:
+			return fmt.Errorf("invalid %s record: %s is occluded by the delegation of %s", record.Type, name, cut)
+		}
+	}
+	return nil
+}
+
+// delegationCut returns the highest delegation point at or above name, this
+// makes sure delegations below another delegation are also occluded
+func delegationCut(name, apex string, delegations map[string]bool) string {
+	var cut string
+	for n := name; n != apex && n != ""; {
+		if delegations[n] {
+			cut = n
+		}
+		_, n, _ = strings.Cut(n, ".")
+	}
+	return cut
+}
@@ -15,10 +15,21 @@ const (
 	SRV
 	CAA
 	PTR
+	TLSA
+	SSHFP
+	SMIMEA
+	SVCB
+	HTTPS
+	NAPTR
+	URI
+	LOC
+	HINFO
+	DS
+	ALIAS
 )
 
 func (t RecordType) IsValid() bool {
-	return t > invalidRecordType && t <= PTR
+	return t > invalidRecordType && int(t) <= len(recordTypeToString)
 }
 
 var recordTypeToString = []string{
@@ -31,6 +42,17 @@ var recordTypeToString = []string{
 	"SRV",
 	"CAA",
 	"PTR",
+	"TLSA",
+	"SSHFP",
+	"SMIMEA",
+	"SVCB",
+	"HTTPS",
+	"NAPTR",
+	"URI",
+	"LOC",
+	"HINFO",
+	"DS",
+	"ALIAS",
 }
 
 func (t RecordType) String() string {
@@ -41,15 +63,26 @@ func (t RecordType) String() string {
 }
 
 var stringToRecordType = map[string]RecordType{
-	"NS":    NS,
-	"MX":    MX,
-	"A":     A,
-	"AAAA":  AAAA,
-	"CNAME": CNAME,
-	"TXT":   TXT,
-	"SRV":   SRV,
-	"CAA":   CAA,
-	"PTR":   PTR,
+	"NS":     NS,
+	"MX":     MX,
+	"A":      A,
+	"AAAA":   AAAA,
+	"CNAME":  CNAME,
+	"TXT":    TXT,
+	"SRV":    SRV,
+	"CAA":    CAA,
+	"PTR":    PTR,
+	"TLSA":   TLSA,
+	"SSHFP":  SSHFP,
+	"SMIMEA": SMIMEA,
+	"SVCB":   SVCB,
+	"HTTPS":  HTTPS,
+	"NAPTR":  NAPTR,
+	"URI":    URI,
+	"LOC":    LOC,
+	"HINFO":  HINFO,
+	"DS":     DS,
+	"ALIAS":  ALIAS,
 }
 
 func RecordTypeFromString(s string) RecordType {
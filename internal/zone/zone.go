@@ -4,7 +4,9 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"math"
 	"net/netip"
+	"net/url"
 	"strconv"
 	"strings"
 
@@ -61,6 +63,11 @@ func WriteZone(w io.Writer, origin string, defaultTtl uint32, soa SoaRecord, rec
 		return err
 	}
 
+	err = validateDelegations(origin, records)
+	if err != nil {
+		return err
+	}
+
 	for _, record := range records {
 		var val string
 		// TODO: valid line parsing better
@@ -147,7 +154,7 @@ func WriteZone(w io.Writer, origin string, defaultTtl uint32, soa SoaRecord, rec
 			}
 			val = fmt.Sprintf("%d\t%d\t%d\t%s", priority, weight, port, dns.Fqdn(srvFields[3]))
 		case CAA:
-			caaFields := strings.Fields(record.Value)
+			caaFields := strings.SplitN(record.Value, "\t", 3)
 			if len(caaFields) != 3 {
 				return fmt.Errorf("invalid CAA record: %s", record.Value)
 			}
@@ -155,16 +162,104 @@ func WriteZone(w io.Writer, origin string, defaultTtl uint32, soa SoaRecord, rec
 			if err != nil {
 				return errors.New("invalid CAA record")
 			}
-			if caaFields[1] != "issue" && caaFields[1] != "issuewild" {
-				return errors.New("invalid CAA record")
+			if !utils.ValidateCaa(uint8(flags), caaFields[1], caaFields[2]) {
+				return fmt.Errorf("invalid CAA record: %s", record.Value)
 			}
-			val = fmt.Sprintf("%d\t%s\t%s", flags, caaFields[1], dns.Fqdn(caaFields[2]))
+			val = fmt.Sprintf("%d\t%s\t%s", flags, caaFields[1], strconv.Quote(caaFields[2]))
 		case PTR:
 			_, ok := dns.IsDomainName(record.Value)
 			if !ok {
 				return fmt.Errorf("invalid PTR record: %s", record.Value)
 			}
 			val = dns.Fqdn(record.Value)
+		case TLSA, SMIMEA:
+			val, err = certAssociationValue(record)
+			if err != nil {
+				return err
+			}
+		case SVCB, HTTPS:
+			val, err = serviceBindingValue(record)
+			if err != nil {
+				return err
+			}
+		case NAPTR:
+			val, err = naptrValue(record)
+			if err != nil {
+				return err
+			}
+		case URI:
+			uriFields := strings.Split(record.Value, "\t")
+			if len(uriFields) != 3 {
+				return fmt.Errorf("invalid URI record: %s", record.Value)
+			}
+			priority, err := strconv.ParseUint(uriFields[0], 10, 16)
+			if err != nil {
+				return errors.New("invalid URI record")
+			}
+			weight, err := strconv.ParseUint(uriFields[1], 10, 16)
+			if err != nil {
+				return errors.New("invalid URI record")
+			}
+			target, err := url.Parse(uriFields[2])
+			if err != nil || target.Scheme == "" || !utils.ValidateCharacterString(uriFields[2]) {
+				return fmt.Errorf("invalid URI record: the provided target is invalid: %s", uriFields[2])
+			}
+			val = fmt.Sprintf("%d\t%d\t%s", priority, weight, strconv.Quote(uriFields[2]))
+		case LOC:
+			val, err = locValue(record)
+			if err != nil {
+				return err
+			}
+		case HINFO:
+			hinfoFields := strings.Split(record.Value, "\t")
+			if len(hinfoFields) != 2 {
+				return fmt.Errorf("invalid HINFO record: %s", record.Value)
+			}
+			if hinfoFields[0] == "" || hinfoFields[1] == "" || !utils.ValidateCharacterString(hinfoFields[0]) || !utils.ValidateCharacterString(hinfoFields[1]) {
+				return errors.New("invalid HINFO record")
+			}
+			val = fmt.Sprintf("%s\t%s", strconv.Quote(hinfoFields[0]), strconv.Quote(hinfoFields[1]))
+		case DS:
+			dsFields := strings.Fields(record.Value)
+			if len(dsFields) != 4 {
+				return fmt.Errorf("invalid DS record: %s", record.Value)
+			}
+			keyTag, err := strconv.ParseUint(dsFields[0], 10, 16)
+			if err != nil {
+				return errors.New("invalid DS record: invalid key tag")
+			}
+			algorithm, err := strconv.ParseUint(dsFields[1], 10, 8)
+			if err != nil || algorithm == 0 {
+				return errors.New("invalid DS record: invalid algorithm")
+			}
+			digestType, err := strconv.ParseUint(dsFields[2], 10, 8)
+			if err != nil || utils.DsDigestSize(uint8(digestType)) == 0 {
+				return errors.New("invalid DS record: unknown digest type")
+			}
+			if !utils.ValidateHex(dsFields[3], utils.DsDigestSize(uint8(digestType))) {
+				return errors.New("invalid DS record: invalid digest")
+			}
+			val = fmt.Sprintf("%d\t%d\t%d\t%s", keyTag, algorithm, digestType, strings.ToUpper(dsFields[3]))
+		case SSHFP:
+			sshfpFields := strings.Fields(record.Value)
+			if len(sshfpFields) != 3 {
+				return fmt.Errorf("invalid SSHFP record: %s", record.Value)
+			}
+			algorithm, err := strconv.ParseUint(sshfpFields[0], 10, 8)
+			if err != nil || algorithm < 1 || algorithm > 6 || algorithm == 5 {
+				return errors.New("invalid SSHFP record: unknown algorithm")
+			}
+			fingerprintType, err := strconv.ParseUint(sshfpFields[1], 10, 8)
+			if err != nil || fingerprintType < 1 || fingerprintType > 2 {
+				return errors.New("invalid SSHFP record: unknown fingerprint type")
+			}
+			if !utils.ValidateHex(sshfpFields[2], utils.SshfpDigestSize(uint8(fingerprintType))) {
+				return errors.New("invalid SSHFP record: invalid fingerprint")
+			}
+			val = fmt.Sprintf("%d\t%d\t%s", algorithm, fingerprintType, strings.ToUpper(sshfpFields[2]))
+		case ALIAS:
+			// the builder replaces ALIAS records with the resolved addresses
+			return fmt.Errorf("ALIAS record %s must be flattened before writing the zone", zoneRecordName(record.Name))
 		default:
 			continue
 		}
@@ -177,3 +272,124 @@ func WriteZone(w io.Writer, origin string, defaultTtl uint32, soa SoaRecord, rec
 
 	return nil
 }
+
+// certAssociationValue renders the shared TLSA and SMIMEA format
+func certAssociationValue(record Record) (string, error) {
+	fields := strings.Fields(record.Value)
+	if len(fields) != 4 {
+		return "", fmt.Errorf("invalid %s record: %s", record.Type, record.Value)
+	}
+	usage, err := strconv.ParseUint(fields[0], 10, 8)
+	if err != nil || usage > 3 {
+		return "", fmt.Errorf("invalid %s record: unknown certificate usage", record.Type)
+	}
+	selector, err := strconv.ParseUint(fields[1], 10, 8)
+	if err != nil || selector > 1 {
+		return "", fmt.Errorf("invalid %s record: unknown selector", record.Type)
+	}
+	matchingType, err := strconv.ParseUint(fields[2], 10, 8)
+	if err != nil || matchingType > 2 {
+		return "", fmt.Errorf("invalid %s record: unknown matching type", record.Type)
+	}
+	if !utils.ValidateHex(fields[3], utils.TlsaDigestSize(uint8(matchingType))) {
+		return "", fmt.Errorf("invalid %s record: invalid certificate association data", record.Type)
+	}
+	return fmt.Sprintf("%d\t%d\t%d\t%s", usage, selector, matchingType, strings.ToUpper(fields[3])), nil
+}
+
+// serviceBindingValue renders the shared SVCB and HTTPS format, the SvcParams
+// are checked by parsing the record with the dns package
+func serviceBindingValue(record Record) (string, error) {
+	fields := strings.Fields(record.Value)
+	if len(fields) < 2 {
+		return "", fmt.Errorf("invalid %s record: %s", record.Type, record.Value)
+	}
+	priority, err := strconv.ParseUint(fields[0], 10, 16)
+	if err != nil {
+		return "", fmt.Errorf("invalid %s record: invalid priority", record.Type)
+	}
+	_, ok := dns.IsDomainName(fields[1])
+	if !ok {
+		return "", fmt.Errorf("invalid %s record: the provided target is invalid: %s", record.Type, fields[1])
+	}
+	if priority == 0 && len(fields) > 2 {
+		return "", fmt.Errorf("invalid %s record: AliasMode records must not have SvcParams", record.Type)
+	}
+
+	val := fmt.Sprintf("%d\t%s", priority, dns.Fqdn(fields[1]))
+	if len(fields) > 2 {
+		val += "\t" + strings.Join(fields[2:], " ")
+	}
+	_, err = dns.NewRR(". IN " + record.Type.String() + " " + val)
+	if err != nil {
+		return "", fmt.Errorf("invalid %s record: %w", record.Type, err)
+	}
+	return val, nil
+}
+
+// naptrValue renders a NAPTR record, the fields are tab separated as the
+// service and regexp may contain spaces
+func naptrValue(record Record) (string, error) {
+	fields := strings.Split(record.Value, "\t")
+	if len(fields) != 6 {
+		return "", fmt.Errorf("invalid NAPTR record: %s", record.Value)
+	}
+	order, err := strconv.ParseUint(fields[0], 10, 16)
+	if err != nil {
+		return "", errors.New("invalid NAPTR record: invalid order")
+	}
+	preference, err := strconv.ParseUint(fields[1], 10, 16)
+	if err != nil {
+		return "", errors.New("invalid NAPTR record: invalid preference")
+	}
+	for _, f := range fields[2:5] {
+		if !utils.ValidateCharacterString(f) {
+			return "", errors.New("invalid NAPTR record: invalid character-string")
+		}
+	}
+	_, ok := dns.IsDomainName(fields[5])
+	if !ok {
+		return "", fmt.Errorf("invalid NAPTR record: the provided replacement is invalid: %s", fields[5])
+	}
+	return fmt.Sprintf("%d\t%d\t%s\t%s\t%s\t%s", order, preference, strconv.Quote(fields[2]), strconv.Quote(fields[3]), strconv.Quote(fields[4]), dns.Fqdn(fields[5])), nil
+}
+
+// locValue converts the decimal degrees and metres stored for a LOC record
+// into the RFC 1876 presentation format
+func locValue(record Record) (string, error) {
+	fields := strings.Fields(record.Value)
+	if len(fields) != 6 {
+		return "", fmt.Errorf("invalid LOC record: %s", record.Value)
+	}
+	var v [6]float64
+	for i, f := range fields {
+		var err error
+		v[i], err = strconv.ParseFloat(f, 64)
+		if err != nil || math.IsNaN(v[i]) || math.IsInf(v[i], 0) {
+			return "", fmt.Errorf("invalid LOC record: %s", record.Value)
+		}
+	}
+	if v[0] < -90 || v[0] > 90 || v[1] < -180 || v[1] > 180 {
+		return "", errors.New("invalid LOC record: coordinates out of range")
+	}
+	if v[2] < -100000 || v[2] > 42849672.95 {
+		return "", errors.New("invalid LOC record: altitude out of range")
+	}
+	for _, m := range v[3:] {
+		if m < 0 || m > 90000000 {
+			return "", errors.New("invalid LOC record: size or precision out of range")
+		}
+	}
+	return fmt.Sprintf("%s\t%s\t%.2fm\t%.2fm\t%.2fm\t%.2fm", locCoordinate(v[0], "N", "S"), locCoordinate(v[1], "E", "W"), v[2], v[3], v[4], v[5]), nil
+}
+
+func locCoordinate(deg float64, positive, negative string) string {
+	hemisphere := positive
+	if deg < 0 {
+		hemisphere = negative
+		deg = -deg
+	}
+	// work in thousandths of an arcsecond to avoid rounding errors
+	total := int64(math.Round(deg * 3600 * 1000))
+	return fmt.Sprintf("%d %d %d.%03d %s", total/3600000, total%3600000/60000, total%60000/1000, total%1000, hemisphere)
+}
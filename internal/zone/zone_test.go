@@ -67,6 +67,25 @@ func TestWriteZone(t *testing.T) {
 		{"*", nu32, TXT, "v=spf1 include:_spf.example.com -all"},
 		{"_dmarc", nu32, TXT, "v=DMARC1; p=quarantine; sp=quarantine; pct=50; rua=mailto:dmarcreports@example.com; ruf=mailto:dmarcfailurereports@example.com; adkim=r; aspf=r;"},
 		{"mail._domainkey", nu32, TXT, exampleDomainKey},
+		{"_443._tcp.www", nu32, TLSA, "3\t1\t1\t0d6fce3f45a8d3aaf5b3c6a4b2c0e8d1f6e0b1a2c3d4e5f60718293a4b5c6d7e"},
+		{"server1", nu32, SSHFP, "4\t2\t9a3f2c1b0d8e7f6a5b4c3d2e1f0a9b8c7d6e5f4a3b2c1d0e9f8a7b6c5d4e3f2a"},
+		{"c93f1e400f26708f98cb19d936620da35eec8f72e57f9eec01c1afd6._smimecert", nu32, SMIMEA, "3\t0\t2\t" + strings.Repeat("ab", 64)},
+		{"", nu32, HTTPS, "1\t.\talpn=h2,h3 port=443 ipv4hint=10.0.1.5 ipv6hint=2001:db8::1:5"},
+		{"www", nu32, HTTPS, "0\tserver1.example.com"},
+		{"_8443._foo", nu32, SVCB, "2\tserver2.example.com\tmandatory=alpn alpn=foo port=8443"},
+		{"", nu32, NAPTR, "100\t10\tU\tE2U+sip\t!^.*$!sip:info@example.com!\t."},
+		{"_sip._udp", nu32, NAPTR, "100\t20\tS\tSIP+D2U\t\t_sip._udp.example.com"},
+		{"_ftp._tcp", nu32, URI, "10\t1\tftp://ftp.example.com/public"},
+		{"server1", nu32, LOC, "51.50344\t-0.12766\t12.5\t1\t10000\t10"},
+		{"server1", nu32, HINFO, "x86_64\tLinux 6.1"},
+		{"", nu32, CAA, "0\tissue\tletsencrypt.org; accounturi=https://acme-v02.api.letsencrypt.org/acme/acct/1234; validationmethods=dns-01"},
+		{"", nu32, CAA, "0\tissuewild\t;"},
+		{"", nu32, CAA, "0\tissuemail\tpki.example.net"},
+		{"", nu32, CAA, "128\tiodef\tmailto:security@example.com"},
+		{"child", nu32, NS, "ns1.child.example.com"},
+		{"child", nu32, NS, "dns1.example.com"},
+		{"child", nu32, DS, "12345\t13\t2\t" + strings.Repeat("3f", 32)},
+		{"ns1.child", nu32, A, "10.0.3.1"},
 	})
 	if err != nil {
 		t.Fatal(err)
@@ -96,6 +115,39 @@ func TestWriteZone(t *testing.T) {
 	checkWithBindCheckZone(t, buf.Bytes(), "example.com")
 }
 
+func TestWriteZone_Delegations(t *testing.T) {
+	var nu32 nulls.UInt32
+	soa := SoaRecord{Nameserver: "dns1.example.com", Admin: "hostmaster.example.com"}
+	ds := "12345\t13\t2\t" + strings.Repeat("3f", 32)
+
+	for _, i := range []struct {
+		name    string
+		records []Record
+		err     string
+	}{
+		{"DS without NS", []Record{{"child", nu32, DS, ds}}, "invalid DS record: child.example.com. is not a delegation point"},
+		{"DS at apex", []Record{{"@", nu32, NS, "dns1.example.com"}, {"@", nu32, DS, ds}}, "invalid DS record: example.com. is not a delegation point"},
+		{"occluded record", []Record{{"child", nu32, NS, "ns1.example.net"}, {"www.child", nu32, A, "10.0.3.2"}}, "invalid A record: www.child.example.com. is occluded by the delegation of child.example.com."},
+		{"record at delegation", []Record{{"child", nu32, NS, "ns1.example.net"}, {"child", nu32, TXT, "hello"}}, "invalid TXT record: child.example.com. is occluded by the delegation of child.example.com."},
+		{"nested delegation", []Record{{"child", nu32, NS, "ns1.example.net"}, {"sub.child", nu32, NS, "ns1.example.net"}}, "invalid NS record: sub.child.example.com. is occluded by the delegation of child.example.com."},
+		{"glue", []Record{{"child", nu32, NS, "ns1.child.example.com"}, {"ns1.child", nu32, A, "10.0.3.1"}, {"ns1.child", nu32, AAAA, "2001:db8::3:1"}, {"child", nu32, DS, ds}}, ""},
+		{"unflattened ALIAS", []Record{{"", nu32, ALIAS, "cdn.example.net"}}, "ALIAS record @ must be flattened before writing the zone"},
+	} {
+		t.Run(i.name, func(t *testing.T) {
+			err := WriteZone(new(bytes.Buffer), "example.com", 86400, soa, i.records)
+			if i.err == "" {
+				if err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			if err == nil || err.Error() != i.err {
+				t.Fatalf("expected error %q, got %v", i.err, err)
+			}
+		})
+	}
+}
+
 func checkWithBindCheckZone(t *testing.T, data []byte, zoneName string) {
 	tempFile, err := os.CreateTemp("", "verbena-test-*.zone")
 	if err != nil {
@@ -0,0 +1,190 @@
+package zonetemplate
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/1f349/verbena/internal/utils"
+	"github.com/1f349/verbena/rest"
+)
+
+// ZoneVariable is always set to the name of the zone the template is applied to
+const ZoneVariable = "zone"
+
+var variablePattern = regexp.MustCompile(`\{\{\s*([a-zA-Z0-9_]+)\s*}}`)
+
+// Template is a named set of records containing {{variable}} placeholders.
+type Template struct {
+	Name      string
+	Variables []string
+	records   []byte
+}
+
+// Load parses the templates from the config, each template is a list of
+// records in the same format as rest.CreateRecord.
+func Load(raw map[string][]map[string]any) (map[string]Template, error) {
+	templates := make(map[string]Template, len(raw))
+	for name, records := range raw {
+		j, err := json.Marshal(records)
+		if err != nil {
+			return nil, fmt.Errorf("template %s: %w", name, err)
+		}
+
+		vars := make(map[string]struct{})
+		for _, m := range variablePattern.FindAllSubmatch(j, -1) {
+			vars[string(m[1])] = struct{}{}
+		}
+		delete(vars, ZoneVariable)
+
+		templates[name] = Template{
+			Name:      name,
+			Variables: slices.Sorted(maps.Keys(vars)),
+			records:   j,
+		}
+	}
+	return templates, nil
+}
+
+// Render substitutes the variables into the template and validates each record.
+func (t Template) Render(zoneName string, vars map[string]string) ([]rest.CreateRecord, error) {
+	missing := make(map[string]struct{})
+	out := variablePattern.ReplaceAllFunc(t.records, func(b []byte) []byte {
+		name := string(variablePattern.FindSubmatch(b)[1])
+		value, ok := vars[name]
+		if name == ZoneVariable {
+			value, ok = zoneName, true
+		}
+		if !ok {
+			missing[name] = struct{}{}
+			return b
+		}
+		// Escape the value so it is safe inside a JSON string
+		quoted, _ := json.Marshal(value)
+		return quoted[1 : len(quoted)-1]
+	})
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("missing template variables: %s", strings.Join(slices.Sorted(maps.Keys(missing)), ", "))
+	}
+
+	var records []rest.CreateRecord
+	err := json.Unmarshal(out, &records)
+	if err != nil {
+		return nil, err
+	}
+	for i, r := range records {
+		r.Value, err = r.Value.ToASCII(r.Type)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value for %s record %d: %w", r.Type, i, err)
+		}
+		records[i].Value = r.Value
+		if !r.Value.IsValidForType(r.Type) {
+			return nil, fmt.Errorf("invalid value for %s record %d", r.Type, i)
+		}
+		records[i].Name, err = utils.NormalizeRecordName(zoneName, r.Type, r.Name)
+		if err != nil {
+			return nil, fmt.Errorf("invalid name for %s record %d: %w", r.Type, i, err)
+		}
+	}
+	return records, nil
+}
+
+type Action string
+
+const (
+	Create    Action = "create"
+	Update    Action = "update"
+	Unchanged Action = "unchanged"
+)
+
+// Change describes how a single template record is applied to a zone.
+type Change struct {
+	Action Action      `json:"action"`
+	Record rest.Record `json:"record"`
+}
+
+// Plan compares the rendered template records with the existing zone records.
+// Records which have drifted from the template are updated in place instead of
+// adding a second record, see driftKey for how records are matched.
+func Plan(existing []rest.Record, records []rest.CreateRecord) []Change {
+	used := make(map[int64]bool)
+	changes := make([]Change, 0, len(records))
+
+	for _, r := range records {
+		want := rest.Record{
+			Name:   r.Name,
+			Ttl:    r.Ttl,
+			Type:   r.Type,
+			Value:  r.Value,
+			Active: true,
+		}
+
+		var match *rest.Record
+		for i := range existing {
+			e := &existing[i]
+			if used[e.ID] || e.Type != r.Type || normalName(e.Name) != normalName(r.Name) {
+				continue
+			}
+			if e.Value.ToValueString(e.Type) == r.Value.ToValueString(r.Type) {
+				match = e
+				break
+			}
+			if match == nil && driftKey(*e) == driftKey(want) {
+				match = e
+			}
+		}
+
+		if match == nil {
+			changes = append(changes, Change{Action: Create, Record: want})
+			continue
+		}
+
+		used[match.ID] = true
+		want.ID = match.ID
+		want.ZoneID = match.ZoneID
+		want.Name = match.Name
+		if match.Ttl == want.Ttl && match.Active && match.Value.ToValueString(match.Type) == want.Value.ToValueString(want.Type) {
+			changes = append(changes, Change{Action: Unchanged, Record: want})
+		} else {
+			changes = append(changes, Change{Action: Update, Record: want})
+		}
+	}
+	return changes
+}
+
+// driftKey identifies the part of a record which stays the same when its value
+// drifts, e.g. an MX record keeps its target but changes preference
+func driftKey(r rest.Record) string {
+	v := r.Value
+	switch r.Type {
+	case "CNAME":
+		return ""
+	case "MX":
+		return strings.ToLower(v.Target)
+	case "SRV":
+		return fmt.Sprintf("%d %s", v.Port, strings.ToLower(v.Target))
+	case "CAA":
+		return v.Tag + " " + v.Value
+	case "TXT":
+		// Match policy records like "v=spf1" and "v=DMARC1" by their version tag
+		first, _, _ := strings.Cut(v.Text, " ")
+		first, _, _ = strings.Cut(first, ";")
+		if strings.HasPrefix(strings.ToLower(first), "v=") {
+			return strings.ToLower(first)
+		}
+		return v.Text
+	default:
+		return r.Value.ToValueString(r.Type)
+	}
+}
+
+func normalName(name string) string {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if name == "@" {
+		return ""
+	}
+	return name
+}
@@ -0,0 +1,87 @@
+package zonetemplate
+
+import (
+	"net/netip"
+	"testing"
+
+	"github.com/1f349/verbena/rest"
+	"github.com/gobuffalo/nulls"
+	"github.com/stretchr/testify/assert"
+)
+
+func testTemplates(t *testing.T) map[string]Template {
+	templates, err := Load(map[string][]map[string]any{
+		"mail": {
+			{"name": "@", "type": "MX", "value": map[string]any{"preference": 10, "target": "mx.{{provider}}"}},
+			{"name": "@", "type": "TXT", "value": map[string]any{"text": "v=spf1 include:{{provider}} ~all"}},
+			{"name": "mail", "ttl": 300, "type": "CNAME", "value": map[string]any{"target": "mail.{{zone}}"}},
+			{"name": "web", "type": "A", "value": map[string]any{"ip": "{{ip}}"}},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return templates
+}
+
+func TestLoad(t *testing.T) {
+	templates := testTemplates(t)
+	assert.Len(t, templates, 1)
+	assert.Equal(t, "mail", templates["mail"].Name)
+	assert.Equal(t, []string{"ip", "provider"}, templates["mail"].Variables)
+}
+
+func TestTemplate_Render(t *testing.T) {
+	template := testTemplates(t)["mail"]
+
+	records, err := template.Render("example.com", map[string]string{"provider": "example.net", "ip": "192.0.2.1"})
+	assert.NoError(t, err)
+	assert.Len(t, records, 4)
+	assert.Equal(t, "mx.example.net", records[0].Value.Target)
+	assert.Equal(t, "v=spf1 include:example.net ~all", records[1].Value.Text)
+	assert.Equal(t, "mail.example.com", records[2].Value.Target)
+	assert.Equal(t, nulls.NewInt32(300), records[2].Ttl)
+	assert.Equal(t, "192.0.2.1", records[3].Value.IP.String())
+
+	_, err = template.Render("example.com", map[string]string{"ip": "192.0.2.1"})
+	assert.EqualError(t, err, "missing template variables: provider")
+
+	_, err = template.Render("example.com", map[string]string{"provider": "example.net", "ip": "2001:db8::1"})
+	assert.EqualError(t, err, "invalid value for A record 3")
+
+	// Variables are escaped so they can't break out of the JSON string
+	_, err = template.Render("example.com", map[string]string{"provider": `example.net"}`, "ip": "192.0.2.1"})
+	assert.EqualError(t, err, "invalid value for MX record 0")
+}
+
+func TestPlan(t *testing.T) {
+	ip := netip.MustParseAddr("192.0.2.1")
+	existing := []rest.Record{
+		{ID: 1, Name: "", ZoneID: 3456, Type: "MX", Value: rest.RecordValue{Preference: 20, Target: "mx.example.net"}, Active: true},
+		{ID: 2, Name: "@", ZoneID: 3456, Type: "TXT", Value: rest.RecordValue{Text: "v=spf1 -all"}, Active: true},
+		{ID: 3, Name: "@", ZoneID: 3456, Type: "TXT", Value: rest.RecordValue{Text: "google-site-verification=abc"}, Active: true},
+		{ID: 4, Name: "web", ZoneID: 3456, Type: "A", Value: rest.RecordValue{IP: &ip}, Active: true},
+	}
+
+	records, err := testTemplates(t)["mail"].Render("example.com", map[string]string{"provider": "example.net", "ip": "192.0.2.1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	changes := Plan(existing, records)
+	assert.Len(t, changes, 4)
+
+	assert.Equal(t, Update, changes[0].Action)
+	assert.Equal(t, int64(1), changes[0].Record.ID)
+	assert.Equal(t, int32(10), changes[0].Record.Value.Preference)
+
+	assert.Equal(t, Update, changes[1].Action)
+	assert.Equal(t, int64(2), changes[1].Record.ID)
+	assert.Equal(t, "v=spf1 include:example.net ~all", changes[1].Record.Value.Text)
+
+	assert.Equal(t, Create, changes[2].Action)
+	assert.Equal(t, "mail", changes[2].Record.Name)
+
+	assert.Equal(t, Unchanged, changes[3].Action)
+	assert.Equal(t, int64(4), changes[3].Record.ID)
+}
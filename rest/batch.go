@@ -0,0 +1,69 @@
+package rest
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/gobuffalo/nulls"
+)
+
+const (
+	BatchCreate = "create"
+	BatchUpdate = "update"
+	BatchDelete = "delete"
+)
+
+// BatchOperation is a single change in a batch. Name and Type are only used
+// when creating a record and ID is only used for updates and deletes.
+type BatchOperation struct {
+	Action string      `json:"action"`
+	ID     int64       `json:"id,omitempty"`
+	Name   string      `json:"name,omitempty"`
+	Type   string      `json:"type,omitempty"`
+	Ttl    nulls.Int32 `json:"ttl"`
+	Value  RecordValue `json:"value"`
+	Active bool        `json:"active"`
+
+	// Views limits the record to the listed views, nil keeps the existing
+	// views of an updated record and an empty list uses every view
+	Views *[]string `json:"views,omitempty"`
+
+	// AutoPTR is not supported in a batch as the PTR records live in other
+	// zones, the record routes must be used for these records
+	AutoPTR bool `json:"auto_ptr,omitempty"`
+
+	// Version is the version of the record being updated or deleted, the
+	// batch fails with ErrModified when it differs and zero skips the check
+	Version int64 `json:"version,omitempty"`
+}
+
+// BatchResult is the record after the operation at the same index was applied
+type BatchResult struct {
+	Action string `json:"action"`
+	Record Record `json:"record"`
+}
+
+// ApplyBatch applies every operation in a single transaction, no changes are
+// made if any operation is invalid
+func (c *Client) ApplyBatch(zoneId int64, operations []BatchOperation) ([]BatchResult, error) {
+	buf := new(bytes.Buffer)
+	err := json.NewEncoder(buf).Encode(operations)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := doRequest(c, http.MethodPost, "/zones/"+strconv.FormatInt(zoneId, 10)+"/batch", buf)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var results []BatchResult
+	err = json.NewDecoder(resp.Body).Decode(&results)
+	if err != nil {
+		return nil, err
+	}
+	return results, nil
+}
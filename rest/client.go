@@ -2,11 +2,12 @@ package rest
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"net/url"
-	"strings"
+	"strconv"
 )
 
 type Client struct {
@@ -29,8 +30,16 @@ func NewClient(host string, refreshToken string) (*Client, error) {
 	}, nil
 }
 
+// ErrModified is returned by the update methods when the expected version no
+// longer matches because the resource was changed by someone else
+var ErrModified = errors.New("resource has been modified")
+
 func doRequest(c *Client, method string, p string, r io.Reader) (*http.Response, error) {
-	resp, err := doRequestInternal(c, method, p, r)
+	return doRequestWithHeader(c, method, p, r, nil)
+}
+
+func doRequestWithHeader(c *Client, method string, p string, r io.Reader, header http.Header) (*http.Response, error) {
+	resp, err := doRequestInternal(c, method, p, r, header)
 	if err != nil {
 		return nil, err
 	}
@@ -42,7 +51,7 @@ func doRequest(c *Client, method string, p string, r io.Reader) (*http.Response,
 			return nil, fmt.Errorf("failed to refresh token: %v: %w", err2, err)
 		}
 
-		resp, err = doRequestInternal(c, method, p, r)
+		resp, err = doRequestInternal(c, method, p, r, header)
 		if err != nil {
 			return nil, err
 		}
@@ -50,12 +59,11 @@ func doRequest(c *Client, method string, p string, r io.Reader) (*http.Response,
 	if resp.StatusCode != http.StatusOK {
 		defer resp.Body.Close()
 
-		var errMsg strings.Builder
-		_, err = io.Copy(&errMsg, resp.Body)
+		body, err := io.ReadAll(resp.Body)
 		if err != nil {
 			return nil, fmt.Errorf("failed to read bad status code error message")
 		}
-		return nil, fmt.Errorf("unexpected status code %d: %s", resp.StatusCode, errMsg.String())
+		return nil, parseError(resp.StatusCode, body)
 	}
 
 	return resp, nil
@@ -87,7 +95,7 @@ func refreshToken(c *Client) error {
 	return nil
 }
 
-func doRequestInternal(c *Client, method string, p string, r io.Reader) (*http.Response, error) {
+func doRequestInternal(c *Client, method string, p string, r io.Reader, header http.Header) (*http.Response, error) {
 	if c.accessToken == "" {
 		// Return a fake unauthorized response to prevent sending an empty access token
 		return &http.Response{StatusCode: http.StatusUnauthorized, Body: io.NopCloser(io.MultiReader())}, nil
@@ -97,6 +105,32 @@ func doRequestInternal(c *Client, method string, p string, r io.Reader) (*http.R
 	if err != nil {
 		return nil, err
 	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
 	req.Header.Set("Authorization", "Bearer "+c.accessToken)
 	return c.HttpClient.Do(req)
 }
+
+// ifMatch makes a request conditional on the version, zero sends the request
+// without a condition
+func ifMatch(version int64) http.Header {
+	if version == 0 {
+		return nil
+	}
+	return http.Header{"If-Match": {strconv.Quote(strconv.FormatInt(version, 10))}}
+}
+
+// versionFromETag reads the version of a zone or record from the ETag header,
+// zero is returned when the header is missing
+func versionFromETag(resp *http.Response) int64 {
+	tag, err := strconv.Unquote(resp.Header.Get("ETag"))
+	if err != nil {
+		return 0
+	}
+	version, err := strconv.ParseInt(tag, 10, 64)
+	if err != nil {
+		return 0
+	}
+	return version
+}
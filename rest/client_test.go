@@ -2,8 +2,11 @@ package rest
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 	"net/http/httptest"
+	"net/netip"
+	"strconv"
 	"strings"
 	"testing"
 	"time"
@@ -96,3 +99,130 @@ func TestNewClient(t *testing.T) {
 		t.Fatal("actual zone does not match expected zone")
 	}
 }
+
+func TestClientVersions(t *testing.T) {
+	r := chi.NewRouter()
+	issuer, err := mjwt.NewIssuer("Test", "1234", jwt.SigningMethodRS512)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	version := 4
+	r.Get("/zones/1/records/2", func(rw http.ResponseWriter, req *http.Request) {
+		rw.Header().Set("ETag", `"`+strconv.Itoa(version)+`"`)
+		rw.Write([]byte(`{"id":2,"name":"www","zone_id":1,"ttl":null,"type":"A","value":{"ip":"192.0.2.1"},"active":true}`))
+	})
+	r.Put("/zones/1/records/2", func(rw http.ResponseWriter, req *http.Request) {
+		if req.Header.Get("If-Match") != `"`+strconv.Itoa(version)+`"` {
+			http.Error(rw, "Precondition failed: the record has been modified", http.StatusPreconditionFailed)
+			return
+		}
+		version++
+		rw.Header().Set("ETag", `"`+strconv.Itoa(version)+`"`)
+		rw.Write([]byte(`{"id":2,"name":"www","zone_id":1,"ttl":null,"type":"A","value":{"ip":"192.0.2.2"},"active":true}`))
+	})
+
+	srv := httptest.NewServer(r)
+	client, err := NewClient(srv.URL, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.accessToken, err = auth.CreateAccessTokenWithDuration(issuer, time.Hour, "example.com", "5678", jwt.ClaimStrings{}, auth.NewPermStorage())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	record, err := client.GetZoneRecord(1, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if record.Version != 4 {
+		t.Fatal("expected version 4, actual", record.Version)
+	}
+
+	ip := netip.MustParseAddr("192.0.2.2")
+	updated, err := client.UpdateZoneRecord(1, 2, record.Version, PutRecord{Value: RecordValue{IP: &ip}, Active: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if updated.Version != 5 {
+		t.Fatal("expected version 5, actual", updated.Version)
+	}
+
+	_, err = client.UpdateZoneRecord(1, 2, record.Version, PutRecord{Value: RecordValue{IP: &ip}, Active: true})
+	if !errors.Is(err, ErrModified) {
+		t.Fatal("expected ErrModified, actual", err)
+	}
+}
+
+func TestClientErrors(t *testing.T) {
+	r := chi.NewRouter()
+	issuer, err := mjwt.NewIssuer("Test", "1234", jwt.SigningMethodRS512)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r.Get("/zones/1/records/2", func(rw http.ResponseWriter, req *http.Request) {
+		rw.WriteHeader(http.StatusNotFound)
+		rw.Write([]byte(`{"code":"not_found","message":"Not found"}`))
+	})
+	r.Put("/zones/1/records/2", func(rw http.ResponseWriter, req *http.Request) {
+		rw.WriteHeader(http.StatusBadRequest)
+		rw.Write([]byte(`{"code":"validation_failed","message":"Invalid value for type","field":"value"}`))
+	})
+	r.Delete("/zones/1/records/2", func(rw http.ResponseWriter, req *http.Request) {
+		rw.WriteHeader(http.StatusConflict)
+		rw.Write([]byte(`{"code":"lint_failed","message":"Zone lint failed: www.example.com CNAME: CNAME conflicts with the A record","lint":[{"severity":"error","name":"www.example.com","type":"CNAME","message":"CNAME conflicts with the A record"}]}`))
+	})
+	r.Get("/zones/1", func(rw http.ResponseWriter, req *http.Request) {
+		http.Error(rw, "Bad gateway", http.StatusBadGateway)
+	})
+
+	srv := httptest.NewServer(r)
+	client, err := NewClient(srv.URL, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.accessToken, err = auth.CreateAccessTokenWithDuration(issuer, time.Hour, "example.com", "5678", jwt.ClaimStrings{}, auth.NewPermStorage())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = client.GetZoneRecord(1, 2)
+	var notFound *ErrNotFound
+	if !errors.As(err, &notFound) {
+		t.Fatal("expected ErrNotFound, actual", err)
+	}
+	if notFound.Code != CodeNotFound {
+		t.Fatal("expected not_found code, actual", notFound.Code)
+	}
+
+	ip := netip.MustParseAddr("2001:db8::1")
+	_, err = client.UpdateZoneRecord(1, 2, 0, PutRecord{Value: RecordValue{IP: &ip}, Active: true})
+	var validation *ErrValidation
+	if !errors.As(err, &validation) {
+		t.Fatal("expected ErrValidation, actual", err)
+	}
+	if validation.Field != "value" || validation.Message != "Invalid value for type" {
+		t.Fatal("unexpected validation error", validation.Field, validation.Message)
+	}
+
+	err = client.DeleteZoneRecord(1, 2, 0)
+	var conflict *ErrConflict
+	if !errors.As(err, &conflict) {
+		t.Fatal("expected ErrConflict, actual", err)
+	}
+	if conflict.Code != CodeLintFailed || len(conflict.Lint) != 1 || conflict.Lint[0].Type != "CNAME" {
+		t.Fatal("unexpected conflict error", conflict.Code, conflict.Lint)
+	}
+
+	// plain text bodies are still returned as an APIError
+	_, err = client.GetZone(1)
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		t.Fatal("expected APIError, actual", err)
+	}
+	if apiErr.StatusCode != http.StatusBadGateway || apiErr.Code != CodeUnknown || apiErr.Message != "Bad gateway" {
+		t.Fatal("unexpected API error", apiErr.StatusCode, apiErr.Code, apiErr.Message)
+	}
+}
@@ -0,0 +1,101 @@
+package rest
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strconv"
+)
+
+// Delegation is a child zone delegated from a zone by NS records below the
+// apex, with optional glue and DS records.
+type Delegation struct {
+	Name        string             `json:"name"`
+	Nameservers []string           `json:"nameservers"`
+	Glue        []Glue             `json:"glue"`
+	DS          []DelegationSigner `json:"ds"`
+
+	// Automatic is set when the child is also a Verbena zone, the DS records
+	// are then kept in sync with the DNSKEY records of the child
+	Automatic bool `json:"automatic"`
+}
+
+type PutDelegation struct {
+	Nameservers []string           `json:"nameservers"`
+	Glue        []Glue             `json:"glue"`
+	DS          []DelegationSigner `json:"ds"`
+}
+
+// Glue is an address record for an in-bailiwick nameserver of a delegation
+type Glue struct {
+	Nameserver string     `json:"nameserver"`
+	IP         netip.Addr `json:"ip"`
+}
+
+type DelegationSigner struct {
+	KeyTag     uint16 `json:"key_tag"`
+	Algorithm  uint8  `json:"algorithm"`
+	DigestType uint8  `json:"digest_type"`
+	Digest     string `json:"digest"`
+}
+
+func (d DelegationSigner) RecordValue() RecordValue {
+	return RecordValue{
+		KeyTag:     d.KeyTag,
+		Algorithm:  d.Algorithm,
+		DigestType: d.DigestType,
+		Digest:     d.Digest,
+	}
+}
+
+func delegationsPath(zoneId int64) string {
+	return "/zones/" + strconv.FormatInt(zoneId, 10) + "/delegations"
+}
+
+func (c *Client) GetZoneDelegations(zoneId int64) ([]Delegation, error) {
+	resp, err := doRequest(c, http.MethodGet, delegationsPath(zoneId), nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var delegations []Delegation
+	err = json.NewDecoder(resp.Body).Decode(&delegations)
+	if err != nil {
+		return nil, err
+	}
+	return delegations, nil
+}
+
+// PutZoneDelegation creates or replaces the delegation of the child name, the
+// name is relative to the zone
+func (c *Client) PutZoneDelegation(zoneId int64, name string, putDelegation PutDelegation) (Delegation, error) {
+	buf := new(bytes.Buffer)
+	err := json.NewEncoder(buf).Encode(putDelegation)
+	if err != nil {
+		return Delegation{}, err
+	}
+
+	resp, err := doRequest(c, http.MethodPut, delegationsPath(zoneId)+"/"+url.PathEscape(name), buf)
+	if err != nil {
+		return Delegation{}, err
+	}
+	defer resp.Body.Close()
+
+	var delegation Delegation
+	err = json.NewDecoder(resp.Body).Decode(&delegation)
+	if err != nil {
+		return Delegation{}, err
+	}
+	return delegation, nil
+}
+
+func (c *Client) DeleteZoneDelegation(zoneId int64, name string) error {
+	resp, err := doRequest(c, http.MethodDelete, delegationsPath(zoneId)+"/"+url.PathEscape(name), nil)
+	if err != nil {
+		return err
+	}
+	return resp.Body.Close()
+}
@@ -0,0 +1,121 @@
+package rest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// Error codes sent in the code field of an error response
+const (
+	CodeValidation         = "validation_failed"
+	CodeUnauthorized       = "unauthorized"
+	CodeForbidden          = "forbidden"
+	CodeNotFound           = "not_found"
+	CodeConflict           = "conflict"
+	CodeLintFailed         = "lint_failed"
+	CodePreconditionFailed = "precondition_failed"
+	CodeInternal           = "internal_error"
+	CodeUnknown            = "error"
+)
+
+// CodeForStatus returns the error code used for responses with the status
+func CodeForStatus(status int) string {
+	switch status {
+	case http.StatusBadRequest:
+		return CodeValidation
+	case http.StatusUnauthorized:
+		return CodeUnauthorized
+	case http.StatusForbidden:
+		return CodeForbidden
+	case http.StatusNotFound:
+		return CodeNotFound
+	case http.StatusConflict:
+		return CodeConflict
+	case http.StatusPreconditionFailed:
+		return CodePreconditionFailed
+	case http.StatusInternalServerError:
+		return CodeInternal
+	}
+	return CodeUnknown
+}
+
+// ErrorBody is the JSON body of every error response. Field is the request
+// field or URL parameter which was rejected and Lint contains the problems
+// which caused a lint_failed conflict.
+type ErrorBody struct {
+	Code    string        `json:"code"`
+	Message string        `json:"message"`
+	Field   string        `json:"field,omitempty"`
+	Lint    []LintProblem `json:"lint,omitempty"`
+}
+
+// APIError is an error response from the API. The client returns the more
+// specific ErrNotFound, ErrValidation and ErrConflict types for those status
+// codes, each of which unwraps to the APIError.
+type APIError struct {
+	StatusCode int
+	ErrorBody
+}
+
+func (e *APIError) Error() string {
+	msg := fmt.Sprintf("unexpected status code %d: %s", e.StatusCode, e.Message)
+	if e.Field != "" {
+		msg += " (field " + e.Field + ")"
+	}
+	return msg
+}
+
+// Is matches ErrModified for 412 responses so conditional updates can still
+// be checked with errors.Is
+func (e *APIError) Is(target error) bool {
+	return target == ErrModified && e.StatusCode == http.StatusPreconditionFailed
+}
+
+// ErrNotFound is returned when the zone, record or other resource does not
+// exist or is not owned by the token
+type ErrNotFound struct {
+	*APIError
+}
+
+func (e *ErrNotFound) Unwrap() error { return e.APIError }
+
+// ErrValidation is returned when the request is invalid, Field names the
+// rejected field when there is a single one
+type ErrValidation struct {
+	*APIError
+}
+
+func (e *ErrValidation) Unwrap() error { return e.APIError }
+
+// ErrConflict is returned when the change conflicts with the existing records
+// or was raced by another request, Lint is set when the zone lint failed
+type ErrConflict struct {
+	*APIError
+}
+
+func (e *ErrConflict) Unwrap() error { return e.APIError }
+
+// parseError converts an error response into a typed error, bodies which are
+// not JSON are used as the message
+func parseError(statusCode int, body []byte) error {
+	apiErr := &APIError{StatusCode: statusCode}
+	if err := json.Unmarshal(body, &apiErr.ErrorBody); err != nil || apiErr.Code == "" {
+		apiErr.ErrorBody = ErrorBody{
+			Code:    CodeForStatus(statusCode),
+			Message: strings.TrimSpace(string(bytes.ToValidUTF8(body, nil))),
+		}
+	}
+
+	switch statusCode {
+	case http.StatusNotFound:
+		return &ErrNotFound{apiErr}
+	case http.StatusBadRequest:
+		return &ErrValidation{apiErr}
+	case http.StatusConflict:
+		return &ErrConflict{apiErr}
+	}
+	return apiErr
+}
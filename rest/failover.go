@@ -0,0 +1,114 @@
+package rest
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gobuffalo/nulls"
+)
+
+const (
+	HealthCheckTCP  = "tcp"
+	HealthCheckHTTP = "http"
+	HealthCheckDNS  = "dns"
+)
+
+// HealthCheck is run by Verbena against the primary value of a failover
+// record. Target is "host:port" for TCP checks, a URL for HTTP checks and the
+// "host:port" of the nameserver for DNS checks.
+type HealthCheck struct {
+	Type   string `json:"type"`
+	Target string `json:"target"`
+
+	// HttpStatus is the expected response status of HTTP checks, defaults to 200
+	HttpStatus int32 `json:"http_status,omitempty"`
+
+	// QueryName and QueryType are sent to the nameserver in DNS checks, the
+	// check passes when the answer section is not empty
+	QueryName string `json:"query_name,omitempty"`
+	QueryType string `json:"query_type,omitempty"`
+
+	// Interval is the number of seconds between checks, defaults to 30
+	Interval int32 `json:"interval,omitempty"`
+
+	// Threshold is the number of consecutive results needed to switch between
+	// the primary and backup values, defaults to 3
+	Threshold int32 `json:"threshold,omitempty"`
+}
+
+// HealthStatus is the latest result of the health check, the backup value is
+// published while Healthy is false
+type HealthStatus struct {
+	Healthy   bool       `json:"healthy"`
+	CheckedAt *time.Time `json:"checked_at,omitempty"`
+	LastError string     `json:"last_error,omitempty"`
+}
+
+// Failover publishes Backup with BackupTtl in place of the record value while
+// the health check is failing
+type Failover struct {
+	RecordID  int64        `json:"record_id"`
+	Backup    RecordValue  `json:"backup"`
+	BackupTtl int32        `json:"backup_ttl"`
+	Check     HealthCheck  `json:"check"`
+	Status    HealthStatus `json:"status"`
+}
+
+// PutFailover uses a backup TTL of 60 seconds when Ttl is null
+type PutFailover struct {
+	Backup    RecordValue `json:"backup"`
+	BackupTtl nulls.Int32 `json:"backup_ttl"`
+	Check     HealthCheck `json:"check"`
+}
+
+func failoverPath(zoneId, recordId int64) string {
+	return "/zones/" + strconv.FormatInt(zoneId, 10) + "/records/" + strconv.FormatInt(recordId, 10) + "/failover"
+}
+
+func (c *Client) GetZoneRecordFailover(zoneId, recordId int64) (Failover, error) {
+	resp, err := doRequest(c, http.MethodGet, failoverPath(zoneId, recordId), nil)
+	if err != nil {
+		return Failover{}, err
+	}
+	defer resp.Body.Close()
+
+	var failover Failover
+	err = json.NewDecoder(resp.Body).Decode(&failover)
+	if err != nil {
+		return Failover{}, err
+	}
+	return failover, nil
+}
+
+func (c *Client) PutZoneRecordFailover(zoneId, recordId int64, putFailover PutFailover) (Failover, error) {
+	buf := new(bytes.Buffer)
+	err := json.NewEncoder(buf).Encode(putFailover)
+	if err != nil {
+		return Failover{}, err
+	}
+
+	resp, err := doRequest(c, http.MethodPut, failoverPath(zoneId, recordId), buf)
+	if err != nil {
+		return Failover{}, err
+	}
+	defer resp.Body.Close()
+
+	var failover Failover
+	err = json.NewDecoder(resp.Body).Decode(&failover)
+	if err != nil {
+		return Failover{}, err
+	}
+	return failover, nil
+}
+
+func (c *Client) DeleteZoneRecordFailover(zoneId, recordId int64) error {
+	resp, err := doRequest(c, http.MethodDelete, failoverPath(zoneId, recordId), nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	return nil
+}
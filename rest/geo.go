@@ -0,0 +1,98 @@
+package rest
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/gobuffalo/nulls"
+)
+
+// GeoRecordSet is every record of a name and type answered per view, clients
+// are matched to a view by address or by the regions of the view looked up in
+// the GeoIP database. The records share a single TTL.
+type GeoRecordSet struct {
+	Name        string      `json:"name"`
+	NameUnicode string      `json:"name_unicode,omitempty"`
+	Type        string      `json:"type"`
+	Ttl         nulls.Int32 `json:"ttl"`
+	Answers     []GeoAnswer `json:"answers"`
+}
+
+// GeoAnswer is the records published in a view, when replacing a record set
+// the answer without a view is published in every view without an answer
+type GeoAnswer struct {
+	View    string        `json:"view,omitempty"`
+	Regions []string      `json:"regions,omitempty"`
+	Records []RRsetRecord `json:"records"`
+}
+
+type PutGeoRecordSet struct {
+	Ttl     nulls.Int32 `json:"ttl"`
+	Answers []GeoAnswer `json:"answers"`
+}
+
+func geoRecordSetsPath(zoneId int64) string {
+	return "/zones/" + strconv.FormatInt(zoneId, 10) + "/geo"
+}
+
+func geoRecordSetPath(zoneId int64, name, recordType string) string {
+	return geoRecordSetsPath(zoneId) + "/" + url.PathEscape(name) + "/" + url.PathEscape(recordType)
+}
+
+// GetZoneGeoRecordSets lists the record sets with records targeting a geo view
+func (c *Client) GetZoneGeoRecordSets(zoneId int64) ([]GeoRecordSet, error) {
+	resp, err := doRequest(c, http.MethodGet, geoRecordSetsPath(zoneId), nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var sets []GeoRecordSet
+	err = json.NewDecoder(resp.Body).Decode(&sets)
+	if err != nil {
+		return nil, err
+	}
+	return sets, nil
+}
+
+func (c *Client) GetZoneGeoRecordSet(zoneId int64, name, recordType string) (GeoRecordSet, error) {
+	resp, err := doRequest(c, http.MethodGet, geoRecordSetPath(zoneId, name, recordType), nil)
+	if err != nil {
+		return GeoRecordSet{}, err
+	}
+	defer resp.Body.Close()
+
+	var set GeoRecordSet
+	err = json.NewDecoder(resp.Body).Decode(&set)
+	if err != nil {
+		return GeoRecordSet{}, err
+	}
+	return set, nil
+}
+
+// PutZoneGeoRecordSet atomically replaces every record of the name and type
+// with the answers of each view, the name is relative to the zone. Records
+// are removed with DeleteZoneRRset.
+func (c *Client) PutZoneGeoRecordSet(zoneId int64, name, recordType string, putSet PutGeoRecordSet) (GeoRecordSet, error) {
+	buf := new(bytes.Buffer)
+	err := json.NewEncoder(buf).Encode(putSet)
+	if err != nil {
+		return GeoRecordSet{}, err
+	}
+
+	resp, err := doRequest(c, http.MethodPut, geoRecordSetPath(zoneId, name, recordType), buf)
+	if err != nil {
+		return GeoRecordSet{}, err
+	}
+	defer resp.Body.Close()
+
+	var set GeoRecordSet
+	err = json.NewDecoder(resp.Body).Decode(&set)
+	if err != nil {
+		return GeoRecordSet{}, err
+	}
+	return set, nil
+}
@@ -0,0 +1,83 @@
+package rest
+
+import (
+	"github.com/1f349/verbena/internal/utils"
+	"github.com/1f349/verbena/internal/zone"
+)
+
+// hasDomainTarget reports whether the Target of the record type is a domain
+// name, URI records use Target for a URI instead
+func hasDomainTarget(recordType string) bool {
+	switch zone.RecordTypeFromString(recordType) {
+	case zone.NS, zone.MX, zone.CNAME, zone.ALIAS, zone.SRV, zone.PTR, zone.SVCB, zone.HTTPS:
+		return true
+	default:
+		return false
+	}
+}
+
+// ToASCII converts a Unicode target to punycode so it can be validated and
+// stored, the Unicode form is only used in responses
+func (v RecordValue) ToASCII(recordType string) (RecordValue, error) {
+	v.TargetUnicode = ""
+	if !hasDomainTarget(recordType) {
+		return v, nil
+	}
+	target, err := utils.ToASCIIName(v.Target)
+	if err != nil {
+		return RecordValue{}, err
+	}
+	v.Target = target
+	return v, nil
+}
+
+// WithUnicode fills in the Unicode form of a punycode target
+func (v RecordValue) WithUnicode(recordType string) RecordValue {
+	v.TargetUnicode = ""
+	if hasDomainTarget(recordType) {
+		v.TargetUnicode = utils.UnicodeNameIfDifferent(v.Target)
+	}
+	return v
+}
+
+// WithUnicode fills in the Unicode forms of the punycode name and target
+func (r Record) WithUnicode() Record {
+	r.NameUnicode = utils.UnicodeNameIfDifferent(r.Name)
+	r.Value = r.Value.WithUnicode(r.Type)
+	return r
+}
+
+// ToASCII converts the Unicode nameservers of the delegation to punycode
+func (d PutDelegation) ToASCII() (PutDelegation, error) {
+	nameservers := make([]string, len(d.Nameservers))
+	for i, ns := range d.Nameservers {
+		a, err := utils.ToASCIIName(ns)
+		if err != nil {
+			return PutDelegation{}, err
+		}
+		nameservers[i] = a
+	}
+	glue := make([]Glue, len(d.Glue))
+	for i, g := range d.Glue {
+		a, err := utils.ToASCIIName(g.Nameserver)
+		if err != nil {
+			return PutDelegation{}, err
+		}
+		glue[i] = Glue{Nameserver: a, IP: g.IP}
+	}
+	d.Nameservers = nameservers
+	d.Glue = glue
+	return d, nil
+}
+
+// WithUnicode fills in the Unicode forms of the punycode name and targets
+func (p Pool) WithUnicode() Pool {
+	p.NameUnicode = utils.UnicodeNameIfDifferent(p.Name)
+	members := make([]PoolMember, len(p.Members))
+	for i, m := range p.Members {
+		m.Value = m.Value.WithUnicode(p.Type)
+		members[i] = m
+	}
+	p.Members = members
+	return p
+}
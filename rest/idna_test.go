@@ -0,0 +1,42 @@
+package rest
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecordValue_ToASCII(t *testing.T) {
+	v, err := RecordValue{Target: "bücher.example", TargetUnicode: "ignored"}.ToASCII("CNAME")
+	assert.NoError(t, err)
+	assert.Equal(t, RecordValue{Target: "xn--bcher-kva.example"}, v)
+	assert.True(t, v.IsValidForType("CNAME"))
+
+	// URI targets are not domain names
+	v, err = RecordValue{Priority: 10, Weight: 1, Target: "https://bücher.example"}.ToASCII("URI")
+	assert.NoError(t, err)
+	assert.Equal(t, "https://bücher.example", v.Target)
+
+	_, err = RecordValue{Preference: 10, Target: "bü cher.example"}.ToASCII("MX")
+	assert.Error(t, err)
+}
+
+func TestRecord_WithUnicode(t *testing.T) {
+	r := Record{
+		ID:     1,
+		Name:   "xn--bcher-kva",
+		ZoneID: 2,
+		Type:   "CNAME",
+		Value:  RecordValue{Target: "xn--r8jz45g.example"},
+		Active: true,
+	}.WithUnicode()
+	j, err := json.Marshal(r)
+	assert.NoError(t, err)
+	assert.Equal(t, `{"id":1,"name":"xn--bcher-kva","name_unicode":"bücher","zone_id":2,"ttl":null,"type":"CNAME","value":{"target":"xn--r8jz45g.example","target_unicode":"例え.example"},"active":true}`, string(j))
+
+	// ASCII names are not repeated
+	r = Record{Name: "www", Type: "CNAME", Value: RecordValue{Target: "example.com"}}.WithUnicode()
+	assert.Equal(t, "", r.NameUnicode)
+	assert.Equal(t, "", r.Value.TargetUnicode)
+}
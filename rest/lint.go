@@ -0,0 +1,36 @@
+package rest
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+)
+
+const (
+	LintError   = "error"
+	LintWarning = "warning"
+)
+
+// LintProblem is a cross-record issue found in a zone, errors block writes and
+// commits while warnings are only reported.
+type LintProblem struct {
+	Severity string `json:"severity"`
+	Name     string `json:"name"`
+	Type     string `json:"type"`
+	Message  string `json:"message"`
+}
+
+func (c *Client) GetZoneLint(zoneId int64) ([]LintProblem, error) {
+	resp, err := doRequest(c, http.MethodGet, "/zones/"+strconv.FormatInt(zoneId, 10)+"/lint", nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var problems []LintProblem
+	err = json.NewDecoder(resp.Body).Decode(&problems)
+	if err != nil {
+		return nil, err
+	}
+	return problems, nil
+}
@@ -0,0 +1,166 @@
+package rest
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/gobuffalo/nulls"
+)
+
+// SPF is the sender policy framework TXT record of a mail domain, Lookups is
+// the number of DNS lookups needed to evaluate the record
+type SPF struct {
+	Name       string      `json:"name"`
+	Ttl        nulls.Int32 `json:"ttl"`
+	Mechanisms []string    `json:"mechanisms"`
+	Lookups    int         `json:"lookups"`
+	Text       string      `json:"text"`
+}
+
+// PutSPF contains the mechanisms and modifiers after the v=spf1 version, for
+// example "mx", "include:_spf.example.net" and "-all"
+type PutSPF struct {
+	Ttl        nulls.Int32 `json:"ttl"`
+	Mechanisms []string    `json:"mechanisms"`
+}
+
+// DKIMKey is the public key published for a DKIM selector
+type DKIMKey struct {
+	// KeyType is either rsa or ed25519
+	KeyType string `json:"key_type"`
+	// PublicKey is the base64 encoded public key, a DER SubjectPublicKeyInfo
+	// for RSA keys and the raw 32 byte key for Ed25519 keys
+	PublicKey      string   `json:"public_key"`
+	HashAlgorithms []string `json:"hash_algorithms,omitempty"`
+	Flags          []string `json:"flags,omitempty"`
+}
+
+type DKIM struct {
+	Name     string      `json:"name"`
+	Selector string      `json:"selector"`
+	Ttl      nulls.Int32 `json:"ttl"`
+	DKIMKey
+	KeyBits int    `json:"key_bits"`
+	Text    string `json:"text"`
+}
+
+type PutDKIM struct {
+	Ttl nulls.Int32 `json:"ttl"`
+	DKIMKey
+}
+
+// DMARCPolicy contains the DMARC tags, empty tags use the defaults from
+// RFC 7489 and are left out of the record
+type DMARCPolicy struct {
+	Policy           string      `json:"policy"`
+	SubdomainPolicy  string      `json:"subdomain_policy,omitempty"`
+	Percent          nulls.Int32 `json:"percent"`
+	AggregateReports []string    `json:"aggregate_reports,omitempty"`
+	FailureReports   []string    `json:"failure_reports,omitempty"`
+	DkimAlignment    string      `json:"dkim_alignment,omitempty"`
+	SpfAlignment     string      `json:"spf_alignment,omitempty"`
+	FailureOptions   string      `json:"failure_options,omitempty"`
+	ReportInterval   nulls.Int32 `json:"report_interval"`
+}
+
+type DMARC struct {
+	Name string      `json:"name"`
+	Ttl  nulls.Int32 `json:"ttl"`
+	DMARCPolicy
+	Text string `json:"text"`
+}
+
+type PutDMARC struct {
+	Ttl nulls.Int32 `json:"ttl"`
+	DMARCPolicy
+}
+
+func mailPath(zoneId int64, name string) string {
+	return "/zones/" + strconv.FormatInt(zoneId, 10) + "/mail/" + url.PathEscape(name)
+}
+
+// GetZoneSPF gets the SPF record of the mail domain, the name is relative to
+// the zone with "@" for the apex
+func (c *Client) GetZoneSPF(zoneId int64, name string) (SPF, error) {
+	var spf SPF
+	err := getMailAuth(c, mailPath(zoneId, name)+"/spf", &spf)
+	return spf, err
+}
+
+func (c *Client) PutZoneSPF(zoneId int64, name string, putSPF PutSPF) (SPF, error) {
+	var spf SPF
+	err := putMailAuth(c, mailPath(zoneId, name)+"/spf", putSPF, &spf)
+	return spf, err
+}
+
+func (c *Client) DeleteZoneSPF(zoneId int64, name string) error {
+	return deleteMailAuth(c, mailPath(zoneId, name)+"/spf")
+}
+
+func (c *Client) GetZoneDKIM(zoneId int64, name, selector string) (DKIM, error) {
+	var dkim DKIM
+	err := getMailAuth(c, mailPath(zoneId, name)+"/dkim/"+url.PathEscape(selector), &dkim)
+	return dkim, err
+}
+
+func (c *Client) PutZoneDKIM(zoneId int64, name, selector string, putDKIM PutDKIM) (DKIM, error) {
+	var dkim DKIM
+	err := putMailAuth(c, mailPath(zoneId, name)+"/dkim/"+url.PathEscape(selector), putDKIM, &dkim)
+	return dkim, err
+}
+
+func (c *Client) DeleteZoneDKIM(zoneId int64, name, selector string) error {
+	return deleteMailAuth(c, mailPath(zoneId, name)+"/dkim/"+url.PathEscape(selector))
+}
+
+func (c *Client) GetZoneDMARC(zoneId int64, name string) (DMARC, error) {
+	var dmarc DMARC
+	err := getMailAuth(c, mailPath(zoneId, name)+"/dmarc", &dmarc)
+	return dmarc, err
+}
+
+func (c *Client) PutZoneDMARC(zoneId int64, name string, putDMARC PutDMARC) (DMARC, error) {
+	var dmarc DMARC
+	err := putMailAuth(c, mailPath(zoneId, name)+"/dmarc", putDMARC, &dmarc)
+	return dmarc, err
+}
+
+func (c *Client) DeleteZoneDMARC(zoneId int64, name string) error {
+	return deleteMailAuth(c, mailPath(zoneId, name)+"/dmarc")
+}
+
+func getMailAuth(c *Client, path string, out any) error {
+	resp, err := doRequest(c, http.MethodGet, path, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+func putMailAuth(c *Client, path string, in, out any) error {
+	buf := new(bytes.Buffer)
+	err := json.NewEncoder(buf).Encode(in)
+	if err != nil {
+		return err
+	}
+
+	resp, err := doRequest(c, http.MethodPut, path, buf)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+func deleteMailAuth(c *Client, path string) error {
+	resp, err := doRequest(c, http.MethodDelete, path, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	return nil
+}
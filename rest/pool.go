@@ -0,0 +1,117 @@
+package rest
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/gobuffalo/nulls"
+)
+
+// Pool is a set of A, AAAA or CNAME values for a single name. Size members are
+// published at a time and the published members rotate on each generator tick
+// in proportion to their weights.
+type Pool struct {
+	Name        string       `json:"name"`
+	NameUnicode string       `json:"name_unicode,omitempty"`
+	Type        string       `json:"type"`
+	Ttl         nulls.Int32  `json:"ttl"`
+	Size        int32        `json:"size"`
+	Members     []PoolMember `json:"members"`
+}
+
+type PoolMember struct {
+	Value   RecordValue `json:"value"`
+	Weight  int32       `json:"weight"`
+	Enabled bool        `json:"enabled"`
+}
+
+// UnmarshalJSON treats members without an enabled field as enabled
+func (p *PoolMember) UnmarshalJSON(b []byte) error {
+	type plain PoolMember
+	v := plain{Enabled: true}
+	err := json.Unmarshal(b, &v)
+	if err != nil {
+		return err
+	}
+	*p = PoolMember(v)
+	return nil
+}
+
+type PutPool struct {
+	Ttl     nulls.Int32  `json:"ttl"`
+	Size    int32        `json:"size"`
+	Members []PoolMember `json:"members"`
+}
+
+func poolsPath(zoneId int64) string {
+	return "/zones/" + strconv.FormatInt(zoneId, 10) + "/pools"
+}
+
+func poolPath(zoneId int64, name, recordType string) string {
+	return poolsPath(zoneId) + "/" + url.PathEscape(name) + "/" + url.PathEscape(recordType)
+}
+
+func (c *Client) GetZonePools(zoneId int64) ([]Pool, error) {
+	resp, err := doRequest(c, http.MethodGet, poolsPath(zoneId), nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var pools []Pool
+	err = json.NewDecoder(resp.Body).Decode(&pools)
+	if err != nil {
+		return nil, err
+	}
+	return pools, nil
+}
+
+func (c *Client) GetZonePool(zoneId int64, name, recordType string) (Pool, error) {
+	resp, err := doRequest(c, http.MethodGet, poolPath(zoneId, name, recordType), nil)
+	if err != nil {
+		return Pool{}, err
+	}
+	defer resp.Body.Close()
+
+	var pool Pool
+	err = json.NewDecoder(resp.Body).Decode(&pool)
+	if err != nil {
+		return Pool{}, err
+	}
+	return pool, nil
+}
+
+// PutZonePool creates or replaces the pool of the name and type, the name is
+// relative to the zone
+func (c *Client) PutZonePool(zoneId int64, name, recordType string, putPool PutPool) (Pool, error) {
+	buf := new(bytes.Buffer)
+	err := json.NewEncoder(buf).Encode(putPool)
+	if err != nil {
+		return Pool{}, err
+	}
+
+	resp, err := doRequest(c, http.MethodPut, poolPath(zoneId, name, recordType), buf)
+	if err != nil {
+		return Pool{}, err
+	}
+	defer resp.Body.Close()
+
+	var pool Pool
+	err = json.NewDecoder(resp.Body).Decode(&pool)
+	if err != nil {
+		return Pool{}, err
+	}
+	return pool, nil
+}
+
+func (c *Client) DeleteZonePool(zoneId int64, name, recordType string) error {
+	resp, err := doRequest(c, http.MethodDelete, poolPath(zoneId, name, recordType), nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	return nil
+}
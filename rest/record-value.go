@@ -3,25 +3,67 @@ package rest
 import (
 	"errors"
 	"fmt"
+	"math"
 	"net/netip"
+	"net/url"
 	"strconv"
 	"strings"
 
 	"github.com/1f349/verbena/internal/utils"
 	"github.com/1f349/verbena/internal/zone"
+	"github.com/miekg/dns"
 )
 
 type RecordValue struct {
-	Text       string      `json:"text,omitempty"`
-	Target     string      `json:"target,omitempty"`
-	IP         *netip.Addr `json:"ip,omitempty"`
-	Preference int32       `json:"preference,omitempty"`
-	Priority   int32       `json:"priority,omitempty"`
-	Weight     int32       `json:"weight,omitempty"`
-	Port       uint16      `json:"port,omitempty"`
-	Flags      uint8       `json:"flags,omitempty"`
-	Tag        string      `json:"tag,omitempty"`
-	Value      string      `json:"value,omitempty"`
+	Text          string      `json:"text,omitempty"`
+	Target        string      `json:"target,omitempty"`
+	TargetUnicode string      `json:"target_unicode,omitempty"`
+	IP            *netip.Addr `json:"ip,omitempty"`
+	Preference    int32       `json:"preference,omitempty"`
+	Priority      int32       `json:"priority,omitempty"`
+	Weight        int32       `json:"weight,omitempty"`
+	Port          uint16      `json:"port,omitempty"`
+	Flags         uint8       `json:"flags,omitempty"`
+	Tag           string      `json:"tag,omitempty"`
+	Value         string      `json:"value,omitempty"`
+
+	// TLSA and SMIMEA
+	Usage        uint8  `json:"usage,omitempty"`
+	Selector     uint8  `json:"selector,omitempty"`
+	MatchingType uint8  `json:"matching_type,omitempty"`
+	Certificate  string `json:"certificate,omitempty"`
+
+	// SSHFP, DS also uses Algorithm
+	Algorithm       uint8  `json:"algorithm,omitempty"`
+	FingerprintType uint8  `json:"fingerprint_type,omitempty"`
+	Fingerprint     string `json:"fingerprint,omitempty"`
+
+	// SVCB and HTTPS, these also use Priority and Target
+	Params *SvcParams `json:"params,omitempty"`
+
+	// NAPTR, this also uses Preference
+	Order       uint16 `json:"order,omitempty"`
+	NaptrFlags  string `json:"naptr_flags,omitempty"`
+	Service     string `json:"service,omitempty"`
+	Regexp      string `json:"regexp,omitempty"`
+	Replacement string `json:"replacement,omitempty"`
+
+	// LOC, coordinates are decimal degrees and everything else is in metres
+	Latitude            float64 `json:"latitude,omitempty"`
+	Longitude           float64 `json:"longitude,omitempty"`
+	Altitude            float64 `json:"altitude,omitempty"`
+	Size                float64 `json:"size,omitempty"`
+	HorizontalPrecision float64 `json:"horizontal_precision,omitempty"`
+	VerticalPrecision   float64 `json:"vertical_precision,omitempty"`
+
+	// HINFO
+	Cpu string `json:"cpu,omitempty"`
+	Os  string `json:"os,omitempty"`
+
+	// DS
+	KeyTag     uint16 `json:"key_tag,omitempty"`
+	DigestType uint8  `json:"digest_type,omitempty"`
+	Digest     string `json:"digest,omitempty"`
 }
 
 func (v RecordValue) IsValidForType(recordType string) bool {
@@ -35,16 +77,46 @@ func (v RecordValue) IsValidForType(recordType string) bool {
 		return v.IP != nil && v.IP.Is4()
 	case zone.AAAA:
 		return v.IP != nil && v.IP.Is6()
-	case zone.CNAME:
+	case zone.CNAME, zone.ALIAS:
 		return utils.ValidateDomainName(v.Target)
 	case zone.TXT:
 		return v.Text != ""
 	case zone.SRV:
 		return v.Priority > 0 && v.Weight >= 0 && v.Port > 0 && utils.ValidateDomainName(v.Target)
 	case zone.CAA:
-		return (v.Tag == "issue" || v.Tag == "issuewild") && !strings.ContainsAny(v.Value, "\t")
+		return utils.ValidateCaa(v.Flags, v.Tag, v.Value)
 	case zone.PTR:
 		return utils.ValidateDomainName(v.Target)
+	case zone.TLSA, zone.SMIMEA:
+		return v.Usage <= 3 && v.Selector <= 1 && v.MatchingType <= 2 && utils.ValidateHex(v.Certificate, utils.TlsaDigestSize(v.MatchingType))
+	case zone.SSHFP:
+		return isValidSshfpAlgorithm(v.Algorithm) && (v.FingerprintType == 1 || v.FingerprintType == 2) && utils.ValidateHex(v.Fingerprint, utils.SshfpDigestSize(v.FingerprintType))
+	case zone.SVCB, zone.HTTPS:
+		if v.Priority < 0 || v.Priority > math.MaxUint16 || !utils.ValidateDomainName(v.Target) {
+			return false
+		}
+		if v.Priority == 0 {
+			// AliasMode
+			return v.Params.IsEmpty()
+		}
+		return v.Params == nil || v.Params.IsValid()
+	case zone.NAPTR:
+		return v.Preference >= 0 && v.Preference <= math.MaxUint16 && isValidNaptrFlags(v.NaptrFlags) &&
+			utils.ValidateCharacterString(v.Service) && utils.ValidateCharacterString(v.Regexp) &&
+			isValidReplacement(v.Replacement) &&
+			// the regexp and replacement fields are mutually exclusive
+			(v.Regexp == "" || v.Replacement == ".")
+	case zone.URI:
+		return v.Priority >= 0 && v.Priority <= math.MaxUint16 && v.Weight >= 0 && v.Weight <= math.MaxUint16 && isValidUri(v.Target)
+	case zone.LOC:
+		return v.Latitude >= -90 && v.Latitude <= 90 && v.Longitude >= -180 && v.Longitude <= 180 &&
+			v.Altitude >= -100000 && v.Altitude <= 42849672.95 &&
+			isValidLocMetres(v.Size) && isValidLocMetres(v.HorizontalPrecision) && isValidLocMetres(v.VerticalPrecision)
+	case zone.HINFO:
+		return v.Cpu != "" && v.Os != "" && utils.ValidateCharacterString(v.Cpu) && utils.ValidateCharacterString(v.Os)
+	case zone.DS:
+		size := utils.DsDigestSize(v.DigestType)
+		return v.Algorithm != 0 && size != 0 && utils.ValidateHex(v.Digest, size)
 	default:
 		return false
 	}
@@ -67,7 +139,7 @@ func (v RecordValue) ToValueString(recordType string) string {
 			return "::"
 		}
 		return v.IP.String()
-	case zone.CNAME:
+	case zone.CNAME, zone.ALIAS:
 		return v.Target
 	case zone.TXT:
 		return v.Text
@@ -77,6 +149,32 @@ func (v RecordValue) ToValueString(recordType string) string {
 		return fmt.Sprintf("%d\t%s\t%s", v.Flags, v.Tag, v.Value)
 	case zone.PTR:
 		return v.Target
+	case zone.TLSA, zone.SMIMEA:
+		return fmt.Sprintf("%d\t%d\t%d\t%s", v.Usage, v.Selector, v.MatchingType, strings.ToLower(v.Certificate))
+	case zone.SSHFP:
+		return fmt.Sprintf("%d\t%d\t%s", v.Algorithm, v.FingerprintType, strings.ToLower(v.Fingerprint))
+	case zone.SVCB, zone.HTTPS:
+		if v.Params.IsEmpty() {
+			return fmt.Sprintf("%d\t%s", v.Priority, v.Target)
+		}
+		return fmt.Sprintf("%d\t%s\t%s", v.Priority, v.Target, v.Params.String())
+	case zone.NAPTR:
+		return fmt.Sprintf("%d\t%d\t%s\t%s\t%s\t%s", v.Order, v.Preference, v.NaptrFlags, v.Service, v.Regexp, v.Replacement)
+	case zone.URI:
+		return fmt.Sprintf("%d\t%d\t%s", v.Priority, v.Weight, v.Target)
+	case zone.LOC:
+		return strings.Join([]string{
+			formatFloat(v.Latitude),
+			formatFloat(v.Longitude),
+			formatFloat(v.Altitude),
+			formatFloat(v.Size),
+			formatFloat(v.HorizontalPrecision),
+			formatFloat(v.VerticalPrecision),
+		}, "\t")
+	case zone.HINFO:
+		return fmt.Sprintf("%s\t%s", v.Cpu, v.Os)
+	case zone.DS:
+		return fmt.Sprintf("%d\t%d\t%d\t%s", v.KeyTag, v.Algorithm, v.DigestType, strings.ToLower(v.Digest))
 	default:
 		return ""
 	}
@@ -118,9 +216,9 @@ func ParseRecordValue(recordType string, value string) (RecordValue, error) {
 			return RecordValue{}, errors.New("invalid AAAA record")
 		}
 		return RecordValue{IP: &v6}, nil
-	case zone.CNAME:
+	case zone.CNAME, zone.ALIAS:
 		if !utils.ValidateDomainName(value) {
-			return RecordValue{}, errors.New("invalid CNAME record")
+			return RecordValue{}, fmt.Errorf("invalid %s record", ty)
 		}
 		return RecordValue{Target: value}, nil
 	case zone.TXT:
@@ -164,7 +262,7 @@ func ParseRecordValue(recordType string, value string) (RecordValue, error) {
 		if err != nil {
 			return RecordValue{}, errors.New("invalid CAA record")
 		}
-		if fields[1] != "issue" && fields[1] != "issuewild" {
+		if !utils.ValidateCaa(uint8(flags), fields[1], fields[2]) {
 			return RecordValue{}, errors.New("invalid CAA record")
 		}
 		return RecordValue{
@@ -177,7 +275,228 @@ func ParseRecordValue(recordType string, value string) (RecordValue, error) {
 			return RecordValue{}, errors.New("invalid PTR record")
 		}
 		return RecordValue{Target: value}, nil
+	case zone.TLSA, zone.SMIMEA:
+		fields := strings.SplitN(value, "\t", 5)
+		if len(fields) != 4 {
+			return RecordValue{}, fmt.Errorf("invalid %s record", ty)
+		}
+		usage, err := strconv.ParseUint(fields[0], 10, 8)
+		if err != nil {
+			return RecordValue{}, fmt.Errorf("invalid %s record", ty)
+		}
+		selector, err := strconv.ParseUint(fields[1], 10, 8)
+		if err != nil {
+			return RecordValue{}, fmt.Errorf("invalid %s record", ty)
+		}
+		matchingType, err := strconv.ParseUint(fields[2], 10, 8)
+		if err != nil {
+			return RecordValue{}, fmt.Errorf("invalid %s record", ty)
+		}
+		v := RecordValue{
+			Usage:        uint8(usage),
+			Selector:     uint8(selector),
+			MatchingType: uint8(matchingType),
+			Certificate:  fields[3],
+		}
+		if !v.IsValidForType(recordType) {
+			return RecordValue{}, fmt.Errorf("invalid %s record", ty)
+		}
+		return v, nil
+	case zone.SSHFP:
+		fields := strings.SplitN(value, "\t", 4)
+		if len(fields) != 3 {
+			return RecordValue{}, errors.New("invalid SSHFP record")
+		}
+		algorithm, err := strconv.ParseUint(fields[0], 10, 8)
+		if err != nil {
+			return RecordValue{}, errors.New("invalid SSHFP record")
+		}
+		fingerprintType, err := strconv.ParseUint(fields[1], 10, 8)
+		if err != nil {
+			return RecordValue{}, errors.New("invalid SSHFP record")
+		}
+		v := RecordValue{
+			Algorithm:       uint8(algorithm),
+			FingerprintType: uint8(fingerprintType),
+			Fingerprint:     fields[2],
+		}
+		if !v.IsValidForType(recordType) {
+			return RecordValue{}, errors.New("invalid SSHFP record")
+		}
+		return v, nil
+	case zone.SVCB, zone.HTTPS:
+		fields := strings.SplitN(value, "\t", 4)
+		if len(fields) != 2 && len(fields) != 3 {
+			return RecordValue{}, fmt.Errorf("invalid %s record", ty)
+		}
+		priority, err := strconv.ParseUint(fields[0], 10, 16)
+		if err != nil {
+			return RecordValue{}, fmt.Errorf("invalid %s record", ty)
+		}
+		v := RecordValue{
+			Priority: int32(priority),
+			Target:   fields[1],
+		}
+		if len(fields) == 3 {
+			v.Params, err = parseSvcParams(fields[2])
+			if err != nil {
+				return RecordValue{}, fmt.Errorf("invalid %s record: %w", ty, err)
+			}
+		}
+		if !v.IsValidForType(recordType) {
+			return RecordValue{}, fmt.Errorf("invalid %s record", ty)
+		}
+		return v, nil
+	case zone.NAPTR:
+		fields := strings.Split(value, "\t")
+		if len(fields) != 6 {
+			return RecordValue{}, errors.New("invalid NAPTR record")
+		}
+		order, err := strconv.ParseUint(fields[0], 10, 16)
+		if err != nil {
+			return RecordValue{}, errors.New("invalid NAPTR record")
+		}
+		preference, err := strconv.ParseUint(fields[1], 10, 16)
+		if err != nil {
+			return RecordValue{}, errors.New("invalid NAPTR record")
+		}
+		v := RecordValue{
+			Order:       uint16(order),
+			Preference:  int32(preference),
+			NaptrFlags:  fields[2],
+			Service:     fields[3],
+			Regexp:      fields[4],
+			Replacement: fields[5],
+		}
+		if !v.IsValidForType(recordType) {
+			return RecordValue{}, errors.New("invalid NAPTR record")
+		}
+		return v, nil
+	case zone.URI:
+		fields := strings.SplitN(value, "\t", 4)
+		if len(fields) != 3 {
+			return RecordValue{}, errors.New("invalid URI record")
+		}
+		priority, err := strconv.ParseUint(fields[0], 10, 16)
+		if err != nil {
+			return RecordValue{}, errors.New("invalid URI record")
+		}
+		weight, err := strconv.ParseUint(fields[1], 10, 16)
+		if err != nil {
+			return RecordValue{}, errors.New("invalid URI record")
+		}
+		v := RecordValue{
+			Priority: int32(priority),
+			Weight:   int32(weight),
+			Target:   fields[2],
+		}
+		if !v.IsValidForType(recordType) {
+			return RecordValue{}, errors.New("invalid URI record")
+		}
+		return v, nil
+	case zone.LOC:
+		fields := strings.Split(value, "\t")
+		if len(fields) != 6 {
+			return RecordValue{}, errors.New("invalid LOC record")
+		}
+		var f [6]float64
+		for i := range fields {
+			var err error
+			f[i], err = strconv.ParseFloat(fields[i], 64)
+			if err != nil {
+				return RecordValue{}, errors.New("invalid LOC record")
+			}
+		}
+		v := RecordValue{
+			Latitude:            f[0],
+			Longitude:           f[1],
+			Altitude:            f[2],
+			Size:                f[3],
+			HorizontalPrecision: f[4],
+			VerticalPrecision:   f[5],
+		}
+		if !v.IsValidForType(recordType) {
+			return RecordValue{}, errors.New("invalid LOC record")
+		}
+		return v, nil
+	case zone.HINFO:
+		fields := strings.Split(value, "\t")
+		if len(fields) != 2 {
+			return RecordValue{}, errors.New("invalid HINFO record")
+		}
+		v := RecordValue{
+			Cpu: fields[0],
+			Os:  fields[1],
+		}
+		if !v.IsValidForType(recordType) {
+			return RecordValue{}, errors.New("invalid HINFO record")
+		}
+		return v, nil
+	case zone.DS:
+		fields := strings.SplitN(value, "\t", 5)
+		if len(fields) != 4 {
+			return RecordValue{}, errors.New("invalid DS record")
+		}
+		keyTag, err := strconv.ParseUint(fields[0], 10, 16)
+		if err != nil {
+			return RecordValue{}, errors.New("invalid DS record")
+		}
+		algorithm, err := strconv.ParseUint(fields[1], 10, 8)
+		if err != nil {
+			return RecordValue{}, errors.New("invalid DS record")
+		}
+		digestType, err := strconv.ParseUint(fields[2], 10, 8)
+		if err != nil {
+			return RecordValue{}, errors.New("invalid DS record")
+		}
+		v := RecordValue{
+			KeyTag:     uint16(keyTag),
+			Algorithm:  uint8(algorithm),
+			DigestType: uint8(digestType),
+			Digest:     fields[3],
+		}
+		if !v.IsValidForType(recordType) {
+			return RecordValue{}, errors.New("invalid DS record")
+		}
+		return v, nil
 	default:
 		return RecordValue{}, errors.New("invalid record type")
 	}
 }
+
+// isValidSshfpAlgorithm accepts RSA, DSA, ECDSA, Ed25519 and Ed448
+func isValidSshfpAlgorithm(algorithm uint8) bool {
+	return algorithm >= 1 && algorithm <= 6 && algorithm != 5
+}
+
+// isValidNaptrFlags only allows alphanumeric flags, see RFC 3403 section 4.1
+func isValidNaptrFlags(flags string) bool {
+	for _, c := range flags {
+		if (c < 'a' || c > 'z') && (c < 'A' || c > 'Z') && (c < '0' || c > '9') {
+			return false
+		}
+	}
+	return true
+}
+
+// isValidReplacement allows underscores as NAPTR replacements usually point at
+// SRV records
+func isValidReplacement(replacement string) bool {
+	_, ok := dns.IsDomainName(replacement)
+	return ok && !strings.ContainsAny(replacement, " \t")
+}
+
+func isValidUri(target string) bool {
+	u, err := url.Parse(target)
+	return err == nil && u.Scheme != "" && utils.ValidateCharacterString(target)
+}
+
+// isValidLocMetres checks the size and precision values fit in the RFC 1876
+// encoding of at most 9e9 centimetres
+func isValidLocMetres(m float64) bool {
+	return m >= 0 && m <= 90000000
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
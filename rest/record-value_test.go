@@ -0,0 +1,91 @@
+package rest
+
+import (
+	"net/netip"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseRecordValue(t *testing.T) {
+	for _, i := range []struct {
+		Type  string
+		Value RecordValue
+	}{
+		{"TLSA", RecordValue{Usage: 3, Selector: 1, MatchingType: 1, Certificate: strings.Repeat("0d", 32)}},
+		{"TLSA", RecordValue{Usage: 0, Selector: 0, MatchingType: 0, Certificate: "3082010a"}},
+		{"SMIMEA", RecordValue{Usage: 3, Selector: 0, MatchingType: 2, Certificate: strings.Repeat("ab", 64)}},
+		{"SSHFP", RecordValue{Algorithm: 4, FingerprintType: 2, Fingerprint: strings.Repeat("9a", 32)}},
+		{"SSHFP", RecordValue{Algorithm: 1, FingerprintType: 1, Fingerprint: strings.Repeat("9a", 20)}},
+		{"HTTPS", RecordValue{Priority: 0, Target: "cdn.example.com"}},
+		{"HTTPS", RecordValue{Priority: 1, Target: "."}},
+		{"HTTPS", RecordValue{Priority: 1, Target: ".", Params: &SvcParams{
+			Mandatory:     []string{"alpn", "ipv4hint"},
+			Alpn:          []string{"h2", "h3"},
+			NoDefaultAlpn: true,
+			Port:          8443,
+			Ipv4Hint:      []netip.Addr{netip.MustParseAddr("192.0.2.1"), netip.MustParseAddr("192.0.2.2")},
+			Ech:           "AEn+DQBFKwAgACABWIHUGj4u+PIggYXcR5JF0gYk3dCRioBW8uJq9H4mKAAIAAEAAQABAANAEnB1YmxpYy50bHMtZWNoLmRldgAA",
+			Ipv6Hint:      []netip.Addr{netip.MustParseAddr("2001:db8::1")},
+		}}},
+		{"SVCB", RecordValue{Priority: 16, Target: "svc.example.net", Params: &SvcParams{Port: 53}}},
+		{"NAPTR", RecordValue{Order: 100, Preference: 10, NaptrFlags: "U", Service: "E2U+sip", Regexp: "!^.*$!sip:info@example.com!", Replacement: "."}},
+		{"NAPTR", RecordValue{Order: 100, Preference: 20, NaptrFlags: "S", Service: "SIP+D2U", Replacement: "_sip._udp.example.com"}},
+		{"URI", RecordValue{Priority: 10, Weight: 1, Target: "ftp://ftp.example.com/public"}},
+		{"LOC", RecordValue{Latitude: 51.50344, Longitude: -0.12766, Altitude: 12.5, Size: 1, HorizontalPrecision: 10000, VerticalPrecision: 10}},
+		{"LOC", RecordValue{Latitude: -33.8568, Longitude: 151.2153}},
+		{"HINFO", RecordValue{Cpu: "x86_64", Os: "Linux 6.1"}},
+		{"CAA", RecordValue{Flags: 0, Tag: "issue", Value: "letsencrypt.org; validationmethods=dns-01"}},
+		{"CAA", RecordValue{Flags: 128, Tag: "iodef", Value: "https://example.com/caa-report"}},
+		{"ALIAS", RecordValue{Target: "cdn.example.net"}},
+	} {
+		t.Run(i.Type, func(t *testing.T) {
+			assert.True(t, i.Value.IsValidForType(i.Type))
+			v, err := ParseRecordValue(i.Type, i.Value.ToValueString(i.Type))
+			assert.NoError(t, err)
+			assert.Equal(t, i.Value, v)
+		})
+	}
+}
+
+func TestRecordValue_IsValidForType(t *testing.T) {
+	for _, i := range []struct {
+		Type  string
+		Value RecordValue
+	}{
+		{"TLSA", RecordValue{Usage: 4, Selector: 1, MatchingType: 1, Certificate: strings.Repeat("0d", 32)}},
+		{"TLSA", RecordValue{Usage: 3, Selector: 2, MatchingType: 1, Certificate: strings.Repeat("0d", 32)}},
+		{"TLSA", RecordValue{Usage: 3, Selector: 1, MatchingType: 1, Certificate: strings.Repeat("0d", 31)}},
+		{"TLSA", RecordValue{Usage: 3, Selector: 1, MatchingType: 0, Certificate: "xyz"}},
+		{"SMIMEA", RecordValue{Usage: 3, Selector: 0, MatchingType: 3, Certificate: "ab"}},
+		{"SSHFP", RecordValue{Algorithm: 5, FingerprintType: 2, Fingerprint: strings.Repeat("9a", 32)}},
+		{"SSHFP", RecordValue{Algorithm: 4, FingerprintType: 2, Fingerprint: strings.Repeat("9a", 20)}},
+		{"SSHFP", RecordValue{Algorithm: 4, FingerprintType: 0, Fingerprint: ""}},
+		{"HTTPS", RecordValue{Priority: 0, Target: "cdn.example.com", Params: &SvcParams{Port: 443}}},
+		{"HTTPS", RecordValue{Priority: 65536, Target: "."}},
+		{"HTTPS", RecordValue{Priority: 1, Target: ".", Params: &SvcParams{Mandatory: []string{"port"}}}},
+		{"HTTPS", RecordValue{Priority: 1, Target: ".", Params: &SvcParams{Mandatory: []string{"mandatory"}}}},
+		{"HTTPS", RecordValue{Priority: 1, Target: ".", Params: &SvcParams{NoDefaultAlpn: true}}},
+		{"HTTPS", RecordValue{Priority: 1, Target: ".", Params: &SvcParams{Alpn: []string{"h2,h3"}}}},
+		{"HTTPS", RecordValue{Priority: 1, Target: ".", Params: &SvcParams{Ipv4Hint: []netip.Addr{netip.MustParseAddr("2001:db8::1")}}}},
+		{"HTTPS", RecordValue{Priority: 1, Target: ".", Params: &SvcParams{Ech: "not base64!"}}},
+		{"NAPTR", RecordValue{Order: 100, Preference: 10, NaptrFlags: "U", Service: "E2U+sip", Regexp: "!^.*$!sip:info@example.com!", Replacement: "example.com"}},
+		{"NAPTR", RecordValue{Order: 100, Preference: 10, NaptrFlags: "U!", Service: "E2U+sip", Replacement: "example.com"}},
+		{"NAPTR", RecordValue{Order: 100, Preference: 10, NaptrFlags: "U", Service: "E2U\tsip", Replacement: "example.com"}},
+		{"NAPTR", RecordValue{Order: 100, Preference: 65536, Replacement: "."}},
+		{"URI", RecordValue{Priority: 10, Weight: 1, Target: "ftp.example.com"}},
+		{"URI", RecordValue{Priority: 10, Weight: 70000, Target: "https://example.com"}},
+		{"LOC", RecordValue{Latitude: 91}},
+		{"LOC", RecordValue{Longitude: -181}},
+		{"LOC", RecordValue{Altitude: -100001}},
+		{"LOC", RecordValue{Size: -1}},
+		{"HINFO", RecordValue{Cpu: "x86_64"}},
+		{"HINFO", RecordValue{Cpu: "x86_64", Os: "Linux\t6.1"}},
+		{"CAA", RecordValue{Flags: 1, Tag: "issue", Value: "letsencrypt.org"}},
+		{"CAA", RecordValue{Flags: 0, Tag: "contactemail", Value: "security@example.com"}},
+		{"ALIAS", RecordValue{Target: "not a domain"}},
+	} {
+		assert.False(t, i.Value.IsValidForType(i.Type), "%s %+v", i.Type, i.Value)
+	}
+}
@@ -3,7 +3,6 @@ package rest
 import (
 	"bytes"
 	"encoding/json"
-	"fmt"
 	"net/http"
 	"strconv"
 
@@ -11,27 +10,47 @@ import (
 )
 
 type Record struct {
-	ID     int64       `json:"id"`
-	Name   string      `json:"name"`
-	ZoneID int64       `json:"zone_id"`
-	Ttl    nulls.Int32 `json:"ttl"`
-	Type   string      `json:"type"`
-	Value  RecordValue `json:"value"`
-	Active bool        `json:"active"`
+	ID          int64       `json:"id"`
+	Name        string      `json:"name"`
+	NameUnicode string      `json:"name_unicode,omitempty"`
+	ZoneID      int64       `json:"zone_id"`
+	Ttl         nulls.Int32 `json:"ttl"`
+	Type        string      `json:"type"`
+	Value       RecordValue `json:"value"`
+	Active      bool        `json:"active"`
+	// AutoPTR keeps a PTR record for the address of an A or AAAA record in
+	// the reverse zone, when the reverse zone is managed by Verbena
+	AutoPTR bool `json:"auto_ptr,omitempty"`
+	// Views limits the record to the listed split-horizon views, a record
+	// without views is served in every view
+	Views []string `json:"views,omitempty"`
+	// Health is the status of the failover health check when the record has
+	// one, the values above are the primary values
+	Health *HealthStatus `json:"health,omitempty"`
+	// Version is read from the ETag header of GetZoneRecord and
+	// UpdateZoneRecord and is zero in lists
+	Version int64 `json:"-"`
 }
 
 type CreateRecord struct {
-	Name   string      `json:"name"`
-	Ttl    nulls.Int32 `json:"ttl"`
-	Type   string      `json:"type"`
-	Value  RecordValue `json:"value"`
-	Active bool        `json:"active"`
+	Name    string      `json:"name"`
+	Ttl     nulls.Int32 `json:"ttl"`
+	Type    string      `json:"type"`
+	Value   RecordValue `json:"value"`
+	Active  bool        `json:"active"`
+	AutoPTR bool        `json:"auto_ptr,omitempty"`
+	Views   []string    `json:"views,omitempty"`
 }
 
 type PutRecord struct {
-	Ttl    nulls.Int32 `json:"ttl"`
-	Value  RecordValue `json:"value"`
-	Active bool        `json:"active"`
+	Ttl     nulls.Int32 `json:"ttl"`
+	Value   RecordValue `json:"value"`
+	Active  bool        `json:"active"`
+	AutoPTR bool        `json:"auto_ptr,omitempty"`
+
+	// Views replaces the views of the record, nil keeps the existing views and
+	// an empty list publishes the record in every view
+	Views *[]string `json:"views,omitempty"`
 }
 
 func (c *Client) GetZoneRecords(zoneId int64) ([]Record, error) {
@@ -41,10 +60,6 @@ func (c *Client) GetZoneRecords(zoneId int64) ([]Record, error) {
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("unexpected status: %s", resp.Status)
-	}
-
 	var records []Record
 	err = json.NewDecoder(resp.Body).Decode(&records)
 	if err != nil {
@@ -60,15 +75,12 @@ func (c *Client) GetZoneRecord(zoneId, recordId int64) (Record, error) {
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return Record{}, fmt.Errorf("unexpected status: %s", resp.Status)
-	}
-
 	var record Record
 	err = json.NewDecoder(resp.Body).Decode(&record)
 	if err != nil {
 		return Record{}, err
 	}
+	record.Version = versionFromETag(resp)
 	return record, nil
 }
 
@@ -84,21 +96,19 @@ func (c *Client) CreateZoneRecord(zoneId int64, createRecord CreateRecord) error
 		return err
 	}
 	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("unexpected status: %s", resp.Status)
-	}
 	return nil
 }
 
-func (c *Client) UpdateZoneRecord(zoneId, recordId int64, putRecord PutRecord) (Record, error) {
+// UpdateZoneRecord replaces the record, when version is not zero the update
+// fails with ErrModified unless the record is still at that version
+func (c *Client) UpdateZoneRecord(zoneId, recordId, version int64, putRecord PutRecord) (Record, error) {
 	buf := new(bytes.Buffer)
 	err := json.NewEncoder(buf).Encode(putRecord)
 	if err != nil {
 		return Record{}, err
 	}
 
-	resp, err := doRequest(c, http.MethodPut, "/zones/"+strconv.FormatInt(zoneId, 10)+"/records/"+strconv.FormatInt(recordId, 10), buf)
+	resp, err := doRequestWithHeader(c, http.MethodPut, "/zones/"+strconv.FormatInt(zoneId, 10)+"/records/"+strconv.FormatInt(recordId, 10), buf, ifMatch(version))
 	if err != nil {
 		return Record{}, err
 	}
@@ -109,18 +119,17 @@ func (c *Client) UpdateZoneRecord(zoneId, recordId int64, putRecord PutRecord) (
 	if err != nil {
 		return Record{}, err
 	}
+	record.Version = versionFromETag(resp)
 	return record, nil
 }
 
-func (c *Client) DeleteZoneRecord(zoneId, recordId int64) error {
-	resp, err := doRequest(c, http.MethodDelete, "/zones/"+strconv.FormatInt(zoneId, 10)+"/records/"+strconv.FormatInt(recordId, 10), nil)
+// DeleteZoneRecord removes the record, when version is not zero the delete
+// fails with ErrModified unless the record is still at that version
+func (c *Client) DeleteZoneRecord(zoneId, recordId, version int64) error {
+	resp, err := doRequestWithHeader(c, http.MethodDelete, "/zones/"+strconv.FormatInt(zoneId, 10)+"/records/"+strconv.FormatInt(recordId, 10), nil, ifMatch(version))
 	if err != nil {
 		return err
 	}
 	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("unexpected status: %s", resp.Status)
-	}
 	return nil
 }
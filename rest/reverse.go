@@ -0,0 +1,111 @@
+package rest
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strconv"
+
+	"github.com/gobuffalo/nulls"
+)
+
+// CreateReverseZone creates the in-addr.arpa or ip6.arpa zones covering the
+// prefix, Admin is the SOA mailbox and defaults to hostmaster in the zone
+type CreateReverseZone struct {
+	Prefix netip.Prefix `json:"prefix"`
+	Admin  string       `json:"admin,omitempty"`
+}
+
+// ClasslessDelegation is an IPv4 block smaller than a /24 delegated from the
+// /24 reverse zone using the CNAME records from RFC 2317. Name is the label of
+// the delegation in the parent zone, for example "64-26".
+type ClasslessDelegation struct {
+	Name        string       `json:"name"`
+	Prefix      netip.Prefix `json:"prefix"`
+	Zone        string       `json:"zone"`
+	Nameservers []string     `json:"nameservers"`
+
+	// Automatic is set when the child zone is also a Verbena zone
+	Automatic bool `json:"automatic"`
+}
+
+// PutClasslessDelegation uses the Verbena nameservers of the child zone when
+// no nameservers are listed and the child is a Verbena zone
+type PutClasslessDelegation struct {
+	Ttl         nulls.Int32 `json:"ttl"`
+	Nameservers []string    `json:"nameservers"`
+}
+
+func (c *Client) CreateReverseZone(createZone CreateReverseZone) ([]Zone, error) {
+	buf := new(bytes.Buffer)
+	err := json.NewEncoder(buf).Encode(createZone)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := doRequest(c, http.MethodPost, "/zones/reverse", buf)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var zones []Zone
+	err = json.NewDecoder(resp.Body).Decode(&zones)
+	if err != nil {
+		return nil, err
+	}
+	return zones, nil
+}
+
+func classlessPath(zoneId int64) string {
+	return "/zones/" + strconv.FormatInt(zoneId, 10) + "/classless"
+}
+
+func (c *Client) GetZoneClasslessDelegations(zoneId int64) ([]ClasslessDelegation, error) {
+	resp, err := doRequest(c, http.MethodGet, classlessPath(zoneId), nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var delegations []ClasslessDelegation
+	err = json.NewDecoder(resp.Body).Decode(&delegations)
+	if err != nil {
+		return nil, err
+	}
+	return delegations, nil
+}
+
+// PutZoneClasslessDelegation creates or replaces the delegation of a block
+// from the /24 reverse zone, the name is the label such as "64-26"
+func (c *Client) PutZoneClasslessDelegation(zoneId int64, name string, putDelegation PutClasslessDelegation) (ClasslessDelegation, error) {
+	buf := new(bytes.Buffer)
+	err := json.NewEncoder(buf).Encode(putDelegation)
+	if err != nil {
+		return ClasslessDelegation{}, err
+	}
+
+	resp, err := doRequest(c, http.MethodPut, classlessPath(zoneId)+"/"+url.PathEscape(name), buf)
+	if err != nil {
+		return ClasslessDelegation{}, err
+	}
+	defer resp.Body.Close()
+
+	var delegation ClasslessDelegation
+	err = json.NewDecoder(resp.Body).Decode(&delegation)
+	if err != nil {
+		return ClasslessDelegation{}, err
+	}
+	return delegation, nil
+}
+
+func (c *Client) DeleteZoneClasslessDelegation(zoneId int64, name string) error {
+	resp, err := doRequest(c, http.MethodDelete, classlessPath(zoneId)+"/"+url.PathEscape(name), nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	return nil
+}
@@ -0,0 +1,84 @@
+package rest
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/gobuffalo/nulls"
+)
+
+// RRset is every record with the same name and type, the records share a
+// single TTL.
+type RRset struct {
+	Name        string        `json:"name"`
+	NameUnicode string        `json:"name_unicode,omitempty"`
+	Type        string        `json:"type"`
+	Ttl         nulls.Int32   `json:"ttl"`
+	Records     []RRsetRecord `json:"records"`
+}
+
+type RRsetRecord struct {
+	ID     int64       `json:"id,omitempty"`
+	Value  RecordValue `json:"value"`
+	Active bool        `json:"active"`
+}
+
+// PutRRset replaces the records of an RRset in every view, a set which has
+// records limited to views is refused.
+type PutRRset struct {
+	Ttl     nulls.Int32   `json:"ttl"`
+	Records []RRsetRecord `json:"records"`
+}
+
+func rrsetPath(zoneId int64, name, recordType string) string {
+	return "/zones/" + strconv.FormatInt(zoneId, 10) + "/rrsets/" + url.PathEscape(name) + "/" + url.PathEscape(recordType)
+}
+
+func (c *Client) GetZoneRRset(zoneId int64, name, recordType string) (RRset, error) {
+	resp, err := doRequest(c, http.MethodGet, rrsetPath(zoneId, name, recordType), nil)
+	if err != nil {
+		return RRset{}, err
+	}
+	defer resp.Body.Close()
+
+	var rrset RRset
+	err = json.NewDecoder(resp.Body).Decode(&rrset)
+	if err != nil {
+		return RRset{}, err
+	}
+	return rrset, nil
+}
+
+// PutZoneRRset atomically replaces every record of the name and type, the name
+// is relative to the zone
+func (c *Client) PutZoneRRset(zoneId int64, name, recordType string, putRRset PutRRset) (RRset, error) {
+	buf := new(bytes.Buffer)
+	err := json.NewEncoder(buf).Encode(putRRset)
+	if err != nil {
+		return RRset{}, err
+	}
+
+	resp, err := doRequest(c, http.MethodPut, rrsetPath(zoneId, name, recordType), buf)
+	if err != nil {
+		return RRset{}, err
+	}
+	defer resp.Body.Close()
+
+	var rrset RRset
+	err = json.NewDecoder(resp.Body).Decode(&rrset)
+	if err != nil {
+		return RRset{}, err
+	}
+	return rrset, nil
+}
+
+func (c *Client) DeleteZoneRRset(zoneId int64, name, recordType string) error {
+	resp, err := doRequest(c, http.MethodDelete, rrsetPath(zoneId, name, recordType), nil)
+	if err != nil {
+		return err
+	}
+	return resp.Body.Close()
+}
@@ -0,0 +1,168 @@
+package rest
+
+import (
+	"encoding/base64"
+	"errors"
+	"net/netip"
+	"strconv"
+	"strings"
+)
+
+// SvcParams are the service parameters of an SVCB or HTTPS record in
+// ServiceMode, see RFC 9460 section 7
+type SvcParams struct {
+	Mandatory     []string     `json:"mandatory,omitempty"`
+	Alpn          []string     `json:"alpn,omitempty"`
+	NoDefaultAlpn bool         `json:"no_default_alpn,omitempty"`
+	Port          uint16       `json:"port,omitempty"`
+	Ipv4Hint      []netip.Addr `json:"ipv4hint,omitempty"`
+	Ech           string       `json:"ech,omitempty"`
+	Ipv6Hint      []netip.Addr `json:"ipv6hint,omitempty"`
+}
+
+func (p *SvcParams) IsEmpty() bool {
+	return p == nil || p.String() == ""
+}
+
+func (p *SvcParams) has(key string) bool {
+	switch key {
+	case "mandatory":
+		return len(p.Mandatory) > 0
+	case "alpn":
+		return len(p.Alpn) > 0
+	case "no-default-alpn":
+		return p.NoDefaultAlpn
+	case "port":
+		return p.Port != 0
+	case "ipv4hint":
+		return len(p.Ipv4Hint) > 0
+	case "ech":
+		return p.Ech != ""
+	case "ipv6hint":
+		return len(p.Ipv6Hint) > 0
+	default:
+		return false
+	}
+}
+
+func (p *SvcParams) IsValid() bool {
+	for _, key := range p.Mandatory {
+		// mandatory must not list itself and every listed key must be present,
+		// unknown keys are never present
+		if key == "mandatory" || !p.has(key) {
+			return false
+		}
+	}
+	for _, alpn := range p.Alpn {
+		if alpn == "" || strings.ContainsAny(alpn, ", \t\"\\") {
+			return false
+		}
+	}
+	if p.NoDefaultAlpn && len(p.Alpn) == 0 {
+		return false
+	}
+	for _, ip := range p.Ipv4Hint {
+		if !ip.Is4() {
+			return false
+		}
+	}
+	for _, ip := range p.Ipv6Hint {
+		if !ip.Is6() || ip.Is4In6() {
+			return false
+		}
+	}
+	if p.Ech != "" {
+		_, err := base64.StdEncoding.DecodeString(p.Ech)
+		if err != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// String returns the SvcParams in presentation format
+func (p *SvcParams) String() string {
+	if p == nil {
+		return ""
+	}
+	var params []string
+	if len(p.Mandatory) > 0 {
+		params = append(params, "mandatory="+strings.Join(p.Mandatory, ","))
+	}
+	if len(p.Alpn) > 0 {
+		params = append(params, "alpn="+strings.Join(p.Alpn, ","))
+	}
+	if p.NoDefaultAlpn {
+		params = append(params, "no-default-alpn")
+	}
+	if p.Port != 0 {
+		params = append(params, "port="+strconv.FormatUint(uint64(p.Port), 10))
+	}
+	if len(p.Ipv4Hint) > 0 {
+		params = append(params, "ipv4hint="+joinAddrs(p.Ipv4Hint))
+	}
+	if p.Ech != "" {
+		params = append(params, "ech="+p.Ech)
+	}
+	if len(p.Ipv6Hint) > 0 {
+		params = append(params, "ipv6hint="+joinAddrs(p.Ipv6Hint))
+	}
+	return strings.Join(params, " ")
+}
+
+func parseSvcParams(value string) (*SvcParams, error) {
+	p := &SvcParams{}
+	for _, param := range strings.Fields(value) {
+		key, val, _ := strings.Cut(param, "=")
+		switch key {
+		case "mandatory":
+			p.Mandatory = strings.Split(val, ",")
+		case "alpn":
+			p.Alpn = strings.Split(val, ",")
+		case "no-default-alpn":
+			p.NoDefaultAlpn = true
+		case "port":
+			port, err := strconv.ParseUint(val, 10, 16)
+			if err != nil {
+				return nil, errors.New("invalid port")
+			}
+			p.Port = uint16(port)
+		case "ipv4hint", "ipv6hint":
+			addrs, err := parseAddrs(val)
+			if err != nil {
+				return nil, err
+			}
+			if key == "ipv4hint" {
+				p.Ipv4Hint = addrs
+			} else {
+				p.Ipv6Hint = addrs
+			}
+		case "ech":
+			p.Ech = val
+		default:
+			return nil, errors.New("unsupported SvcParam: " + key)
+		}
+	}
+	return p, nil
+}
+
+func joinAddrs(addrs []netip.Addr) string {
+	s := make([]string, len(addrs))
+	for i, addr := range addrs {
+		s[i] = addr.String()
+	}
+	return strings.Join(s, ",")
+}
+
+func parseAddrs(value string) ([]netip.Addr, error) {
+	parts := strings.Split(value, ",")
+	addrs := make([]netip.Addr, 0, len(parts))
+	for _, part := range parts {
+		addr, err := netip.ParseAddr(part)
+		if err != nil {
+			return nil, err
+		}
+		addrs = append(addrs, addr)
+	}
+	return addrs, nil
+}
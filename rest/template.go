@@ -0,0 +1,66 @@
+package rest
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+type Template struct {
+	Name      string   `json:"name"`
+	Variables []string `json:"variables"`
+}
+
+type ApplyTemplate struct {
+	Variables map[string]string `json:"variables"`
+}
+
+type TemplateChange struct {
+	Action string `json:"action"`
+	Record Record `json:"record"`
+}
+
+func (c *Client) GetTemplates() ([]Template, error) {
+	resp, err := doRequest(c, http.MethodGet, "/templates", nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var templates []Template
+	err = json.NewDecoder(resp.Body).Decode(&templates)
+	if err != nil {
+		return nil, err
+	}
+	return templates, nil
+}
+
+// ApplyZoneTemplate stages the template records in the zone, records which have
+// drifted from the template are updated. When dryRun is set the changes are
+// returned without being applied.
+func (c *Client) ApplyZoneTemplate(zoneId int64, templateName string, variables map[string]string, dryRun bool) ([]TemplateChange, error) {
+	buf := new(bytes.Buffer)
+	err := json.NewEncoder(buf).Encode(ApplyTemplate{Variables: variables})
+	if err != nil {
+		return nil, err
+	}
+
+	p := "/zones/" + strconv.FormatInt(zoneId, 10) + "/templates/" + url.PathEscape(templateName) + "/apply"
+	if dryRun {
+		p += "?dry_run=true"
+	}
+	resp, err := doRequest(c, http.MethodPost, p, buf)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var changes []TemplateChange
+	err = json.NewDecoder(resp.Body).Decode(&changes)
+	if err != nil {
+		return nil, err
+	}
+	return changes, nil
+}
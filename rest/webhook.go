@@ -0,0 +1,125 @@
+package rest
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+type Webhook struct {
+	ID     int64    `json:"id"`
+	ZoneID int64    `json:"zone_id"`
+	Url    string   `json:"url"`
+	Events []string `json:"events"`
+	Active bool     `json:"active"`
+}
+
+type CreateWebhook struct {
+	Url    string   `json:"url"`
+	Secret string   `json:"secret"`
+	Events []string `json:"events"`
+	Active bool     `json:"active"`
+}
+
+type PutWebhook struct {
+	Url    string   `json:"url"`
+	Events []string `json:"events"`
+	Active bool     `json:"active"`
+}
+
+type WebhookDelivery struct {
+	ID         int64           `json:"id"`
+	Event      string          `json:"event"`
+	Payload    json.RawMessage `json:"payload"`
+	Attempt    int32           `json:"attempt"`
+	StatusCode int32           `json:"status_code"`
+	Error      string          `json:"error,omitempty"`
+	Success    bool            `json:"success"`
+	Time       time.Time       `json:"time"`
+}
+
+func webhooksPath(zoneId int64) string {
+	return "/zones/" + strconv.FormatInt(zoneId, 10) + "/webhooks"
+}
+
+func (c *Client) GetZoneWebhooks(zoneId int64) ([]Webhook, error) {
+	resp, err := doRequest(c, http.MethodGet, webhooksPath(zoneId), nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var webhooks []Webhook
+	err = json.NewDecoder(resp.Body).Decode(&webhooks)
+	if err != nil {
+		return nil, err
+	}
+	return webhooks, nil
+}
+
+func (c *Client) CreateZoneWebhook(zoneId int64, createWebhook CreateWebhook) (Webhook, error) {
+	buf := new(bytes.Buffer)
+	err := json.NewEncoder(buf).Encode(createWebhook)
+	if err != nil {
+		return Webhook{}, err
+	}
+
+	resp, err := doRequest(c, http.MethodPost, webhooksPath(zoneId), buf)
+	if err != nil {
+		return Webhook{}, err
+	}
+	defer resp.Body.Close()
+
+	var webhook Webhook
+	err = json.NewDecoder(resp.Body).Decode(&webhook)
+	if err != nil {
+		return Webhook{}, err
+	}
+	return webhook, nil
+}
+
+func (c *Client) UpdateZoneWebhook(zoneId, webhookId int64, putWebhook PutWebhook) (Webhook, error) {
+	buf := new(bytes.Buffer)
+	err := json.NewEncoder(buf).Encode(putWebhook)
+	if err != nil {
+		return Webhook{}, err
+	}
+
+	resp, err := doRequest(c, http.MethodPut, webhooksPath(zoneId)+"/"+strconv.FormatInt(webhookId, 10), buf)
+	if err != nil {
+		return Webhook{}, err
+	}
+	defer resp.Body.Close()
+
+	var webhook Webhook
+	err = json.NewDecoder(resp.Body).Decode(&webhook)
+	if err != nil {
+		return Webhook{}, err
+	}
+	return webhook, nil
+}
+
+func (c *Client) DeleteZoneWebhook(zoneId, webhookId int64) error {
+	resp, err := doRequest(c, http.MethodDelete, webhooksPath(zoneId)+"/"+strconv.FormatInt(webhookId, 10), nil)
+	if err != nil {
+		return err
+	}
+	return resp.Body.Close()
+}
+
+func (c *Client) GetWebhookDeliveries(zoneId, webhookId int64) ([]WebhookDelivery, error) {
+	resp, err := doRequest(c, http.MethodGet, webhooksPath(zoneId)+"/"+strconv.FormatInt(webhookId, 10)+"/deliveries", nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var deliveries []WebhookDelivery
+	err = json.NewDecoder(resp.Body).Decode(&deliveries)
+	if err != nil {
+		return nil, err
+	}
+	return deliveries, nil
+}
@@ -1,26 +1,40 @@
 package rest
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"net/http"
 	"strconv"
 
+	"github.com/1f349/verbena/internal/utils"
 	"github.com/miekg/dns"
 )
 
 type Zone struct {
-	ID      int64  `json:"id"`
-	Name    string `json:"name"`
-	Serial  uint32 `json:"serial"`
-	Admin   string `json:"admin"`
-	Refresh int32  `json:"refresh"`
-	Retry   int32  `json:"retry"`
-	Expire  int32  `json:"expire"`
-	Ttl     int32  `json:"ttl"`
-	Active  bool   `json:"active"`
+	ID          int64  `json:"id"`
+	Name        string `json:"name"`
+	NameUnicode string `json:"name_unicode,omitempty"`
+	Serial      uint32 `json:"serial"`
+	Admin       string `json:"admin"`
+	Refresh     int32  `json:"refresh"`
+	Retry       int32  `json:"retry"`
+	Expire      int32  `json:"expire"`
+	Ttl         int32  `json:"ttl"`
+	Active      bool   `json:"active"`
 
 	Nameservers []string `json:"nameservers"`
+
+	// Version is read from the ETag header of GetZone and is zero in lists,
+	// pass it to UpdateZone to only update an unchanged zone
+	Version int64 `json:"-"`
+}
+
+type PutZone struct {
+	Refresh int32 `json:"refresh"`
+	Retry   int32 `json:"retry"`
+	Expire  int32 `json:"expire"`
+	Ttl     int32 `json:"ttl"`
 }
 
 func (c *Client) GetZones() ([]Zone, error) {
@@ -50,14 +64,40 @@ func (c *Client) GetZone(zoneId int64) (Zone, error) {
 	if err != nil {
 		return Zone{}, err
 	}
+	zone.Version = versionFromETag(resp)
 	return zone, nil
 }
 
+// UpdateZone changes the SOA timers of the zone, when version is not zero the
+// update fails with ErrModified unless the zone is still at that version. The
+// new version is returned.
+func (c *Client) UpdateZone(zoneId, version int64, putZone PutZone) (int64, error) {
+	buf := new(bytes.Buffer)
+	err := json.NewEncoder(buf).Encode(putZone)
+	if err != nil {
+		return 0, err
+	}
+
+	resp, err := doRequestWithHeader(c, http.MethodPut, "/zones/"+strconv.FormatInt(zoneId, 10), buf, ifMatch(version))
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	return versionFromETag(resp), nil
+}
+
+// LookupZone finds the ID of a zone, Unicode zone names are converted to
+// punycode before the lookup
 func (c *Client) LookupZone(zoneName string) (int64, error) {
-	_, validDomain := dns.IsDomainName(zoneName)
+	asciiName, err := utils.ToASCIIName(zoneName)
+	if err != nil {
+		return 0, fmt.Errorf("invalid zone: %s: %w", zoneName, err)
+	}
+	_, validDomain := dns.IsDomainName(asciiName)
 	if !validDomain {
 		return 0, fmt.Errorf("invalid zone: %s", zoneName)
 	}
+	zoneName = asciiName
 
 	resp, err := doRequest(c, http.MethodGet, "/zones/lookup/"+zoneName, nil)
 	if err != nil {